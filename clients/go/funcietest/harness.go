@@ -0,0 +1,396 @@
+// Package funcietest runs the full funcie request path within a single process, so that the routing between a
+// deployed and a local handler can be tested without a Lambda, Redis, or running bastions.
+//
+// A Harness wires together the Lambda proxy, the server bastion, the memory transport, the client bastion, and a
+// bastion receiver for the local handler. Invoking the harness behaves like an invocation from the Lambda runtime,
+// with the result indicating whether the local or deployed handler served the request.
+package funcietest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/clients/go/funcietunnel"
+	clientbastion "github.com/Kapps/funcie/cmd/client-bastion/bastion"
+	serverbastion "github.com/Kapps/funcie/cmd/server-bastion/bastion"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"github.com/Kapps/funcie/pkg/funcie/transports"
+	"github.com/Kapps/funcie/pkg/funcie/transports/memory"
+	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
+	"github.com/Kapps/funcie/pkg/receiver"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startTimeout is how long to wait for the local receiver to be registered with the client bastion.
+const startTimeout = 10 * time.Second
+
+// Path is the handler that served an invocation.
+type Path string
+
+const (
+	// PathNone means that neither handler served the invocation, such as when the request was not proxied.
+	PathNone Path = ""
+	// PathLocal means that the local handler, reached through the bastions, served the invocation.
+	PathLocal Path = "local"
+	// PathDeployed means that the deployed handler, running in the Lambda itself, served the invocation.
+	PathDeployed Path = "deployed"
+)
+
+// Options are the options for a Harness.
+type Options struct {
+	// ProxyOptions are the options for the Lambda proxy, such as its fallback policy.
+	ProxyOptions []funcietunnel.ProxyOptionSetter
+	// ReceiverOptions are the options for the receiver of the local handler, such as its concurrency limit.
+	ReceiverOptions []funcietunnel.ReceiverOptionSetter
+	// Logger is the logger used by every component of the harness.
+	Logger *slog.Logger
+}
+
+// OptionSetter sets an option for a Harness.
+type OptionSetter func(*Options)
+
+// WithProxyOptions sets the options for the Lambda proxy.
+func WithProxyOptions(opts ...funcietunnel.ProxyOptionSetter) OptionSetter {
+	return func(options *Options) {
+		options.ProxyOptions = append(options.ProxyOptions, opts...)
+	}
+}
+
+// WithReceiverOptions sets the options for the receiver of the local handler.
+func WithReceiverOptions(opts ...funcietunnel.ReceiverOptionSetter) OptionSetter {
+	return func(options *Options) {
+		options.ReceiverOptions = append(options.ReceiverOptions, opts...)
+	}
+}
+
+// WithLogger sets the logger used by every component of the harness.
+func WithLogger(logger *slog.Logger) OptionSetter {
+	return func(options *Options) {
+		options.Logger = logger
+	}
+}
+
+// Invocation is the result of invoking the harness.
+type Invocation struct {
+	// Response is the serialized response returned to the Lambda runtime, or nil if the invocation failed.
+	Response []byte
+	// Err is the error returned to the Lambda runtime.
+	Err error
+	// ServedBy is the handler whose result was returned to the Lambda runtime.
+	ServedBy Path
+	// LocalInvoked indicates that the local handler was invoked, even if its result was not used.
+	LocalInvoked bool
+	// DeployedInvoked indicates that the deployed handler was invoked.
+	DeployedInvoked bool
+}
+
+// Unmarshal unmarshals the response of the invocation into the given value.
+func (i *Invocation) Unmarshal(v any) error {
+	if i.Err != nil {
+		return fmt.Errorf("invocation failed: %w", i.Err)
+	}
+	return json.Unmarshal(i.Response, v)
+}
+
+// RequireServedBy fails the test if the invocation was not served by the given path.
+func (i *Invocation) RequireServedBy(t testing.TB, path Path) {
+	t.Helper()
+	require.Equal(t, path, i.ServedBy, "unexpected handler served the invocation (error: %v)", i.Err)
+}
+
+// Harness runs the full path of a funcie request in-process for a single application.
+type Harness struct {
+	t             testing.TB
+	applicationId string
+	opts          Options
+
+	consumer        funcie.Consumer
+	stopConsuming   context.CancelFunc
+	registry        funcie.ApplicationRegistry
+	shadowReporter  clientbastion.ShadowReporter
+	serverHost      transports.Host
+	clientHost      transports.Host
+	serverEndpoint  url.URL
+	clientEndpoint  url.URL
+	localHandler    interface{}
+	receiver        funcietunnel.BastionReceiver
+	receiverStopped chan struct{}
+	proxy           lambda.Handler
+
+	// invokeLock ensures only one invocation runs at a time, so that the handlers that ran belong to that invocation.
+	invokeLock    sync.Mutex
+	stateLock     sync.Mutex
+	localCalls    int
+	deployedCalls int
+}
+
+// New creates and starts a Harness for the given application, which is stopped when the test completes.
+// The local handler is what would run on a developer's machine, while the deployed handler is what would run in the
+// Lambda. Both have the same restrictions as the handler for lambda.Start, except that InitFuncs are not supported.
+// The local handler starts registered, as if it were being debugged; see StopLocal to test the Lambda without it.
+func New(t testing.TB, applicationId string, local interface{}, deployed interface{}, opts ...OptionSetter) *Harness {
+	t.Helper()
+
+	options := Options{
+		Logger: slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	h := &Harness{
+		t:             t,
+		applicationId: applicationId,
+		opts:          options,
+		registry:      receiver.NewMemoryApplicationRegistry(),
+	}
+	h.localHandler = h.recordingHandler(local, &h.localCalls)
+
+	bus := memory.NewBus()
+	h.consumer = memory.NewConsumer(bus, utils.NewClientHandlerRouter())
+	require.NoError(t, h.consumer.Connect(context.Background()))
+
+	fallbackPolicies := receiver.NewMemoryFallbackPolicyStore()
+
+	shadowReporter, err := clientbastion.NewShadowReporter(clientbastion.NewConfig())
+	require.NoError(t, err)
+	h.shadowReporter = shadowReporter
+
+	serverHandler := serverbastion.NewRequestHandler(memory.NewPublisher(bus), fallbackPolicies)
+	h.serverHost, h.serverEndpoint = newLocalHost(t, transports.NewMessageProcessor(serverHandler))
+
+	clientHandler := clientbastion.NewHandler(
+		h.registry,
+		clientbastion.NewHTTPApplicationClient(&http.Client{}),
+		h.consumer,
+		clientbastion.NewPassthroughHostTranslator(),
+		fallbackPolicies,
+		shadowReporter,
+	)
+	h.clientHost, h.clientEndpoint = newLocalHost(t, transports.NewMessageProcessor(clientHandler))
+
+	consumeCtx, stopConsuming := context.WithCancel(context.Background())
+	h.stopConsuming = stopConsuming
+	go func() {
+		_ = h.consumer.Consume(consumeCtx)
+	}()
+
+	h.startHost(h.serverHost, h.serverEndpoint)
+	h.startHost(h.clientHost, h.clientEndpoint)
+
+	client := funcietunnel.NewHTTPBastionClient(*h.serverEndpoint.JoinPath("dispatch"), options.Logger)
+	deployedHandler := h.recordingHandler(deployed, &h.deployedCalls)
+	h.proxy = funcietunnel.NewLambdaHandler(applicationId, client, deployedHandler, options.Logger, options.ProxyOptions...)
+
+	h.StartLocal()
+
+	t.Cleanup(h.close)
+	return h
+}
+
+// ServerBastionEndpoint returns the endpoint of the server bastion, which the Lambda proxy sends requests to.
+func (h *Harness) ServerBastionEndpoint() url.URL {
+	return h.serverEndpoint
+}
+
+// ClientBastionEndpoint returns the endpoint of the client bastion, which the local handler is registered with.
+func (h *Harness) ClientBastionEndpoint() url.URL {
+	return h.clientEndpoint
+}
+
+// Invoke invokes the Lambda proxy with the given event as if from the Lambda runtime.
+// The event is serialized to JSON, unless it is already a []byte or json.RawMessage.
+func (h *Harness) Invoke(ctx context.Context, event any) *Invocation {
+	h.t.Helper()
+
+	var payload []byte
+	switch e := event.(type) {
+	case []byte:
+		payload = e
+	case json.RawMessage:
+		payload = e
+	default:
+		marshaled, err := json.Marshal(event)
+		require.NoError(h.t, err, "failed to marshal event")
+		payload = marshaled
+	}
+
+	h.invokeLock.Lock()
+	defer h.invokeLock.Unlock()
+
+	localBefore, deployedBefore := h.calls()
+	response, err := h.proxy.Invoke(ctx, payload)
+	localAfter, deployedAfter := h.calls()
+
+	invocation := &Invocation{
+		Err:             err,
+		LocalInvoked:    localAfter > localBefore,
+		DeployedInvoked: deployedAfter > deployedBefore,
+	}
+	if err == nil {
+		// The proxy reuses its output buffer between invocations.
+		invocation.Response = bytes.Clone(response)
+	}
+
+	switch {
+	case invocation.DeployedInvoked:
+		// Either the request was not proxied, the local handler failed and the policy fell back, or shadow mode
+		// compared the local result with the deployed one; in every case the deployed result is what was returned.
+		invocation.ServedBy = PathDeployed
+	case invocation.LocalInvoked:
+		invocation.ServedBy = PathLocal
+	}
+
+	return invocation
+}
+
+// StartLocal registers the local handler with the client bastion, as if it had been started in a debugger.
+func (h *Harness) StartLocal() {
+	h.t.Helper()
+
+	h.stateLock.Lock()
+	if h.receiver != nil {
+		h.stateLock.Unlock()
+		return
+	}
+	h.receiver = funcietunnel.NewLambdaBastionReceiver(
+		h.applicationId,
+		"127.0.0.1:0",
+		h.clientEndpoint,
+		h.localHandler,
+		h.opts.Logger,
+		h.opts.ReceiverOptions...,
+	)
+	h.receiverStopped = make(chan struct{})
+	receiver, stopped := h.receiver, h.receiverStopped
+	h.stateLock.Unlock()
+
+	go func() {
+		defer close(stopped)
+		receiver.Start()
+	}()
+
+	require.Eventually(h.t, func() bool {
+		_, err := h.registry.GetApplication(context.Background(), h.applicationId)
+		return err == nil
+	}, startTimeout, 10*time.Millisecond, "local handler was not registered with the client bastion")
+}
+
+// StopLocal deregisters the local handler from the client bastion, as if the developer had stopped debugging.
+func (h *Harness) StopLocal() {
+	h.t.Helper()
+
+	h.stateLock.Lock()
+	receiver, stopped := h.receiver, h.receiverStopped
+	h.receiver = nil
+	h.stateLock.Unlock()
+
+	if receiver == nil {
+		return
+	}
+
+	receiver.Stop()
+	<-stopped
+}
+
+// SetFallbackPolicy overrides the fallback policy of the application through the client bastion, as the CLI would.
+func (h *Harness) SetFallbackPolicy(ctx context.Context, policy funcie.FallbackPolicy) error {
+	return funcietunnel.SetFallbackPolicy(ctx, h.clientEndpoint, h.applicationId, policy)
+}
+
+// SubscribeShadow returns a channel that receives the comparisons made by the client bastion for requests in shadow
+// mode, and a function to unsubscribe.
+func (h *Harness) SubscribeShadow() (<-chan *messages.ShadowDiff, func()) {
+	return h.shadowReporter.Subscribe()
+}
+
+func (h *Harness) calls() (local int, deployed int) {
+	h.stateLock.Lock()
+	defer h.stateLock.Unlock()
+	return h.localCalls, h.deployedCalls
+}
+
+// recordingHandler wraps the given handler to count its invocations in the given counter.
+// Functions are wrapped with one of the same signature, so that their events are decoded and their responses are
+// buffered or streamed exactly as they would be for the handler itself.
+func (h *Harness) recordingHandler(handler interface{}, counter *int) interface{} {
+	record := func() {
+		h.stateLock.Lock()
+		defer h.stateLock.Unlock()
+		*counter++
+	}
+
+	if lambdaHandler, ok := handler.(lambda.Handler); ok {
+		return recordingLambdaHandler{handler: lambdaHandler, record: record}
+	}
+
+	value := reflect.ValueOf(handler)
+	if value.Kind() != reflect.Func {
+		// Not a valid handler, which is reported when it is invoked.
+		return handler
+	}
+
+	return reflect.MakeFunc(value.Type(), func(args []reflect.Value) []reflect.Value {
+		record()
+		return value.Call(args)
+	}).Interface()
+}
+
+// recordingLambdaHandler is a lambda.Handler that records each of its invocations.
+type recordingLambdaHandler struct {
+	handler lambda.Handler
+	record  func()
+}
+
+func (r recordingLambdaHandler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	r.record()
+	return r.handler.Invoke(ctx, payload)
+}
+
+func (h *Harness) startHost(host transports.Host, endpoint url.URL) {
+	go func() {
+		err := host.Listen(context.Background())
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			h.opts.Logger.Error("host closed", "error", err, "endpoint", endpoint.String())
+		}
+	}()
+}
+
+func (h *Harness) close() {
+	h.StopLocal()
+
+	ctx := context.Background()
+	if err := h.clientHost.Close(ctx); err != nil {
+		h.opts.Logger.Warn("failed to close client bastion", "error", err)
+	}
+	if err := h.serverHost.Close(ctx); err != nil {
+		h.opts.Logger.Warn("failed to close server bastion", "error", err)
+	}
+	h.stopConsuming()
+	_ = h.shadowReporter.Close()
+}
+
+// newLocalHost creates a host listening on a random local port, returning it along with its endpoint.
+// The host is accepting connections as soon as it is created, even before Listen is called.
+func newLocalHost(t testing.TB, processor transports.MessageProcessor) (transports.Host, url.URL) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err, "failed to listen on a local port")
+
+	address := listener.Addr().String()
+	host := transports.NewHost(address, processor, transports.WithListener(listener))
+	return host, url.URL{Scheme: "http", Host: address}
+}
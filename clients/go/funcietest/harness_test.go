@@ -0,0 +1,154 @@
+package funcietest_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Kapps/funcie/clients/go/funcietest"
+	"github.com/Kapps/funcie/clients/go/funcietunnel"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type greeting struct {
+	Name    string   `json:"name"`
+	Tags    []string `json:"tags"`
+	Handler string   `json:"handler"`
+}
+
+func newGreeter(handlerName string) func(ctx context.Context, event greeting) (greeting, error) {
+	return func(ctx context.Context, event greeting) (greeting, error) {
+		event.Handler = handlerName
+		return event, nil
+	}
+}
+
+func TestHarness_Integration(t *testing.T) {
+	ctx := context.Background()
+	event := greeting{Name: "funcie", Tags: []string{"a", "b"}}
+
+	t.Run("should serve from the local handler while registered", func(t *testing.T) {
+		h := funcietest.New(t, "app", newGreeter("local"), newGreeter("deployed"))
+
+		invocation := h.Invoke(ctx, event)
+		require.NoError(t, invocation.Err)
+		invocation.RequireServedBy(t, funcietest.PathLocal)
+
+		var resp greeting
+		require.NoError(t, invocation.Unmarshal(&resp))
+		require.Equal(t, greeting{Name: "funcie", Tags: []string{"a", "b"}, Handler: "local"}, resp)
+	})
+
+	t.Run("should fall back to the deployed handler once the local handler stops", func(t *testing.T) {
+		h := funcietest.New(t, "app", newGreeter("local"), newGreeter("deployed"))
+		h.StopLocal()
+
+		invocation := h.Invoke(ctx, event)
+		require.NoError(t, invocation.Err)
+		invocation.RequireServedBy(t, funcietest.PathDeployed)
+		require.False(t, invocation.LocalInvoked)
+
+		h.StartLocal()
+		h.Invoke(ctx, event).RequireServedBy(t, funcietest.PathLocal)
+	})
+
+	t.Run("should not fall back when the policy forbids it", func(t *testing.T) {
+		h := funcietest.New(t, "app", newGreeter("local"), newGreeter("deployed"),
+			funcietest.WithProxyOptions(funcietunnel.WithFallbackPolicy(funcie.FallbackPolicyNever)))
+		h.StopLocal()
+
+		invocation := h.Invoke(ctx, event)
+		require.ErrorIs(t, invocation.Err, funcietunnel.ErrNotProxied)
+		invocation.RequireServedBy(t, funcietest.PathNone)
+	})
+
+	t.Run("should apply fallback policy overrides from the client bastion", func(t *testing.T) {
+		failing := func(ctx context.Context, event greeting) (greeting, error) {
+			return greeting{}, errors.New("local failure")
+		}
+		h := funcietest.New(t, "app", failing, newGreeter("deployed"))
+
+		invocation := h.Invoke(ctx, event)
+		require.ErrorContains(t, invocation.Err, "local failure")
+		invocation.RequireServedBy(t, funcietest.PathLocal)
+
+		require.NoError(t, h.SetFallbackPolicy(ctx, funcie.FallbackPolicyOnError))
+
+		invocation = h.Invoke(ctx, event)
+		require.NoError(t, invocation.Err)
+		require.True(t, invocation.LocalInvoked)
+		invocation.RequireServedBy(t, funcietest.PathDeployed)
+	})
+
+	t.Run("should return values from the local handler as buffered responses", func(t *testing.T) {
+		logs := &lockedBuffer{}
+		logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		h := funcietest.New(t, "app", newGreeter("local"), newGreeter("deployed"), funcietest.WithLogger(logger))
+
+		invocation := h.Invoke(ctx, event)
+		require.NoError(t, invocation.Err)
+		invocation.RequireServedBy(t, funcietest.PathLocal)
+
+		require.Contains(t, logs.String(), `msg="received response"`)
+		require.NotContains(t, logs.String(), "received streamed response")
+	})
+
+	t.Run("should stream readers from the local handler", func(t *testing.T) {
+		streaming := func(ctx context.Context, event greeting) (io.Reader, error) {
+			return strings.NewReader("streamed " + event.Name), nil
+		}
+		logs := &lockedBuffer{}
+		logger := slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		h := funcietest.New(t, "app", streaming, newGreeter("deployed"), funcietest.WithLogger(logger))
+
+		invocation := h.Invoke(ctx, event)
+		require.NoError(t, invocation.Err)
+		invocation.RequireServedBy(t, funcietest.PathLocal)
+		require.Equal(t, "streamed funcie", string(invocation.Response))
+		require.Contains(t, logs.String(), "received streamed response")
+	})
+
+	t.Run("should compare both handlers in shadow mode", func(t *testing.T) {
+		h := funcietest.New(t, "app", newGreeter("local"), newGreeter("deployed"),
+			funcietest.WithProxyOptions(funcietunnel.WithShadowMode(true, time.Second)))
+
+		diffs, unsubscribe := h.SubscribeShadow()
+		defer unsubscribe()
+
+		invocation := h.Invoke(ctx, event)
+		require.NoError(t, invocation.Err)
+		require.True(t, invocation.LocalInvoked)
+		invocation.RequireServedBy(t, funcietest.PathDeployed)
+
+		select {
+		case diff := <-diffs:
+			require.Equal(t, "app", diff.Application)
+			require.False(t, diff.Matched())
+		case <-time.After(5 * time.Second):
+			t.Fatal("no shadow comparison was reported")
+		}
+	})
+}
+
+// lockedBuffer is a buffer that may be written to by the harness while being read from the test.
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,154 @@
+package funcietunnel
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the reason given when a request is not sent to the bastion because the circuit breaker is open.
+var ErrCircuitOpen = errors.New("the bastion circuit breaker is open after repeated failures")
+
+// CircuitState is the state of the circuit breaker between a Lambda proxy and the bastion.
+type CircuitState string
+
+const (
+	// CircuitClosed sends every request to the bastion.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen skips the bastion entirely until the backoff window has passed.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen sends a single probe request to the bastion to decide whether to close the circuit again.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreakerOptions are the options for the circuit breaker of a Lambda proxy.
+type CircuitBreakerOptions struct {
+	// Threshold is the number of consecutive unavailability errors that open the circuit, or 0 to disable the breaker.
+	Threshold int
+	// Backoff is how long the circuit stays open before the bastion is probed again.
+	Backoff time.Duration
+	// MaxBackoff is the limit for the backoff, which doubles each time a probe fails.
+	MaxBackoff time.Duration
+}
+
+// DefaultCircuitBreakerOptions returns the circuit breaker options used when none are configured.
+// The maximum backoff is kept short, as it is also how long someone may wait for requests once they start debugging.
+func DefaultCircuitBreakerOptions() CircuitBreakerOptions {
+	return CircuitBreakerOptions{
+		Threshold:  3,
+		Backoff:    10 * time.Second,
+		MaxBackoff: time.Minute,
+	}
+}
+
+// circuitBreaker tracks the availability of the bastion across the warm invocations of a Lambda, so that requests
+// skip the bastion while it is unavailable rather than each paying for a failed attempt.
+type circuitBreaker struct {
+	opts     CircuitBreakerOptions
+	logger   *slog.Logger
+	now      func() time.Time
+	state    CircuitState
+	failures int
+	backoff  time.Duration
+	openedAt time.Time
+	probing  bool
+	lock     sync.Mutex
+}
+
+func newCircuitBreaker(opts CircuitBreakerOptions, logger *slog.Logger) *circuitBreaker {
+	return &circuitBreaker{
+		opts:    opts,
+		logger:  logger,
+		now:     time.Now,
+		state:   CircuitClosed,
+		backoff: opts.Backoff,
+	}
+}
+
+// Allow returns whether a request should be sent to the bastion.
+// Once the backoff window has passed, a single request is allowed through as a probe.
+func (b *circuitBreaker) Allow() bool {
+	if b.opts.Threshold <= 0 {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		retryIn := b.openedAt.Add(b.backoff).Sub(b.now())
+		if retryIn > 0 {
+			b.logger.Debug("skipping bastion while circuit is open", "circuitState", b.state, "retryIn", retryIn)
+			return false
+		}
+		b.transition(CircuitHalfOpen)
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess records that the bastion was available, closing the circuit.
+func (b *circuitBreaker) RecordSuccess() {
+	if b.opts.Threshold <= 0 {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	b.backoff = b.opts.Backoff
+	if b.state != CircuitClosed {
+		b.transition(CircuitClosed)
+	}
+}
+
+// RecordFailure records that the bastion was unavailable, opening the circuit once the threshold is reached.
+// A failed probe reopens the circuit with double the previous backoff.
+func (b *circuitBreaker) RecordFailure() {
+	if b.opts.Threshold <= 0 {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures++
+	switch {
+	case b.state == CircuitHalfOpen:
+		b.probing = false
+		b.backoff = min(b.backoff*2, b.opts.MaxBackoff)
+		b.open()
+	case b.state == CircuitClosed && b.failures >= b.opts.Threshold:
+		b.open()
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *circuitBreaker) State() CircuitState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
+
+func (b *circuitBreaker) open() {
+	b.openedAt = b.now()
+	b.transition(CircuitOpen)
+}
+
+func (b *circuitBreaker) transition(state CircuitState) {
+	b.logger.Info("bastion circuit breaker changed state",
+		"circuitState", state, "previousState", b.state, "consecutiveFailures", b.failures, "backoff", b.backoff)
+	b.state = state
+}
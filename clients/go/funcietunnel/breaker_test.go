@@ -0,0 +1,83 @@
+package funcietunnel
+
+import (
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	newBreaker := func() (*circuitBreaker, *time.Time) {
+		now := time.Now()
+		breaker := newCircuitBreaker(CircuitBreakerOptions{
+			Threshold:  2,
+			Backoff:    time.Second,
+			MaxBackoff: 3 * time.Second,
+		}, slog.Default())
+		breaker.now = func() time.Time { return now }
+		return breaker, &now
+	}
+
+	t.Run("should open after consecutive failures", func(t *testing.T) {
+		t.Parallel()
+		breaker, _ := newBreaker()
+
+		breaker.RecordFailure()
+		require.True(t, breaker.Allow())
+		breaker.RecordSuccess()
+		breaker.RecordFailure()
+		require.Equal(t, CircuitClosed, breaker.State())
+
+		breaker.RecordFailure()
+		require.Equal(t, CircuitOpen, breaker.State())
+		require.False(t, breaker.Allow())
+	})
+
+	t.Run("should allow a single probe once the backoff has passed", func(t *testing.T) {
+		t.Parallel()
+		breaker, now := newBreaker()
+		breaker.RecordFailure()
+		breaker.RecordFailure()
+
+		*now = now.Add(time.Second)
+		require.True(t, breaker.Allow())
+		require.Equal(t, CircuitHalfOpen, breaker.State())
+		require.False(t, breaker.Allow())
+
+		breaker.RecordSuccess()
+		require.Equal(t, CircuitClosed, breaker.State())
+		require.True(t, breaker.Allow())
+	})
+
+	t.Run("should double the backoff when a probe fails", func(t *testing.T) {
+		t.Parallel()
+		breaker, now := newBreaker()
+		breaker.RecordFailure()
+		breaker.RecordFailure()
+
+		for _, backoff := range []time.Duration{2 * time.Second, 3 * time.Second, 3 * time.Second} {
+			*now = now.Add(breaker.backoff)
+			require.True(t, breaker.Allow())
+			breaker.RecordFailure()
+			require.Equal(t, CircuitOpen, breaker.State())
+			require.Equal(t, backoff, breaker.backoff)
+
+			*now = now.Add(backoff - time.Millisecond)
+			require.False(t, breaker.Allow())
+			*now = now.Add(-(backoff - time.Millisecond))
+		}
+	})
+
+	t.Run("should never open when disabled", func(t *testing.T) {
+		t.Parallel()
+		breaker := newCircuitBreaker(CircuitBreakerOptions{}, slog.Default())
+
+		for i := 0; i < 10; i++ {
+			breaker.RecordFailure()
+		}
+		require.True(t, breaker.Allow())
+	})
+}
@@ -43,11 +43,26 @@ func (c *httpBastionClient) SendRequest(ctx context.Context, request *funcie.Mes
 
 	c.logger.DebugContext(ctx, "sending message", "message", string(requestBytes))
 
-	httpResp, err := c.client.Post(c.endpoint.String(), "application/json", bytes.NewReader(requestBytes))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint.String(), bytes.NewReader(requestBytes))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("sending request: %w", err)
 	}
 
+	if httpResp.StatusCode == http.StatusOK && httpResp.Header.Get("Content-Type") == funcie.StreamContentType {
+		// The body is closed by the stream once the response has been read.
+		response, err := funcie.ReadStreamedResponse(httpResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading streamed response: %w", err)
+		}
+		return response, nil
+	}
+
 	defer func() { _ = httpResp.Body.Close() }()
 
 	var response funcie.Response
@@ -57,7 +72,7 @@ func (c *httpBastionClient) SendRequest(ctx context.Context, request *funcie.Mes
 	}
 
 	if httpResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-200 response %v: %w", httpResp.StatusCode, ErrStatusNotOK)
+		return nil, fmt.Errorf("received non-200 response %v (%s): %w", httpResp.StatusCode, string(responseData), ErrStatusNotOK)
 	}
 
 	err = json.Unmarshal(responseData, &response)
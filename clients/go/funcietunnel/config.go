@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"github.com/Kapps/funcie/clients/go/funcietunnel/internal"
+	"github.com/Kapps/funcie/pkg/funcie"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"net/url"
 	"os"
+	"time"
 )
 
 // FuncieConfig is the basic configuration for both the local and Lambda versions of the Funcie tunnel.
@@ -20,6 +22,16 @@ type FuncieConfig struct {
 	ListenAddress string `json:"listenAddress"`
 	// ApplicationId is the ID of the application that the tunnel is for.
 	ApplicationId string `json:"applicationId"`
+	// Receiver contains the options for how the local receiver invokes the handler.
+	Receiver ReceiverOptions `json:"receiver"`
+	// FallbackPolicy controls when the Lambda runs the deployed handler rather than forwarding to the local one.
+	FallbackPolicy funcie.FallbackPolicy `json:"fallbackPolicy"`
+	// ShadowMode makes the Lambda respond with the deployed handler, while comparing its results with the local handler.
+	ShadowMode bool `json:"shadowMode"`
+	// ShadowTimeout is how long the Lambda waits for the local handler in shadow mode.
+	ShadowTimeout time.Duration `json:"shadowTimeout"`
+	// CircuitBreaker controls when the Lambda skips the bastion after it has repeatedly been unavailable.
+	CircuitBreaker CircuitBreakerOptions `json:"circuitBreaker"`
 }
 
 // SsmParameterStoreClient is a minimal interface for the SSM client.
@@ -34,12 +46,22 @@ type SsmParameterStoreClient interface {
 //	FUNCIE_CLIENT_BASTION_ENDPOINT (optional; for client, defaults to port 24193 on localhost)
 //	FUNCIE_SERVER_BASTION_ENDPOINT (required for server)
 //	FUNCIE_LISTEN_ADDRESS (optional; defaults to localhost on a random port)
+//	FUNCIE_MAX_CONCURRENCY, FUNCIE_THROTTLE_MODE, FUNCIE_SANDBOX_MODE (optional; see receiverOptionsFromEnvironment)
+//	FUNCIE_FALLBACK_POLICY (optional; see fallbackPolicyFromEnvironment)
+//	FUNCIE_SHADOW_MODE (optional; "true" to run in shadow mode, defaults to false)
+//	FUNCIE_SHADOW_TIMEOUT (optional; a duration such as "5s" to wait for the local handler in shadow mode, defaults to 5s)
+//	FUNCIE_CIRCUIT_BREAKER_THRESHOLD, FUNCIE_CIRCUIT_BREAKER_BACKOFF, FUNCIE_CIRCUIT_BREAKER_MAX_BACKOFF (optional; see circuitBreakerOptionsFromEnvironment)
 func NewConfigFromEnvironment() *FuncieConfig {
 	return &FuncieConfig{
 		ClientBastionEndpoint: internal.OptionalUrlEnv("FUNCIE_CLIENT_BASTION_ENDPOINT", "http://127.0.0.1:24193"),
 		ServerBastionEndpoint: internal.RequireUrlEnv("FUNCIE_SERVER_BASTION_ENDPOINT", internal.ConfigPurposeServer),
 		ApplicationId:         internal.RequiredEnv("FUNCIE_APPLICATION_ID", internal.ConfigPurposeAny),
 		ListenAddress:         internal.OptionalEnv("FUNCIE_LISTEN_ADDRESS", "0.0.0.0:0"),
+		Receiver:              receiverOptionsFromEnvironment(),
+		FallbackPolicy:        fallbackPolicyFromEnvironment(),
+		ShadowMode:            internal.OptionalBoolEnv("FUNCIE_SHADOW_MODE", false),
+		ShadowTimeout:         internal.OptionalDurationEnv("FUNCIE_SHADOW_TIMEOUT", defaultShadowTimeout),
+		CircuitBreaker:        circuitBreakerOptionsFromEnvironment(),
 	}
 }
 
@@ -48,12 +70,18 @@ func NewConfigFromEnvironment() *FuncieConfig {
 //
 //	FUNCIE_CLIENT_BASTION_ENDPOINT (optional; for client, defaults to port 24193 on localhost)
 //	FUNCIE_SERVER_BASTION_ENDPOINT -> /funcie/<env>/bastion_host (required)
+//	FUNCIE_ENVIRONMENT (optional; the funcie environment whose SSM parameters are used, defaults to "default")
 //	FUNCIE_LISTEN_ADDRESS (optional; defaults to localhost on a random port)
+//	FUNCIE_MAX_CONCURRENCY, FUNCIE_THROTTLE_MODE, FUNCIE_SANDBOX_MODE (optional; see receiverOptionsFromEnvironment)
+//	FUNCIE_FALLBACK_POLICY (optional; see fallbackPolicyFromEnvironment)
+//	FUNCIE_SHADOW_MODE (optional; "true" to run in shadow mode, defaults to false)
+//	FUNCIE_SHADOW_TIMEOUT (optional; a duration such as "5s" to wait for the local handler in shadow mode, defaults to 5s)
+//	FUNCIE_CIRCUIT_BREAKER_THRESHOLD, FUNCIE_CIRCUIT_BREAKER_BACKOFF, FUNCIE_CIRCUIT_BREAKER_MAX_BACKOFF (optional; see circuitBreakerOptionsFromEnvironment)
 func NewConfig(ctx context.Context, applicationId string, ssmClient *ssm.Client) *FuncieConfig {
 	serverEndpoint := os.Getenv("FUNCIE_SERVER_BASTION_ENDPOINT")
 	if serverEndpoint == "" {
 		// Do this after the env check to allow avoiding SSM calls entirely.
-		serverEndpoint = fmt.Sprintf("http://%v:8082/dispatch", loadSSMParameter(ctx, ssmClient, "default", "bastion_host"))
+		serverEndpoint = fmt.Sprintf("http://%v:8082/dispatch", loadSSMParameter(ctx, ssmClient, configEnvironment(), "bastion_host"))
 	}
 
 	return &FuncieConfig{
@@ -61,9 +89,19 @@ func NewConfig(ctx context.Context, applicationId string, ssmClient *ssm.Client)
 		ServerBastionEndpoint: internal.OptionalUrlEnv("FUNCIE_SERVER_BASTION_ENDPOINT", serverEndpoint),
 		ApplicationId:         applicationId,
 		ListenAddress:         internal.OptionalEnv("FUNCIE_LISTEN_ADDRESS", "0.0.0.0:0"),
+		Receiver:              receiverOptionsFromEnvironment(),
+		FallbackPolicy:        fallbackPolicyFromEnvironment(),
+		ShadowMode:            internal.OptionalBoolEnv("FUNCIE_SHADOW_MODE", false),
+		ShadowTimeout:         internal.OptionalDurationEnv("FUNCIE_SHADOW_TIMEOUT", defaultShadowTimeout),
+		CircuitBreaker:        circuitBreakerOptionsFromEnvironment(),
 	}
 }
 
+// configEnvironment returns the funcie environment to load SSM parameters for.
+func configEnvironment() string {
+	return internal.OptionalEnv("FUNCIE_ENVIRONMENT", "default")
+}
+
 func loadSSMParameter(ctx context.Context, ssmClient SsmParameterStoreClient, env string, name string) string {
 	path := fmt.Sprintf("/funcie/%s/%s", env, name)
 	req := ssm.GetParameterInput{
@@ -76,3 +114,36 @@ func loadSSMParameter(ctx context.Context, ssmClient SsmParameterStoreClient, en
 
 	return *resp.Parameter.Value
 }
+
+// receiverOptionsFromEnvironment loads the ReceiverOptions from the following environment variables:
+//
+//	FUNCIE_MAX_CONCURRENCY (optional; maximum concurrent invocations per application, defaults to 0 for unlimited)
+//	FUNCIE_THROTTLE_MODE (optional; "queue" or "reject" once the concurrency limit is reached, defaults to "queue")
+//	FUNCIE_SANDBOX_MODE (optional; "reuse" for warm sandboxes or "per-invocation" for a cold start on every invocation, defaults to "reuse")
+func receiverOptionsFromEnvironment() ReceiverOptions {
+	return ReceiverOptions{
+		MaxConcurrency: internal.OptionalIntEnv("FUNCIE_MAX_CONCURRENCY", 0),
+		ThrottleMode:   internal.OptionalEnumEnv("FUNCIE_THROTTLE_MODE", ThrottleModeQueue, ThrottleModeQueue, ThrottleModeReject),
+		SandboxMode:    internal.OptionalEnumEnv("FUNCIE_SANDBOX_MODE", SandboxModeReuse, SandboxModeReuse, SandboxModePerInvocation),
+	}
+}
+
+// fallbackPolicyFromEnvironment loads the FallbackPolicy from the FUNCIE_FALLBACK_POLICY environment variable.
+// This is one of "never", "on-unavailable", "on-error", or "local-only", and defaults to "on-unavailable".
+func fallbackPolicyFromEnvironment() funcie.FallbackPolicy {
+	return internal.OptionalEnumEnv("FUNCIE_FALLBACK_POLICY", funcie.DefaultFallbackPolicy, funcie.FallbackPolicies()...)
+}
+
+// circuitBreakerOptionsFromEnvironment loads the CircuitBreakerOptions from the following environment variables:
+//
+//	FUNCIE_CIRCUIT_BREAKER_THRESHOLD (optional; consecutive unavailability errors before skipping the bastion, or 0 to disable; defaults to 3)
+//	FUNCIE_CIRCUIT_BREAKER_BACKOFF (optional; how long to skip the bastion before probing it again, defaults to 10s)
+//	FUNCIE_CIRCUIT_BREAKER_MAX_BACKOFF (optional; the limit as the backoff doubles after failed probes, defaults to 1m)
+func circuitBreakerOptionsFromEnvironment() CircuitBreakerOptions {
+	defaults := DefaultCircuitBreakerOptions()
+	return CircuitBreakerOptions{
+		Threshold:  internal.OptionalIntEnv("FUNCIE_CIRCUIT_BREAKER_THRESHOLD", defaults.Threshold),
+		Backoff:    internal.OptionalDurationEnv("FUNCIE_CIRCUIT_BREAKER_BACKOFF", defaults.Backoff),
+		MaxBackoff: internal.OptionalDurationEnv("FUNCIE_CIRCUIT_BREAKER_MAX_BACKOFF", defaults.MaxBackoff),
+	}
+}
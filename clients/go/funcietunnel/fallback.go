@@ -0,0 +1,36 @@
+package funcietunnel
+
+import (
+	"context"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"log/slog"
+	"net/url"
+)
+
+// SetFallbackPolicy overrides the fallback policy of the given application at runtime, through the client bastion at
+// the given endpoint. The override applies to every Lambda for the application until it is changed again, unless the
+// Lambda is configured with funcie.FallbackPolicyLocalOnly. An empty policy removes the override.
+func SetFallbackPolicy(ctx context.Context, clientBastionEndpoint url.URL, applicationId string, policy funcie.FallbackPolicy) error {
+	client := NewHTTPBastionClient(*clientBastionEndpoint.JoinPath("dispatch"), slog.Default())
+
+	payload := messages.NewSetFallbackPolicyPayload(policy)
+	message := funcie.NewMessageWithPayload(applicationId, messages.MessageKindSetFallbackPolicy, payload)
+
+	marshaled, err := funcie.MarshalMessagePayload(*message)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	response, err := client.SendRequest(ctx, marshaled)
+	if err != nil {
+		return fmt.Errorf("send fallback policy override: %w", err)
+	}
+
+	if response.Error != nil {
+		return fmt.Errorf("fallback policy override rejected: %w", response.Error)
+	}
+
+	return nil
+}
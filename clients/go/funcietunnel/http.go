@@ -0,0 +1,391 @@
+package funcietunnel
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupportedHTTPEvent is returned when an HTTP handler receives an event that is not an HTTP event.
+var ErrUnsupportedHTTPEvent = errors.New("unsupported HTTP event")
+
+// HTTPEventKind is the kind of Lambda event that an HTTP request was translated from.
+type HTTPEventKind string
+
+const (
+	// HTTPEventAPIGatewayV1 is an API Gateway REST API (payload version 1.0) proxy event.
+	HTTPEventAPIGatewayV1 HTTPEventKind = "apigateway-v1"
+	// HTTPEventAPIGatewayV2 is an API Gateway HTTP API (payload version 2.0) event.
+	HTTPEventAPIGatewayV2 HTTPEventKind = "apigateway-v2"
+	// HTTPEventALB is an Application Load Balancer target group event.
+	HTTPEventALB HTTPEventKind = "alb"
+	// HTTPEventFunctionURL is a Lambda Function URL event.
+	HTTPEventFunctionURL HTTPEventKind = "function-url"
+)
+
+type httpEventKey struct{}
+
+// HTTPEventFromContext returns the kind and raw payload of the Lambda event that the request with the given context
+// was translated from by a handler created with NewHTTPHandler.
+func HTTPEventFromContext(ctx context.Context) (HTTPEventKind, json.RawMessage, bool) {
+	event, ok := ctx.Value(httpEventKey{}).(*httpEvent)
+	if !ok {
+		return "", nil, false
+	}
+	return event.kind, event.raw, true
+}
+
+// NewHTTPHandler creates a Lambda handler that translates API Gateway v1 and v2, ALB, and Function URL events into an
+// *http.Request for the given http.Handler, and translates the handler's output back into the matching event response.
+// The returned handler can be used anywhere that a Lambda handler is expected, such as with Start or StartWithConfig.
+func NewHTTPHandler(handler http.Handler) func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		event, err := parseHTTPEvent(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := event.toRequest(context.WithValue(ctx, httpEventKey{}, event))
+		if err != nil {
+			return nil, fmt.Errorf("creating request from %v event: %w", event.kind, err)
+		}
+
+		w := newHTTPResponseWriter()
+		handler.ServeHTTP(w, req)
+
+		return event.toResponse(w), nil
+	}
+}
+
+// httpEvent is a Lambda HTTP event of any supported kind.
+type httpEvent struct {
+	kind HTTPEventKind
+	raw  json.RawMessage
+
+	method          string
+	path            string
+	rawQuery        string
+	headers         http.Header
+	body            string
+	isBase64Encoded bool
+	remoteAddr      string
+	// multiValue indicates that the ALB target group has multi-value headers enabled, which must be used in the response.
+	multiValue bool
+}
+
+// httpEventProbe contains the fields used to determine the kind of an HTTP event.
+type httpEventProbe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		DomainName string           `json:"domainName"`
+		ELB        *json.RawMessage `json:"elb"`
+		HTTP       *json.RawMessage `json:"http"`
+	} `json:"requestContext"`
+}
+
+func parseHTTPEvent(payload json.RawMessage) (*httpEvent, error) {
+	var probe httpEventProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedHTTPEvent, err)
+	}
+
+	switch {
+	case probe.RequestContext.ELB != nil:
+		return parseALBEvent(payload)
+	case probe.Version == "2.0" && probe.RequestContext.HTTP != nil:
+		return parseV2Event(payload, strings.Contains(probe.RequestContext.DomainName, ".lambda-url."))
+	case probe.HTTPMethod != "":
+		return parseV1Event(payload)
+	default:
+		return nil, ErrUnsupportedHTTPEvent
+	}
+}
+
+func parseV1Event(payload json.RawMessage) (*httpEvent, error) {
+	var request events.APIGatewayProxyRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, fmt.Errorf("unmarshal API Gateway v1 event: %w", err)
+	}
+
+	// API Gateway v1 decodes query string parameters, so they need to be re-encoded.
+	query := url.Values{}
+	for key, value := range request.QueryStringParameters {
+		query.Set(key, value)
+	}
+	for key, values := range request.MultiValueQueryStringParameters {
+		query[key] = values
+	}
+
+	return &httpEvent{
+		kind:            HTTPEventAPIGatewayV1,
+		raw:             payload,
+		method:          request.HTTPMethod,
+		path:            request.Path,
+		rawQuery:        query.Encode(),
+		headers:         mergeHeaders(request.Headers, request.MultiValueHeaders),
+		body:            request.Body,
+		isBase64Encoded: request.IsBase64Encoded,
+		remoteAddr:      request.RequestContext.Identity.SourceIP,
+	}, nil
+}
+
+func parseV2Event(payload json.RawMessage, functionURL bool) (*httpEvent, error) {
+	var request events.APIGatewayV2HTTPRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, fmt.Errorf("unmarshal API Gateway v2 event: %w", err)
+	}
+
+	headers := mergeHeaders(request.Headers, nil)
+	if len(request.Cookies) > 0 {
+		headers.Set("Cookie", strings.Join(request.Cookies, "; "))
+	}
+
+	kind := HTTPEventAPIGatewayV2
+	if functionURL {
+		kind = HTTPEventFunctionURL
+	}
+
+	path := request.RawPath
+	if path == "" {
+		path = request.RequestContext.HTTP.Path
+	}
+
+	return &httpEvent{
+		kind:            kind,
+		raw:             payload,
+		method:          request.RequestContext.HTTP.Method,
+		path:            path,
+		rawQuery:        request.RawQueryString,
+		headers:         headers,
+		body:            request.Body,
+		isBase64Encoded: request.IsBase64Encoded,
+		remoteAddr:      request.RequestContext.HTTP.SourceIP,
+	}, nil
+}
+
+func parseALBEvent(payload json.RawMessage) (*httpEvent, error) {
+	var request events.ALBTargetGroupRequest
+	if err := json.Unmarshal(payload, &request); err != nil {
+		return nil, fmt.Errorf("unmarshal ALB event: %w", err)
+	}
+
+	// ALB passes query string parameters as they were received, so they are already encoded.
+	var query []string
+	if len(request.MultiValueQueryStringParameters) > 0 {
+		for _, key := range sortedKeys(request.MultiValueQueryStringParameters) {
+			for _, value := range request.MultiValueQueryStringParameters[key] {
+				query = append(query, key+"="+value)
+			}
+		}
+	} else {
+		for _, key := range sortedKeys(request.QueryStringParameters) {
+			query = append(query, key+"="+request.QueryStringParameters[key])
+		}
+	}
+
+	return &httpEvent{
+		kind:            HTTPEventALB,
+		raw:             payload,
+		method:          request.HTTPMethod,
+		path:            request.Path,
+		rawQuery:        strings.Join(query, "&"),
+		headers:         mergeHeaders(request.Headers, request.MultiValueHeaders),
+		body:            request.Body,
+		isBase64Encoded: request.IsBase64Encoded,
+		multiValue:      len(request.MultiValueHeaders) > 0,
+	}, nil
+}
+
+func (e *httpEvent) toRequest(ctx context.Context) (*http.Request, error) {
+	body := []byte(e.body)
+	if e.isBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(e.body)
+		if err != nil {
+			return nil, fmt.Errorf("decode base64 body: %w", err)
+		}
+		body = decoded
+	}
+
+	target := &url.URL{Path: e.path, RawQuery: e.rawQuery}
+	req, err := http.NewRequestWithContext(ctx, e.method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = e.headers
+	req.Host = e.headers.Get("Host")
+	req.RequestURI = target.RequestURI()
+	req.RemoteAddr = e.remoteAddr
+
+	return req, nil
+}
+
+func (e *httpEvent) toResponse(w *httpResponseWriter) interface{} {
+	body, isBase64Encoded := w.encodedBody()
+
+	switch e.kind {
+	case HTTPEventAPIGatewayV1:
+		return &events.APIGatewayProxyResponse{
+			StatusCode:        w.status,
+			MultiValueHeaders: w.header,
+			Body:              body,
+			IsBase64Encoded:   isBase64Encoded,
+		}
+	case HTTPEventALB:
+		response := &events.ALBTargetGroupResponse{
+			StatusCode:        w.status,
+			StatusDescription: fmt.Sprintf("%d %s", w.status, http.StatusText(w.status)),
+			Body:              body,
+			IsBase64Encoded:   isBase64Encoded,
+		}
+		if e.multiValue {
+			response.MultiValueHeaders = w.header
+		} else {
+			// Without multi-value headers, ALB only supports a single value per header.
+			response.Headers = singleValueHeaders(w.header)
+		}
+		return response
+	case HTTPEventFunctionURL:
+		headers, cookies := splitCookies(w.header)
+		return &events.LambdaFunctionURLResponse{
+			StatusCode:      w.status,
+			Headers:         headers,
+			Body:            body,
+			IsBase64Encoded: isBase64Encoded,
+			Cookies:         cookies,
+		}
+	default:
+		headers, cookies := splitCookies(w.header)
+		return &events.APIGatewayV2HTTPResponse{
+			StatusCode:      w.status,
+			Headers:         headers,
+			Body:            body,
+			IsBase64Encoded: isBase64Encoded,
+			Cookies:         cookies,
+		}
+	}
+}
+
+// httpResponseWriter is an http.ResponseWriter that buffers the response so it can be converted to a Lambda event response.
+type httpResponseWriter struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func newHTTPResponseWriter() *httpResponseWriter {
+	return &httpResponseWriter{
+		header: http.Header{},
+		status: http.StatusOK,
+	}
+}
+
+func (w *httpResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *httpResponseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.body.Write(data)
+}
+
+func (w *httpResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+// encodedBody returns the body of the response, base64 encoded if it is not text.
+// Like net/http, the content type is detected from the body if the handler did not set one.
+func (w *httpResponseWriter) encodedBody() (string, bool) {
+	body := w.body.Bytes()
+	if len(body) > 0 && w.header.Get("Content-Type") == "" {
+		w.header.Set("Content-Type", http.DetectContentType(body))
+	}
+
+	if len(body) == 0 || (w.header.Get("Content-Encoding") == "" && isTextContentType(w.header.Get("Content-Type"))) {
+		return string(body), false
+	}
+
+	return base64.StdEncoding.EncodeToString(body), true
+}
+
+func isTextContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-www-form-urlencoded",
+		"image/svg+xml":
+		return true
+	default:
+		return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+	}
+}
+
+// mergeHeaders combines single and multi-value headers from an event, preferring the multi-value headers when present.
+func mergeHeaders(single map[string]string, multi map[string][]string) http.Header {
+	headers := http.Header{}
+	for key, value := range single {
+		headers.Set(key, value)
+	}
+	for key, values := range multi {
+		headers.Del(key)
+		for _, value := range values {
+			headers.Add(key, value)
+		}
+	}
+	return headers
+}
+
+// splitCookies converts the headers of a response to the single-value headers and cookies used by payload version 2.0.
+func splitCookies(header http.Header) (map[string]string, []string) {
+	headers := make(map[string]string, len(header))
+	for key, values := range header {
+		if key == "Set-Cookie" {
+			continue
+		}
+		headers[key] = strings.Join(values, ",")
+	}
+	return headers, header.Values("Set-Cookie")
+}
+
+func singleValueHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for key, values := range header {
+		if len(values) > 0 {
+			headers[key] = values[len(values)-1]
+		}
+	}
+	return headers
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,155 @@
+package funcietunnel
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestNewHTTPHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var received *http.Request
+	var receivedBody []byte
+	handler := NewHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+
+		http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+		http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+		w.Header().Add("X-Multi", "one")
+		w.Header().Add("X-Multi", "two")
+
+		if r.URL.Query().Get("binary") == "true" {
+			w.Header().Set("Content-Type", "image/png")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"hello":"world"}`))
+	}))
+
+	invoke := func(t *testing.T, event interface{}) interface{} {
+		resp, err := handler(ctx, funcie.MustSerialize(event))
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("API Gateway v1", func(t *testing.T) {
+		resp := invoke(t, events.APIGatewayProxyRequest{
+			HTTPMethod:                      http.MethodPost,
+			Path:                            "/items",
+			MultiValueHeaders:               map[string][]string{"Accept": {"a", "b"}, "Host": {"example.com"}},
+			MultiValueQueryStringParameters: map[string][]string{"q": {"x y", "z"}},
+			Body:                            base64.StdEncoding.EncodeToString([]byte("body")),
+			IsBase64Encoded:                 true,
+			RequestContext:                  events.APIGatewayProxyRequestContext{Identity: events.APIGatewayRequestIdentity{SourceIP: "1.2.3.4"}},
+		}).(*events.APIGatewayProxyResponse)
+
+		require.Equal(t, http.MethodPost, received.Method)
+		require.Equal(t, "/items", received.URL.Path)
+		require.Equal(t, []string{"x y", "z"}, received.URL.Query()["q"])
+		require.Equal(t, []string{"a", "b"}, received.Header.Values("Accept"))
+		require.Equal(t, "example.com", received.Host)
+		require.Equal(t, "1.2.3.4", received.RemoteAddr)
+		require.Equal(t, "body", string(receivedBody))
+
+		kind, raw, ok := HTTPEventFromContext(received.Context())
+		require.True(t, ok)
+		require.Equal(t, HTTPEventAPIGatewayV1, kind)
+		require.NotEmpty(t, raw)
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, `{"hello":"world"}`, resp.Body)
+		require.False(t, resp.IsBase64Encoded)
+		require.Equal(t, []string{"one", "two"}, resp.MultiValueHeaders["X-Multi"])
+		require.Len(t, resp.MultiValueHeaders["Set-Cookie"], 2)
+	})
+
+	t.Run("API Gateway v2", func(t *testing.T) {
+		event := events.APIGatewayV2HTTPRequest{
+			Version:        "2.0",
+			RawPath:        "/items/1",
+			RawQueryString: "binary=true",
+			Cookies:        []string{"c=3", "d=4"},
+			Headers:        map[string]string{"accept": "a,b"},
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				DomainName: "id.execute-api.us-east-1.amazonaws.com",
+				HTTP:       events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+			},
+		}
+		resp := invoke(t, event).(*events.APIGatewayV2HTTPResponse)
+
+		require.Equal(t, http.MethodGet, received.Method)
+		require.Equal(t, "/items/1", received.URL.Path)
+		require.Len(t, received.Cookies(), 2)
+
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.True(t, resp.IsBase64Encoded)
+		require.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G'}), resp.Body)
+		require.Equal(t, []string{"a=1", "b=2"}, resp.Cookies)
+		require.Equal(t, "one,two", resp.Headers["X-Multi"])
+		require.NotContains(t, resp.Headers, "Set-Cookie")
+	})
+
+	t.Run("Function URL", func(t *testing.T) {
+		event := events.LambdaFunctionURLRequest{
+			Version: "2.0",
+			RawPath: "/",
+			RequestContext: events.LambdaFunctionURLRequestContext{
+				DomainName: "id.lambda-url.us-east-1.on.aws",
+				HTTP:       events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodGet},
+			},
+		}
+		resp := invoke(t, event).(*events.LambdaFunctionURLResponse)
+
+		kind, _, _ := HTTPEventFromContext(received.Context())
+		require.Equal(t, HTTPEventFunctionURL, kind)
+		require.Equal(t, `{"hello":"world"}`, resp.Body)
+		require.Equal(t, []string{"a=1", "b=2"}, resp.Cookies)
+	})
+
+	t.Run("ALB with multi-value headers", func(t *testing.T) {
+		event := events.ALBTargetGroupRequest{
+			HTTPMethod:                      http.MethodGet,
+			Path:                            "/",
+			MultiValueHeaders:               map[string][]string{"accept": {"a"}},
+			MultiValueQueryStringParameters: map[string][]string{"q": {"x%20y"}},
+			RequestContext:                  events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn"}},
+		}
+		resp := invoke(t, event).(*events.ALBTargetGroupResponse)
+
+		require.Equal(t, "x y", received.URL.Query().Get("q"))
+		require.Equal(t, "200 OK", resp.StatusDescription)
+		require.Nil(t, resp.Headers)
+		require.Equal(t, []string{"one", "two"}, resp.MultiValueHeaders["X-Multi"])
+	})
+
+	t.Run("ALB without multi-value headers", func(t *testing.T) {
+		event := events.ALBTargetGroupRequest{
+			HTTPMethod:     http.MethodGet,
+			Path:           "/",
+			Headers:        map[string]string{"accept": "a"},
+			RequestContext: events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn"}},
+		}
+		resp := invoke(t, event).(*events.ALBTargetGroupResponse)
+
+		require.Nil(t, resp.MultiValueHeaders)
+		require.Equal(t, "two", resp.Headers["X-Multi"])
+	})
+
+	t.Run("unsupported event", func(t *testing.T) {
+		_, err := handler(ctx, json.RawMessage(`{"foo":"bar"}`))
+		require.ErrorIs(t, err, ErrUnsupportedHTTPEvent)
+	})
+}
@@ -5,6 +5,9 @@ import (
 	"github.com/Kapps/funcie/pkg/funcie"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"time"
 )
 
 type ConfigPurpose int
@@ -58,3 +61,47 @@ func OptionalUrlEnv(name string, defaultValue string) url.URL {
 	}
 	return *parsedUrl
 }
+
+func OptionalIntEnv(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse %s %s: %s", name, value, err))
+	}
+	return parsed
+}
+
+func OptionalBoolEnv(name string, defaultValue bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse %s %s: %s", name, value, err))
+	}
+	return parsed
+}
+
+func OptionalDurationEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse %s %s: %s", name, value, err))
+	}
+	return parsed
+}
+
+func OptionalEnumEnv[T ~string](name string, defaultValue T, allowed ...T) T {
+	value := T(OptionalEnv(name, string(defaultValue)))
+	if !slices.Contains(allowed, value) {
+		panic(fmt.Sprintf("invalid %s %s: must be one of %v", name, value, allowed))
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+package funcietunnel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/lambda"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// handlerInvoker invokes a Lambda handler with a raw payload, returning either the serialized response,
+// or, for handlers that return an io.Reader such as those using Lambda response streaming, the reader as-is.
+// This matches the behaviour of the Lambda runtime, which streams readers rather than buffering them.
+type handlerInvoker func(ctx context.Context, payload []byte) (response []byte, stream io.Reader, err error)
+
+// newHandlerInvoker creates a handlerInvoker for the given handler, which has the same restrictions as lambda.Start.
+func newHandlerInvoker(handler interface{}) handlerInvoker {
+	if !returnsReader(handler) {
+		return func(ctx context.Context, payload []byte) ([]byte, io.Reader, error) {
+			// The handler from the Lambda SDK reuses its output buffer, so it is not safe for concurrent requests.
+			res, err := lambda.NewHandler(handler).Invoke(ctx, payload)
+			return res, nil, err
+		}
+	}
+
+	handlerValue := reflect.ValueOf(handler)
+	handlerType := handlerValue.Type()
+	takesContext := handlerType.NumIn() > 0 && handlerType.In(0).Implements(contextType)
+	takesEvent := handlerType.NumIn() == 2 || (handlerType.NumIn() == 1 && !takesContext)
+
+	return func(ctx context.Context, payload []byte) ([]byte, io.Reader, error) {
+		var args []reflect.Value
+		if takesContext {
+			args = append(args, reflect.ValueOf(ctx))
+		}
+		if takesEvent {
+			event := reflect.New(handlerType.In(handlerType.NumIn() - 1))
+			if err := json.Unmarshal(payload, event.Interface()); err != nil {
+				return nil, nil, fmt.Errorf("unmarshal event: %w", err)
+			}
+			args = append(args, event.Elem())
+		}
+
+		results := handlerValue.Call(args)
+		if err, ok := results[1].Interface().(error); ok && err != nil {
+			return nil, nil, err
+		}
+
+		reader, _ := results[0].Interface().(io.Reader)
+		if reader == nil {
+			return []byte("null"), nil, nil
+		}
+
+		// For compatibility, the Lambda runtime only streams readers that do not serialize to a non-empty object.
+		serialized, err := json.Marshal(reader)
+		if err == nil && !strings.HasPrefix(string(serialized), "{}") {
+			return serialized, nil, nil
+		}
+
+		return nil, reader, nil
+	}
+}
+
+// returnsReader returns true if the given handler is a valid handler function whose response is an io.Reader.
+func returnsReader(handler interface{}) bool {
+	if _, ok := handler.(lambda.Handler); ok {
+		return false
+	}
+
+	handlerType := reflect.TypeOf(handler)
+	if handlerType == nil || handlerType.Kind() != reflect.Func || handlerType.NumIn() > 2 {
+		return false
+	}
+	if handlerType.NumIn() == 2 && !handlerType.In(0).Implements(contextType) {
+		return false
+	}
+
+	return handlerType.NumOut() == 2 &&
+		handlerType.Out(0).Implements(readerType) &&
+		handlerType.Out(1).Implements(errorType)
+}
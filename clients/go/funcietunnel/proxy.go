@@ -3,11 +3,14 @@ package funcietunnel
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/Kapps/funcie/pkg/funcie"
 	"github.com/Kapps/funcie/pkg/funcie/messages"
 	"github.com/aws/aws-lambda-go/lambda"
+	"io"
 	"log/slog"
+	"time"
 )
 
 var lambdaStart = lambda.Start
@@ -18,29 +21,120 @@ type FunctionProxy interface {
 	Start()
 }
 
+// ErrNotProxied is returned when a request was not handled by a local consumer, and the fallback policy
+// does not allow the deployed code to handle it instead.
+var ErrNotProxied = errors.New("request was not handled by a local consumer")
+
+// ProxyOptions are the options for how a FunctionProxy handles requests.
+type ProxyOptions struct {
+	// FallbackPolicy controls when the deployed handler is invoked instead of the local one.
+	// This may be overridden at runtime from the client side, unless it is funcie.FallbackPolicyLocalOnly.
+	FallbackPolicy funcie.FallbackPolicy
+	// ShadowMode runs the deployed handler for every request, and also forwards the request to the local handler so
+	// that the results can be compared by the client bastion. The deployed result is always used as the response.
+	ShadowMode bool
+	// ShadowTimeout is how long to wait for the local handler in shadow mode before giving up on the comparison.
+	// The deployed response is only returned once the local handler responds or this timeout passes, so every
+	// shadowed invocation can take up to this much longer than it otherwise would.
+	ShadowTimeout time.Duration
+	// CircuitBreaker controls when requests skip the bastion after it has repeatedly been unavailable.
+	CircuitBreaker CircuitBreakerOptions
+}
+
+// defaultShadowTimeout is the ShadowTimeout used when none is set, which is short enough that a local handler paused
+// in a debugger does not hold up the deployed response for long.
+const defaultShadowTimeout = 5 * time.Second
+
+// ProxyOptionSetter sets an option for a FunctionProxy.
+type ProxyOptionSetter func(*ProxyOptions)
+
+// WithFallbackPolicy sets when the deployed handler is invoked instead of the local one.
+func WithFallbackPolicy(policy funcie.FallbackPolicy) ProxyOptionSetter {
+	return func(opts *ProxyOptions) {
+		opts.FallbackPolicy = policy
+	}
+}
+
+// WithCircuitBreaker sets when requests skip the bastion after it has repeatedly been unavailable.
+// A threshold of 0 disables the circuit breaker, so that every request is sent to the bastion.
+func WithCircuitBreaker(options CircuitBreakerOptions) ProxyOptionSetter {
+	return func(opts *ProxyOptions) {
+		opts.CircuitBreaker = options
+	}
+}
+
+// WithShadowMode sets whether requests are handled by the deployed handler and only shadowed by the local one,
+// waiting at most the given timeout for the local handler. A zero timeout uses the default of 5 seconds.
+// Each shadowed invocation waits for the local handler before responding, so may take up to the timeout longer.
+func WithShadowMode(enabled bool, timeout time.Duration) ProxyOptionSetter {
+	return func(opts *ProxyOptions) {
+		opts.ShadowMode = enabled
+		opts.ShadowTimeout = timeout
+	}
+}
+
 type lambdaProxy struct {
 	applicationId string
 	client        BastionClient
-	handler       lambda.Handler
+	invoke        handlerInvoker
 	logger        *slog.Logger
+	opts          ProxyOptions
+	breaker       *circuitBreaker
 }
 
 // NewLambdaFunctionProxy creates a new FunctionProxy for AWS Lambda operations.
 // The handler is the handler that will be invoked when a request is received.
-// It is subject to the same restrictions as the handler for the underlying serverless function provider (such as lambda.Start).
+// It is subject to the same restrictions as the handler for the underlying serverless function provider (such as lambda.Start),
+// or may be an InitFunc, which is run the first time that a request is not forwarded and must be handled directly.
 func NewLambdaFunctionProxy(
 	applicationId string,
 	client BastionClient,
 	handler interface{},
 	logger *slog.Logger,
+	opts ...ProxyOptionSetter,
 ) FunctionProxy {
-	lambdaHandler := lambda.NewHandler(handler)
+	return newLambdaProxy(applicationId, client, handler, logger, opts...)
+}
+
+// NewLambdaHandler creates the lambda.Handler that a FunctionProxy created with the same arguments would start.
+// This allows invoking the proxy directly, such as to test how requests are routed without a Lambda runtime.
+func NewLambdaHandler(
+	applicationId string,
+	client BastionClient,
+	handler interface{},
+	logger *slog.Logger,
+	opts ...ProxyOptionSetter,
+) lambda.Handler {
+	return newLambdaProxy(applicationId, client, handler, logger, opts...).lambdaHandler()
+}
+
+func newLambdaProxy(
+	applicationId string,
+	client BastionClient,
+	handler interface{},
+	logger *slog.Logger,
+	opts ...ProxyOptionSetter,
+) *lambdaProxy {
+	options := ProxyOptions{
+		CircuitBreaker: DefaultCircuitBreakerOptions(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.FallbackPolicy == "" {
+		options.FallbackPolicy = funcie.DefaultFallbackPolicy
+	}
+	if options.ShadowTimeout == 0 {
+		options.ShadowTimeout = defaultShadowTimeout
+	}
 
 	return &lambdaProxy{
 		applicationId: applicationId,
 		client:        client,
-		handler:       lambdaHandler,
+		invoke:        newSandboxPool(handler, newReceiverOptions()).Invoke,
 		logger:        logger,
+		opts:          options,
+		breaker:       newCircuitBreaker(options.CircuitBreaker, logger),
 	}
 }
 
@@ -51,65 +145,207 @@ func (p *lambdaProxy) Start() {
 }
 
 // lambdaHandler is the Lambda-level wrapper around the handler.
-// It is responsible for publishing the message to the tunnel, and waiting for a response.
 func (p *lambdaProxy) lambdaHandler() lambda.Handler {
-	wrapper := func(ctx context.Context, payload *json.RawMessage) (*json.RawMessage, error) {
-		p.logger.DebugContext(ctx, "publishing message to tunnel", "message", string(*payload))
+	return lambda.NewHandler(p.handle)
+}
 
-		// Raw constant to avoid cycles -- this needs to be moved.
-		forwardPayload := messages.NewForwardRequestPayload(*payload)
-		message := funcie.NewMessageWithPayload(p.applicationId, "FORWARD_REQUEST", forwardPayload)
+// handle is responsible for publishing the message to the tunnel, and waiting for a response.
+// Responses are returned as readers so that streamed responses are streamed back to Lambda as they arrive.
+func (p *lambdaProxy) handle(ctx context.Context, payload *json.RawMessage) (io.Reader, error) {
+	if p.opts.ShadowMode {
+		return p.handleShadow(ctx, payload)
+	}
 
-		marshaled, err := funcie.MarshalMessagePayload(*message)
-		if err != nil {
-			return nil, fmt.Errorf("marshalling message payload: %w", err)
-		}
+	p.logger.DebugContext(ctx, "publishing message to tunnel", "message", string(*payload))
+
+	// Raw constant to avoid cycles -- this needs to be moved.
+	forwardPayload := messages.NewForwardRequestPayload(*payload)
+	message := funcie.NewMessageWithPayload(p.applicationId, "FORWARD_REQUEST", forwardPayload)
+
+	marshaled, err := funcie.MarshalMessagePayload(*message)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling message payload: %w", err)
+	}
+
+	if !p.allowBastion() {
+		// The bastion has been unavailable recently, so skip it rather than paying for another failed attempt.
+		return p.handleUnavailable(ctx, payload, p.opts.FallbackPolicy, ErrCircuitOpen)
+	}
+
+	resp, err := p.client.SendRequest(ctx, marshaled)
+	if err != nil {
+		// If we can't reach the bastion, there is no override, so only the configured policy applies.
+		p.breaker.RecordFailure()
+		p.logger.WarnContext(ctx, "failed to send request to bastion",
+			"error", err, "messageId", message.ID, "circuitState", p.breaker.State())
+		p.logger.DebugContext(ctx, "failed delivery details", "message", message)
+		return p.handleUnavailable(ctx, payload, p.opts.FallbackPolicy, err)
+	}
+	p.recordResponse(resp)
+
+	forwardResponse, err := funcie.UnmarshalResponsePayload[messages.ForwardRequestResponse](resp)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling response from bastion: %w", err)
+	}
 
-		resp, err := p.client.SendRequest(ctx, marshaled)
-		if err != nil {
-			// If we can't reach the bastion, we should just handle the request directly.
-			p.logger.WarnContext(ctx, "failed to send request to bastion", "error", err, "messageId", message.ID)
-			p.logger.DebugContext(ctx, "failed delivery details", "message", message)
+	policy := p.opts.FallbackPolicy.WithOverride(forwardResponse.Data.FallbackPolicy)
+
+	if forwardResponse.Error != nil {
+		// This is a bit of a gross way to check this, but... it is what it is.
+		// We need to add error codes in the future and make this less gross.
+		if isExpectedProxyError(forwardResponse.Error) {
+			// If there is no active consumer, or it is throttled, the policy decides whether we handle it directly.
+			p.logger.DebugContext(ctx, "request not handled by consumer", "reason", forwardResponse.Error, "message", message)
+			return p.handleUnavailable(ctx, payload, policy, forwardResponse.Error)
+		}
+		// In this case though, the request was handled and the handling returned an error.
+		// So we should forward that error back to the Lambda, unless the policy is to fall back on errors.
+		p.logger.DebugContext(ctx, "received error from bastion", "error", forwardResponse.Error)
+		if policy.FallbackOnError() {
+			p.logger.WarnContext(ctx, "local handler failed; falling back to the deployed handler",
+				"error", forwardResponse.Error, "fallbackPolicy", policy)
 			return p.handleDirect(ctx, payload)
 		}
+		return nil, fmt.Errorf("received error from proxied implementation: %w", forwardResponse.Error)
+	}
 
-		forwardResponse, err := funcie.UnmarshalResponsePayload[messages.ForwardRequestResponse](resp)
-		if err != nil {
-			return nil, fmt.Errorf("unmarshalling response from bastion: %w", err)
+	if forwardResponse.Data.Streaming {
+		if forwardResponse.Stream == nil {
+			return nil, fmt.Errorf("received a streamed response, but the server bastion does not support response streaming; upgrade it to v%v", funcie.Version())
 		}
 
-		if forwardResponse.Error != nil {
-			// This is a bit of a gross way to check this, but... it is what it is.
-			// We need to add error codes in the future and make this less gross.
-			if isExpectedProxyError(forwardResponse.Error) {
-				// If there is no active consumer, we should just handle the request directly.
-				p.logger.DebugContext(ctx, "no active consumer for request", "message", message)
-				return p.handleDirect(ctx, payload)
-			}
-			// In this case though, the request was handled and the handling returned an error.
-			// So we should forward that error back to the Lambda.
-			p.logger.DebugContext(ctx, "received error from bastion", "error", forwardResponse.Error)
-			return nil, fmt.Errorf("received error from proxied implementation: %w", forwardResponse.Error)
+		p.logger.DebugContext(ctx, "received streamed response from bastion", "contentType", forwardResponse.Data.ContentType)
+		return newChunkReader(ctx, forwardResponse.Stream, forwardResponse.Data.ContentType), nil
+	}
+
+	p.logger.DebugContext(ctx, "received response from bastion", "response", string(forwardResponse.Data.Body))
+
+	return newBufferedResponse(forwardResponse.Data.Body), nil
+}
+
+// allowBastion returns whether a request should be sent to the bastion, according to the circuit breaker.
+// The breaker is only used when requests are allowed to fall back, as otherwise skipping the bastion would only delay
+// recovery in exchange for failing faster.
+func (p *lambdaProxy) allowBastion() bool {
+	return !p.opts.FallbackPolicy.FallbackWhenUnavailable() || p.breaker.Allow()
+}
+
+// recordResponse records the availability of the bastion in the circuit breaker based on the given response.
+// A missing consumer counts as unavailable, as the bastion is of no use until someone starts debugging.
+func (p *lambdaProxy) recordResponse(resp *funcie.Response) {
+	if resp.Error != nil && isUnavailableProxyError(resp.Error) {
+		p.breaker.RecordFailure()
+		return
+	}
+	p.breaker.RecordSuccess()
+}
+
+// handleUnavailable handles a request that no local consumer handled, either directly or by failing with
+// ErrNotProxied depending on the fallback policy.
+func (p *lambdaProxy) handleUnavailable(ctx context.Context, payload *json.RawMessage, policy funcie.FallbackPolicy, reason error) (io.Reader, error) {
+	if !policy.FallbackWhenUnavailable() {
+		return nil, fmt.Errorf("%w, and fallback policy %q does not allow the deployed handler to run: %w", ErrNotProxied, policy, reason)
+	}
+
+	return p.handleDirect(ctx, payload)
+}
+
+// handleShadow handles the request with the deployed handler, then forwards the request along with the deployed
+// result to the local handler so that the client bastion can compare the two. Failures of the local handler or of
+// the forwarding never affect the response. Streamed responses are buffered, as they must be read to be compared.
+func (p *lambdaProxy) handleShadow(ctx context.Context, payload *json.RawMessage) (io.Reader, error) {
+	start := time.Now()
+	res, stream, err := p.invoke(ctx, *payload)
+	var contentType string
+	if err == nil && stream != nil {
+		contentType = streamContentType(stream)
+		res, err = io.ReadAll(stream)
+		if closer, ok := stream.(io.Closer); ok {
+			_ = closer.Close()
 		}
+	}
 
-		p.logger.DebugContext(ctx, "received response from bastion", "response", string(forwardResponse.Data.Body))
+	result := messages.ShadowResult{
+		Error:    funcie.NewProxyErrorFromError(err),
+		Duration: time.Since(start),
+	}
+	if err == nil {
+		result.Body = res
+	}
+
+	p.forwardShadow(ctx, payload, result)
 
-		return &forwardResponse.Data.Body, nil
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke handler: %w", err)
 	}
-	return lambda.NewHandler(wrapper)
+
+	if stream != nil {
+		return newBufferedStream(res, contentType), nil
+	}
+	return newBufferedResponse(res), nil
+}
+
+// forwardShadow forwards the request with the result of the deployed handler, waiting for the local handler to finish
+// so that the Lambda is not frozen before the comparison is made.
+func (p *lambdaProxy) forwardShadow(ctx context.Context, payload *json.RawMessage, result messages.ShadowResult) {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.ShadowTimeout)
+	defer cancel()
+
+	forwardPayload := messages.NewForwardRequestPayload(*payload)
+	forwardPayload.Shadow = &result
+	message := funcie.NewMessageWithPayload(p.applicationId, messages.MessageKindForwardRequest, forwardPayload)
+
+	marshaled, err := funcie.MarshalMessagePayload(*message)
+	if err != nil {
+		p.logger.WarnContext(ctx, "failed to marshal shadow request", "error", err, "messageId", message.ID)
+		return
+	}
+
+	if !p.breaker.Allow() {
+		p.logger.DebugContext(ctx, "skipping shadow request while circuit is open", "messageId", message.ID)
+		return
+	}
+
+	resp, err := p.client.SendRequest(ctx, marshaled)
+	if err != nil {
+		p.breaker.RecordFailure()
+		p.logger.WarnContext(ctx, "failed to send shadow request to bastion",
+			"error", err, "messageId", message.ID, "circuitState", p.breaker.State())
+		return
+	}
+	p.recordResponse(resp)
+
+	if resp.Stream != nil {
+		_ = resp.Stream.Close()
+	}
+
+	if resp.Error != nil {
+		p.logger.DebugContext(ctx, "shadow request not handled by consumer", "reason", resp.Error, "messageId", message.ID)
+		return
+	}
+
+	p.logger.DebugContext(ctx, "shadow request handled by consumer", "messageId", message.ID)
 }
 
-func (p *lambdaProxy) handleDirect(ctx context.Context, payload *json.RawMessage) (*json.RawMessage, error) {
-	res, err := p.handler.Invoke(ctx, *payload)
+func (p *lambdaProxy) handleDirect(ctx context.Context, payload *json.RawMessage) (io.Reader, error) {
+	res, stream, err := p.invoke(ctx, *payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to invoke handler: %w", err)
 	}
 
-	raw := json.RawMessage(res)
-	return &raw, nil
+	if stream != nil {
+		return stream, nil
+	}
+
+	return newBufferedResponse(res), nil
 }
 
 func isExpectedProxyError(err *funcie.ProxyError) bool {
+	return isUnavailableProxyError(err) || err.Error() == funcie.ErrThrottled.Error()
+}
+
+func isUnavailableProxyError(err *funcie.ProxyError) bool {
 	str := err.Error()
-	return str == funcie.ErrNoActiveConsumer.Error() || str == funcie.ErrApplicationNotFound.Error()
+	return str == funcie.ErrNoActiveConsumer.Error() ||
+		str == funcie.ErrApplicationNotFound.Error()
 }
@@ -3,6 +3,7 @@ package funcietunnel
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/Kapps/funcie/clients/go/funcietunnel/mocks"
 	"github.com/Kapps/funcie/pkg/funcie"
 	"github.com/Kapps/funcie/pkg/funcie/messages"
@@ -10,8 +11,11 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"io"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLambdaProxy_Start(t *testing.T) {
@@ -28,6 +32,7 @@ func TestLambdaProxy_Start(t *testing.T) {
 	client := mocks.NewBastionClient(t)
 
 	proxy := NewLambdaFunctionProxy(app, client, rawHandler, slog.Default())
+	handle := proxy.(*lambdaProxy).handle
 
 	var handler lambda.Handler
 	lambdaProxy := func(wrappedHandler interface{}) {
@@ -81,4 +86,240 @@ func TestLambdaProxy_Start(t *testing.T) {
 		require.Equal(t, 200, response.StatusCode)
 		require.Equal(t, "Hello world direct", response.Body)
 	})
+
+	t.Run("throttled consumer", func(t *testing.T) {
+		req := events.LambdaFunctionURLRequest{}
+		reqBytes := funcie.MustSerialize(req)
+
+		resp := funcie.NewResponse("id", nil, funcie.ErrThrottled)
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(resp, nil).Once()
+
+		responseBytes, err := handler.Invoke(ctx, reqBytes)
+		require.NoError(t, err)
+
+		var response events.LambdaFunctionURLResponse
+		require.NoError(t, json.Unmarshal(responseBytes, &response))
+
+		require.Equal(t, "Hello world direct", response.Body)
+	})
+
+	t.Run("streamed response", func(t *testing.T) {
+		req := events.LambdaFunctionURLRequest{}
+		reqBytes := funcie.MustSerialize(req)
+
+		respPayload := messages.NewStreamingForwardRequestResponsePayload("text/plain")
+		resp, err := funcie.MarshalResponsePayload(funcie.NewResponseWithPayload("id", respPayload, nil))
+		require.NoError(t, err)
+		resp.AttachStream(newChunkedResponseStream(app, "id", strings.NewReader("Hello world streamed")))
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(resp, nil).Once()
+
+		responseBytes, err := handler.Invoke(ctx, reqBytes)
+		require.NoError(t, err)
+
+		require.Equal(t, "Hello world streamed", string(responseBytes))
+	})
+
+	t.Run("should report buffered responses as JSON", func(t *testing.T) {
+		payload := json.RawMessage(funcie.MustSerialize(events.LambdaFunctionURLRequest{}))
+
+		respPayload := messages.NewForwardRequestResponsePayload(funcie.MustSerialize(events.LambdaFunctionURLResponse{StatusCode: 200}))
+		resp := funcie.NewResponse("id", funcie.MustSerialize(respPayload), nil)
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(resp, nil).Once()
+
+		forwarded, err := handle(ctx, &payload)
+		require.NoError(t, err)
+		require.Equal(t, "application/json", streamContentType(forwarded))
+
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(funcie.NewResponse("id", nil, funcie.ErrNoActiveConsumer), nil).Once()
+
+		direct, err := handle(ctx, &payload)
+		require.NoError(t, err)
+		require.Equal(t, "application/json", streamContentType(direct))
+	})
+
+	t.Run("streamed response without a stream", func(t *testing.T) {
+		req := events.LambdaFunctionURLRequest{}
+		reqBytes := funcie.MustSerialize(req)
+
+		respPayload := messages.NewStreamingForwardRequestResponsePayload("text/plain")
+		resp := funcie.NewResponse("id", funcie.MustSerialize(respPayload), nil)
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(resp, nil).Once()
+
+		_, err := handler.Invoke(ctx, reqBytes)
+		require.ErrorContains(t, err, "server bastion does not support response streaming")
+	})
+}
+
+func TestLambdaProxy_FallbackPolicy(t *testing.T) {
+	ctx := context.Background()
+	reqBytes := funcie.MustSerialize(events.LambdaFunctionURLRequest{})
+
+	rawHandler := func(ctx context.Context, payload events.LambdaFunctionURLRequest) (string, error) {
+		return "Hello world direct", nil
+	}
+
+	newHandler := func(t *testing.T, policy funcie.FallbackPolicy) (lambda.Handler, *mocks.BastionClient) {
+		client := mocks.NewBastionClient(t)
+		proxy := NewLambdaFunctionProxy("app", client, rawHandler, slog.Default(), WithFallbackPolicy(policy))
+		return proxy.(*lambdaProxy).lambdaHandler(), client
+	}
+
+	newResponse := func(err error, override funcie.FallbackPolicy) *funcie.Response {
+		var payload []byte
+		if override != "" {
+			payload = funcie.MustSerialize(messages.ForwardRequestResponsePayload{FallbackPolicy: override})
+		}
+		return funcie.NewResponse("id", payload, err)
+	}
+
+	requireDirect := func(t *testing.T, handler lambda.Handler) {
+		responseBytes, err := handler.Invoke(ctx, reqBytes)
+		require.NoError(t, err)
+		require.Equal(t, "Hello world direct", funcie.MustDeserialize[string](responseBytes))
+	}
+
+	t.Run("never should fail when there is no active consumer", func(t *testing.T) {
+		handler, client := newHandler(t, funcie.FallbackPolicyNever)
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(newResponse(funcie.ErrNoActiveConsumer, ""), nil).Once()
+
+		_, err := handler.Invoke(ctx, reqBytes)
+		require.ErrorContains(t, err, ErrNotProxied.Error())
+	})
+
+	t.Run("never should fail when the bastion is unreachable", func(t *testing.T) {
+		handler, client := newHandler(t, funcie.FallbackPolicyNever)
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(nil, errors.New("connection refused")).Once()
+
+		_, err := handler.Invoke(ctx, reqBytes)
+		require.ErrorContains(t, err, "connection refused")
+	})
+
+	t.Run("on-error should fall back when the local handler fails", func(t *testing.T) {
+		handler, client := newHandler(t, funcie.FallbackPolicyOnError)
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(newResponse(errors.New("local failure"), ""), nil).Once()
+
+		requireDirect(t, handler)
+	})
+
+	t.Run("on-unavailable should return errors from the local handler", func(t *testing.T) {
+		handler, client := newHandler(t, funcie.FallbackPolicyOnUnavailable)
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(newResponse(errors.New("local failure"), ""), nil).Once()
+
+		_, err := handler.Invoke(ctx, reqBytes)
+		require.ErrorContains(t, err, "local failure")
+	})
+
+	t.Run("should apply a runtime override", func(t *testing.T) {
+		handler, client := newHandler(t, funcie.FallbackPolicyNever)
+		client.EXPECT().SendRequest(ctx, mock.Anything).
+			Return(newResponse(funcie.ErrNoActiveConsumer, funcie.FallbackPolicyOnUnavailable), nil).Once()
+
+		requireDirect(t, handler)
+	})
+
+	t.Run("local-only should ignore runtime overrides", func(t *testing.T) {
+		handler, client := newHandler(t, funcie.FallbackPolicyLocalOnly)
+		client.EXPECT().SendRequest(ctx, mock.Anything).
+			Return(newResponse(funcie.ErrNoActiveConsumer, funcie.FallbackPolicyOnError), nil).Once()
+
+		_, err := handler.Invoke(ctx, reqBytes)
+		require.ErrorContains(t, err, ErrNotProxied.Error())
+	})
+}
+
+func TestLambdaProxy_ShadowMode(t *testing.T) {
+	ctx := context.Background()
+	reqBytes := funcie.MustSerialize(events.LambdaFunctionURLRequest{})
+
+	rawHandler := func(ctx context.Context, payload events.LambdaFunctionURLRequest) (string, error) {
+		return "Hello world direct", nil
+	}
+
+	client := mocks.NewBastionClient(t)
+	proxy := NewLambdaFunctionProxy("app", client, rawHandler, slog.Default(), WithShadowMode(true, time.Second))
+	handler := proxy.(*lambdaProxy).lambdaHandler()
+
+	isShadowRequest := mock.MatchedBy(func(message *funcie.Message) bool {
+		forwardRequest, err := funcie.UnmarshalMessagePayload[messages.ForwardRequestMessage](message)
+		return err == nil &&
+			forwardRequest.Payload.Shadow != nil &&
+			string(forwardRequest.Payload.Shadow.Body) == `"Hello world direct"`
+	})
+
+	t.Run("should respond with the deployed result after forwarding it", func(t *testing.T) {
+		client.EXPECT().SendRequest(mock.Anything, isShadowRequest).
+			Return(funcie.NewResponse("id", funcie.MustSerialize(messages.ForwardRequestResponsePayload{}), nil), nil).Once()
+
+		responseBytes, err := handler.Invoke(ctx, reqBytes)
+		require.NoError(t, err)
+		require.Equal(t, "Hello world direct", funcie.MustDeserialize[string](responseBytes))
+	})
+
+	t.Run("should respond with the deployed result if the shadow request fails", func(t *testing.T) {
+		client.EXPECT().SendRequest(mock.Anything, isShadowRequest).Return(nil, errors.New("connection refused")).Once()
+
+		responseBytes, err := handler.Invoke(ctx, reqBytes)
+		require.NoError(t, err)
+		require.Equal(t, "Hello world direct", funcie.MustDeserialize[string](responseBytes))
+	})
+}
+
+func TestLambdaProxy_ShadowModeStreaming(t *testing.T) {
+	ctx := context.Background()
+	payload := json.RawMessage(funcie.MustSerialize(events.LambdaFunctionURLRequest{}))
+
+	rawHandler := func(ctx context.Context, payload events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+		return &events.LambdaFunctionURLStreamingResponse{
+			StatusCode: 200,
+			Body:       strings.NewReader("Hello world streamed"),
+		}, nil
+	}
+
+	client := mocks.NewBastionClient(t)
+	proxy := NewLambdaFunctionProxy("app", client, rawHandler, slog.Default(), WithShadowMode(true, time.Second))
+	handle := proxy.(*lambdaProxy).handle
+
+	t.Run("should respond with the deployed stream and its content type", func(t *testing.T) {
+		client.EXPECT().SendRequest(mock.Anything, mock.Anything).
+			Return(funcie.NewResponse("id", funcie.MustSerialize(messages.ForwardRequestResponsePayload{}), nil), nil).Once()
+
+		res, err := handle(ctx, &payload)
+		require.NoError(t, err)
+		require.Equal(t, "application/vnd.awslambda.http-integration-response", streamContentType(res))
+
+		body, err := io.ReadAll(res)
+		require.NoError(t, err)
+		require.Contains(t, string(body), `"statusCode":200`)
+		require.True(t, strings.HasSuffix(string(body), "Hello world streamed"))
+	})
+}
+
+func TestLambdaProxy_CircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	reqBytes := funcie.MustSerialize(events.LambdaFunctionURLRequest{})
+
+	rawHandler := func(ctx context.Context, payload events.LambdaFunctionURLRequest) (string, error) {
+		return "Hello world direct", nil
+	}
+
+	client := mocks.NewBastionClient(t)
+	proxy := NewLambdaFunctionProxy("app", client, rawHandler, slog.Default(), WithCircuitBreaker(CircuitBreakerOptions{
+		Threshold:  2,
+		Backoff:    time.Hour,
+		MaxBackoff: time.Hour,
+	}))
+	handler := proxy.(*lambdaProxy).lambdaHandler()
+
+	t.Run("should skip the bastion once the circuit opens", func(t *testing.T) {
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(nil, errors.New("connection refused")).Once()
+		client.EXPECT().SendRequest(ctx, mock.Anything).Return(funcie.NewResponse("id", nil, funcie.ErrNoActiveConsumer), nil).Once()
+
+		for i := 0; i < 4; i++ {
+			responseBytes, err := handler.Invoke(ctx, reqBytes)
+			require.NoError(t, err)
+			require.Equal(t, "Hello world direct", funcie.MustDeserialize[string](responseBytes))
+		}
+
+		require.Equal(t, CircuitOpen, proxy.(*lambdaProxy).breaker.State())
+	})
 }
@@ -2,17 +2,20 @@ package funcietunnel
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/Kapps/funcie/pkg/funcie"
 	"github.com/Kapps/funcie/pkg/funcie/messages"
-	"github.com/aws/aws-lambda-go/lambda"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 // BastionReceiver represents a receiver that can be used to receive requests from a bastion.
@@ -23,37 +26,67 @@ type BastionReceiver interface {
 	Stop()
 }
 
+// applicationPathPrefix is the path prefix under which each application served by a receiver is registered.
+const applicationPathPrefix = "/apps/"
+
+// deregisterTimeout is the maximum amount of time to wait for the applications to be deregistered when stopping.
+const deregisterTimeout = 5 * time.Second
+
 type bastionReceiver struct {
-	applicationId   string
+	// applications are the sandboxes for each application served by this receiver, keyed by application ID.
+	applications    map[string]*sandboxPool
 	bastionEndpoint url.URL
 	listenAddress   string
 	server          *http.Server
 	client          *http.Client
 	logger          *slog.Logger
-	// handlerFactory is a function that returns a new handler for each request.
-	// This is necessary because the AWS SDK handler is not safe for concurrent requests.
-	handlerFactory func() lambda.Handler
+	// bastionProtocol is the protocol of the client bastion, as reported when registering.
+	bastionProtocol *funcie.ProtocolInfo
+	// registered are the IDs of the applications that are currently registered with the client bastion.
+	registered []string
+	lock       sync.Mutex
 }
 
 // NewLambdaBastionReceiver creates a new BastionReceiver for AWS Lambda operations.
 // The handler is the handler that will be invoked when a request is received.
-// It is subject to the same restrictions as the handler for the underlying serverless function provider (such as lambda.Start).
+// It is subject to the same restrictions as the handler for the underlying serverless function provider (such as lambda.Start),
+// or may be an InitFunc that creates the handler for each sandbox.
+// By default, invocations are unlimited and run in reused sandboxes; see ReceiverOptions for how to change this.
 func NewLambdaBastionReceiver(
 	applicationId string,
 	listenAddress string,
 	bastionEndpoint url.URL,
 	handler interface{},
 	logger *slog.Logger,
+	opts ...ReceiverOptionSetter,
 ) BastionReceiver {
+	handlers := map[string]interface{}{applicationId: handler}
+	return NewMultiLambdaBastionReceiver(listenAddress, bastionEndpoint, handlers, logger, opts...)
+}
+
+// NewMultiLambdaBastionReceiver creates a new BastionReceiver for AWS Lambda operations that serves multiple applications
+// from a single listener. The handlers map each application ID to the handler that will be invoked for its requests,
+// with the same restrictions as NewLambdaBastionReceiver. Requests are dispatched to each application based on the path
+// that it was registered with. All applications are registered together when started, and deregistered together when stopped.
+func NewMultiLambdaBastionReceiver(
+	listenAddress string,
+	bastionEndpoint url.URL,
+	handlers map[string]interface{},
+	logger *slog.Logger,
+	opts ...ReceiverOptionSetter,
+) BastionReceiver {
+	options := newReceiverOptions(opts...)
+	applications := make(map[string]*sandboxPool, len(handlers))
+	for applicationId, handler := range handlers {
+		applications[applicationId] = newSandboxPool(handler, options)
+	}
+
 	return &bastionReceiver{
-		applicationId:   applicationId,
+		applications:    applications,
 		bastionEndpoint: bastionEndpoint,
 		listenAddress:   listenAddress,
 		client:          &http.Client{},
 		logger:          logger,
-		handlerFactory: func() lambda.Handler {
-			return lambda.NewHandler(handler)
-		},
 		server: &http.Server{
 			Addr: listenAddress,
 		},
@@ -70,73 +103,167 @@ func (r *bastionReceiver) Start() {
 	// This is just easier.
 	listener, err := net.Listen("tcp4", r.listenAddress)
 	if err != nil {
-		r.logger.Error("failed to listen", err)
+		r.logger.Error("failed to listen", "error", err)
 		panic(err)
 	}
 
 	// And we should subscribe using our listen address, but with the port that the listener is listening on.
 	// This allows us to do something like 127.0.0.1:0 as a listen address for a random port.
 
-	err = r.subscribe(listener.Addr())
+	err = r.subscribeAll(listener.Addr())
 	if err != nil {
-		r.logger.Error("failed to subscribe", err)
+		r.logger.Error("failed to subscribe", "error", err)
+		_ = listener.Close()
 		panic(err)
 	}
 
-	r.logger.Info("starting bastion receiver", "applicationId", r.applicationId, "listenAddress", listener.Addr())
+	r.logger.Info("starting bastion receiver", "applications", r.applicationIds(), "listenAddress", listener.Addr())
 
 	err = r.server.Serve(listener)
-	r.logger.Warn("server stopped", "err", err)
+	r.logger.Warn("server stopped", "error", err)
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		panic(err)
 	}
 }
 
 func (r *bastionReceiver) Stop() {
-	r.logger.Info("stopping bastion receiver", "applicationId", r.applicationId)
+	r.logger.Info("stopping bastion receiver", "applications", r.applicationIds())
+	r.unsubscribeAll()
+
 	err := r.server.Close()
 	if err != nil {
-		r.logger.Error("failed to close server", err)
+		r.logger.Error("failed to close server", "error", err)
 	}
 }
 
-func (r *bastionReceiver) subscribe(addr net.Addr) error {
-	localEndpoint := funcie.MustNewEndpointFromAddress(fmt.Sprintf("http://%s/", addr))
-	registerEndpoint := fmt.Sprintf("%s/dispatch", r.bastionEndpoint.String())
-	payload := messages.NewRegistrationRequestPayload(r.applicationId, localEndpoint)
-	message := funcie.NewMessageWithPayload(r.applicationId, messages.MessageKindRegister, payload)
+// applicationIds returns the IDs of every application served by this receiver, in a consistent order.
+func (r *bastionReceiver) applicationIds() []string {
+	return sortedKeys(r.applications)
+}
 
-	marshaled, err := json.Marshal(message)
+// subscribeAll registers every application with the bastion.
+// If any application fails to register, the applications that were already registered are deregistered.
+func (r *bastionReceiver) subscribeAll(addr net.Addr) error {
+	for _, applicationId := range r.applicationIds() {
+		if err := r.subscribe(addr, applicationId); err != nil {
+			r.unsubscribeAll()
+			return fmt.Errorf("register application %v: %w", applicationId, err)
+		}
+
+		r.lock.Lock()
+		r.registered = append(r.registered, applicationId)
+		r.lock.Unlock()
+	}
+
+	return nil
+}
+
+// unsubscribeAll deregisters every registered application from the bastion.
+// Failures are only logged, as the bastion will stop routing to the application once it is unreachable regardless.
+func (r *bastionReceiver) unsubscribeAll() {
+	r.lock.Lock()
+	registered := r.registered
+	r.registered = nil
+	r.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancel()
+
+	for _, applicationId := range registered {
+		if err := r.unsubscribe(ctx, applicationId); err != nil {
+			r.logger.Warn("failed to deregister application", "applicationId", applicationId, "error", err)
+		}
+	}
+}
+
+func (r *bastionReceiver) subscribe(addr net.Addr, applicationId string) error {
+	localEndpoint := funcie.MustNewEndpointFromAddress(fmt.Sprintf("http://%s%s", addr, applicationPath(applicationId)))
+	payload := messages.NewRegistrationRequestPayload(applicationId, localEndpoint)
+	message := funcie.NewMessageWithPayload(applicationId, messages.MessageKindRegister, payload)
+
+	r.logger.Info("sending registration request", "message", message, "bastionEndpoint", r.bastionEndpoint.String())
+
+	response, err := r.dispatch(context.Background(), message)
 	if err != nil {
-		return fmt.Errorf("marshal message: %w", err)
+		return err
 	}
 
-	r.logger.Info("sending registration request",
-		"message", message, "bastionEndpoint", r.bastionEndpoint.String(), "registerEndpoint", registerEndpoint)
+	if response.Error != nil {
+		return fmt.Errorf("registration rejected: %w", response.Error)
+	}
 
-	resp, err := r.client.Post(registerEndpoint, "application/json", bytes.NewReader(marshaled))
+	registration, err := funcie.UnmarshalResponsePayload[messages.RegistrationResponse](response)
 	if err != nil {
-		return fmt.Errorf("post: %w", err)
+		return fmt.Errorf("unmarshal registration response: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	bastionProtocol := registration.Data.Protocol
+	if err := funcie.CheckProtocolCompatibility(bastionProtocol, "client bastion"); err != nil {
+		return err
+	}
+
+	r.logger.Info("received registration response", "response", response, "bastionVersion", bastionProtocol)
+	r.bastionProtocol = bastionProtocol
+
+	return nil
+}
+
+func (r *bastionReceiver) unsubscribe(ctx context.Context, applicationId string) error {
+	payload := messages.NewDeregistrationRequestPayload(applicationId)
+	message := funcie.NewMessageWithPayload(applicationId, messages.MessageKindDeregister, payload)
+
+	r.logger.Info("sending deregistration request", "message", message, "bastionEndpoint", r.bastionEndpoint.String())
+
+	response, err := r.dispatch(ctx, message)
+	if err != nil {
+		return err
 	}
 
+	if response.Error != nil {
+		return fmt.Errorf("deregistration rejected: %w", response.Error)
+	}
+
+	return nil
+}
+
+// dispatch sends the given message to the bastion, returning the bastion's response.
+func (r *bastionReceiver) dispatch(ctx context.Context, message any) (*funcie.Response, error) {
+	dispatchEndpoint := fmt.Sprintf("%s/dispatch", r.bastionEndpoint.String())
+
+	marshaled, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatchEndpoint, bytes.NewReader(marshaled))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("read response body: %w", err)
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(responseBody))
 	}
 
 	var response funcie.Response
 	err = json.Unmarshal(responseBody, &response)
 	if err != nil {
-		return fmt.Errorf("unmarshal response: %w", err)
+		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
-	r.logger.Info("received registration response", "response", response)
-
-	return nil
+	return &response, nil
 }
 
 func (r *bastionReceiver) handleRequest(w http.ResponseWriter, req *http.Request) {
@@ -145,7 +272,7 @@ func (r *bastionReceiver) handleRequest(w http.ResponseWriter, req *http.Request
 	ctx := req.Context()
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "failed to read request body", err)
+		r.logger.ErrorContext(ctx, "failed to read request body", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -155,7 +282,7 @@ func (r *bastionReceiver) handleRequest(w http.ResponseWriter, req *http.Request
 	var message funcie.Message
 	err = json.Unmarshal(body, &message)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "failed to unmarshal request body", err)
+		r.logger.ErrorContext(ctx, "failed to unmarshal request body", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -167,41 +294,134 @@ func (r *bastionReceiver) handleRequest(w http.ResponseWriter, req *http.Request
 		return
 	}
 
+	applicationId, ok := applicationFromPath(req.URL.EscapedPath())
+	if !ok {
+		// Client bastions that predate application paths send every request to the root path.
+		applicationId = message.Application
+	}
+	if applicationId != message.Application {
+		r.logger.WarnContext(ctx, "received message for a different application than its path",
+			"application", message.Application, "path", req.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	pool, ok := r.applications[applicationId]
+	if !ok {
+		r.logger.WarnContext(ctx, "received message for unknown application", "application", applicationId)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	unmarshaled, err := funcie.UnmarshalMessagePayload[messages.ForwardRequestMessage](&message)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "failed to unmarshal request message", err)
+		r.logger.ErrorContext(ctx, "failed to unmarshal request message", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	payload := []byte(unmarshaled.Payload.Body)
-	handler := r.handlerFactory()
+	response := r.invokeHandler(ctx, pool.Invoke, &message, payload)
 
-	var response *funcie.ResponseBase[messages.ForwardRequestResponsePayload]
-	invokeResponse, err := handler.Invoke(ctx, payload)
-	if err != nil {
-		r.logger.ErrorContext(ctx, "failed to handle message", "error", err)
-		response = funcie.NewResponseWithPayload[messages.ForwardRequestResponsePayload](message.ID, nil, err)
-	} else {
-		r.logger.DebugContext(ctx, "received response", "response", invokeResponse)
-		responsePayload := messages.NewForwardRequestResponsePayload(invokeResponse)
-		response = funcie.NewResponseWithPayload(message.ID, responsePayload, nil)
+	r.logger.DebugContext(ctx, "sending response", "response", response)
+	if response.Stream != nil {
+		marshaled, err := funcie.MarshalResponsePayload(response)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "failed to marshal response", "error", err)
+			funcie.CloseOrLog("response stream", response.Stream)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Once the stream has started, the status code has already been sent, so we can only log failures.
+		if err := funcie.WriteStreamedResponse(ctx, w, marshaled); err != nil {
+			r.logger.ErrorContext(ctx, "failed to write streamed response", "error", err)
+			return
+		}
+
+		r.logger.DebugContext(ctx, "sent streamed response")
+		return
 	}
 
-	r.logger.DebugContext(ctx, "sending response", "response", response)
 	responseBody, err := json.Marshal(response)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "failed to marshal response", err)
+		r.logger.ErrorContext(ctx, "failed to marshal response", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	_, err = w.Write(responseBody)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "failed to write response", err)
+		r.logger.ErrorContext(ctx, "failed to write response", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	r.logger.DebugContext(ctx, "sent response")
 }
+
+// invokeHandler invokes the handler for the given message, returning the response to send back to the bastion.
+// If the handler streams its response, the response is streamed back when both the bastion and the caller support it.
+func (r *bastionReceiver) invokeHandler(
+	ctx context.Context,
+	invoke handlerInvoker,
+	message *funcie.Message,
+	payload []byte,
+) *funcie.ResponseBase[messages.ForwardRequestResponsePayload] {
+	invokeResponse, stream, err := invoke(ctx, payload)
+	if errors.Is(err, funcie.ErrThrottled) {
+		r.logger.InfoContext(ctx, "throttled invocation", "application", message.Application)
+		return funcie.NewResponseWithPayload[messages.ForwardRequestResponsePayload](message.ID, nil, funcie.ErrThrottled)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to handle message", "error", err)
+		return funcie.NewResponseWithPayload[messages.ForwardRequestResponsePayload](message.ID, nil, err)
+	}
+
+	if stream == nil {
+		r.logger.DebugContext(ctx, "received response", "response", invokeResponse)
+		responsePayload := messages.NewForwardRequestResponsePayload(invokeResponse)
+		return funcie.NewResponseWithPayload(message.ID, responsePayload, nil)
+	}
+
+	if !message.Protocol.HasCapability(funcie.CapabilityResponseStreaming) ||
+		!r.bastionProtocol.HasCapability(funcie.CapabilityResponseStreaming) {
+		if closer, ok := stream.(io.Closer); ok {
+			funcie.CloseOrLog("unsupported response stream", closer)
+		}
+		err := fmt.Errorf(
+			"handler returned a streamed response, but the Lambda proxy (%v) or client bastion (%v) does not support response streaming; upgrade both to v%v",
+			message.Protocol, r.bastionProtocol, funcie.Version(),
+		)
+		r.logger.ErrorContext(ctx, "failed to handle message", "error", err)
+		return funcie.NewResponseWithPayload[messages.ForwardRequestResponsePayload](message.ID, nil, err)
+	}
+
+	contentType := streamContentType(stream)
+	r.logger.DebugContext(ctx, "received streamed response", "contentType", contentType)
+	responsePayload := messages.NewStreamingForwardRequestResponsePayload(contentType)
+	response := funcie.NewResponseWithPayload(message.ID, responsePayload, nil)
+	response.AttachStream(newChunkedResponseStream(message.Application, message.ID, stream))
+	return response
+}
+
+// applicationPath returns the path that requests for the given application are sent to.
+func applicationPath(applicationId string) string {
+	return applicationPathPrefix + url.PathEscape(applicationId)
+}
+
+// applicationFromPath returns the application ID that the given escaped request path is for, if any.
+func applicationFromPath(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, applicationPathPrefix)
+	if !ok {
+		return "", false
+	}
+
+	escaped, _, _ := strings.Cut(rest, "/")
+	applicationId, err := url.PathUnescape(escaped)
+	if err != nil || applicationId == "" {
+		return "", false
+	}
+
+	return applicationId, true
+}
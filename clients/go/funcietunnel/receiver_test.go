@@ -9,12 +9,15 @@ import (
 	"github.com/Kapps/funcie/pkg/funcie/messages"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -55,41 +58,233 @@ func TestLambdaBastionReceiver_Integration(t *testing.T) {
 
 }
 
-func registerServer(t *testing.T, handler interface{}) funcie.Endpoint {
-	applicationId := "app"
-	registrationChannel := make(chan funcie.Endpoint)
+func TestLambdaBastionReceiver_Streaming(t *testing.T) {
+	body := strings.Repeat("Hello streaming world\n", chunkSize/10)
+	handler := func(ctx context.Context, request events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+		return &events.LambdaFunctionURLStreamingResponse{
+			StatusCode: 200,
+			Body:       strings.NewReader(body),
+		}, nil
+	}
 
-	bastionRegistrationStubHandler := func(w http.ResponseWriter, r *http.Request) {
-		req, err := io.ReadAll(r.Body)
+	listenerAddress := registerServer(t, handler)
+
+	sendRequest := func(t *testing.T, protocol *funcie.ProtocolInfo) *http.Response {
+		forwardRequestPayload := messages.NewForwardRequestPayload(funcie.MustSerialize(events.LambdaFunctionURLRequest{}))
+		forwardMessage := funcie.NewMessageWithPayload("app", messages.MessageKindForwardRequest, &forwardRequestPayload)
+		forwardMessage.Protocol = protocol
+
+		resp, err := http.Post(listenerAddress.String(), "application/json", bytes.NewReader(funcie.MustSerialize(forwardMessage)))
 		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
 
-		var message messages.RegistrationMessage
-		require.NoError(t, json.Unmarshal(req, &message))
+		return resp
+	}
 
-		require.Equal(t, messages.MessageKindRegister, message.Kind)
+	t.Run("should stream the response in chunks", func(t *testing.T) {
+		resp := sendRequest(t, funcie.NewProtocolInfo())
+		require.Equal(t, funcie.StreamContentType, resp.Header.Get("Content-Type"))
 
-		respPayload := messages.NewRegistrationResponsePayload(uuid.New())
-		resp := funcie.NewResponseWithPayload(message.ID, &respPayload, err)
+		response, err := funcie.ReadStreamedResponse(resp.Body)
+		require.NoError(t, err)
 
-		respBytes, err := json.Marshal(resp)
+		forwardResponse, err := funcie.UnmarshalResponsePayload[messages.ForwardRequestResponse](response)
 		require.NoError(t, err)
+		require.True(t, forwardResponse.Data.Streaming)
+		require.Equal(t, "application/vnd.awslambda.http-integration-response", forwardResponse.Data.ContentType)
 
-		_, err = w.Write(respBytes)
+		reader := newChunkReader(context.Background(), forwardResponse.Stream, forwardResponse.Data.ContentType)
+		t.Cleanup(func() { _ = reader.Close() })
+
+		streamed, err := io.ReadAll(reader)
 		require.NoError(t, err)
+		require.Greater(t, reader.sequence, 2)
+		require.Contains(t, string(streamed), `"statusCode":200`)
+		require.True(t, strings.HasSuffix(string(streamed), body))
+	})
+
+	t.Run("should return an error if the caller does not support streaming", func(t *testing.T) {
+		resp := sendRequest(t, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var responseMessage funcie.ResponseBase[messages.ForwardRequestResponsePayload]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&responseMessage))
+
+		require.NotNil(t, responseMessage.Error)
+		require.Contains(t, responseMessage.Error.Message, "does not support response streaming")
+	})
+}
 
-		registrationChannel <- message.Payload.Endpoint
+func registerServer(t *testing.T, handler interface{}) funcie.Endpoint {
+	_, endpoints, _ := registerMultiServer(t, map[string]interface{}{"app": handler})
+	return endpoints["app"]
+}
+
+// registerMultiServer starts a receiver for the given handlers with a stub bastion, returning the endpoint that each
+// application registered with, and a channel that receives the name of each application as it is deregistered.
+func registerMultiServer(t *testing.T, handlers map[string]interface{}) (BastionReceiver, map[string]funcie.Endpoint, <-chan string) {
+	registrationChannel := make(chan messages.RegistrationMessage, len(handlers))
+	deregistrationChannel := make(chan string, len(handlers))
+
+	// Runs on the goroutine of the server, so failures are reported with assert rather than require.
+	bastionStubHandler := func(w http.ResponseWriter, r *http.Request) {
+		req, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var message funcie.Message
+		if !assert.NoError(t, json.Unmarshal(req, &message)) {
+			return
+		}
+
+		switch message.Kind {
+		case messages.MessageKindRegister:
+			registration, err := funcie.UnmarshalMessagePayload[messages.RegistrationMessage](&message)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			respPayload := messages.NewRegistrationResponsePayload(uuid.New())
+			_, err = w.Write(funcie.MustSerialize(funcie.NewResponseWithPayload(message.ID, respPayload, nil)))
+			assert.NoError(t, err)
+
+			registrationChannel <- *registration
+		case messages.MessageKindDeregister:
+			respPayload := messages.NewDeregistrationResponsePayload()
+			_, err = w.Write(funcie.MustSerialize(funcie.NewResponseWithPayload(message.ID, respPayload, nil)))
+			assert.NoError(t, err)
+
+			deregistrationChannel <- message.Application
+		default:
+			t.Errorf("unexpected message kind %v", message.Kind)
+		}
 	}
 
-	bastionServer := httptest.NewServer(http.HandlerFunc(bastionRegistrationStubHandler))
+	bastionServer := httptest.NewServer(http.HandlerFunc(bastionStubHandler))
 	t.Cleanup(bastionServer.Close)
 
 	bastionUrl, err := url.Parse(fmt.Sprintf("http://%s", bastionServer.Listener.Addr().String()))
 	require.NoError(t, err)
 
-	receiver := NewLambdaBastionReceiver(applicationId, "localhost:0", *bastionUrl, handler, slog.Default())
+	receiver := NewMultiLambdaBastionReceiver("localhost:0", *bastionUrl, handlers, slog.Default())
 	t.Cleanup(receiver.Stop)
 
 	go receiver.Start()
 
-	return <-registrationChannel
+	endpoints := make(map[string]funcie.Endpoint, len(handlers))
+	for range handlers {
+		registration := <-registrationChannel
+		require.Equal(t, registration.Application, registration.Payload.Name)
+		endpoints[registration.Payload.Name] = registration.Payload.Endpoint
+	}
+
+	return receiver, endpoints, deregistrationChannel
+}
+
+func TestLambdaBastionReceiver_MultipleApplications(t *testing.T) {
+	newHandler := func(name string) interface{} {
+		return func(ctx context.Context) (string, error) {
+			return "Hello from " + name, nil
+		}
+	}
+
+	receiver, endpoints, deregistrations := registerMultiServer(t, map[string]interface{}{
+		"first":  newHandler("first"),
+		"second": newHandler("second"),
+	})
+
+	sendRequest := func(t *testing.T, address string, application string) *http.Response {
+		forwardRequestPayload := messages.NewForwardRequestPayload([]byte("{}"))
+		forwardMessage := funcie.NewMessageWithPayload(application, messages.MessageKindForwardRequest, &forwardRequestPayload)
+
+		resp, err := http.Post(address, "application/json", bytes.NewReader(funcie.MustSerialize(forwardMessage)))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		return resp
+	}
+
+	readBody := func(t *testing.T, resp *http.Response) string {
+		var responseMessage funcie.ResponseBase[messages.ForwardRequestResponsePayload]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&responseMessage))
+		return funcie.MustDeserialize[string](responseMessage.Data.Body)
+	}
+
+	t.Run("should register each application on the same listener with its own path", func(t *testing.T) {
+		first, second := endpoints["first"], endpoints["second"]
+		require.Equal(t, first.Port, second.Port)
+		require.Equal(t, "/apps/first", first.Path)
+		require.Equal(t, "/apps/second", second.Path)
+	})
+
+	t.Run("should dispatch requests based on the path", func(t *testing.T) {
+		for name, endpoint := range endpoints {
+			resp := sendRequest(t, endpoint.String()+"/process", name)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, "Hello from "+name, readBody(t, resp))
+		}
+	})
+
+	t.Run("should dispatch requests without a path based on the message", func(t *testing.T) {
+		root := endpoints["second"]
+		root.Path = ""
+
+		resp := sendRequest(t, root.String()+"/process", "second")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "Hello from second", readBody(t, resp))
+	})
+
+	t.Run("should reject requests for a different application than the path", func(t *testing.T) {
+		resp := sendRequest(t, endpoints["first"].String()+"/process", "second")
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should reject requests for unknown applications", func(t *testing.T) {
+		root := endpoints["first"]
+		root.Path = "/apps/unknown"
+
+		resp := sendRequest(t, root.String()+"/process", "unknown")
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("should deregister every application when stopped", func(t *testing.T) {
+		receiver.Stop()
+
+		deregistered := []string{<-deregistrations, <-deregistrations}
+		require.ElementsMatch(t, []string{"first", "second"}, deregistered)
+	})
+}
+
+func TestLambdaBastionReceiver_IncompatibleBastion(t *testing.T) {
+	bastionHandler := func(w http.ResponseWriter, r *http.Request) {
+		req, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var message messages.RegistrationMessage
+		require.NoError(t, json.Unmarshal(req, &message))
+		require.Equal(t, funcie.NewProtocolInfo(), message.Protocol)
+
+		respPayload := messages.NewRegistrationResponsePayload(uuid.New())
+		respPayload.Protocol = &funcie.ProtocolInfo{Version: funcie.ProtocolVersion + 1, Release: "99.0.0"}
+		resp := funcie.NewResponseWithPayload(message.ID, respPayload, nil)
+
+		_, err = w.Write(funcie.MustSerialize(resp))
+		require.NoError(t, err)
+	}
+
+	bastionServer := httptest.NewServer(http.HandlerFunc(bastionHandler))
+	t.Cleanup(bastionServer.Close)
+
+	bastionUrl, err := url.Parse(bastionServer.URL)
+	require.NoError(t, err)
+
+	receiver := NewLambdaBastionReceiver("app", "localhost:0", *bastionUrl, func() {}, slog.Default()).(*bastionReceiver)
+
+	addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:8080")
+	require.NoError(t, err)
+
+	err = receiver.subscribe(addr, "app")
+	require.ErrorIs(t, err, funcie.ErrIncompatibleProtocol)
+	require.Contains(t, err.Error(), "client bastion is v99.0.0")
 }
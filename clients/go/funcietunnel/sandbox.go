@@ -0,0 +1,241 @@
+package funcietunnel
+
+import (
+	"context"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"io"
+	"sync"
+)
+
+// InitFunc performs the initialization phase of a Lambda sandbox, returning the handler for that sandbox.
+// It is the equivalent of the code that runs before lambda.Start in a real Lambda, such as creating clients.
+// An InitFunc can be passed anywhere a handler is accepted; it is run when a new sandbox is created.
+type InitFunc func(ctx context.Context) (interface{}, error)
+
+// ThrottleMode controls what happens to an invocation when every sandbox of an application is busy.
+type ThrottleMode string
+
+const (
+	// ThrottleModeQueue waits for a sandbox to become available, as an asynchronous invocation would.
+	ThrottleModeQueue ThrottleMode = "queue"
+	// ThrottleModeReject immediately rejects the invocation with funcie.ErrThrottled, causing the Lambda to handle it instead.
+	ThrottleModeReject ThrottleMode = "reject"
+)
+
+// SandboxMode controls whether sandboxes are reused between invocations.
+type SandboxMode string
+
+const (
+	// SandboxModeReuse keeps sandboxes warm, so the handler is initialized once per sandbox and then reused.
+	SandboxModeReuse SandboxMode = "reuse"
+	// SandboxModePerInvocation creates a new sandbox for every invocation, so every invocation is a cold start.
+	SandboxModePerInvocation SandboxMode = "per-invocation"
+)
+
+// ReceiverOptions are the options for how a BastionReceiver invokes its handlers.
+type ReceiverOptions struct {
+	// MaxConcurrency is the maximum number of concurrent invocations per application, or 0 for no limit.
+	// Each concurrent invocation runs in its own sandbox, like a Lambda with reserved concurrency.
+	MaxConcurrency int
+	// ThrottleMode controls what happens when MaxConcurrency invocations are already running.
+	ThrottleMode ThrottleMode
+	// SandboxMode controls whether sandboxes are reused between invocations.
+	SandboxMode SandboxMode
+}
+
+// ReceiverOptionSetter sets an option for a BastionReceiver.
+type ReceiverOptionSetter func(*ReceiverOptions)
+
+// WithMaxConcurrency limits the number of concurrent invocations per application.
+func WithMaxConcurrency(maxConcurrency int) ReceiverOptionSetter {
+	return func(opts *ReceiverOptions) {
+		opts.MaxConcurrency = maxConcurrency
+	}
+}
+
+// WithThrottleMode sets what happens to an invocation when the concurrency limit has been reached.
+func WithThrottleMode(mode ThrottleMode) ReceiverOptionSetter {
+	return func(opts *ReceiverOptions) {
+		opts.ThrottleMode = mode
+	}
+}
+
+// WithSandboxMode sets whether sandboxes are reused between invocations.
+func WithSandboxMode(mode SandboxMode) ReceiverOptionSetter {
+	return func(opts *ReceiverOptions) {
+		opts.SandboxMode = mode
+	}
+}
+
+// WithReceiverOptions sets all options from the given ReceiverOptions, such as those from a FuncieConfig.
+func WithReceiverOptions(options ReceiverOptions) ReceiverOptionSetter {
+	return func(opts *ReceiverOptions) {
+		*opts = options
+	}
+}
+
+func newReceiverOptions(setters ...ReceiverOptionSetter) ReceiverOptions {
+	opts := ReceiverOptions{
+		ThrottleMode: ThrottleModeQueue,
+		SandboxMode:  SandboxModeReuse,
+	}
+	for _, setter := range setters {
+		setter(&opts)
+	}
+	return opts
+}
+
+// sandbox is a single simulated Lambda execution environment, which handles one invocation at a time.
+type sandbox struct {
+	invoke handlerInvoker
+}
+
+// sandboxPool manages the sandboxes for a single application.
+type sandboxPool struct {
+	handler interface{}
+	opts    ReceiverOptions
+	// slots has a buffer for every sandbox that may be in use, or is nil if there is no concurrency limit.
+	slots chan struct{}
+	idle  []*sandbox
+	lock  sync.Mutex
+}
+
+func newSandboxPool(handler interface{}, opts ReceiverOptions) *sandboxPool {
+	pool := &sandboxPool{
+		handler: handler,
+		opts:    opts,
+	}
+	if opts.MaxConcurrency > 0 {
+		pool.slots = make(chan struct{}, opts.MaxConcurrency)
+	}
+	return pool
+}
+
+// Invoke runs the payload in an available sandbox, creating and initializing a new sandbox if none are warm.
+// A streamed response keeps its sandbox busy until it has been read to the end or closed, as the handler may still be
+// writing it until then.
+// Returns funcie.ErrThrottled if the concurrency limit is reached and the pool is configured to reject invocations.
+func (p *sandboxPool) Invoke(ctx context.Context, payload []byte) ([]byte, io.Reader, error) {
+	if err := p.acquireSlot(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	sb, err := p.acquireSandbox(ctx)
+	if err != nil {
+		p.releaseSlot()
+		return nil, nil, err
+	}
+
+	release := sync.OnceFunc(func() {
+		p.releaseSandbox(sb)
+		p.releaseSlot()
+	})
+
+	res, stream, err := sb.invoke(ctx, payload)
+	if stream == nil {
+		release()
+		return res, nil, err
+	}
+
+	return nil, &sandboxStream{reader: stream, release: release}, nil
+}
+
+// sandboxStream is a streamed response that releases its sandbox once it has been read to the end or closed.
+// Its fields are unexported so that it still marshals to an empty object, and so is streamed rather than buffered.
+type sandboxStream struct {
+	reader  io.Reader
+	release func()
+}
+
+func (s *sandboxStream) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	if err != nil {
+		s.release()
+	}
+	return n, err
+}
+
+func (s *sandboxStream) Close() error {
+	defer s.release()
+	if closer, ok := s.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ContentType returns the content type of the underlying stream, so that wrapping it does not change the response.
+func (s *sandboxStream) ContentType() string {
+	return streamContentType(s.reader)
+}
+
+func (p *sandboxPool) acquireSlot(ctx context.Context) error {
+	if p.slots == nil {
+		return nil
+	}
+
+	if p.opts.ThrottleMode == ThrottleModeReject {
+		select {
+		case p.slots <- struct{}{}:
+			return nil
+		default:
+			return funcie.ErrThrottled
+		}
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *sandboxPool) releaseSlot() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+func (p *sandboxPool) acquireSandbox(ctx context.Context) (*sandbox, error) {
+	p.lock.Lock()
+	if n := len(p.idle); n > 0 {
+		sb := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.lock.Unlock()
+		return sb, nil
+	}
+	p.lock.Unlock()
+
+	handler, err := initHandler(ctx, p.handler)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sandbox{invoke: newHandlerInvoker(handler)}, nil
+}
+
+func (p *sandboxPool) releaseSandbox(sb *sandbox) {
+	if p.opts.SandboxMode == SandboxModePerInvocation {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.idle = append(p.idle, sb)
+}
+
+// initHandler returns the handler for a new sandbox, running the init function if the handler is an InitFunc.
+func initHandler(ctx context.Context, handler interface{}) (interface{}, error) {
+	init, ok := handler.(InitFunc)
+	if !ok {
+		return handler, nil
+	}
+
+	initialized, err := init(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("initializing sandbox: %w", err)
+	}
+
+	return initialized, nil
+}
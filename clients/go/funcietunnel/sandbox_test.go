@@ -0,0 +1,167 @@
+package funcietunnel
+
+import (
+	"context"
+	"errors"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSandboxPool_Invoke(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// newInit returns an InitFunc that counts how often it is run, and a handler that blocks until released.
+	newInit := func(inits *atomic.Int32, release <-chan struct{}) InitFunc {
+		return func(ctx context.Context) (interface{}, error) {
+			inits.Add(1)
+			return func(ctx context.Context) (string, error) {
+				if release != nil {
+					<-release
+				}
+				return "ok", nil
+			}, nil
+		}
+	}
+
+	t.Run("should reuse warm sandboxes", func(t *testing.T) {
+		var inits atomic.Int32
+		pool := newSandboxPool(newInit(&inits, nil), newReceiverOptions())
+
+		for i := 0; i < 3; i++ {
+			res, _, err := pool.Invoke(ctx, []byte("{}"))
+			require.NoError(t, err)
+			require.Equal(t, `"ok"`, string(res))
+		}
+
+		require.EqualValues(t, 1, inits.Load())
+	})
+
+	t.Run("should initialize a new sandbox per invocation", func(t *testing.T) {
+		var inits atomic.Int32
+		pool := newSandboxPool(newInit(&inits, nil), newReceiverOptions(WithSandboxMode(SandboxModePerInvocation)))
+
+		for i := 0; i < 3; i++ {
+			_, _, err := pool.Invoke(ctx, []byte("{}"))
+			require.NoError(t, err)
+		}
+
+		require.EqualValues(t, 3, inits.Load())
+	})
+
+	t.Run("should return errors from the init function", func(t *testing.T) {
+		initErr := errors.New("init failed")
+		pool := newSandboxPool(InitFunc(func(ctx context.Context) (interface{}, error) {
+			return nil, initErr
+		}), newReceiverOptions())
+
+		_, _, err := pool.Invoke(ctx, []byte("{}"))
+		require.ErrorIs(t, err, initErr)
+	})
+
+	t.Run("should reject invocations over the concurrency limit", func(t *testing.T) {
+		var inits atomic.Int32
+		release := make(chan struct{})
+		pool := newSandboxPool(newInit(&inits, release), newReceiverOptions(
+			WithMaxConcurrency(1),
+			WithThrottleMode(ThrottleModeReject),
+		))
+
+		done := make(chan error)
+		go func() {
+			_, _, err := pool.Invoke(ctx, []byte("{}"))
+			done <- err
+		}()
+
+		require.Eventually(t, func() bool { return inits.Load() == 1 }, time.Second, time.Millisecond)
+
+		_, _, err := pool.Invoke(ctx, []byte("{}"))
+		require.ErrorIs(t, err, funcie.ErrThrottled)
+
+		close(release)
+		require.NoError(t, <-done)
+	})
+
+	t.Run("should queue invocations over the concurrency limit", func(t *testing.T) {
+		var inits atomic.Int32
+		release := make(chan struct{})
+		pool := newSandboxPool(newInit(&inits, release), newReceiverOptions(WithMaxConcurrency(1)))
+
+		done := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				_, _, err := pool.Invoke(ctx, []byte("{}"))
+				done <- err
+			}()
+		}
+
+		require.Eventually(t, func() bool { return inits.Load() == 1 }, time.Second, time.Millisecond)
+		select {
+		case <-done:
+			t.Fatal("invocation completed before being released")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		require.NoError(t, <-done)
+		require.NoError(t, <-done)
+
+		// Only a single sandbox is ever needed, since the second invocation waited for the first.
+		require.EqualValues(t, 1, inits.Load())
+	})
+
+	t.Run("should keep a sandbox busy until its streamed response is closed", func(t *testing.T) {
+		var inits atomic.Int32
+		pool := newSandboxPool(InitFunc(func(ctx context.Context) (interface{}, error) {
+			inits.Add(1)
+			return func(ctx context.Context) (io.Reader, error) {
+				return strings.NewReader("streamed"), nil
+			}, nil
+		}), newReceiverOptions(
+			WithMaxConcurrency(1),
+			WithThrottleMode(ThrottleModeReject),
+		))
+
+		_, stream, err := pool.Invoke(ctx, []byte("{}"))
+		require.NoError(t, err)
+		require.NotNil(t, stream)
+
+		_, _, err = pool.Invoke(ctx, []byte("{}"))
+		require.ErrorIs(t, err, funcie.ErrThrottled)
+
+		require.NoError(t, stream.(io.Closer).Close())
+
+		_, stream, err = pool.Invoke(ctx, []byte("{}"))
+		require.NoError(t, err)
+		body, err := io.ReadAll(stream)
+		require.NoError(t, err)
+		require.Equal(t, "streamed", string(body))
+
+		// Reading the stream to the end also releases the sandbox, which is reused.
+		_, _, err = pool.Invoke(ctx, []byte("{}"))
+		require.NoError(t, err)
+		require.EqualValues(t, 1, inits.Load())
+	})
+
+	t.Run("should stop waiting when the context is cancelled", func(t *testing.T) {
+		var inits atomic.Int32
+		release := make(chan struct{})
+		defer close(release)
+		pool := newSandboxPool(newInit(&inits, release), newReceiverOptions(WithMaxConcurrency(1)))
+
+		go func() { _, _, _ = pool.Invoke(ctx, []byte("{}")) }()
+		require.Eventually(t, func() bool { return inits.Load() == 1 }, time.Second, time.Millisecond)
+
+		cancelCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, _, err := pool.Invoke(cancelCtx, []byte("{}"))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,186 @@
+package funcietunnel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"io"
+)
+
+// defaultStreamContentType is the content type used by the Lambda runtime for streamed responses that do not specify one.
+const defaultStreamContentType = "application/octet-stream"
+
+// bufferedContentType is the content type used by the Lambda runtime for responses that handlers return as values,
+// which are marshalled to JSON.
+const bufferedContentType = "application/json"
+
+// chunkSize is the maximum number of bytes included in a single chunk of a streamed response.
+const chunkSize = 32 * 1024
+
+// ErrIncompleteStream is returned when a streamed response ends before its final chunk is received.
+var ErrIncompleteStream = errors.New("streamed response ended before the final chunk")
+
+// contentTyper is implemented by streamed responses that specify their content type, such as
+// events.LambdaFunctionURLStreamingResponse.
+type contentTyper interface {
+	ContentType() string
+}
+
+// streamContentType returns the content type that the Lambda runtime would use for the given streamed response.
+func streamContentType(reader io.Reader) string {
+	if typed, ok := reader.(contentTyper); ok {
+		return typed.ContentType()
+	}
+	return defaultStreamContentType
+}
+
+// bufferedResponse is a response that was returned by a handler as a value rather than streamed, and so is already
+// marshalled to JSON. Reporting its content type keeps the Lambda runtime from treating it as an octet stream.
+type bufferedResponse struct {
+	*bytes.Reader
+	contentType string
+}
+
+func newBufferedResponse(body []byte) io.Reader {
+	return bufferedResponse{Reader: bytes.NewReader(body), contentType: bufferedContentType}
+}
+
+// newBufferedStream returns a streamed response that was read into memory, keeping the content type of the stream so
+// that the Lambda runtime handles it the same way, such as the prelude of a Function URL streaming response.
+func newBufferedStream(body []byte, contentType string) io.Reader {
+	return bufferedResponse{Reader: bytes.NewReader(body), contentType: contentType}
+}
+
+func (r bufferedResponse) ContentType() string {
+	return r.contentType
+}
+
+// chunkedResponseStream is a ResponseStream that reads the body of a streamed response from a handler,
+// and splits it into RESPONSE_CHUNK messages.
+type chunkedResponseStream struct {
+	applicationId string
+	requestId     string
+	reader        io.Reader
+	sequence      int
+	done          bool
+}
+
+func newChunkedResponseStream(applicationId string, requestId string, reader io.Reader) *chunkedResponseStream {
+	return &chunkedResponseStream{
+		applicationId: applicationId,
+		requestId:     requestId,
+		reader:        reader,
+	}
+}
+
+func (s *chunkedResponseStream) Next(_ context.Context) (*funcie.Message, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+
+	var payload *messages.ResponseChunkPayload
+	buf := make([]byte, chunkSize)
+	n, err := io.ReadFull(s.reader, buf)
+	switch {
+	case n > 0:
+		payload = messages.NewResponseChunkPayload(s.requestId, s.sequence, buf[:n])
+	case errors.Is(err, io.EOF):
+		payload = messages.NewFinalResponseChunkPayload(s.requestId, s.sequence, nil)
+		s.done = true
+	case err != nil:
+		payload = messages.NewFinalResponseChunkPayload(s.requestId, s.sequence, fmt.Errorf("reading response: %w", err))
+		s.done = true
+	}
+
+	s.sequence++
+	message := funcie.NewMessageWithPayload(s.applicationId, messages.MessageKindResponseChunk, payload)
+	return funcie.MarshalMessagePayload(*message)
+}
+
+func (s *chunkedResponseStream) Close() error {
+	if closer, ok := s.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// chunkReader is an io.Reader that reads the body of a streamed response from its RESPONSE_CHUNK messages.
+type chunkReader struct {
+	ctx         context.Context
+	stream      funcie.ResponseStream
+	contentType string
+	pending     []byte
+	sequence    int
+	done        bool
+}
+
+func newChunkReader(ctx context.Context, stream funcie.ResponseStream, contentType string) *chunkReader {
+	return &chunkReader{
+		ctx:         ctx,
+		stream:      stream,
+		contentType: contentType,
+	}
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *chunkReader) readChunk() error {
+	message, err := r.stream.Next(r.ctx)
+	if errors.Is(err, io.EOF) {
+		return ErrIncompleteStream
+	}
+	if err != nil {
+		return fmt.Errorf("reading response chunk: %w", err)
+	}
+
+	if message.Kind != messages.MessageKindResponseChunk {
+		return fmt.Errorf("unexpected message kind %v in streamed response", message.Kind)
+	}
+
+	chunk, err := funcie.UnmarshalMessagePayload[messages.ResponseChunkMessage](message)
+	if err != nil {
+		return fmt.Errorf("unmarshalling response chunk: %w", err)
+	}
+
+	if chunk.Payload.Sequence != r.sequence {
+		return fmt.Errorf("expected response chunk %d, got %d", r.sequence, chunk.Payload.Sequence)
+	}
+	r.sequence++
+
+	if chunk.Payload.Error != nil {
+		return fmt.Errorf("received error from proxied implementation: %w", chunk.Payload.Error)
+	}
+
+	r.pending = chunk.Payload.Data
+	r.done = chunk.Payload.Final
+	return nil
+}
+
+// ContentType returns the content type reported by the handler that produced the stream.
+// This is used by the Lambda runtime as the content type of the streamed response.
+func (r *chunkReader) ContentType() string {
+	if r.contentType == "" {
+		return defaultStreamContentType
+	}
+	return r.contentType
+}
+
+func (r *chunkReader) Close() error {
+	return r.stream.Close()
+}
@@ -7,6 +7,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -17,26 +21,33 @@ import (
 // See NewConfig for the environment variables that are used.
 // The application name is an arbitrary identifier to uniquely identify this application in order to route messages.
 func Start(appName string, handler interface{}) {
-	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancelFn()
-
-	conf, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		panic(fmt.Sprintf("failed to load AWS config: %s", err))
-	}
-
-	ssmClient := ssm.NewFromConfig(conf)
-	funcieConfig := NewConfig(ctx, appName, ssmClient)
+	funcieConfig := loadConfig(appName)
 	StartWithConfig(*funcieConfig, slog.Default(), handler)
 }
 
+// StartHTTP is a replacement to lambda.Start for Lambdas that are implemented as an http.Handler.
+// API Gateway v1 and v2, ALB, and Function URL events are translated into an *http.Request for the handler,
+// and the handler's output is translated back into the matching event response.
+// See Start for more information, and NewHTTPHandler for details on the translation.
+func StartHTTP(appName string, handler http.Handler) {
+	Start(appName, NewHTTPHandler(handler))
+}
+
 // StartWithConfig is a replacement to lambda.Start that configures the proxy from the given config.
 // See `Start` for more information.
 func StartWithConfig(config FuncieConfig, logger *slog.Logger, handler interface{}) {
 	if funcie.IsRunningWithLambda() {
 		// In a Lambda, we wait for the Lambda runtime to call the handler and forward that request to the bastion.
 		client := NewHTTPBastionClient(config.ServerBastionEndpoint, logger)
-		proxy := NewLambdaFunctionProxy(config.ApplicationId, client, handler, logger)
+		proxy := NewLambdaFunctionProxy(
+			config.ApplicationId,
+			client,
+			handler,
+			logger,
+			WithFallbackPolicy(config.FallbackPolicy),
+			WithShadowMode(config.ShadowMode, config.ShadowTimeout),
+			WithCircuitBreaker(config.CircuitBreaker),
+		)
 		proxy.Start()
 	} else {
 		// Locally, we receive the request from the bastion.
@@ -46,7 +57,68 @@ func StartWithConfig(config FuncieConfig, logger *slog.Logger, handler interface
 			config.ClientBastionEndpoint,
 			handler,
 			logger,
+			WithReceiverOptions(config.Receiver),
 		)
-		receiver.Start()
+		startReceiver(receiver)
 	}
 }
+
+// StartApplications is a replacement to lambda.Start for a binary that contains the handlers for multiple applications,
+// where handlers maps each application ID to its handler.
+// Locally, every application is registered with the client bastion and served from a single listener, so that they can
+// all be debugged from the same process. They are deregistered together when the process is interrupted.
+// In a Lambda, only the handler for the application ID in the FUNCIE_APPLICATION_ID environment variable is started.
+// See Start for more information.
+func StartApplications(handlers map[string]interface{}) {
+	funcieConfig := loadConfig(os.Getenv("FUNCIE_APPLICATION_ID"))
+	StartApplicationsWithConfig(*funcieConfig, slog.Default(), handlers)
+}
+
+// StartApplicationsWithConfig is a replacement to lambda.Start for multiple applications that uses the given config.
+// The ApplicationId of the config is only used in a Lambda, to select the handler to start.
+// See `StartApplications` for more information.
+func StartApplicationsWithConfig(config FuncieConfig, logger *slog.Logger, handlers map[string]interface{}) {
+	if funcie.IsRunningWithLambda() {
+		handler, ok := handlers[config.ApplicationId]
+		if !ok {
+			panic(fmt.Sprintf("no handler for application %q; set FUNCIE_APPLICATION_ID to one of %v",
+				config.ApplicationId, sortedKeys(handlers)))
+		}
+		StartWithConfig(config, logger, handler)
+	} else {
+		receiver := NewMultiLambdaBastionReceiver(
+			config.ListenAddress,
+			config.ClientBastionEndpoint,
+			handlers,
+			logger,
+			WithReceiverOptions(config.Receiver),
+		)
+		startReceiver(receiver)
+	}
+}
+
+// startReceiver starts the receiver, stopping it (and so deregistering its applications) when the process is interrupted.
+func startReceiver(receiver BastionReceiver) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		receiver.Stop()
+	}()
+
+	receiver.Start()
+}
+
+func loadConfig(appName string) *FuncieConfig {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFn()
+
+	conf, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load AWS config: %s", err))
+	}
+
+	ssmClient := ssm.NewFromConfig(conf)
+	return NewConfig(ctx, appName, ssmClient)
+}
@@ -53,6 +53,15 @@ func (h *httpApplicationClient) ProcessRequest(ctx context.Context, application
 		return nil, fmt.Errorf("send request to %v: %w", url, err)
 	}
 
+	if httpResponse.Header.Get("Content-Type") == funcie.StreamContentType {
+		// The body is closed by the stream once it has been relayed.
+		response, err := funcie.ReadStreamedResponse(httpResponse.Body)
+		if err != nil {
+			return nil, fmt.Errorf("reading streamed response from %v: %w", url, err)
+		}
+		return response, nil
+	}
+
 	defer func() { _ = httpResponse.Body.Close() }()
 
 	responsePayload, err := io.ReadAll(httpResponse.Body)
@@ -3,6 +3,8 @@ package bastion
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +14,20 @@ type Config struct {
 	ListenAddress string `json:"listenAddress"`
 	// BaseChannelName is the base name of the Redis channel keys to use.
 	BaseChannelName string `json:"baseChannelName"`
+	// ShadowLogPath is the path of the file to append shadow mode comparisons to, or empty to not keep a diff log.
+	ShadowLogPath string `json:"shadowLogPath"`
+	// ShadowLatencyThreshold is how much the local and deployed durations may differ before being reported as a mismatch.
+	ShadowLatencyThreshold time.Duration `json:"shadowLatencyThreshold"`
+	// RuntimeApiApplication is the application to serve an emulated Lambda Runtime API for at ListenAddress,
+	// so that any Lambda runtime can be debugged locally without the funcie client library, or empty to not serve one.
+	RuntimeApiApplication string `json:"runtimeApiApplication"`
+	// RuntimeApiPollTimeout is how long a request waits for an emulated runtime to poll for it before the
+	// application is treated as not running, and the deployed Lambda handles the request instead.
+	RuntimeApiPollTimeout time.Duration `json:"runtimeApiPollTimeout"`
+	// HostTranslation are the names of the host translation strategies to try in order, or empty for DefaultHostStrategies.
+	HostTranslation []string `json:"hostTranslation"`
+	// HostOverride is the explicit host mapping used by the override strategy; see NewOverrideHostTranslator.
+	HostOverride string `json:"hostOverride"`
 }
 
 // NewConfig creates a new Config with no values set.
@@ -25,11 +41,32 @@ func NewConfig() *Config {
 //	FUNCIE_REDIS_ADDRESS (required)
 //	FUNCIE_LISTEN_ADDRESS (required)
 //	FUNCIE_BASE_CHANNEL_NAME (optional)
+//	FUNCIE_SHADOW_LOG (optional)
+//	FUNCIE_SHADOW_LATENCY_THRESHOLD (optional; defaults to 500ms)
+//	FUNCIE_RUNTIME_API_APPLICATION (optional)
+//	FUNCIE_RUNTIME_API_POLL_TIMEOUT (optional; defaults to 5s)
+//	FUNCIE_HOST_TRANSLATION (optional; comma-separated strategies, defaults to override,known-hosts,gateway,none)
+//	FUNCIE_HOST_OVERRIDE (optional)
 func NewConfigFromEnvironment() *Config {
+	return NewConfigFromEnvironmentWithAddresses(
+		requiredEnv("FUNCIE_REDIS_ADDRESS"),
+		optionalEnv("FUNCIE_LISTEN_ADDRESS", "127.0.0.1:24193"),
+	)
+}
+
+// NewConfigFromEnvironmentWithAddresses creates a new Config with the given Redis and listen addresses,
+// reading the remaining settings from the same optional environment variables as NewConfigFromEnvironment.
+func NewConfigFromEnvironmentWithAddresses(redisAddress string, listenAddress string) *Config {
 	return &Config{
-		RedisAddress:    requiredEnv("FUNCIE_REDIS_ADDRESS"),
-		ListenAddress:   optionalEnv("FUNCIE_LISTEN_ADDRESS", "127.0.0.1:24193"),
-		BaseChannelName: optionalEnv("FUNCIE_BASE_CHANNEL_NAME", "funcie:requests"),
+		RedisAddress:           redisAddress,
+		ListenAddress:          listenAddress,
+		BaseChannelName:        optionalEnv("FUNCIE_BASE_CHANNEL_NAME", "funcie:requests"),
+		ShadowLogPath:          optionalEnv("FUNCIE_SHADOW_LOG", ""),
+		ShadowLatencyThreshold: optionalDurationEnv("FUNCIE_SHADOW_LATENCY_THRESHOLD", 500*time.Millisecond),
+		RuntimeApiApplication:  optionalEnv("FUNCIE_RUNTIME_API_APPLICATION", ""),
+		RuntimeApiPollTimeout:  optionalDurationEnv("FUNCIE_RUNTIME_API_POLL_TIMEOUT", 5*time.Second),
+		HostTranslation:        optionalListEnv("FUNCIE_HOST_TRANSLATION"),
+		HostOverride:           optionalEnv("FUNCIE_HOST_OVERRIDE", ""),
 	}
 }
 
@@ -48,3 +85,25 @@ func optionalEnv(name string, defaultValue string) string {
 	}
 	return value
 }
+
+func optionalDurationEnv(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		panic(fmt.Sprintf("invalid duration for environment variable %s: %v", name, err))
+	}
+	return parsed
+}
+
+func optionalListEnv(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
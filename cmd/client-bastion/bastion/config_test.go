@@ -4,6 +4,7 @@ import (
 	"github.com/Kapps/funcie/cmd/client-bastion/bastion"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNewConfigFromEnvironment(t *testing.T) {
@@ -11,12 +12,24 @@ func TestNewConfigFromEnvironment(t *testing.T) {
 		t.Setenv("FUNCIE_REDIS_ADDRESS", "redis://localhost:6379")
 		t.Setenv("FUNCIE_LISTEN_ADDRESS", "localhost:8080")
 		t.Setenv("FUNCIE_BASE_CHANNEL_NAME", "override")
+		t.Setenv("FUNCIE_SHADOW_LOG", "/tmp/shadow.log")
+		t.Setenv("FUNCIE_SHADOW_LATENCY_THRESHOLD", "2s")
+		t.Setenv("FUNCIE_RUNTIME_API_APPLICATION", "my-app")
+		t.Setenv("FUNCIE_RUNTIME_API_POLL_TIMEOUT", "1s")
+		t.Setenv("FUNCIE_HOST_TRANSLATION", "override, none")
+		t.Setenv("FUNCIE_HOST_OVERRIDE", "192.168.1.10")
 
 		config := bastion.NewConfigFromEnvironment()
 
 		assert.Equal(t, "redis://localhost:6379", config.RedisAddress)
 		assert.Equal(t, "localhost:8080", config.ListenAddress)
 		assert.Equal(t, "override", config.BaseChannelName)
+		assert.Equal(t, "/tmp/shadow.log", config.ShadowLogPath)
+		assert.Equal(t, 2*time.Second, config.ShadowLatencyThreshold)
+		assert.Equal(t, "my-app", config.RuntimeApiApplication)
+		assert.Equal(t, time.Second, config.RuntimeApiPollTimeout)
+		assert.Equal(t, []string{"override", "none"}, config.HostTranslation)
+		assert.Equal(t, "192.168.1.10", config.HostOverride)
 	})
 
 	t.Run("with only required environment variables set", func(t *testing.T) {
@@ -29,6 +42,12 @@ func TestNewConfigFromEnvironment(t *testing.T) {
 		assert.Equal(t, "redis://localhost:6379", config.RedisAddress)
 		assert.Equal(t, "localhost:8080", config.ListenAddress)
 		assert.Equal(t, "funcie:requests", config.BaseChannelName)
+		assert.Empty(t, config.ShadowLogPath)
+		assert.Equal(t, 500*time.Millisecond, config.ShadowLatencyThreshold)
+		assert.Empty(t, config.RuntimeApiApplication)
+		assert.Equal(t, 5*time.Second, config.RuntimeApiPollTimeout)
+		assert.Empty(t, config.HostTranslation)
+		assert.Empty(t, config.HostOverride)
 	})
 
 	t.Run("with no environment variables set", func(t *testing.T) {
@@ -37,3 +56,15 @@ func TestNewConfigFromEnvironment(t *testing.T) {
 		})
 	})
 }
+
+func TestNewConfigFromEnvironmentWithAddresses(t *testing.T) {
+	t.Setenv("FUNCIE_REDIS_ADDRESS", "redis://ignored:6379")
+	t.Setenv("FUNCIE_RUNTIME_API_APPLICATION", "my-app")
+
+	config := bastion.NewConfigFromEnvironmentWithAddresses("127.0.0.1:6379", "127.0.0.1:24193")
+
+	assert.Equal(t, "127.0.0.1:6379", config.RedisAddress)
+	assert.Equal(t, "127.0.0.1:24193", config.ListenAddress)
+	assert.Equal(t, "funcie:requests", config.BaseChannelName)
+	assert.Equal(t, "my-app", config.RuntimeApiApplication)
+}
@@ -10,27 +10,36 @@ import (
 	"github.com/google/uuid"
 	"log/slog"
 	"syscall"
+	"time"
 )
 
 type handler struct {
-	registry       funcie.ApplicationRegistry
-	appClient      ApplicationClient
-	consumer       funcie.Consumer
-	hostTranslator HostTranslator
+	registry         funcie.ApplicationRegistry
+	appClient        ApplicationClient
+	consumer         funcie.Consumer
+	hostTranslator   HostTranslator
+	fallbackPolicies funcie.FallbackPolicyStore
+	shadowReporter   ShadowReporter
 }
 
-// NewHandler creates a new Handler that can register and unregister applications and forward requests.
+// NewHandler creates a new Handler that can register and unregister applications, forward requests,
+// and override the fallback policies of applications.
+// Requests forwarded in shadow mode are compared with the deployed result and reported to the shadowReporter.
 func NewHandler(
 	registry funcie.ApplicationRegistry,
 	appClient ApplicationClient,
 	consumer funcie.Consumer,
 	hostTranslator HostTranslator,
+	fallbackPolicies funcie.FallbackPolicyStore,
+	shadowReporter ShadowReporter,
 ) transports.MessageHandler {
 	return &handler{
-		registry:       registry,
-		appClient:      appClient,
-		consumer:       consumer,
-		hostTranslator: hostTranslator,
+		registry:         registry,
+		appClient:        appClient,
+		consumer:         consumer,
+		hostTranslator:   hostTranslator,
+		fallbackPolicies: fallbackPolicies,
+		shadowReporter:   shadowReporter,
 	}
 }
 
@@ -53,7 +62,8 @@ func (h *handler) Register(ctx context.Context, message messages.RegistrationMes
 	}
 
 	registrationId := uuid.New()
-	slog.InfoContext(ctx, "registered application", "application", application, "registrationId", registrationId)
+	slog.InfoContext(ctx, "registered application",
+		"application", application, "registrationId", registrationId, "clientVersion", message.Protocol)
 
 	responsePayload := messages.NewRegistrationResponsePayload(registrationId)
 	return funcie.NewResponseWithPayload(message.ID, responsePayload, nil), nil
@@ -74,6 +84,24 @@ func (h *handler) Deregister(ctx context.Context, message messages.Deregistratio
 	return funcie.NewResponseWithPayload(message.ID, responsePayload, nil), nil
 }
 
+func (h *handler) SetFallbackPolicy(ctx context.Context, message messages.SetFallbackPolicyMessage) (*messages.SetFallbackPolicyResponse, error) {
+	policy := message.Payload.Policy
+	if policy != "" {
+		if _, err := funcie.ParseFallbackPolicy(string(policy)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.fallbackPolicies.SetFallbackPolicy(ctx, message.Application, policy); err != nil {
+		return nil, fmt.Errorf("set fallback policy for application %v: %w", message.Application, err)
+	}
+
+	slog.InfoContext(ctx, "set fallback policy override", "application", message.Application, "policy", policy)
+
+	responsePayload := messages.NewSetFallbackPolicyResponsePayload()
+	return funcie.NewResponseWithPayload(message.ID, responsePayload, nil), nil
+}
+
 func (h *handler) ForwardRequest(ctx context.Context, request messages.ForwardRequestMessage) (*messages.ForwardRequestResponse, error) {
 	app, err := h.registry.GetApplication(ctx, request.Application)
 	if errors.Is(err, funcie.ErrApplicationNotFound) {
@@ -116,6 +144,15 @@ func (h *handler) onConsumerMessageReceived(ctx context.Context, message *funcie
 		return nil, fmt.Errorf("getting application %v: %w", message.Application, err)
 	}
 
+	forwardRequest, err := funcie.UnmarshalMessagePayload[messages.ForwardRequestMessage](message)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal forward request: %w", err)
+	}
+
+	if forwardRequest.Payload.Shadow != nil {
+		return h.handleShadowRequest(ctx, *app, message, *forwardRequest.Payload.Shadow)
+	}
+
 	resp, err := h.appClient.ProcessRequest(ctx, *app, message)
 	if errors.Is(err, syscall.ECONNREFUSED) {
 		slog.WarnContext(ctx, "application not available", "application", message.Application)
@@ -132,3 +169,38 @@ func (h *handler) onConsumerMessageReceived(ctx context.Context, message *funcie
 
 	return marshaled, nil
 }
+
+// handleShadowRequest forwards a request in shadow mode to the application, reporting how the local result compares
+// with the deployed result. The Lambda has already responded with the deployed result, so only the outcome is returned.
+func (h *handler) handleShadowRequest(
+	ctx context.Context,
+	app funcie.Application,
+	message *funcie.Message,
+	deployed messages.ShadowResult,
+) (*funcie.Response, error) {
+	start := time.Now()
+	resp, err := h.appClient.ProcessRequest(ctx, app, message)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		slog.WarnContext(ctx, "application not available", "application", message.Application)
+		return funcie.NewResponse(message.ID, nil, funcie.ErrNoActiveConsumer), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("forward shadow request: %w", err)
+	}
+
+	local, err := readShadowResult(ctx, resp)
+	if err != nil {
+		return nil, fmt.Errorf("read shadow result: %w", err)
+	}
+	local.Duration = time.Since(start)
+
+	h.shadowReporter.Report(ctx, app.Name, message.ID, deployed, local)
+
+	response := funcie.NewResponseWithPayload(message.ID, &messages.ForwardRequestResponsePayload{}, nil)
+	marshaled, err := funcie.MarshalResponsePayload(response)
+	if err != nil {
+		return nil, fmt.Errorf("marshal response payload: %w", err)
+	}
+
+	return marshaled, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 // TODO: These tests are already unwieldy due to the number of mocks.
@@ -24,10 +25,13 @@ func TestHandler(t *testing.T) {
 	appClient := bastionMocks.NewApplicationClient(t)
 	consumer := mocks.NewConsumer(t)
 	hostTranslator := bastionMocks.NewHostTranslator(t)
+	fallbackPolicies := mocks.NewFallbackPolicyStore(t)
+	shadowReporter, err := bastion.NewShadowReporter(&bastion.Config{ShadowLatencyThreshold: time.Hour})
+	require.NoError(t, err)
 
 	hostTranslator.EXPECT().TranslateLocalHostToResolvedHost(ctx, "localhost").Return("localhost", nil)
 
-	handler := bastion.NewHandler(registry, appClient, consumer, hostTranslator)
+	handler := bastion.NewHandler(registry, appClient, consumer, hostTranslator, fallbackPolicies, shadowReporter)
 
 	endpoint := funcie.MustNewEndpointFromAddress("http://localhost:8080")
 	app := funcie.NewApplication("app", endpoint)
@@ -72,6 +76,26 @@ func TestHandler(t *testing.T) {
 		require.Nil(t, resp)
 	})
 
+	t.Run("should override the fallback policy of an application", func(t *testing.T) {
+		payload := messages.NewSetFallbackPolicyPayload(funcie.FallbackPolicyOnError)
+		message := funcie.NewMessageWithPayload(app.Name, messages.MessageKindSetFallbackPolicy, *payload)
+
+		fallbackPolicies.EXPECT().SetFallbackPolicy(ctx, app.Name, funcie.FallbackPolicyOnError).Return(nil).Once()
+
+		resp, err := handler.SetFallbackPolicy(ctx, *message)
+		require.NoError(t, err)
+		require.Nil(t, resp.Error)
+	})
+
+	t.Run("should reject an invalid fallback policy", func(t *testing.T) {
+		payload := messages.NewSetFallbackPolicyPayload("sometimes")
+		message := funcie.NewMessageWithPayload(app.Name, messages.MessageKindSetFallbackPolicy, *payload)
+
+		resp, err := handler.SetFallbackPolicy(ctx, *message)
+		require.ErrorContains(t, err, "invalid fallback policy")
+		require.Nil(t, resp)
+	})
+
 	t.Run("should forward a request to an application", func(t *testing.T) {
 		payload := messages.NewForwardRequestPayload(json.RawMessage("{}"))
 		request := funcie.NewMessageWithPayload("app", messages.MessageKindForwardRequest, *payload)
@@ -122,4 +146,33 @@ func TestHandler(t *testing.T) {
 
 		RequireEqualResponse(t, marshaledResponse, resp)
 	})
+
+	t.Run("should report a comparison when consuming a shadowed message", func(t *testing.T) {
+		diffs, unsubscribe := shadowReporter.Subscribe()
+		t.Cleanup(unsubscribe)
+
+		forwardPayload := messages.NewForwardRequestPayload(json.RawMessage("{}"))
+		forwardPayload.Shadow = &messages.ShadowResult{Body: []byte(`{"a": 1}`), Duration: time.Millisecond}
+		forwardRequest := funcie.NewMessageWithPayload("app", messages.MessageKindForwardRequest, *forwardPayload)
+
+		marshaledForwardRequest, err := funcie.MarshalMessagePayload(*forwardRequest)
+		require.NoError(t, err)
+
+		responsePayload := messages.NewForwardRequestResponsePayload(json.RawMessage(`{"a":2}`))
+		marshaledResponse, err := funcie.MarshalResponsePayload(funcie.NewResponseWithPayload("id", responsePayload, nil))
+		require.NoError(t, err)
+
+		registry.EXPECT().GetApplication(ctx, app.Name).Return(app, nil).Once()
+		appClient.EXPECT().ProcessRequest(ctx, *app, marshaledForwardRequest).Return(marshaledResponse, nil).Once()
+
+		consumeCallback := consumer.Calls[0].Arguments[2].(funcie.Handler)
+		resp, err := consumeCallback(ctx, marshaledForwardRequest)
+		require.NoError(t, err)
+		require.Nil(t, resp.Error)
+
+		diff := <-diffs
+		require.Equal(t, "app", diff.Application)
+		require.Equal(t, forwardRequest.ID, diff.RequestId)
+		require.Equal(t, []messages.ShadowMismatch{messages.ShadowMismatchBody}, diff.Mismatches)
+	})
 }
@@ -1,13 +1,23 @@
 package bastion
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"strings"
 	"sync"
 )
 
 var lookupHost = net.LookupHost
+var readFile = os.ReadFile
 
 // HostTranslator is an interface for translating hosts from an entered host to a resolved host.
 // For example, this could translate local or unspecified IPs to the docker host when running in MacOS.
@@ -22,26 +32,184 @@ type HostTranslator interface {
 	TranslateLocalHostToResolvedHost(ctx context.Context, host string) (string, error)
 }
 
+// The names of the host translation strategies, as used in Config.HostTranslation.
+const (
+	// HostStrategyOverride translates hosts using the explicit mapping in Config.HostOverride.
+	HostStrategyOverride = "override"
+	// HostStrategyKnownHosts translates local hosts to the first of the hostnames that container runtimes provide for
+	// the host machine which resolves, such as host.docker.internal (Docker Desktop) or host.containers.internal (Podman).
+	HostStrategyKnownHosts = "known-hosts"
+	// HostStrategyGateway translates local hosts to the default gateway when running in a container,
+	// which is the host machine for the Linux Docker bridge network and WSL2.
+	HostStrategyGateway = "gateway"
+	// HostStrategyNone never translates hosts, as when running natively rather than in a container.
+	HostStrategyNone = "none"
+)
+
+// DefaultHostStrategies are the host translation strategies that are tried, in order, when none are configured.
+var DefaultHostStrategies = []string{HostStrategyOverride, HostStrategyKnownHosts, HostStrategyGateway, HostStrategyNone}
+
+// HostTranslationPath is the path that the client bastion reports its HostTranslationStatus on.
+const HostTranslationPath = "/host-translation"
+
+// HostTranslationStatus reports how the client bastion translates the hosts of local applications.
+type HostTranslationStatus struct {
+	// Strategy is the name of the selected host translation strategy, if the translator is a ChainedHostTranslator.
+	Strategy string `json:"strategy,omitempty"`
+	// Localhost is the host that localhost is translated to.
+	Localhost string `json:"localhost,omitempty"`
+	// Error is the error that translating localhost failed with, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// NewHostTranslationHandler creates an http.Handler that reports the HostTranslationStatus of the translator as JSON.
+func NewHostTranslationHandler(translator HostTranslator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var status HostTranslationStatus
+		if chain, ok := translator.(ChainedHostTranslator); ok {
+			status.Strategy = chain.SelectedStrategy(r.Context())
+		}
+
+		localhost, err := translator.TranslateLocalHostToResolvedHost(r.Context(), "localhost")
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Localhost = localhost
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			slog.WarnContext(r.Context(), "failed to write host translation status", "error", err)
+		}
+	})
+}
+
+// knownHostNames are the hostnames that container runtimes provide to reach the host machine.
+var knownHostNames = []string{"host.docker.internal", "host.containers.internal"}
+
+// containerMarkerFiles are files that Docker and Podman create inside of containers.
+var containerMarkerFiles = []string{"/.dockerenv", "/run/.containerenv"}
+
+// HostTranslatorStrategy is a named HostTranslator that may be used as part of a ChainedHostTranslator.
+type HostTranslatorStrategy struct {
+	// Name is the name of the strategy, which is reported when it is selected.
+	Name string
+	// Translator is the HostTranslator for the strategy, which applies if it requires host translation.
+	Translator HostTranslator
+}
+
+// ChainedHostTranslator is a HostTranslator that uses the first of several strategies that applies to the environment.
+type ChainedHostTranslator interface {
+	HostTranslator
+	// SelectedStrategy returns the name of the strategy that is used to translate hosts.
+	SelectedStrategy(ctx context.Context) string
+}
+
+type chainedHostTranslator struct {
+	strategies []HostTranslatorStrategy
+	selected   HostTranslatorStrategy
+	selectOnce sync.Once
+}
+
+// NewHostTranslatorChain creates a ChainedHostTranslator that uses the first of the given strategies that requires
+// host translation, or does not translate hosts if none do. Strategies that fail to check whether they apply are skipped.
+func NewHostTranslatorChain(strategies ...HostTranslatorStrategy) ChainedHostTranslator {
+	return &chainedHostTranslator{
+		strategies: strategies,
+	}
+}
+
+// NewHostTranslatorFromConfig creates a ChainedHostTranslator from the strategies named in the config.
+func NewHostTranslatorFromConfig(config *Config) (HostTranslator, error) {
+	names := config.HostTranslation
+	if len(names) == 0 {
+		names = DefaultHostStrategies
+	}
+
+	strategies := make([]HostTranslatorStrategy, 0, len(names))
+	for _, name := range names {
+		var translator HostTranslator
+		switch name {
+		case HostStrategyOverride:
+			override, err := NewOverrideHostTranslator(config.HostOverride)
+			if err != nil {
+				return nil, err
+			}
+			translator = override
+		case HostStrategyKnownHosts:
+			translator = NewKnownHostsTranslator(knownHostNames...)
+		case HostStrategyGateway:
+			translator = NewGatewayHostTranslator()
+		case HostStrategyNone:
+			translator = NewPassthroughHostTranslator()
+		default:
+			return nil, fmt.Errorf("unknown host translation strategy %q; expected one of %v", name, DefaultHostStrategies)
+		}
+		strategies = append(strategies, HostTranslatorStrategy{Name: name, Translator: translator})
+	}
+
+	return NewHostTranslatorChain(strategies...), nil
+}
+
+func (c *chainedHostTranslator) SelectedStrategy(ctx context.Context) string {
+	return c.selectStrategy(ctx).Name
+}
+
+func (c *chainedHostTranslator) IsHostTranslationRequired(ctx context.Context) (bool, error) {
+	return c.selectStrategy(ctx).Translator.IsHostTranslationRequired(ctx)
+}
+
+func (c *chainedHostTranslator) TranslateLocalHostToResolvedHost(ctx context.Context, host string) (string, error) {
+	return c.selectStrategy(ctx).Translator.TranslateLocalHostToResolvedHost(ctx, host)
+}
+
+func (c *chainedHostTranslator) selectStrategy(ctx context.Context) HostTranslatorStrategy {
+	c.selectOnce.Do(func() {
+		c.selected = HostTranslatorStrategy{Name: HostStrategyNone, Translator: NewPassthroughHostTranslator()}
+		for _, strategy := range c.strategies {
+			required, err := strategy.Translator.IsHostTranslationRequired(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "skipping host translation strategy", "strategy", strategy.Name, "error", err)
+				continue
+			}
+			if required || strategy.Name == HostStrategyNone {
+				c.selected = strategy
+				break
+			}
+		}
+
+		slog.InfoContext(ctx, "selected host translation strategy", "strategy", c.selected.Name)
+	})
+
+	return c.selected
+}
+
 type dockerHostTranslator struct {
+	candidates                      []string
 	translatedHost                  string
 	isDockerHostTranslationRequired bool
 	checkTranslationRequiredOnce    sync.Once
 }
 
+// NewDockerHostTranslator creates a HostTranslator that translates local hosts to host.docker.internal if it resolves.
 func NewDockerHostTranslator() HostTranslator {
-	return &dockerHostTranslator{}
+	return NewKnownHostsTranslator("host.docker.internal")
+}
+
+// NewKnownHostsTranslator creates a HostTranslator that translates local hosts to the first of the given hostnames
+// that resolves, or does not translate hosts if none of them resolve.
+func NewKnownHostsTranslator(hosts ...string) HostTranslator {
+	return &dockerHostTranslator{
+		candidates: hosts,
+	}
 }
 
 func (t *dockerHostTranslator) IsHostTranslationRequired(_ context.Context) (bool, error) {
 	t.checkTranslationRequiredOnce.Do(func() {
-		// MacOS uses host.docker.internal
-		t.setHostIfResolves("host.docker.internal")
-
-		// Anything below here is pretty untested...
+		for _, host := range t.candidates {
+			t.setHostIfResolves(host)
+		}
 
-		// Some Linux distros use the static IP 172.17.0.1
-		// However it's possible that this IP is used for other things, so we don't want to assume it's always the docker host
-		// For now we'll leave this out, and if we find a case where it's needed, we can add it back in
 		if t.translatedHost != "" {
 			slog.Info("redirecting localhost requests to resolved host.", "host", t.translatedHost)
 		}
@@ -71,28 +239,164 @@ func (t *dockerHostTranslator) TranslateLocalHostToResolvedHost(ctx context.Cont
 		return "", err
 	}
 
-	if !translationRequired {
+	if !translationRequired || !isLocalHost(host) {
 		return host, nil
 	}
 
-	// First, check if the host is an IP address.
-	ip := net.ParseIP(host)
-	if ip != nil {
-		// If it is an IP, we translate if it's a loopback address (127.x.x.x) or an unspecified address (0.0.0.0).
-		if ip.IsLoopback() || ip.IsUnspecified() {
-			return t.translatedHost, nil
-		} else {
-			// Otherwise, we return the original IP address.
-			return host, nil
+	return t.translatedHost, nil
+}
+
+type gatewayHostTranslator struct {
+	gateway   string
+	err       error
+	checkOnce sync.Once
+}
+
+// NewGatewayHostTranslator creates a HostTranslator that translates local hosts to the default gateway from
+// /proc/net/route when running in a Docker or Podman container, and otherwise does not translate hosts.
+// On the default Linux bridge network and under WSL2, the gateway is the host machine.
+func NewGatewayHostTranslator() HostTranslator {
+	return &gatewayHostTranslator{}
+}
+
+func (t *gatewayHostTranslator) IsHostTranslationRequired(_ context.Context) (bool, error) {
+	t.checkOnce.Do(func() {
+		if !isRunningInContainer() {
+			return
 		}
+
+		t.gateway, t.err = readDefaultGateway()
+		if t.err == nil {
+			slog.Info("redirecting localhost requests to default gateway.", "host", t.gateway)
+		}
+	})
+
+	return t.gateway != "", t.err
+}
+
+func (t *gatewayHostTranslator) TranslateLocalHostToResolvedHost(ctx context.Context, host string) (string, error) {
+	translationRequired, err := t.IsHostTranslationRequired(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	// If it's not an IP address, we translate if it's localhost.
-	// This could certainly be improved to handle other cases.
-	if host == "localhost" {
-		return t.translatedHost, nil
+	if !translationRequired || !isLocalHost(host) {
+		return host, nil
+	}
+
+	return t.gateway, nil
+}
+
+type overrideHostTranslator struct {
+	// allLocalHosts is the host that every local host is translated to, if set.
+	allLocalHosts string
+	// mapping maps specific hosts to the host they are translated to.
+	mapping map[string]string
+}
+
+// NewOverrideHostTranslator creates a HostTranslator from an explicit override, such as FUNCIE_HOST_OVERRIDE.
+// The override is either a single host that every local host is translated to (such as "192.168.1.10"),
+// or a comma-separated list of host=translated mappings (such as "localhost=192.168.1.10,127.0.0.1=192.168.1.10").
+// An empty override never translates hosts, and so does not apply as a strategy.
+func NewOverrideHostTranslator(override string) (HostTranslator, error) {
+	translator := &overrideHostTranslator{
+		mapping: make(map[string]string),
+	}
+	override = strings.TrimSpace(override)
+	if override == "" {
+		return translator, nil
+	}
+
+	if !strings.Contains(override, "=") {
+		translator.allLocalHosts = override
+		return translator, nil
+	}
+
+	for _, entry := range strings.Split(override, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid host override %q; expected host=translated", entry)
+		}
+		translator.mapping[from] = to
+	}
+
+	return translator, nil
+}
+
+func (t *overrideHostTranslator) IsHostTranslationRequired(_ context.Context) (bool, error) {
+	return t.allLocalHosts != "" || len(t.mapping) > 0, nil
+}
+
+func (t *overrideHostTranslator) TranslateLocalHostToResolvedHost(_ context.Context, host string) (string, error) {
+	if translated, ok := t.mapping[host]; ok {
+		return translated, nil
+	}
+	if t.allLocalHosts != "" && isLocalHost(host) {
+		return t.allLocalHosts, nil
 	}
+	return host, nil
+}
+
+type passthroughHostTranslator struct{}
+
+// NewPassthroughHostTranslator creates a HostTranslator that never translates hosts.
+// This is used when the client bastion runs directly on the host rather than in a container, such as with `funcie dev`.
+func NewPassthroughHostTranslator() HostTranslator {
+	return passthroughHostTranslator{}
+}
 
-	// Otherwise, we return the original host.
+func (passthroughHostTranslator) IsHostTranslationRequired(_ context.Context) (bool, error) {
+	return false, nil
+}
+
+func (passthroughHostTranslator) TranslateLocalHostToResolvedHost(_ context.Context, host string) (string, error) {
 	return host, nil
 }
+
+// isLocalHost returns whether the host refers to the local machine: localhost, a loopback address (127.x.x.x),
+// or an unspecified address (0.0.0.0).
+func isLocalHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsUnspecified()
+	}
+
+	// This could certainly be improved to handle other cases.
+	return host == "localhost"
+}
+
+func isRunningInContainer() bool {
+	for _, path := range containerMarkerFiles {
+		if _, err := readFile(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readDefaultGateway reads the gateway of the default route from /proc/net/route.
+func readDefaultGateway() (string, error) {
+	routes, err := readFile("/proc/net/route")
+	if err != nil {
+		return "", fmt.Errorf("read routes: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(routes))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+
+		// Addresses are in hex, in the byte order of the host, which is little endian for any platform we run on.
+		gateway, err := hex.DecodeString(fields[2])
+		if err != nil || len(gateway) != net.IPv4len {
+			return "", fmt.Errorf("invalid gateway %q in default route", fields[2])
+		}
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(gateway))
+		return ip.String(), nil
+	}
+
+	return "", fmt.Errorf("no default route found")
+}
@@ -2,7 +2,12 @@ package bastion
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -73,7 +78,221 @@ func TestDockerHostTranslator_TranslationsRequired(t *testing.T) {
 
 func setCleanup(t *testing.T) {
 	originalLookupHost := lookupHost
+	originalReadFile := readFile
 	t.Cleanup(func() {
 		lookupHost = originalLookupHost
+		readFile = originalReadFile
 	})
 }
+
+func TestKnownHostsTranslator(t *testing.T) {
+	ctx := context.Background()
+	setCleanup(t)
+
+	lookupHost = func(host string) ([]string, error) {
+		if host == "host.containers.internal" {
+			return []string{"10.88.0.1"}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	translator := NewKnownHostsTranslator("host.docker.internal", "host.containers.internal")
+
+	required, err := translator.IsHostTranslationRequired(ctx)
+	require.NoError(t, err)
+	require.True(t, required)
+
+	host, err := translator.TranslateLocalHostToResolvedHost(ctx, "localhost")
+	require.NoError(t, err)
+	require.Equal(t, "host.containers.internal", host)
+}
+
+func TestGatewayHostTranslator(t *testing.T) {
+	ctx := context.Background()
+	routes := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t000011AC\t00000000\t0001\t0\t0\t0\t0000FFFF\t0\t0\t0\n" +
+		"eth0\t00000000\t010011AC\t0003\t0\t0\t0\t00000000\t0\t0\t0\n"
+
+	t.Run("should translate to the default gateway in a container", func(t *testing.T) {
+		setCleanup(t)
+		readFile = func(name string) ([]byte, error) {
+			switch name {
+			case "/.dockerenv":
+				return nil, nil
+			case "/proc/net/route":
+				return []byte(routes), nil
+			}
+			return nil, os.ErrNotExist
+		}
+
+		translator := NewGatewayHostTranslator()
+		required, err := translator.IsHostTranslationRequired(ctx)
+		require.NoError(t, err)
+		require.True(t, required)
+
+		host, err := translator.TranslateLocalHostToResolvedHost(ctx, "127.0.0.1")
+		require.NoError(t, err)
+		require.Equal(t, "172.17.0.1", host)
+
+		host, err = translator.TranslateLocalHostToResolvedHost(ctx, "example.com")
+		require.NoError(t, err)
+		require.Equal(t, "example.com", host)
+	})
+
+	t.Run("should not apply outside of a container", func(t *testing.T) {
+		setCleanup(t)
+		readFile = func(name string) ([]byte, error) {
+			if name == "/proc/net/route" {
+				return []byte(routes), nil
+			}
+			return nil, os.ErrNotExist
+		}
+
+		required, err := NewGatewayHostTranslator().IsHostTranslationRequired(ctx)
+		require.NoError(t, err)
+		require.False(t, required)
+	})
+}
+
+func TestOverrideHostTranslator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should translate every local host to a single override", func(t *testing.T) {
+		translator, err := NewOverrideHostTranslator("192.168.1.10")
+		require.NoError(t, err)
+
+		required, err := translator.IsHostTranslationRequired(ctx)
+		require.NoError(t, err)
+		require.True(t, required)
+
+		for input, expected := range map[string]string{"localhost": "192.168.1.10", "0.0.0.0": "192.168.1.10", "example.com": "example.com"} {
+			host, err := translator.TranslateLocalHostToResolvedHost(ctx, input)
+			require.NoError(t, err)
+			require.Equal(t, expected, host)
+		}
+	})
+
+	t.Run("should translate mapped hosts", func(t *testing.T) {
+		translator, err := NewOverrideHostTranslator("localhost=host.lima.internal, 127.0.0.1=10.0.0.2")
+		require.NoError(t, err)
+
+		for input, expected := range map[string]string{"localhost": "host.lima.internal", "127.0.0.1": "10.0.0.2", "0.0.0.0": "0.0.0.0"} {
+			host, err := translator.TranslateLocalHostToResolvedHost(ctx, input)
+			require.NoError(t, err)
+			require.Equal(t, expected, host)
+		}
+	})
+
+	t.Run("should not apply without an override", func(t *testing.T) {
+		translator, err := NewOverrideHostTranslator("")
+		require.NoError(t, err)
+
+		required, err := translator.IsHostTranslationRequired(ctx)
+		require.NoError(t, err)
+		require.False(t, required)
+	})
+
+	t.Run("should reject invalid mappings", func(t *testing.T) {
+		_, err := NewOverrideHostTranslator("localhost=10.0.0.2,127.0.0.1")
+		require.Error(t, err)
+	})
+}
+
+// fixedHostTranslator is a HostTranslator that applies according to required, translating every host to translated.
+type fixedHostTranslator struct {
+	required   bool
+	err        error
+	translated string
+}
+
+func (f fixedHostTranslator) IsHostTranslationRequired(_ context.Context) (bool, error) {
+	return f.required, f.err
+}
+
+func (f fixedHostTranslator) TranslateLocalHostToResolvedHost(_ context.Context, _ string) (string, error) {
+	return f.translated, f.err
+}
+
+func TestHostTranslatorChain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should use the first strategy that applies", func(t *testing.T) {
+		chain := NewHostTranslatorChain(
+			HostTranslatorStrategy{Name: "failing", Translator: fixedHostTranslator{err: errors.New("boom")}},
+			HostTranslatorStrategy{Name: "inapplicable", Translator: fixedHostTranslator{translated: "wrong"}},
+			HostTranslatorStrategy{Name: "applicable", Translator: fixedHostTranslator{required: true, translated: "right"}},
+			HostTranslatorStrategy{Name: "later", Translator: fixedHostTranslator{required: true, translated: "wrong"}},
+		)
+
+		require.Equal(t, "applicable", chain.SelectedStrategy(ctx))
+		host, err := chain.TranslateLocalHostToResolvedHost(ctx, "localhost")
+		require.NoError(t, err)
+		require.Equal(t, "right", host)
+	})
+
+	t.Run("should not translate if no strategy applies", func(t *testing.T) {
+		chain := NewHostTranslatorChain(
+			HostTranslatorStrategy{Name: "inapplicable", Translator: fixedHostTranslator{translated: "wrong"}},
+		)
+
+		require.Equal(t, HostStrategyNone, chain.SelectedStrategy(ctx))
+		host, err := chain.TranslateLocalHostToResolvedHost(ctx, "localhost")
+		require.NoError(t, err)
+		require.Equal(t, "localhost", host)
+	})
+
+	t.Run("should create the configured strategies", func(t *testing.T) {
+		translator, err := NewHostTranslatorFromConfig(&Config{
+			HostTranslation: []string{HostStrategyOverride, HostStrategyNone},
+			HostOverride:    "192.168.1.10",
+		})
+		require.NoError(t, err)
+
+		chain := translator.(ChainedHostTranslator)
+		require.Equal(t, HostStrategyOverride, chain.SelectedStrategy(ctx))
+	})
+
+	t.Run("should reject unknown strategies", func(t *testing.T) {
+		_, err := NewHostTranslatorFromConfig(&Config{HostTranslation: []string{"magic"}})
+		require.ErrorContains(t, err, "magic")
+	})
+}
+
+func TestHostTranslationHandler(t *testing.T) {
+	getStatus := func(t *testing.T, translator HostTranslator) HostTranslationStatus {
+		recorder := httptest.NewRecorder()
+		NewHostTranslationHandler(translator).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, HostTranslationPath, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var status HostTranslationStatus
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+		return status
+	}
+
+	t.Run("should report the selected strategy and translated host", func(t *testing.T) {
+		chain := NewHostTranslatorChain(
+			HostTranslatorStrategy{Name: "applicable", Translator: fixedHostTranslator{required: true, translated: "192.168.1.10"}},
+		)
+
+		status := getStatus(t, chain)
+		require.Equal(t, HostTranslationStatus{Strategy: "applicable", Localhost: "192.168.1.10"}, status)
+	})
+
+	t.Run("should report translation errors", func(t *testing.T) {
+		status := getStatus(t, fixedHostTranslator{err: errors.New("boom")})
+		require.Equal(t, HostTranslationStatus{Error: "boom"}, status)
+	})
+}
+
+func TestPassthroughHostTranslator(t *testing.T) {
+	ctx := context.Background()
+	translator := NewPassthroughHostTranslator()
+
+	required, err := translator.IsHostTranslationRequired(ctx)
+	require.NoError(t, err)
+	require.False(t, required)
+
+	host, err := translator.TranslateLocalHostToResolvedHost(ctx, "127.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", host)
+}
@@ -189,6 +189,61 @@ func (_c *Handler_Register_Call) RunAndReturn(run func(context.Context, funcie.M
 	return _c
 }
 
+// SetFallbackPolicy provides a mock function with given fields: ctx, message
+func (_m *Handler) SetFallbackPolicy(ctx context.Context, message funcie.MessageBase[messages.SetFallbackPolicyPayload]) (*funcie.ResponseBase[messages.SetFallbackPolicyResponsePayload], error) {
+	ret := _m.Called(ctx, message)
+
+	var r0 *funcie.ResponseBase[messages.SetFallbackPolicyResponsePayload]
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, funcie.MessageBase[messages.SetFallbackPolicyPayload]) (*funcie.ResponseBase[messages.SetFallbackPolicyResponsePayload], error)); ok {
+		return rf(ctx, message)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, funcie.MessageBase[messages.SetFallbackPolicyPayload]) *funcie.ResponseBase[messages.SetFallbackPolicyResponsePayload]); ok {
+		r0 = rf(ctx, message)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*funcie.ResponseBase[messages.SetFallbackPolicyResponsePayload])
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, funcie.MessageBase[messages.SetFallbackPolicyPayload]) error); ok {
+		r1 = rf(ctx, message)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Handler_SetFallbackPolicy_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetFallbackPolicy'
+type Handler_SetFallbackPolicy_Call struct {
+	*mock.Call
+}
+
+// SetFallbackPolicy is a helper method to define mock.On call
+//   - ctx context.Context
+//   - message funcie.MessageBase[messages.SetFallbackPolicyPayload]
+func (_e *Handler_Expecter) SetFallbackPolicy(ctx interface{}, message interface{}) *Handler_SetFallbackPolicy_Call {
+	return &Handler_SetFallbackPolicy_Call{Call: _e.mock.On("SetFallbackPolicy", ctx, message)}
+}
+
+func (_c *Handler_SetFallbackPolicy_Call) Run(run func(ctx context.Context, message funcie.MessageBase[messages.SetFallbackPolicyPayload])) *Handler_SetFallbackPolicy_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(funcie.MessageBase[messages.SetFallbackPolicyPayload]))
+	})
+	return _c
+}
+
+func (_c *Handler_SetFallbackPolicy_Call) Return(_a0 *funcie.ResponseBase[messages.SetFallbackPolicyResponsePayload], _a1 error) *Handler_SetFallbackPolicy_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Handler_SetFallbackPolicy_Call) RunAndReturn(run func(context.Context, funcie.MessageBase[messages.SetFallbackPolicyPayload]) (*funcie.ResponseBase[messages.SetFallbackPolicyResponsePayload], error)) *Handler_SetFallbackPolicy_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 type mockConstructorTestingTNewHandler interface {
 	mock.TestingT
 	Cleanup(func())
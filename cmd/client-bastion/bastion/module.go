@@ -0,0 +1,154 @@
+package bastion
+
+import (
+	"context"
+	"errors"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/transports"
+	r "github.com/Kapps/funcie/pkg/funcie/transports/redis"
+	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
+	"github.com/Kapps/funcie/pkg/receiver"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Module provides the client bastion, which is started along with the fx application.
+// The application must supply the context.Context that the bastion runs with, the *http.Client used to reach
+// client applications, and the *Config of the bastion.
+var Module = fx.Options(
+	fx.Provide(
+		newRedisClient,
+		utils.NewClientHandlerRouter,
+		transports.NewMessageProcessor,
+		newApplicationRegistry,
+		newFallbackPolicyStore,
+		newPublisher,
+		newHost,
+		newConsumer,
+		newRuntimeEmulator,
+		newApplicationClient,
+		NewHandler,
+		NewHostTranslatorFromConfig,
+		newShadowReporter,
+	),
+	fx.Invoke(func(lc fx.Lifecycle, shutdowner fx.Shutdowner, ctx context.Context, consumer funcie.Consumer, host transports.Host) {
+		lc.Append(fx.Hook{
+			OnStart: func(_ context.Context) error {
+				return Start(ctx, consumer, host, shutdowner)
+			},
+			OnStop: func(_ context.Context) error {
+				return host.Close(ctx)
+			},
+		})
+	}),
+)
+
+func newRedisClient(conf *Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:       conf.RedisAddress,
+		ClientName: "funcie-client-bastion",
+	})
+}
+
+func newApplicationRegistry(redis *redis.Client) funcie.ApplicationRegistry {
+	return receiver.NewRedisApplicationRegistry(redis)
+}
+
+func newFallbackPolicyStore(redis *redis.Client) funcie.FallbackPolicyStore {
+	return receiver.NewRedisFallbackPolicyStore(redis)
+}
+
+func newPublisher(redisClient *redis.Client, conf *Config) funcie.Publisher {
+	return r.NewPublisher(redisClient, conf.BaseChannelName)
+}
+
+func newHost(
+	conf *Config,
+	messageProcessor transports.MessageProcessor,
+	shadowReporter ShadowReporter,
+	runtimeEmulator RuntimeEmulator,
+	hostTranslator HostTranslator,
+) transports.Host {
+	opts := []transports.HostOptionSetter{
+		transports.WithHandler("/shadow", NewShadowTailHandler(shadowReporter)),
+		transports.WithHandler(HostTranslationPath, NewHostTranslationHandler(hostTranslator)),
+	}
+	if conf.RuntimeApiApplication != "" {
+		opts = append(opts, transports.WithHandler(RuntimeApiPath, runtimeEmulator.Handler(messageProcessor, conf.RuntimeApiApplication)))
+	}
+
+	return transports.NewHost(conf.ListenAddress, messageProcessor, opts...)
+}
+
+func newShadowReporter(lc fx.Lifecycle, conf *Config) (ShadowReporter, error) {
+	reporter, err := NewShadowReporter(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(_ context.Context) error {
+			return reporter.Close()
+		},
+	})
+
+	return reporter, nil
+}
+
+func newRuntimeEmulator(conf *Config, client *http.Client) RuntimeEmulator {
+	return NewRuntimeEmulator(conf, NewHTTPApplicationClient(client))
+}
+
+func newApplicationClient(runtimeEmulator RuntimeEmulator) ApplicationClient {
+	return runtimeEmulator
+}
+
+func newConsumer(redisClient *redis.Client, conf *Config, router utils.ClientHandlerRouter) funcie.Consumer {
+	return r.NewConsumer(redisClient, conf.BaseChannelName, router)
+}
+
+// Start connects the consumer, retrying until Redis is available, then serves the host and consumes messages
+// in the background. If either stops unexpectedly, the application is shut down with an exit code of 1.
+// Stopping because the context was cancelled or the host was closed is not unexpected.
+func Start(ctx context.Context, consumer funcie.Consumer, host transports.Host, shutdowner fx.Shutdowner) error {
+	for {
+		err := consumer.Connect(ctx)
+		if err == nil {
+			break
+		}
+
+		slog.WarnContext(ctx, "failed to connect to Redis; trying again in 10 seconds", "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+
+	stopped := func(name string, err error) {
+		if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, http.ErrServerClosed) {
+			slog.InfoContext(ctx, name+" stopped", "error", err)
+			return
+		}
+
+		slog.ErrorContext(ctx, name+" stopped unexpectedly", "error", err)
+		if err := shutdowner.Shutdown(fx.ExitCode(1)); err != nil {
+			slog.ErrorContext(ctx, "failed to shut down", "error", err)
+		}
+	}
+
+	go func() {
+		// Goroutine for host requests -- a socket for receiving messages from other clients.
+		stopped("host", host.Listen(ctx))
+	}()
+
+	go func() {
+		// Goroutine for incoming messages -- registers on the consumer and starts listening.
+		stopped("consumer", consumer.Consume(ctx))
+	}()
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package bastion_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Kapps/funcie/cmd/client-bastion/bastion"
+	"github.com/Kapps/funcie/pkg/funcie/mocks"
+	"github.com/Kapps/funcie/pkg/funcie/transports"
+	transportMocks "github.com/Kapps/funcie/pkg/funcie/transports/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStart(t *testing.T) {
+	// newApp returns a started fx application whose shutdowner is passed to Start, stopping it when the test ends.
+	newApp := func(t *testing.T) (*fx.App, fx.Shutdowner) {
+		var shutdowner fx.Shutdowner
+		app := fx.New(fx.Populate(&shutdowner), fx.NopLogger)
+		require.NoError(t, app.Start(context.Background()))
+		t.Cleanup(func() { _ = app.Stop(context.Background()) })
+		return app, shutdowner
+	}
+
+	newHost := func(t *testing.T) (transports.Host, string) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		host := transports.NewHost(listener.Addr().String(), transportMocks.NewMessageProcessor(t), transports.WithListener(listener))
+		return host, "http://" + listener.Addr().String()
+	}
+
+	t.Run("should serve until stopped without shutting down the application", func(t *testing.T) {
+		app, shutdowner := newApp(t)
+		host, url := newHost(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		consumer := mocks.NewConsumer(t)
+		consumer.EXPECT().Connect(mock.Anything).Return(nil).Once()
+		consumer.EXPECT().Consume(mock.Anything).RunAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).Once()
+
+		require.NoError(t, bastion.Start(ctx, consumer, host, shutdowner))
+
+		require.Eventually(t, func() bool {
+			resp, err := http.Get(url + "/health")
+			if err != nil {
+				return false
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, host.Close(context.Background()))
+
+		require.Never(t, func() bool {
+			select {
+			case <-app.Wait():
+				return true
+			default:
+				return false
+			}
+		}, 200*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("should shut down the application when the consumer fails", func(t *testing.T) {
+		app, shutdowner := newApp(t)
+		host, _ := newHost(t)
+		defer func() { _ = host.Close(context.Background()) }()
+
+		consumer := mocks.NewConsumer(t)
+		consumer.EXPECT().Connect(mock.Anything).Return(nil).Once()
+		consumer.EXPECT().Consume(mock.Anything).Return(errors.New("connection lost")).Once()
+
+		require.NoError(t, bastion.Start(context.Background(), consumer, host, shutdowner))
+
+		select {
+		case signal := <-app.Wait():
+			require.Equal(t, 1, signal.ExitCode)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "application was not shut down")
+		}
+	})
+
+	t.Run("should stop retrying to connect when the context is cancelled", func(t *testing.T) {
+		_, shutdowner := newApp(t)
+		host, _ := newHost(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		consumer := mocks.NewConsumer(t)
+		consumer.EXPECT().Connect(mock.Anything).RunAndReturn(func(context.Context) error {
+			cancel()
+			return errors.New("connection refused")
+		}).Once()
+
+		require.ErrorIs(t, bastion.Start(ctx, consumer, host, shutdowner), context.Canceled)
+	})
+}
@@ -0,0 +1,153 @@
+package bastion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"github.com/Kapps/funcie/pkg/funcie/runtimeapi"
+	"github.com/Kapps/funcie/pkg/funcie/transports"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// RuntimeApiPath is the path that the emulated Runtime API is served under, which is the same as the real Runtime API.
+// AWS_LAMBDA_RUNTIME_API must be a plain host:port, so a runtime is pointed at the emulator with
+// AWS_LAMBDA_RUNTIME_API=<bastion address>, and each address serves the Runtime API of a single application.
+const RuntimeApiPath = "/2018-06-01/"
+
+// RuntimeEndpointScheme is the endpoint scheme of applications whose requests are served by an emulated runtime,
+// rather than by a funcie client library over HTTP.
+const RuntimeEndpointScheme = "runtime"
+
+// RuntimeEmulator is an ApplicationClient that serves requests for applications through an emulated Lambda Runtime API,
+// allowing any Lambda runtime (such as Python, Java, or a custom runtime) to be debugged locally without a funcie library.
+// Requests for applications that registered with a funcie client library are sent to the underlying ApplicationClient.
+type RuntimeEmulator interface {
+	ApplicationClient
+	// Handler returns the http.Handler serving the Runtime API of the given application under RuntimeApiPath.
+	// The application is registered through the given processor the first time its runtime polls for an invocation.
+	Handler(processor transports.MessageProcessor, applicationId string) http.Handler
+}
+
+type runtimeEmulator struct {
+	config     *Config
+	appClient  ApplicationClient
+	runtimes   map[string]runtimeapi.Runtime
+	registered map[string]bool
+	lock       sync.Mutex
+}
+
+// NewRuntimeEmulator creates a new RuntimeEmulator that sends requests for non-emulated applications to appClient.
+func NewRuntimeEmulator(config *Config, appClient ApplicationClient) RuntimeEmulator {
+	return &runtimeEmulator{
+		config:     config,
+		appClient:  appClient,
+		runtimes:   make(map[string]runtimeapi.Runtime),
+		registered: make(map[string]bool),
+	}
+}
+
+func (e *runtimeEmulator) ProcessRequest(ctx context.Context, application funcie.Application, request *funcie.Message) (*funcie.Response, error) {
+	if application.Endpoint.Scheme != RuntimeEndpointScheme {
+		return e.appClient.ProcessRequest(ctx, application, request)
+	}
+
+	forwardRequest, err := funcie.UnmarshalMessagePayload[messages.ForwardRequestMessage](request)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal forward request: %w", err)
+	}
+
+	slog.InfoContext(ctx, "sending request to emulated runtime", "id", request.ID, "application", application.Name)
+
+	body, err := e.getRuntime(application.Name).Invoke(ctx, forwardRequest.Payload.Body)
+	if errors.Is(err, runtimeapi.ErrNoRuntime) {
+		slog.WarnContext(ctx, "no runtime polled for the request", "application", application.Name)
+		// The consumer unsubscribes from applications with no active consumer, so register again on the next poll.
+		e.setRegistered(application.Name, false)
+		return funcie.NewResponse(request.ID, nil, funcie.ErrNoActiveConsumer), nil
+	}
+
+	var functionErr *runtimeapi.FunctionError
+	if errors.As(err, &functionErr) {
+		response := funcie.NewResponseWithPayload[messages.ForwardRequestResponsePayload](request.ID, nil, functionErr)
+		return funcie.MarshalResponsePayload(response)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invoke emulated runtime for %v: %w", application.Name, err)
+	}
+
+	response := funcie.NewResponseWithPayload(request.ID, messages.NewForwardRequestResponsePayload(body), nil)
+	return funcie.MarshalResponsePayload(response)
+}
+
+func (e *runtimeEmulator) Handler(processor transports.MessageProcessor, applicationId string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := e.ensureRegistered(req.Context(), processor, applicationId); err != nil {
+			slog.ErrorContext(req.Context(), "failed to register emulated runtime", "application", applicationId, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		e.getRuntime(applicationId).ServeHTTP(w, req)
+	})
+}
+
+// ensureRegistered registers the application with the bastion if it is not already, so that requests are forwarded to it.
+func (e *runtimeEmulator) ensureRegistered(ctx context.Context, processor transports.MessageProcessor, applicationId string) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.registered[applicationId] {
+		return nil
+	}
+
+	endpoint := funcie.Endpoint{
+		Scheme: RuntimeEndpointScheme,
+		Host:   "emulator",
+		Path:   "/" + applicationId,
+	}
+	payload := messages.NewRegistrationRequestPayload(applicationId, endpoint)
+	message := funcie.NewMessageWithPayload(applicationId, messages.MessageKindRegister, *payload)
+	marshaled, err := funcie.MarshalMessagePayload(*message)
+	if err != nil {
+		return fmt.Errorf("marshal registration: %w", err)
+	}
+
+	response, err := processor.ProcessMessage(ctx, marshaled)
+	if err != nil {
+		return fmt.Errorf("register application %v: %w", applicationId, err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("register application %v: %w", applicationId, response.Error)
+	}
+
+	slog.InfoContext(ctx, "registered emulated runtime", "application", applicationId)
+	e.registered[applicationId] = true
+	return nil
+}
+
+func (e *runtimeEmulator) setRegistered(applicationId string, registered bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.registered[applicationId] = registered
+}
+
+func (e *runtimeEmulator) getRuntime(applicationId string) runtimeapi.Runtime {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	runtime, ok := e.runtimes[applicationId]
+	if !ok {
+		runtime = runtimeapi.NewRuntime(
+			slog.Default().With("application", applicationId),
+			runtimeapi.WithFunctionName(applicationId),
+			runtimeapi.WithPollTimeout(e.config.RuntimeApiPollTimeout),
+		)
+		e.runtimes[applicationId] = runtime
+	}
+
+	return runtime
+}
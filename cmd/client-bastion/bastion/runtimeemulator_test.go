@@ -0,0 +1,152 @@
+package bastion_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Kapps/funcie/cmd/client-bastion/bastion"
+	bastionMocks "github.com/Kapps/funcie/cmd/client-bastion/bastion/mocks"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	transportMocks "github.com/Kapps/funcie/pkg/funcie/transports/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRuntimeEmulator(t *testing.T) {
+	ctx := context.Background()
+	config := &bastion.Config{RuntimeApiPollTimeout: 500 * time.Millisecond}
+
+	runtimeApp := funcie.NewApplication("app", funcie.Endpoint{Scheme: bastion.RuntimeEndpointScheme, Host: "emulator", Path: "/app"})
+	forwardRequest := func(body string) *funcie.Message {
+		payload := messages.NewForwardRequestPayload(json.RawMessage(body))
+		message := funcie.NewMessageWithPayload(runtimeApp.Name, messages.MessageKindForwardRequest, *payload)
+		marshaled, err := funcie.MarshalMessagePayload(*message)
+		require.NoError(t, err)
+		return marshaled
+	}
+
+	newEmulator := func(t *testing.T) (bastion.RuntimeEmulator, *transportMocks.MessageProcessor, string) {
+		processor := transportMocks.NewMessageProcessor(t)
+		emulator := bastion.NewRuntimeEmulator(config, bastionMocks.NewApplicationClient(t))
+		server := httptest.NewServer(emulator.Handler(processor, runtimeApp.Name))
+		t.Cleanup(server.Close)
+		return emulator, processor, server.URL
+	}
+
+	expectRegistration := func(processor *transportMocks.MessageProcessor) *transportMocks.MessageProcessor_ProcessMessage_Call {
+		return processor.EXPECT().ProcessMessage(mock.Anything, mock.MatchedBy(func(message *funcie.Message) bool {
+			registration, err := funcie.UnmarshalMessagePayload[messages.RegistrationMessage](message)
+			if err != nil {
+				return false
+			}
+			return message.Kind == messages.MessageKindRegister && registration.Payload.Endpoint == runtimeApp.Endpoint
+		})).RunAndReturn(func(_ context.Context, message *funcie.Message) (*funcie.Response, error) {
+			return funcie.NewResponse(message.ID, []byte(`{}`), nil), nil
+		})
+	}
+
+	t.Run("should register the application and return the response of the runtime", func(t *testing.T) {
+		emulator, processor, runtimeUrl := newEmulator(t)
+		expectRegistration(processor).Once()
+
+		go func() {
+			requestId, payload := pollRuntime(t, runtimeUrl)
+			postRuntimeResult(t, runtimeUrl, requestId, "response", payload)
+		}()
+
+		request := forwardRequest(`{"name":"funcie"}`)
+		resp, err := emulator.ProcessRequest(ctx, *runtimeApp, request)
+		require.NoError(t, err)
+		require.Nil(t, resp.Error)
+
+		unmarshaled, err := funcie.UnmarshalResponsePayload[messages.ForwardRequestResponse](resp)
+		require.NoError(t, err)
+		require.Equal(t, request.ID, unmarshaled.ID)
+		require.JSONEq(t, `{"name":"funcie"}`, string(unmarshaled.Data.Body))
+	})
+
+	t.Run("should return errors reported by the runtime", func(t *testing.T) {
+		emulator, processor, runtimeUrl := newEmulator(t)
+		expectRegistration(processor).Once()
+
+		go func() {
+			requestId, _ := pollRuntime(t, runtimeUrl)
+			postRuntimeResult(t, runtimeUrl, requestId, "error", []byte(`{"errorMessage":"boom","errorType":"Failure"}`))
+		}()
+
+		resp, err := emulator.ProcessRequest(ctx, *runtimeApp, forwardRequest(`{}`))
+		require.NoError(t, err)
+		require.NotNil(t, resp.Error)
+		require.Equal(t, "Failure: boom", resp.Error.Message)
+	})
+
+	t.Run("should report no active consumer and register again if no runtime polls", func(t *testing.T) {
+		emulator, processor, runtimeUrl := newEmulator(t)
+		expectRegistration(processor).Twice()
+
+		resp, err := http.Get(runtimeUrl + "/2018-06-01/runtime/invocation/unknown")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		response, err := emulator.ProcessRequest(ctx, *runtimeApp, forwardRequest(`{}`))
+		require.NoError(t, err)
+		require.Equal(t, funcie.ErrNoActiveConsumer.Error(), response.Error.Message)
+
+		resp, err = http.Get(runtimeUrl + "/2018-06-01/runtime/invocation/unknown")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	})
+
+	t.Run("should send requests for other applications to the underlying client", func(t *testing.T) {
+		appClient := bastionMocks.NewApplicationClient(t)
+		emulator := bastion.NewRuntimeEmulator(config, appClient)
+
+		app := funcie.NewApplication("http-app", funcie.MustNewEndpointFromAddress("http://localhost:8080"))
+		request := funcie.NewMessage(app.Name, messages.MessageKindForwardRequest, json.RawMessage(`{}`))
+		expected := funcie.NewResponse(request.ID, []byte(`{}`), nil)
+		appClient.EXPECT().ProcessRequest(ctx, *app, request).Return(expected, nil).Once()
+
+		resp, err := emulator.ProcessRequest(ctx, *app, request)
+		require.NoError(t, err)
+		require.Equal(t, expected, resp)
+	})
+}
+
+// pollRuntime polls the emulated runtime at the given URL for the next invocation, as a Lambda runtime would.
+// As this runs alongside the test rather than in it, failures are reported with assert and an empty request ID.
+func pollRuntime(t *testing.T, runtimeUrl string) (string, []byte) {
+	resp, err := http.Get(runtimeUrl + "/2018-06-01/runtime/invocation/next")
+	if !assert.NoError(t, err) {
+		return "", nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return "", nil
+	}
+
+	payload, err := io.ReadAll(resp.Body)
+	if !assert.NoError(t, err) {
+		return "", nil
+	}
+
+	return resp.Header.Get("Lambda-Runtime-Aws-Request-Id"), payload
+}
+
+// postRuntimeResult posts the result of an invocation to the emulated runtime at the given URL.
+// As this runs alongside the test rather than in it, failures are reported with assert.
+func postRuntimeResult(t *testing.T, runtimeUrl string, requestId string, action string, body []byte) {
+	url := runtimeUrl + "/2018-06-01/runtime/invocation/" + requestId + "/" + action
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
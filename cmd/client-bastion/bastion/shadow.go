@@ -0,0 +1,207 @@
+package bastion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// shadowSubscriberBuffer is how many comparisons may be queued for a subscriber before newer ones are dropped.
+const shadowSubscriberBuffer = 64
+
+// ShadowReporter compares and reports the results of requests that were forwarded in shadow mode.
+type ShadowReporter interface {
+	// Report compares the deployed and local results of the given request, then writes the comparison to the diff
+	// log and sends it to any subscribers.
+	Report(ctx context.Context, application string, requestId string, deployed messages.ShadowResult, local messages.ShadowResult) *messages.ShadowDiff
+	// Subscribe returns a channel that receives every comparison reported after the call, and a function to unsubscribe.
+	// Comparisons are dropped for subscribers that fall too far behind.
+	Subscribe() (<-chan *messages.ShadowDiff, func())
+	// Close closes the diff log, if there is one.
+	Close() error
+}
+
+type shadowReporter struct {
+	config      *Config
+	log         io.WriteCloser
+	subscribers map[chan *messages.ShadowDiff]struct{}
+	lock        sync.Mutex
+}
+
+// NewShadowReporter creates a new ShadowReporter that appends comparisons to the diff log at Config.ShadowLogPath,
+// or only logs and sends them to subscribers if no path is set.
+func NewShadowReporter(config *Config) (ShadowReporter, error) {
+	reporter := &shadowReporter{
+		config:      config,
+		subscribers: make(map[chan *messages.ShadowDiff]struct{}),
+	}
+
+	if config.ShadowLogPath != "" {
+		file, err := os.OpenFile(config.ShadowLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open shadow diff log %v: %w", config.ShadowLogPath, err)
+		}
+		reporter.log = file
+	}
+
+	return reporter, nil
+}
+
+func (r *shadowReporter) Report(
+	ctx context.Context,
+	application string,
+	requestId string,
+	deployed messages.ShadowResult,
+	local messages.ShadowResult,
+) *messages.ShadowDiff {
+	diff := messages.NewShadowDiff(application, requestId, deployed, local, r.config.ShadowLatencyThreshold)
+
+	if diff.Matched() {
+		slog.InfoContext(ctx, "shadow request matched",
+			"application", application, "requestId", requestId, "latencyDelta", diff.LatencyDelta)
+	} else {
+		slog.WarnContext(ctx, "shadow request mismatched",
+			"application", application, "requestId", requestId, "mismatches", diff.Mismatches, "latencyDelta", diff.LatencyDelta)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.log != nil {
+		if err := json.NewEncoder(r.log).Encode(diff); err != nil {
+			slog.WarnContext(ctx, "failed to write shadow diff log", "error", err, "requestId", requestId)
+		}
+	}
+
+	for subscriber := range r.subscribers {
+		select {
+		case subscriber <- diff:
+		default:
+			slog.WarnContext(ctx, "dropping shadow diff for slow subscriber", "requestId", requestId)
+		}
+	}
+
+	return diff
+}
+
+func (r *shadowReporter) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.log == nil {
+		return nil
+	}
+
+	err := r.log.Close()
+	r.log = nil
+	return err
+}
+
+func (r *shadowReporter) Subscribe() (<-chan *messages.ShadowDiff, func()) {
+	subscriber := make(chan *messages.ShadowDiff, shadowSubscriberBuffer)
+
+	r.lock.Lock()
+	r.subscribers[subscriber] = struct{}{}
+	r.lock.Unlock()
+
+	unsubscribe := func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.subscribers, subscriber)
+	}
+
+	return subscriber, unsubscribe
+}
+
+// NewShadowTailHandler creates an http.Handler that streams each reported comparison as a line of JSON until the
+// request is cancelled, such as for `funcie tail`. The application query parameter filters to a single application.
+func NewShadowTailHandler(reporter ShadowReporter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		application := r.URL.Query().Get("application")
+
+		diffs, unsubscribe := reporter.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", funcie.StreamContentType)
+		w.WriteHeader(http.StatusOK)
+		flush(w)
+
+		encoder := json.NewEncoder(w)
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case diff := <-diffs:
+				if application != "" && diff.Application != application {
+					continue
+				}
+				if err := encoder.Encode(diff); err != nil {
+					slog.WarnContext(r.Context(), "failed to write shadow diff to subscriber", "error", err)
+					return
+				}
+				flush(w)
+			}
+		}
+	})
+}
+
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// readShadowResult reads the local result from the response to a shadowed request, including the body of a streamed
+// response, which is read in full so that it can be compared.
+func readShadowResult(ctx context.Context, response *funcie.Response) (messages.ShadowResult, error) {
+	if response.Stream != nil {
+		defer func() { _ = response.Stream.Close() }()
+	}
+
+	if response.Error != nil {
+		return messages.ShadowResult{Error: response.Error}, nil
+	}
+
+	forwardResponse, err := funcie.UnmarshalResponsePayload[messages.ForwardRequestResponse](response)
+	if err != nil {
+		return messages.ShadowResult{}, fmt.Errorf("unmarshal response payload: %w", err)
+	}
+
+	if !forwardResponse.Data.Streaming {
+		return messages.ShadowResult{Body: forwardResponse.Data.Body}, nil
+	}
+
+	if response.Stream == nil {
+		return messages.ShadowResult{}, fmt.Errorf("streamed response %v has no stream", response.ID)
+	}
+
+	var result messages.ShadowResult
+	for {
+		message, err := response.Stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return messages.ShadowResult{}, fmt.Errorf("read response stream: %w", err)
+		}
+
+		chunk, err := funcie.UnmarshalMessagePayload[messages.ResponseChunkMessage](message)
+		if err != nil {
+			return messages.ShadowResult{}, fmt.Errorf("unmarshal response chunk: %w", err)
+		}
+
+		result.Body = append(result.Body, chunk.Payload.Data...)
+		if chunk.Payload.Final {
+			result.Error = chunk.Payload.Error
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package bastion_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/Kapps/funcie/cmd/client-bastion/bastion"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShadowReporter(t *testing.T) {
+	ctx := context.Background()
+
+	matching := messages.ShadowResult{Body: []byte(`{"a":1}`)}
+	different := messages.ShadowResult{Body: []byte(`{"a":2}`)}
+
+	t.Run("should append diffs to the log until closed", func(t *testing.T) {
+		logPath := filepath.Join(t.TempDir(), "shadow.log")
+		reporter, err := bastion.NewShadowReporter(&bastion.Config{ShadowLogPath: logPath, ShadowLatencyThreshold: time.Second})
+		require.NoError(t, err)
+
+		reporter.Report(ctx, "app", "req-1", matching, matching)
+		reporter.Report(ctx, "app", "req-2", matching, different)
+		require.NoError(t, reporter.Close())
+		require.NoError(t, reporter.Close())
+
+		// Reports after closing are still returned, but no longer logged.
+		diff := reporter.Report(ctx, "app", "req-3", matching, matching)
+		require.True(t, diff.Matched())
+
+		file, err := os.Open(logPath)
+		require.NoError(t, err)
+		defer func() { _ = file.Close() }()
+
+		var logged []messages.ShadowDiff
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var diff messages.ShadowDiff
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &diff))
+			logged = append(logged, diff)
+		}
+		require.NoError(t, scanner.Err())
+
+		require.Len(t, logged, 2)
+		require.Equal(t, "req-1", logged[0].RequestId)
+		require.True(t, logged[0].Matched())
+		require.Equal(t, "req-2", logged[1].RequestId)
+		require.Equal(t, []messages.ShadowMismatch{messages.ShadowMismatchBody}, logged[1].Mismatches)
+	})
+
+	t.Run("should fail when the log cannot be opened", func(t *testing.T) {
+		logPath := filepath.Join(t.TempDir(), "missing", "shadow.log")
+		_, err := bastion.NewShadowReporter(&bastion.Config{ShadowLogPath: logPath})
+		require.ErrorContains(t, err, "open shadow diff log")
+	})
+
+	t.Run("should send diffs to subscribers until they unsubscribe", func(t *testing.T) {
+		reporter, err := bastion.NewShadowReporter(&bastion.Config{ShadowLatencyThreshold: time.Second})
+		require.NoError(t, err)
+		defer func() { _ = reporter.Close() }()
+
+		diffs, unsubscribe := reporter.Subscribe()
+		reported := reporter.Report(ctx, "app", "req-1", matching, different)
+		require.Same(t, reported, <-diffs)
+
+		unsubscribe()
+		reporter.Report(ctx, "app", "req-2", matching, matching)
+		require.Empty(t, diffs)
+	})
+
+	t.Run("should stream diffs for the requested application", func(t *testing.T) {
+		reporter, err := bastion.NewShadowReporter(&bastion.Config{ShadowLatencyThreshold: time.Second})
+		require.NoError(t, err)
+		defer func() { _ = reporter.Close() }()
+
+		server := httptest.NewServer(bastion.NewShadowTailHandler(reporter))
+		defer server.Close()
+
+		reqCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, server.URL+"?application=app", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// The handler subscribes before writing the headers, so reports from here on are streamed.
+		reporter.Report(ctx, "other", "req-1", matching, matching)
+		reporter.Report(ctx, "app", "req-2", matching, different)
+
+		scanner := bufio.NewScanner(resp.Body)
+		require.True(t, scanner.Scan())
+
+		var diff messages.ShadowDiff
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &diff))
+		require.Equal(t, "app", diff.Application)
+		require.Equal(t, "req-2", diff.RequestId)
+		require.False(t, diff.Matched())
+	})
+}
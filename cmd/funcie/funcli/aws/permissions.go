@@ -0,0 +1,77 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"strings"
+)
+
+// PermissionChecker checks the identity and permissions of the AWS credentials used by the CLI.
+type PermissionChecker interface {
+	// CallerIdentity returns the ARN of the identity that the credentials belong to.
+	CallerIdentity(ctx context.Context) (string, error)
+	// DeniedActions simulates the policies of the identity with the given ARN, returning the actions it is not allowed.
+	DeniedActions(ctx context.Context, principalArn string, actions []string) ([]string, error)
+}
+
+type awsPermissionChecker struct {
+	stsClient StsClient
+	iamClient IamClient
+}
+
+// NewAwsPermissionChecker creates a new PermissionChecker that uses the provided STS and IAM clients.
+func NewAwsPermissionChecker(stsClient StsClient, iamClient IamClient) PermissionChecker {
+	return &awsPermissionChecker{
+		stsClient: stsClient,
+		iamClient: iamClient,
+	}
+}
+
+func (c *awsPermissionChecker) CallerIdentity(ctx context.Context) (string, error) {
+	result, err := c.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	return aws.ToString(result.Arn), nil
+}
+
+func (c *awsPermissionChecker) DeniedActions(ctx context.Context, principalArn string, actions []string) ([]string, error) {
+	paginator := iam.NewSimulatePrincipalPolicyPaginator(c.iamClient, &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(simulationPrincipalArn(principalArn)),
+		ActionNames:     actions,
+	})
+
+	var denied []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to simulate policies of %v: %w", principalArn, err)
+		}
+
+		for _, result := range page.EvaluationResults {
+			if result.EvalDecision != types.PolicyEvaluationDecisionTypeAllowed {
+				denied = append(denied, aws.ToString(result.EvalActionName))
+			}
+		}
+	}
+
+	return denied, nil
+}
+
+// simulationPrincipalArn returns the ARN of the IAM principal whose policies apply to the identity with the given ARN.
+// Policies cannot be simulated for assumed role sessions, such as from SSO, so the role that was assumed is used instead.
+func simulationPrincipalArn(identityArn string) string {
+	// arn:aws:sts::123456789012:assumed-role/RoleName/SessionName -> arn:aws:iam::123456789012:role/RoleName
+	parts := strings.SplitN(identityArn, ":", 6)
+	if len(parts) != 6 || parts[2] != "sts" || !strings.HasPrefix(parts[5], "assumed-role/") {
+		return identityArn
+	}
+
+	resource := strings.Split(parts[5], "/")
+	return fmt.Sprintf("%v:%v:iam::%v:role/%v", parts[0], parts[1], parts[4], resource[1])
+}
@@ -0,0 +1,70 @@
+package aws_test
+
+import (
+	"context"
+	. "github.com/Kapps/funcie/cmd/funcie/funcli/aws"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/aws/mocks"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCallerIdentity(t *testing.T) {
+	ctx := context.Background()
+	mockSts := mocks.NewStsClient(t)
+	checker := NewAwsPermissionChecker(mockSts, mocks.NewIamClient(t))
+
+	mockSts.EXPECT().GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}).Return(&sts.GetCallerIdentityOutput{
+		Arn: aws.String("arn:aws:iam::123456789012:user/dev"),
+	}, nil).Once()
+
+	arn, err := checker.CallerIdentity(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:iam::123456789012:user/dev", arn)
+}
+
+func TestDeniedActions(t *testing.T) {
+	ctx := context.Background()
+	actions := []string{"ssm:StartSession", "ec2:DescribeVpcs"}
+
+	result := func(action string, decision types.PolicyEvaluationDecisionType) types.EvaluationResult {
+		return types.EvaluationResult{EvalActionName: aws.String(action), EvalDecision: decision}
+	}
+
+	t.Run("should return the actions that are not allowed", func(t *testing.T) {
+		mockIam := mocks.NewIamClient(t)
+		checker := NewAwsPermissionChecker(mocks.NewStsClient(t), mockIam)
+
+		mockIam.EXPECT().SimulatePrincipalPolicy(ctx, &iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: aws.String("arn:aws:iam::123456789012:user/dev"),
+			ActionNames:     actions,
+		}, mock.Anything).Return(&iam.SimulatePrincipalPolicyOutput{
+			EvaluationResults: []types.EvaluationResult{
+				result("ssm:StartSession", types.PolicyEvaluationDecisionTypeImplicitDeny),
+				result("ec2:DescribeVpcs", types.PolicyEvaluationDecisionTypeAllowed),
+			},
+		}, nil).Once()
+
+		denied, err := checker.DeniedActions(ctx, "arn:aws:iam::123456789012:user/dev", actions)
+		require.NoError(t, err)
+		require.Equal(t, []string{"ssm:StartSession"}, denied)
+	})
+
+	t.Run("should simulate the role of an assumed role session", func(t *testing.T) {
+		mockIam := mocks.NewIamClient(t)
+		checker := NewAwsPermissionChecker(mocks.NewStsClient(t), mockIam)
+
+		mockIam.EXPECT().SimulatePrincipalPolicy(ctx, &iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: aws.String("arn:aws:iam::123456789012:role/Developer"),
+			ActionNames:     actions,
+		}, mock.Anything).Return(&iam.SimulatePrincipalPolicyOutput{}, nil).Once()
+
+		denied, err := checker.DeniedActions(ctx, "arn:aws:sts::123456789012:assumed-role/Developer/dev@example.com", actions)
+		require.NoError(t, err)
+		require.Empty(t, denied)
+	})
+}
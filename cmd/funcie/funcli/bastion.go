@@ -0,0 +1,312 @@
+package funcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	clientbastion "github.com/Kapps/funcie/cmd/client-bastion/bastion"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"go.uber.org/fx"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// bastionStartupTimeout is how long to wait for a detached client bastion to become healthy.
+const bastionStartupTimeout = 15 * time.Second
+
+// bastionStopTimeout is how long to wait for a client bastion to exit after being asked to stop.
+const bastionStopTimeout = 10 * time.Second
+
+type BastionConfig struct {
+	Stop    *BastionStopConfig    `arg:"subcommand:stop" help:"Stop the client bastion running in the background."`
+	Logs    *BastionLogsConfig    `arg:"subcommand:logs" help:"Show the logs of the client bastion running in the background."`
+	Restart *BastionRestartConfig `arg:"subcommand:restart" help:"Restart the client bastion in the background."`
+	Upgrade *BastionUpgradeConfig `arg:"subcommand:upgrade" help:"Switch the client bastion container to the image matching this version of funcie."`
+
+	RedisAddress  string `arg:"--redis-address,env:FUNCIE_REDIS_ADDRESS" help:"The address of Redis, usually forwarded by funcie connect." default:"127.0.0.1:6379"`
+	ListenAddress string `arg:"--listen-address,env:FUNCIE_LISTEN_ADDRESS" help:"The address that the client bastion listens on for local applications." default:"127.0.0.1:24193"`
+	Detach        bool   `arg:"--detach,-d" help:"Run the client bastion in the background."`
+	PidFile       string `arg:"--pid-file" help:"The pidfile of the client bastion; defaults to ~/.funcie/bastion.pid."`
+	LogFile       string `arg:"--log-file" help:"The log file of a client bastion running in the background; defaults to ~/.funcie/bastion.log."`
+}
+
+type BastionStopConfig struct{}
+
+type BastionLogsConfig struct {
+	Follow bool `arg:"--follow,-f" help:"Keep printing new log lines as they are written."`
+}
+
+type BastionRestartConfig struct{}
+
+type BastionUpgradeConfig struct{}
+
+// BastionCommand runs the client bastion natively, rather than in a Docker container.
+// As it runs on the host, applications registering with a loopback address need no host translation.
+// It also upgrades the client bastion container for those that run it in Docker.
+type BastionCommand struct {
+	cliConfig  *CliConfig
+	containers BastionContainerManager
+	client     *http.Client
+	output     io.Writer
+}
+
+func NewBastionCommand(cliConfig *CliConfig, containers BastionContainerManager) *BastionCommand {
+	return &BastionCommand{
+		cliConfig:  cliConfig,
+		containers: containers,
+		client:     &http.Client{Timeout: time.Second},
+		output:     os.Stdout,
+	}
+}
+
+func (c *BastionCommand) Run(ctx context.Context) error {
+	conf := c.cliConfig.BastionConfig
+
+	switch {
+	case conf.Stop != nil:
+		return c.stop()
+	case conf.Logs != nil:
+		return c.logs(ctx, conf.Logs.Follow)
+	case conf.Restart != nil:
+		if err := c.stop(); err != nil && !errors.Is(err, errBastionNotRunning) {
+			return err
+		}
+		return c.startDetached(ctx)
+	case conf.Upgrade != nil:
+		return c.upgrade()
+	case conf.Detach:
+		return c.startDetached(ctx)
+	default:
+		return c.runForeground(ctx)
+	}
+}
+
+// errBastionNotRunning is returned when stopping a client bastion that is not running in the background.
+var errBastionNotRunning = errors.New("the client bastion is not running in the background")
+
+// runForeground runs the client bastion in this process until it is interrupted.
+func (c *BastionCommand) runForeground(ctx context.Context) error {
+	conf := c.cliConfig.BastionConfig
+	pidPath := c.pidPath()
+
+	if pid, running := readRunningPid(pidPath); running {
+		return fmt.Errorf("the client bastion is already running with pid %v; stop it with funcie bastion stop", pid)
+	}
+	if err := writePidFile(pidPath, os.Getpid()); err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(pidPath) }()
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	funcie.ConfigureLogging()
+
+	bastionConfig := clientbastion.NewConfigFromEnvironmentWithAddresses(conf.RedisAddress, conf.ListenAddress)
+	if len(bastionConfig.HostTranslation) == 0 {
+		// Applications run on the same host, so only explicit overrides need translating.
+		bastionConfig.HostTranslation = []string{clientbastion.HostStrategyOverride, clientbastion.HostStrategyNone}
+	}
+
+	app := fx.New(
+		fx.Supply(
+			fx.Annotate(ctx, fx.As(new(context.Context))),
+			&http.Client{},
+			bastionConfig,
+		),
+		clientbastion.Module,
+		fx.NopLogger,
+	)
+	if err := app.Err(); err != nil {
+		return fmt.Errorf("failed to initialize client bastion: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(c.output, "Starting client bastion on %v using Redis at %v...\n", conf.ListenAddress, conf.RedisAddress)
+	if err := app.Start(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to start client bastion: %w", err)
+	}
+	_, _ = fmt.Fprintf(c.output, "Client bastion listening on %v\n", conf.ListenAddress)
+
+	var exitCode int
+	select {
+	case <-ctx.Done():
+	case shutdown := <-app.Wait():
+		exitCode = shutdown.ExitCode
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), bastionStopTimeout)
+	defer cancel()
+	if err := app.Stop(stopCtx); err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		return fmt.Errorf("client bastion stopped unexpectedly with exit code %v; see the logs above", exitCode)
+	}
+	return nil
+}
+
+// startDetached runs the client bastion in a new background process, writing its output to the log file.
+func (c *BastionCommand) startDetached(ctx context.Context) error {
+	conf := c.cliConfig.BastionConfig
+	pidPath := c.pidPath()
+	logPath := c.logPath()
+
+	if pid, running := readRunningPid(pidPath); running {
+		return fmt.Errorf("the client bastion is already running with pid %v; stop it with funcie bastion stop", pid)
+	}
+
+	proc, exited, err := startDetachedProcess([]string{"bastion",
+		"--redis-address", conf.RedisAddress,
+		"--listen-address", conf.ListenAddress,
+		"--pid-file", pidPath,
+		"--log-file", logPath,
+	}, logPath)
+	if err != nil {
+		return fmt.Errorf("failed to start client bastion: %w", err)
+	}
+
+	healthy, err := c.waitForHealthy(ctx, conf.ListenAddress, exited)
+	if err != nil {
+		return fmt.Errorf("client bastion exited during startup (%w); see %v", err, logPath)
+	}
+
+	if healthy {
+		_, _ = fmt.Fprintf(c.output, "Client bastion running in the background on %v with pid %v.\n", conf.ListenAddress, proc.Process.Pid)
+	} else {
+		_, _ = fmt.Fprintf(c.output, "Client bastion started in the background with pid %v, but is not yet healthy; it may still be waiting for Redis at %v.\n",
+			proc.Process.Pid, conf.RedisAddress)
+	}
+	_, _ = fmt.Fprintf(c.output, "Logs are written to %v; view them with funcie bastion logs.\n", logPath)
+
+	return nil
+}
+
+// waitForHealthy waits for the client bastion to respond to health checks, returning an error if it exits first.
+func (c *BastionCommand) waitForHealthy(ctx context.Context, listenAddress string, exited <-chan error) (bool, error) {
+	healthUrl := fmt.Sprintf("http://%v/health", listenAddress)
+	deadline := time.After(bastionStartupTimeout)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exit status 0")
+			}
+			return false, err
+		case <-deadline:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthUrl, nil)
+			if err != nil {
+				return false, err
+			}
+			resp, err := c.client.Do(req)
+			if err != nil {
+				continue
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return true, nil
+			}
+		}
+	}
+}
+
+// stop asks the client bastion running in the background to exit, and waits for it to do so.
+func (c *BastionCommand) stop() error {
+	pidPath := c.pidPath()
+
+	pid, running := readRunningPid(pidPath)
+	if !running {
+		_ = os.Remove(pidPath)
+		return errBastionNotRunning
+	}
+
+	if err := stopProcess(pid, bastionStopTimeout); err != nil {
+		return fmt.Errorf("failed to stop client bastion: %w", err)
+	}
+
+	_ = os.Remove(pidPath)
+	_, _ = fmt.Fprintf(c.output, "Stopped client bastion with pid %v.\n", pid)
+	return nil
+}
+
+// logs prints the log file of the client bastion, optionally following it until interrupted.
+func (c *BastionCommand) logs(ctx context.Context, follow bool) error {
+	logPath := c.logPath()
+
+	file, err := os.Open(logPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no client bastion logs found at %v; start it with funcie bastion --detach", logPath)
+		}
+		return fmt.Errorf("failed to open log file %v: %w", logPath, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := io.Copy(c.output, file); err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+	if !follow {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := io.Copy(c.output, file); err != nil {
+				return fmt.Errorf("failed to read log file: %w", err)
+			}
+		}
+	}
+}
+
+// upgrade replaces the client bastion container with one running the image matching this version of funcie.
+func (c *BastionCommand) upgrade() error {
+	upgraded, err := c.containers.Upgrade()
+	if err != nil {
+		return fmt.Errorf("failed to upgrade client bastion container: %w", err)
+	}
+
+	if upgraded {
+		_, _ = fmt.Fprintf(c.output, "Client bastion container is now running %v.\n", c.containers.Image())
+	} else {
+		_, _ = fmt.Fprintf(c.output, "Client bastion container is already running %v.\n", c.containers.Image())
+	}
+
+	return nil
+}
+
+func (c *BastionCommand) pidPath() string {
+	if path := c.cliConfig.BastionConfig.PidFile; path != "" {
+		return path
+	}
+	return internal.GetBastionPidPath()
+}
+
+func (c *BastionCommand) logPath() string {
+	if path := c.cliConfig.BastionConfig.LogFile; path != "" {
+		return path
+	}
+	return internal.GetBastionLogPath()
+}
@@ -0,0 +1,83 @@
+package funcli_test
+
+import (
+	"context"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBastionCommand(t *testing.T) {
+	ctx := context.Background()
+
+	newCommand := func(t *testing.T, conf *funcli.BastionConfig) *funcli.BastionCommand {
+		dir := t.TempDir()
+		conf.PidFile = filepath.Join(dir, "bastion.pid")
+		conf.LogFile = filepath.Join(dir, "bastion.log")
+
+		cliConfig := funcli.NewCliConfig("test")
+		cliConfig.BastionConfig = conf
+		return funcli.NewBastionCommand(cliConfig, nil)
+	}
+
+	t.Run("should fail to stop a bastion that is not running", func(t *testing.T) {
+		cmd := newCommand(t, &funcli.BastionConfig{Stop: &funcli.BastionStopConfig{}})
+
+		err := cmd.Run(ctx)
+		require.ErrorContains(t, err, "not running")
+	})
+
+	t.Run("should remove a stale pidfile when stopping", func(t *testing.T) {
+		conf := &funcli.BastionConfig{Stop: &funcli.BastionStopConfig{}}
+		cmd := newCommand(t, conf)
+		require.NoError(t, os.WriteFile(conf.PidFile, []byte("not-a-pid\n"), 0644))
+
+		err := cmd.Run(ctx)
+		require.ErrorContains(t, err, "not running")
+		require.NoFileExists(t, conf.PidFile)
+	})
+
+	t.Run("should stop cleanly when interrupted while waiting for Redis", func(t *testing.T) {
+		// Nothing listens on a port that was just released, so the bastion keeps retrying to connect.
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		redisAddress := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		conf := &funcli.BastionConfig{RedisAddress: redisAddress, ListenAddress: "127.0.0.1:0"}
+		cmd := newCommand(t, conf)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- cmd.Run(ctx)
+		}()
+
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(conf.PidFile)
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "client bastion did not stop after being interrupted")
+		}
+		require.NoFileExists(t, conf.PidFile)
+	})
+
+	t.Run("should fail to show logs that do not exist", func(t *testing.T) {
+		cmd := newCommand(t, &funcli.BastionConfig{Logs: &funcli.BastionLogsConfig{}})
+
+		err := cmd.Run(ctx)
+		require.ErrorContains(t, err, "no client bastion logs found")
+	})
+}
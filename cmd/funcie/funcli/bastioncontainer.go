@@ -0,0 +1,187 @@
+package funcli
+
+import (
+	"fmt"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	"net"
+	"runtime"
+	"strings"
+)
+
+const dockerClientImage = "public.ecr.aws/w1h1o7p8/funcie-client-bastion"
+
+const (
+	// BastionContainerName is the name of the Docker container running the client bastion.
+	BastionContainerName = "funcie-client-bastion"
+	// BastionComponentLabel is the label identifying the funcie component that a Docker container runs.
+	BastionComponentLabel = "funcie.component"
+	// BastionComponent is the value of BastionComponentLabel for the Docker container running the client bastion.
+	BastionComponent = "client-bastion"
+	// BastionVersionLabel is the label holding the funcie version of the Docker container running the client bastion.
+	BastionVersionLabel = "funcie.version"
+	// BastionPort is the port that the client bastion container listens on.
+	BastionPort = 24193
+)
+
+// BastionContainerManager manages the lifecycle of the Docker container running the client bastion.
+// The container is pinned to the image matching the version of the CLI.
+type BastionContainerManager interface {
+	// Image returns the client bastion image matching the version of the CLI.
+	Image() string
+	// Find returns the existing client bastion container, or nil if there is none.
+	Find() (*tools.DockerContainer, error)
+	// Start pulls the image for this version and starts a new client bastion container from it.
+	// Fails if the client bastion port is already in use.
+	Start() error
+	// Upgrade replaces the existing client bastion container with one using the image for this version.
+	// Returns false if the existing container already uses that image and is running.
+	Upgrade() (bool, error)
+	// Remove stops and removes the existing client bastion container, returning false if there was none.
+	Remove() (bool, error)
+}
+
+type dockerBastionContainerManager struct {
+	docker  tools.DockerClient
+	version string
+	port    int
+}
+
+// NewBastionContainerManager creates a new BastionContainerManager that manages the container with the Docker CLI.
+func NewBastionContainerManager(cliConfig *CliConfig, docker tools.DockerClient) BastionContainerManager {
+	return NewBastionContainerManagerWithPort(cliConfig, docker, BastionPort)
+}
+
+// NewBastionContainerManagerWithPort creates a new BastionContainerManager for a client bastion on the given port.
+func NewBastionContainerManagerWithPort(cliConfig *CliConfig, docker tools.DockerClient, port int) BastionContainerManager {
+	return &dockerBastionContainerManager{
+		docker:  docker,
+		version: strings.TrimSpace(cliConfig.versionString),
+		port:    port,
+	}
+}
+
+func (m *dockerBastionContainerManager) Image() string {
+	return fmt.Sprintf("%v:v%v", dockerClientImage, m.version)
+}
+
+func (m *dockerBastionContainerManager) Find() (*tools.DockerContainer, error) {
+	for _, filter := range []string{"name=^" + BastionContainerName + "$", "label=" + BastionComponentLabel + "=" + BastionComponent} {
+		containers, err := m.docker.ListContainers(filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(containers) > 0 {
+			return &containers[0], nil
+		}
+	}
+
+	// Containers started by older versions of the CLI have neither a name nor labels, so match them by image.
+	containers, err := m.docker.ListContainers("")
+	if err != nil {
+		return nil, err
+	}
+	for _, container := range containers {
+		if strings.HasPrefix(container.Image, dockerClientImage+":") {
+			return &container, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *dockerBastionContainerManager) Start() error {
+	image := m.Image()
+	if err := m.docker.PullImage(image); err != nil {
+		return err
+	}
+
+	return m.run(image)
+}
+
+func (m *dockerBastionContainerManager) Upgrade() (bool, error) {
+	existing, err := m.Find()
+	if err != nil {
+		return false, fmt.Errorf("failed to find client bastion container: %w", err)
+	}
+
+	image := m.Image()
+	if existing != nil && existing.Image == image && existing.Running() {
+		return false, nil
+	}
+
+	// Pull before removing the existing container, so that a failed pull leaves it running.
+	if err := m.docker.PullImage(image); err != nil {
+		return false, err
+	}
+
+	if existing != nil {
+		if err := m.docker.RemoveContainer(existing.Id); err != nil {
+			return false, err
+		}
+	}
+
+	if err := m.run(image); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (m *dockerBastionContainerManager) Remove() (bool, error) {
+	existing, err := m.Find()
+	if err != nil {
+		return false, fmt.Errorf("failed to find client bastion container: %w", err)
+	}
+	if existing == nil {
+		return false, nil
+	}
+
+	if err := m.docker.RemoveContainer(existing.Id); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// run starts a new client bastion container from the given image, which must already be pulled.
+func (m *dockerBastionContainerManager) run(image string) error {
+	if err := checkPortAvailable(m.port); err != nil {
+		return err
+	}
+
+	// TODO: Determine appropriate redis host value for Linux/Windows.
+	redisHost := "localhost"
+	if runtime.GOOS == "darwin" {
+		redisHost = "host.docker.internal"
+	}
+
+	err := m.docker.RunContainer(image, tools.DockerRunOptions{
+		Name: BastionContainerName,
+		Labels: map[string]string{
+			BastionComponentLabel: BastionComponent,
+			BastionVersionLabel:   m.version,
+		},
+		Env: map[string]string{
+			"FUNCIE_REDIS_ADDRESS":  fmt.Sprintf("%v:%v", redisHost, "6379"),
+			"FUNCIE_LISTEN_ADDRESS": fmt.Sprintf("0.0.0.0:%v", m.port),
+		},
+		ExposedPorts:  []int{m.port},
+		RestartPolicy: "unless-stopped",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run client bastion container: %w", err)
+	}
+
+	return nil
+}
+
+// checkPortAvailable returns an error if something is already listening on the given port.
+func checkPortAvailable(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
+	if err != nil {
+		return fmt.Errorf("port %v is already in use, possibly by a client bastion started with funcie bastion "+
+			"(stop it with funcie bastion stop): %w", port, err)
+	}
+
+	return listener.Close()
+}
@@ -0,0 +1,112 @@
+package funcli_test
+
+import (
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestBastionContainerManager(t *testing.T) {
+	const image = "public.ecr.aws/w1h1o7p8/funcie-client-bastion:v1.2.3"
+	nameFilter := "name=^" + funcli.BastionContainerName + "$"
+	labelFilter := "label=" + funcli.BastionComponentLabel + "=" + funcli.BastionComponent
+
+	// freePort returns a port that nothing is listening on.
+	freePort := func(t *testing.T) int {
+		listener, err := net.Listen("tcp", ":0")
+		require.NoError(t, err)
+		port := listener.Addr().(*net.TCPAddr).Port
+		require.NoError(t, listener.Close())
+		return port
+	}
+
+	newManager := func(t *testing.T, docker *mocks.DockerClient, port int) funcli.BastionContainerManager {
+		return funcli.NewBastionContainerManagerWithPort(funcli.NewCliConfig("1.2.3\n"), docker, port)
+	}
+
+	t.Run("should pin the image to the CLI version", func(t *testing.T) {
+		manager := newManager(t, mocks.NewDockerClient(t), freePort(t))
+
+		require.Equal(t, image, manager.Image())
+	})
+
+	t.Run("should find the container by name", func(t *testing.T) {
+		docker := mocks.NewDockerClient(t)
+		container := tools.DockerContainer{Id: "abc", Name: funcli.BastionContainerName, Image: image, State: "running"}
+		docker.EXPECT().ListContainers(nameFilter).Return([]tools.DockerContainer{container}, nil).Once()
+
+		found, err := newManager(t, docker, freePort(t)).Find()
+		require.NoError(t, err)
+		require.Equal(t, &container, found)
+	})
+
+	t.Run("should find containers started by older versions by their image", func(t *testing.T) {
+		docker := mocks.NewDockerClient(t)
+		legacy := tools.DockerContainer{Id: "old", Name: "eager_turing", Image: "public.ecr.aws/w1h1o7p8/funcie-client-bastion:v0.1.0", State: "exited"}
+		docker.EXPECT().ListContainers(nameFilter).Return(nil, nil).Once()
+		docker.EXPECT().ListContainers(labelFilter).Return(nil, nil).Once()
+		docker.EXPECT().ListContainers("").Return([]tools.DockerContainer{
+			{Id: "other", Name: "redis", Image: "redis:7", State: "running"},
+			legacy,
+		}, nil).Once()
+
+		found, err := newManager(t, docker, freePort(t)).Find()
+		require.NoError(t, err)
+		require.Equal(t, &legacy, found)
+	})
+
+	t.Run("should not upgrade a running container that is already up to date", func(t *testing.T) {
+		docker := mocks.NewDockerClient(t)
+		container := tools.DockerContainer{Id: "abc", Name: funcli.BastionContainerName, Image: image, State: "running"}
+		docker.EXPECT().ListContainers(nameFilter).Return([]tools.DockerContainer{container}, nil).Once()
+
+		upgraded, err := newManager(t, docker, freePort(t)).Upgrade()
+		require.NoError(t, err)
+		require.False(t, upgraded)
+	})
+
+	t.Run("should replace an outdated container with one for the CLI version", func(t *testing.T) {
+		docker := mocks.NewDockerClient(t)
+		port := freePort(t)
+		container := tools.DockerContainer{Id: "abc", Name: funcli.BastionContainerName, Image: "public.ecr.aws/w1h1o7p8/funcie-client-bastion:v1.0.0", State: "running"}
+		docker.EXPECT().ListContainers(nameFilter).Return([]tools.DockerContainer{container}, nil).Once()
+		pull := docker.EXPECT().PullImage(image).Return(nil).Once()
+		remove := docker.EXPECT().RemoveContainer("abc").Return(nil).Once().NotBefore(pull)
+		docker.EXPECT().RunContainer(image, mock.Anything).Run(func(_ string, opts tools.DockerRunOptions) {
+			require.Equal(t, funcli.BastionContainerName, opts.Name)
+			require.Equal(t, funcli.BastionComponent, opts.Labels[funcli.BastionComponentLabel])
+			require.Equal(t, "1.2.3", opts.Labels[funcli.BastionVersionLabel])
+			require.Equal(t, []int{port}, opts.ExposedPorts)
+			require.Equal(t, "unless-stopped", opts.RestartPolicy)
+		}).Return(nil).Once().NotBefore(remove)
+
+		upgraded, err := newManager(t, docker, port).Upgrade()
+		require.NoError(t, err)
+		require.True(t, upgraded)
+	})
+
+	t.Run("should not start a container when the port is in use", func(t *testing.T) {
+		listener, err := net.Listen("tcp", ":0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = listener.Close() })
+
+		docker := mocks.NewDockerClient(t)
+		docker.EXPECT().PullImage(image).Return(nil).Once()
+
+		err = newManager(t, docker, listener.Addr().(*net.TCPAddr).Port).Start()
+		require.ErrorContains(t, err, "already in use")
+	})
+
+	t.Run("should report when there is no container to remove", func(t *testing.T) {
+		docker := mocks.NewDockerClient(t)
+		docker.EXPECT().ListContainers(mock.Anything).Return(nil, nil).Times(3)
+
+		removed, err := newManager(t, docker, freePort(t)).Remove()
+		require.NoError(t, err)
+		require.False(t, removed)
+	})
+}
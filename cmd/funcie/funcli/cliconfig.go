@@ -6,11 +6,18 @@ import (
 
 // CliConfig provides user input parameters specific to the CLI tool.
 type CliConfig struct {
-	ConnectConfig *ConnectConfig `arg:"subcommand:connect" help:"Connect to a funcie deployment to allow local development."`
-	InitConfig    *InitConfig    `arg:"subcommand:init" help:"Initialize a new funcie deployment."`
-	DestroyConfig *DestroyConfig `arg:"subcommand:destroy" help:"Destroy an existing funcie deployment."`
+	ConnectConfig    *ConnectConfig    `arg:"subcommand:connect" help:"Connect to a funcie deployment to allow local development."`
+	DisconnectConfig *DisconnectConfig `arg:"subcommand:disconnect" help:"Stop funcie connect running in the background."`
+	InitConfig       *InitConfig       `arg:"subcommand:init" help:"Initialize a new funcie deployment."`
+	DestroyConfig    *DestroyConfig    `arg:"subcommand:destroy" help:"Destroy an existing funcie deployment."`
+	UpgradeConfig    *UpgradeConfig    `arg:"subcommand:upgrade" help:"Upgrade an existing funcie deployment to the version of the CLI."`
+	TailConfig       *TailConfig       `arg:"subcommand:tail" help:"Follow the comparisons of Lambdas running in shadow mode."`
+	DevConfig        *DevConfig        `arg:"subcommand:dev" help:"Run funcie entirely locally, without AWS."`
+	BastionConfig    *BastionConfig    `arg:"subcommand:bastion" help:"Run the client bastion natively instead of in Docker."`
+	EnvConfig        *EnvConfig        `arg:"subcommand:env" help:"Manage named funcie environments."`
+	DoctorConfig     *DoctorConfig     `arg:"subcommand:doctor" help:"Check the prerequisites and configuration of funcie."`
 
-	Environment string `arg:"--env" help:"Funcie environment used if multiple deployments are present." default:"default"`
+	Environment string `arg:"--env,env:FUNCIE_ENVIRONMENT" help:"Funcie environment used if multiple deployments are present; defaults to the one selected with funcie env use."`
 	Region      string `arg:"env:AWS_REGION" help:"AWS region to use for deployments; otherwise uses the default AWS CLI region."`
 
 	versionString string `arg:"-"`
@@ -20,6 +27,14 @@ func (c *CliConfig) Version() string {
 	return fmt.Sprintf("funcie v%v", c.versionString)
 }
 
+// ResolveEnvironment selects the current environment if none was given, and validates the environment name.
+func (c *CliConfig) ResolveEnvironment() error {
+	if c.Environment == "" {
+		c.Environment = CurrentEnvironment()
+	}
+	return ValidateEnvironmentName(c.Environment)
+}
+
 func NewCliConfig(version string) *CliConfig {
 	return &CliConfig{
 		versionString: version,
@@ -2,17 +2,42 @@ package funcli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	aws2 "github.com/Kapps/funcie/cmd/funcie/funcli/aws"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 )
 
+// connectStartupTimeout is how long to wait for a detached funcie connect to report its status.
+const connectStartupTimeout = 30 * time.Second
+
+// sessionTerminateTimeout is how long to wait for a session to be terminated once its tunnel stops.
+const sessionTerminateTimeout = 5 * time.Second
+
 type ConnectConfig struct {
-	RemoteHost string `arg:"--remote-host,-r" help:"Override the remote host to connect to instead of the Redis default."`
-	RemotePort int    `arg:"--remote-port,-p" help:"Override the remote port to bind to." default:"6379"`
-	LocalPort  int    `arg:"--local-port,-l" help:"Override the local port to bind to." default:"6379"`
+	ConfigFile   string        `arg:"--config,-c" help:"A YAML file listing the forwards to open; defaults to forwards.yaml in the environment's state directory, if it exists and no remote host is given."`
+	RemoteHost   string        `arg:"--remote-host,-r" help:"Override the remote host to connect to instead of the Redis default."`
+	RemotePort   int           `arg:"--remote-port,-p" help:"Override the remote port to bind to." default:"6379"`
+	LocalPort    int           `arg:"--local-port,-l" help:"Override the local port to bind to." default:"6379"`
+	RestartDelay time.Duration `arg:"--restart-delay" help:"How long to wait before restarting a forward that stopped." default:"5s"`
+	Hosts        bool          `arg:"--hosts" help:"Map the hostnames of the forwards to localhost in the hosts file while connected, listening on their remote ports; requires running as an administrator."`
+	HostsFile    string        `arg:"--hosts-file" help:"The hosts file to update with --hosts; defaults to the one of the operating system."`
+	Detach       bool          `arg:"--detach,-d" help:"Run in the background until stopped with funcie disconnect."`
+	HealthCheck  time.Duration `arg:"--health-check" help:"How often to PING Redis through its forward, restarting the session after 3 failures in a row; 0 disables." default:"30s"`
 }
 
 type ConnectCommand struct {
@@ -21,6 +46,8 @@ type ConnectCommand struct {
 	connectClient       aws2.SsmClient
 	tunneller           Tunneller
 	connectivityService ConnectivityService
+	probe               tunnelProbe
+	output              io.Writer
 }
 
 func NewConnectCommand(
@@ -37,67 +64,383 @@ func NewConnectCommand(
 		connectClient:       connectClient,
 		tunneller:           tunneller,
 		connectivityService: connectivityService,
+		probe:               pingRedis,
+		output:              os.Stdout,
 	}
 }
 
 func (c *ConnectCommand) Run(ctx context.Context) error {
 	conf := c.cliConfig.ConnectConfig
+	env := c.cliConfig.Environment
+
+	if conf.Detach {
+		return c.startDetached(ctx)
+	}
+
+	pidPath := internal.GetConnectPidPath(env)
+	if pid, running := readRunningPid(pidPath); running {
+		return fmt.Errorf("funcie connect is already running for the %v environment with pid %v; stop it with funcie disconnect", env, pid)
+	}
+
+	// Stop on interrupt rather than exiting, so that the hosts file can be restored.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	forwards, err := c.forwards()
+	if err != nil {
+		return err
+	}
+
+	for i, forward := range forwards {
+		forwards[i], err = resolveForward(ctx, c.configStore, forward)
+		if err != nil {
+			return err
+		}
+	}
+
+	if conf.Hosts {
+		var hostnames []string
+		forwards, hostnames, err = mapForwardHosts(forwards)
+		if err != nil {
+			return err
+		}
 
-	if conf.RemoteHost == "" {
-		host, err := c.configStore.GetConfigValue(ctx, "redis_host")
+		restore, err := c.mapHostnames(hostnames)
 		if err != nil {
-			return fmt.Errorf("failed to get Redis host: %w", err)
+			return err
 		}
-		conf.RemoteHost = strings.Split(host, ":")[0]
+		defer restore()
+	} else {
+		c.removeStaleHostnames()
 	}
 
+	if err := writePidFile(pidPath, os.Getpid()); err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(pidPath) }()
+
+	status := newConnectStatusTracker(forwards)
+	stopServing, err := serveConnectStatus(internal.GetConnectSocketPath(env), status)
+	if err != nil {
+		return err
+	}
+	defer stopServing()
+
+	c.printForwards(status.Snapshot().Forwards)
+
 	ssmEndpoint := fmt.Sprintf("https://ssm.%v.amazonaws.com", c.cliConfig.Region)
 
-	for ctx.Err() == nil {
-		if err := c.connectivityService.WaitForConnectivity(ctx, ssmEndpoint); err != nil {
-			return fmt.Errorf("failed to wait for connectivity: %w", err)
+	// Forwards wait for connectivity one at a time, so that an outage is only polled for and reported once.
+	var connectivityLock sync.Mutex
+	waitForConnectivity := func() error {
+		connectivityLock.Lock()
+		defer connectivityLock.Unlock()
+		return c.connectivityService.WaitForConnectivity(ctx, ssmEndpoint)
+	}
+
+	// A forward failing in a way that restarting would not fix stops the others too.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(forwards))
+
+	var wg sync.WaitGroup
+	for _, forward := range forwards {
+		wg.Add(1)
+		go func(forward Forward) {
+			defer wg.Done()
+			if err := c.runForward(ctx, forward, status, waitForConnectivity); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(forward)
+	}
+	go c.restartOnWake(ctx, status)
+
+	// Each tunnel stops its session process once the context is done, so the hosts file is only restored afterwards.
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// startDetached runs funcie connect in a new background process, waiting for it to open its status socket.
+func (c *ConnectCommand) startDetached(ctx context.Context) error {
+	env := c.cliConfig.Environment
+	logPath := internal.GetConnectLogPath(env)
+
+	if pid, running := readRunningPid(internal.GetConnectPidPath(env)); running {
+		return fmt.Errorf("funcie connect is already running for the %v environment with pid %v; stop it with funcie disconnect", env, pid)
+	}
+
+	// The environment is given explicitly so that selecting another one does not affect the background process.
+	args := append([]string{"--env", env}, withoutDetachFlag(os.Args[1:])...)
+	proc, exited, err := startDetachedProcess(args, logPath)
+	if err != nil {
+		return fmt.Errorf("failed to start funcie connect: %w", err)
+	}
+
+	status, err := c.waitForStatus(ctx, internal.GetConnectSocketPath(env), exited)
+	if err != nil {
+		return fmt.Errorf("funcie connect exited during startup (%w); see %v", err, logPath)
+	}
+
+	switch {
+	case status == nil:
+		_, _ = fmt.Fprintf(c.output, "funcie connect started in the background with pid %v, but has not yet opened its forwards.\n", proc.Process.Pid)
+	case !allForwardsConnected(status):
+		c.printForwards(status.Forwards)
+		_, _ = fmt.Fprintf(c.output, "funcie connect started in the background with pid %v, but not every forward has connected yet.\n",
+			proc.Process.Pid)
+	default:
+		c.printForwards(status.Forwards)
+		_, _ = fmt.Fprintf(c.output, "Connected in the background with pid %v.\n", proc.Process.Pid)
+	}
+	_, _ = fmt.Fprintf(c.output, "Logs are written to %v; stop it with funcie disconnect.\n", logPath)
+
+	return nil
+}
+
+// waitForStatus waits for a detached funcie connect to report that every forward has started a session, returning an
+// error if it exits first. Returns the last status reported if they did not all start in time, or nil if none was.
+func (c *ConnectCommand) waitForStatus(ctx context.Context, socketPath string, exited <-chan error) (*ConnectStatus, error) {
+	deadline := time.After(connectStartupTimeout)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	var last *ConnectStatus
+	for {
+		select {
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exit status 0")
+			}
+			return nil, err
+		case <-deadline:
+			return last, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			status, err := readConnectStatus(ctx, socketPath)
+			if err != nil {
+				continue
+			}
+			last = &status
+			if allForwardsConnected(last) {
+				return last, nil
+			}
+		}
+	}
+}
+
+// allForwardsConnected returns whether every forward in the status has a session open.
+func allForwardsConnected(status *ConnectStatus) bool {
+	for _, forward := range status.Forwards {
+		if forward.SessionId == "" {
+			return false
 		}
+	}
+	return true
+}
 
-		if err := c.startTunnel(ctx); err != nil {
-			return fmt.Errorf("failed to start tunnel: %w", err)
+// withoutDetachFlag returns the arguments without the flag asking funcie connect to run in the background.
+func withoutDetachFlag(args []string) []string {
+	res := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "--detach" || arg == "-d" || strings.HasPrefix(arg, "--detach=") {
+			continue
 		}
+		res = append(res, arg)
 	}
 
-	return nil
+	return res
 }
 
-func (c *ConnectCommand) startTunnel(ctx context.Context) error {
+// mapHostnames maps the hostnames to localhost in the hosts file, returning a function that removes them again.
+// Any entries left for the environment by a previous funcie connect are replaced.
+func (c *ConnectCommand) mapHostnames(hostnames []string) (func(), error) {
+	env := c.cliConfig.Environment
+	path := c.hostsFilePath()
+
+	if len(hostnames) == 0 {
+		_, _ = fmt.Fprintln(c.output, "None of the forwards are to hostnames, so the hosts file is left as is.")
+		return func() {}, nil
+	}
+
+	if err := updateHostsFile(path, env, hostnames); err != nil {
+		return nil, err
+	}
+	_, _ = fmt.Fprintf(c.output, "Mapped %v to %v in %v until disconnected.\n", strings.Join(hostnames, ", "), hostsLoopbackAddress, path)
+
+	return func() {
+		if err := updateHostsFile(path, env, nil); err != nil {
+			log.Printf("Failed to remove the funcie entries from the hosts file: %v\n", err)
+		}
+	}, nil
+}
+
+// removeStaleHostnames removes the entries left in the hosts file for the environment by a funcie connect --hosts that
+// did not stop cleanly, as they would otherwise send the hostnames to tunnels that are no longer listening.
+func (c *ConnectCommand) removeStaleHostnames() {
+	env := c.cliConfig.Environment
+	path := c.hostsFilePath()
+
+	if !hasHostsBlock(path, env) {
+		return
+	}
+
+	if err := updateHostsFile(path, env, nil); err != nil {
+		log.Printf("The hosts file still maps hostnames to localhost for a previous funcie connect --hosts, "+
+			"but they could not be removed: %v\n", err)
+		return
+	}
+	_, _ = fmt.Fprintf(c.output, "Removed the hostnames left in %v by a previous funcie connect --hosts.\n", path)
+}
+
+func (c *ConnectCommand) hostsFilePath() string {
+	if path := c.cliConfig.ConnectConfig.HostsFile; path != "" {
+		return path
+	}
+	return defaultHostsFilePath()
+}
+
+// forwards returns the forwards from the forwards file, or the single forward given as flags if there is none.
+func (c *ConnectCommand) forwards() ([]Forward, error) {
 	conf := c.cliConfig.ConnectConfig
 
+	path := conf.ConfigFile
+	if path == "" && conf.RemoteHost == "" {
+		path = defaultForwardsPath(c.cliConfig.Environment)
+	}
+	if path != "" {
+		return loadForwardsFile(path)
+	}
+
+	if conf.RemoteHost != "" {
+		return []Forward{{
+			Name:       conf.RemoteHost,
+			RemoteHost: conf.RemoteHost,
+			RemotePort: conf.RemotePort,
+			LocalPort:  conf.LocalPort,
+		}}, nil
+	}
+
+	return []Forward{{
+		Name:                "redis",
+		RemoteHostParameter: knownForwards["redis"].RemoteHostParameter,
+		RemotePort:          conf.RemotePort,
+		LocalPort:           conf.LocalPort,
+	}}, nil
+}
+
+func (c *ConnectCommand) printForwards(forwards []ForwardStatus) {
+	writer := tabwriter.NewWriter(c.output, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "NAME\tLOCAL\tREMOTE")
+	for _, forward := range forwards {
+		_, _ = fmt.Fprintf(writer, "%v\tlocalhost:%v\t%v:%v\n", forward.Name, forward.LocalPort, forward.RemoteHost, forward.RemotePort)
+	}
+	_ = writer.Flush()
+}
+
+// runForward keeps a tunnel open for the forward, restarting it whenever it stops, until the context is done.
+// Returns an error if the forward fails in a way that restarting it would not fix, such as access being denied.
+func (c *ConnectCommand) runForward(ctx context.Context, forward Forward, status *connectStatusTracker, waitForConnectivity func() error) error {
+	restartDelay := c.cliConfig.ConnectConfig.RestartDelay
+
+	// downSince is when the forward last stopped, if it has not yet been reconnected.
+	var downSince time.Time
+	started := func(sessionId string, stop func()) {
+		status.SessionStarted(forward.Name, sessionId, stop)
+		if !downSince.IsZero() {
+			log.Printf("Forward %v reconnected after being down for %v.\n", forward.Name, time.Since(downSince).Round(time.Second))
+			downSince = time.Time{}
+		}
+	}
+
+	for ctx.Err() == nil {
+		err := waitForConnectivity()
+		if err != nil {
+			// Only errors that waiting longer would not fix are returned.
+			err = permanentForwardError{fmt.Errorf("failed to wait for connectivity: %w", err)}
+		} else {
+			err = c.startTunnel(ctx, forward, started)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		status.SessionStopped(forward.Name, err)
+
+		var permanent permanentForwardError
+		if errors.As(err, &permanent) {
+			return fmt.Errorf("forward %v failed: %w", forward.Name, err)
+		}
+		if downSince.IsZero() {
+			downSince = time.Now()
+		}
+		if err != nil {
+			log.Printf("Forward %v stopped: %v; restarting in %v.\n", forward.Name, err, restartDelay)
+		} else {
+			log.Printf("Forward %v closed; restarting in %v.\n", forward.Name, restartDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(restartDelay):
+		}
+	}
+
+	return nil
+}
+
+// startTunnel opens a tunnel for the forward until it stops, calling started once its session has been started with
+// a function that stops the tunnel.
+func (c *ConnectCommand) startTunnel(ctx context.Context, forward Forward, started func(sessionId string, stop func())) error {
 	instanceId, err := c.configStore.GetConfigValue(ctx, "bastion_instance_id")
 	if err != nil {
-		return fmt.Errorf("failed to get instance ID: %w", err)
+		return classifyForwardError(fmt.Errorf("failed to get instance ID: %w", err))
 	}
 
 	sess, err := c.connectClient.StartSession(ctx, &ssm.StartSessionInput{
 		Target:       aws.String(instanceId),
 		DocumentName: aws.String("AWS-StartPortForwardingSessionToRemoteHost"),
 		Parameters: map[string][]string{
-			"portNumber":      {fmt.Sprintf("%v", conf.RemotePort)},
-			"localPortNumber": {fmt.Sprintf("%v", conf.LocalPort)},
-			"host":            {conf.RemoteHost},
+			"portNumber":      {fmt.Sprintf("%v", forward.RemotePort)},
+			"localPortNumber": {fmt.Sprintf("%v", forward.LocalPort)},
+			"host":            {forward.RemoteHost},
 		},
 		Reason: nil,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to start session: %w", err)
+		return classifyForwardError(fmt.Errorf("failed to start session: %w", err))
+	}
+	// Cancelling the tunnel's context stops it even if terminating its session does not reach the session process.
+	tunnelCtx, stopTunnel := context.WithCancel(ctx)
+	defer stopTunnel()
+	started(aws.ToString(sess.SessionId), stopTunnel)
+
+	if interval := c.cliConfig.ConnectConfig.HealthCheck; interval > 0 && isRedisForward(forward) {
+		go c.probeTunnel(tunnelCtx, forward, aws.ToString(sess.SessionId), interval, stopTunnel)
 	}
 
 	opts := &SsmTunnellerOptions{
 		Output:     sess,
 		InstanceID: instanceId,
 	}
-	err = c.tunneller.OpenTunnel(ctx, opts)
-	if err != nil {
-		return fmt.Errorf("failed to open tunnel: %w", err)
+	tunnelErr := c.tunneller.OpenTunnel(tunnelCtx, opts)
+
+	// The session outlives its tunnel, so terminate it however the tunnel stopped, including when ctx was cancelled.
+	terminateCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sessionTerminateTimeout)
+	defer cancel()
+	if tunnelErr != nil {
+		c.terminateSession(terminateCtx, forward.Name, aws.ToString(sess.SessionId))
+		return fmt.Errorf("failed to open tunnel: %w", tunnelErr)
 	}
 
-	_, err = c.connectClient.TerminateSession(ctx, &ssm.TerminateSessionInput{
+	_, err = c.connectClient.TerminateSession(terminateCtx, &ssm.TerminateSessionInput{
 		SessionId: sess.SessionId,
 	})
 	if err != nil {
@@ -106,3 +449,96 @@ func (c *ConnectCommand) startTunnel(ctx context.Context) error {
 
 	return nil
 }
+
+// probeTunnel PINGs Redis through the tunnel of the forward at each interval until the context is done, terminating
+// its session and stopping the tunnel once enough probes in a row fail so that the forward is restarted.
+func (c *ConnectCommand) probeTunnel(ctx context.Context, forward Forward, sessionId string, interval time.Duration, stop func()) {
+	address := net.JoinHostPort("localhost", strconv.Itoa(forward.LocalPort))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := c.probe(ctx, address)
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		failures++
+		if failures < tunnelProbeFailureThreshold || ctx.Err() != nil {
+			continue
+		}
+
+		log.Printf("Forward %v failed %v health checks in a row (%v); restarting its session.\n", forward.Name, failures, err)
+		c.terminateSession(ctx, forward.Name, sessionId)
+		stop()
+		return
+	}
+}
+
+// restartOnWake terminates the session of every forward and stops its tunnel each time the machine wakes from sleep,
+// until the context is done. Sessions are dropped while asleep, so this restarts their forwards once connectivity is
+// restored.
+func (c *ConnectCommand) restartOnWake(ctx context.Context, status *connectStatusTracker) {
+	wakes := c.connectivityService.Wakes(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case slept, ok := <-wakes:
+			if !ok {
+				return
+			}
+
+			log.Printf("Woke from sleep after %v; restarting the session of every forward.\n", slept.Round(time.Second))
+			for _, forward := range status.Snapshot().Forwards {
+				if forward.SessionId != "" {
+					c.terminateSession(ctx, forward.Name, forward.SessionId)
+					status.StopTunnel(forward.Name)
+				}
+			}
+		}
+	}
+}
+
+// terminateSession terminates the session of the forward, which stops its tunnel so that the forward is restarted.
+func (c *ConnectCommand) terminateSession(ctx context.Context, name string, sessionId string) {
+	_, err := c.connectClient.TerminateSession(ctx, &ssm.TerminateSessionInput{
+		SessionId: aws.String(sessionId),
+	})
+	if err != nil {
+		log.Printf("Failed to terminate session %v of forward %v: %v\n", sessionId, name, err)
+	}
+}
+
+// permanentForwardError is an error that restarting the forward would not fix, which stops funcie connect.
+type permanentForwardError struct {
+	error
+}
+
+func (e permanentForwardError) Unwrap() error {
+	return e.error
+}
+
+// forwardRetryables are the AWS errors that restarting a forward may fix; those that the AWS SDK retries, along with
+// the bastion instance not being connected to SSM, such as while it restarts.
+var forwardRetryables = retry.IsErrorRetryables(append(
+	[]retry.IsErrorRetryable{retry.RetryableErrorCode{Codes: map[string]struct{}{"TargetNotConnected": {}}}},
+	retry.DefaultRetryables...,
+))
+
+// classifyForwardError marks an error from starting a session as permanent unless it is one that restarting may fix,
+// such as connectivity being lost or requests being throttled.
+func classifyForwardError(err error) error {
+	if isTransientNetworkError(err) || forwardRetryables.IsErrorRetryable(err) == aws.TrueTernary {
+		return err
+	}
+	return permanentForwardError{err}
+}
@@ -0,0 +1,614 @@
+package funcli_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	awsMocks "github.com/Kapps/funcie/cmd/funcie/funcli/aws/mocks"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/mocks"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConnectCommand(t *testing.T) {
+	type fixture struct {
+		cliConfig    *funcli.CliConfig
+		configStore  *mocks.ConfigStore
+		ssmClient    *awsMocks.SsmClient
+		tunneller    *mocks.Tunneller
+		connectivity *mocks.ConnectivityService
+		terminated   chan string
+		wakes        chan time.Duration
+		cmd          *funcli.ConnectCommand
+	}
+
+	newFixture := func(t *testing.T, conf *funcli.ConnectConfig) *fixture {
+		t.Setenv("HOME", t.TempDir())
+
+		if conf.RestartDelay == 0 {
+			conf.RestartDelay = time.Millisecond
+		}
+
+		cliConfig := funcli.NewCliConfig("1.2.3")
+		cliConfig.Region = "us-east-1"
+		cliConfig.Environment = internal.DefaultEnvironment
+		cliConfig.ConnectConfig = conf
+
+		f := &fixture{
+			cliConfig:    cliConfig,
+			configStore:  mocks.NewConfigStore(t),
+			ssmClient:    awsMocks.NewSsmClient(t),
+			tunneller:    mocks.NewTunneller(t),
+			connectivity: mocks.NewConnectivityService(t),
+			terminated:   make(chan string, 10),
+			wakes:        make(chan time.Duration),
+		}
+		f.cmd = funcli.NewConnectCommand(cliConfig, f.configStore, f.ssmClient, f.tunneller, f.connectivity)
+
+		f.configStore.EXPECT().GetConfigValue(mock.Anything, "bastion_instance_id").Return("i-123", nil).Maybe()
+		f.connectivity.EXPECT().WaitForConnectivity(mock.Anything, "https://ssm.us-east-1.amazonaws.com").Return(nil).Maybe()
+		f.connectivity.EXPECT().Wakes(mock.Anything).Return(f.wakes).Maybe()
+		f.ssmClient.EXPECT().TerminateSession(mock.Anything, mock.Anything).RunAndReturn(
+			func(ctx context.Context, input *ssm.TerminateSessionInput, _ ...func(*ssm.Options)) (*ssm.TerminateSessionOutput, error) {
+				// Like the SDK, a cancelled context fails the request without reaching SSM.
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				select {
+				case f.terminated <- aws.ToString(input.SessionId):
+				default:
+				}
+				return &ssm.TerminateSessionOutput{}, nil
+			}).Maybe()
+
+		return f
+	}
+
+	writeForwards := func(t *testing.T, contents string) string {
+		path := filepath.Join(t.TempDir(), "forwards.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+		return path
+	}
+
+	// expectSession expects a session forwarding the local port to the remote host and port.
+	expectSession := func(f *fixture, host string, remotePort string, localPort string) {
+		f.ssmClient.EXPECT().StartSession(mock.Anything, &ssm.StartSessionInput{
+			Target:       aws.String("i-123"),
+			DocumentName: aws.String("AWS-StartPortForwardingSessionToRemoteHost"),
+			Parameters: map[string][]string{
+				"portNumber":      {remotePort},
+				"localPortNumber": {localPort},
+				"host":            {host},
+			},
+		}).Return(&ssm.StartSessionOutput{SessionId: aws.String("session-" + localPort)}, nil)
+	}
+
+	t.Run("should open every forward in the file concurrently", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{
+			ConfigFile: writeForwards(t, `
+forwards:
+  - name: redis
+  - name: server-bastion
+    local_port: 18082
+  - name: rds
+    remote_host: db.example.com
+    remote_port: 5432
+    local_port: 5432
+`),
+		})
+		f.configStore.EXPECT().GetConfigValue(mock.Anything, "redis_host").Return("cache.example.com:6379", nil).Once()
+		f.configStore.EXPECT().GetConfigValue(mock.Anything, "bastion_host").Return("10.0.0.1", nil).Once()
+		expectSession(f, "cache.example.com", "6379", "6379")
+		expectSession(f, "10.0.0.1", "8082", "18082")
+		expectSession(f, "db.example.com", "5432", "5432")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var opened sync.WaitGroup
+		opened.Add(3)
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, _ interface{}) error {
+			// Every tunnel stays open until the others have opened too.
+			opened.Done()
+			<-ctx.Done()
+			return ctx.Err()
+		}).Times(3)
+		var status funcli.ConnectStatus
+		var statusErr error
+		go func() {
+			opened.Wait()
+			status, statusErr = readStatus(internal.GetConnectSocketPath(internal.DefaultEnvironment))
+			cancel()
+		}()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.NoError(t, statusErr)
+		require.Equal(t, os.Getpid(), status.Pid)
+		require.Equal(t, []funcli.ForwardStatus{
+			{Name: "redis", LocalPort: 6379, RemoteHost: "cache.example.com", RemotePort: 6379, SessionId: "session-6379"},
+			{Name: "server-bastion", LocalPort: 18082, RemoteHost: "10.0.0.1", RemotePort: 8082, SessionId: "session-18082"},
+			{Name: "rds", LocalPort: 5432, RemoteHost: "db.example.com", RemotePort: 5432, SessionId: "session-5432"},
+		}, status.Forwards)
+		require.NoFileExists(t, internal.GetConnectPidPath(internal.DefaultEnvironment))
+		require.NoFileExists(t, internal.GetConnectSocketPath(internal.DefaultEnvironment))
+	})
+
+	t.Run("should refuse to connect twice to the same environment", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		pidPath := internal.GetConnectPidPath(internal.DefaultEnvironment)
+		require.NoError(t, os.MkdirAll(filepath.Dir(pidPath), 0755))
+		require.NoError(t, os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644))
+
+		require.ErrorContains(t, f.cmd.Run(context.Background()), "already running")
+	})
+
+	t.Run("should restart a Redis session that stops answering PING", func(t *testing.T) {
+		// Stands in for a tunnel whose session died, accepting connections but never replying.
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer func() { _ = listener.Close() }()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.Close()
+			}
+		}()
+		port := listener.Addr().(*net.TCPAddr).Port
+
+		f := newFixture(t, &funcli.ConnectConfig{RemotePort: 6379, LocalPort: port, HealthCheck: time.Millisecond})
+		f.configStore.EXPECT().GetConfigValue(mock.Anything, "redis_host").Return("cache.example.com", nil).Once()
+		expectSession(f, "cache.example.com", "6379", strconv.Itoa(port))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var terminated string
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			// The tunnel stays open until the health check terminates its session.
+			terminated = <-f.terminated
+			return nil
+		}).Once()
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			cancel()
+			return nil
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.Equal(t, "session-"+strconv.Itoa(port), terminated)
+	})
+
+	t.Run("should restart a forward that stops", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		expectSession(f, "db.example.com", "5432", "5432")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).Return(errors.New("connection lost")).Once()
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			cancel()
+			return nil
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+	})
+
+	t.Run("should terminate the session when stopped while the tunnel is open", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		expectSession(f, "db.example.com", "5432", "5432")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, _ interface{}) error {
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.Equal(t, "session-5432", <-f.terminated)
+	})
+
+	t.Run("should restart a forward whose session process exits", func(t *testing.T) {
+		sessions := filepath.Join(t.TempDir(), "sessions")
+		t.Setenv(tunnelSessionsEnv, sessions)
+
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		f.cmd = funcli.NewConnectCommand(f.cliConfig, f.configStore, f.ssmClient, newTunnelSessionTunneller(f.cliConfig), f.connectivity)
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(&ssm.StartSessionOutput{SessionId: aws.String("session-closed")}, nil).Once()
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(&ssm.StartSessionOutput{SessionId: aws.String("session-open")}, nil).Once()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			// Stops once the process of the restarted session is running, which stops it in turn.
+			assert.Eventually(t, func() bool {
+				contents, _ := os.ReadFile(sessions)
+				return strings.Contains(string(contents), "session-open")
+			}, 10*time.Second, 10*time.Millisecond)
+			cancel()
+		}()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.Equal(t, "session-closed", <-f.terminated)
+
+		contents, err := os.ReadFile(sessions)
+		require.NoError(t, err)
+		require.Equal(t, "session-closed\nsession-open\n", string(contents))
+	})
+
+	t.Run("should stop every forward when one fails in a way that restarting would not fix", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{
+			ConfigFile: writeForwards(t, `
+forwards:
+  - name: rds
+    remote_host: db.example.com
+    remote_port: 5432
+    local_port: 5432
+  - name: opensearch
+    remote_host: search.example.com
+    remote_port: 443
+    local_port: 9200
+`),
+		})
+		// The other forward may or may not have started by the time that this one fails.
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.MatchedBy(func(input *ssm.StartSessionInput) bool {
+			return input.Parameters["host"][0] == "db.example.com"
+		})).Return(&ssm.StartSessionOutput{SessionId: aws.String("session-5432")}, nil).Maybe()
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.MatchedBy(func(input *ssm.StartSessionInput) bool {
+			return input.Parameters["host"][0] == "search.example.com"
+		})).Return(nil, errors.New("AccessDeniedException: not authorized to perform ssm:StartSession")).Once()
+
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, _ interface{}) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).Maybe()
+
+		err := f.cmd.Run(context.Background())
+		require.ErrorContains(t, err, "forward opensearch failed: failed to start session: AccessDeniedException")
+		require.NoFileExists(t, internal.GetConnectPidPath(internal.DefaultEnvironment))
+	})
+
+	t.Run("should retry a session while the bastion is not connected to SSM", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(nil, &apiError{code: "TargetNotConnected"}).Once()
+		expectSession(f, "db.example.com", "5432", "5432")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			cancel()
+			return nil
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+	})
+
+	t.Run("should restart every session after waking from sleep", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		expectSession(f, "db.example.com", "5432", "5432")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var terminated string
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			// The session was dropped while asleep, so the tunnel stays open until it is terminated.
+			f.wakes <- time.Hour
+			terminated = <-f.terminated
+			return nil
+		}).Once()
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			cancel()
+			return nil
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.Equal(t, "session-5432", terminated)
+	})
+
+	t.Run("should stop the session process of every forward after waking from sleep", func(t *testing.T) {
+		sessions := filepath.Join(t.TempDir(), "sessions")
+		t.Setenv(tunnelSessionsEnv, sessions)
+
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		f.cmd = funcli.NewConnectCommand(f.cliConfig, f.configStore, f.ssmClient, newTunnelSessionTunneller(f.cliConfig), f.connectivity)
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(&ssm.StartSessionOutput{SessionId: aws.String("session-asleep")}, nil).Once()
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(&ssm.StartSessionOutput{SessionId: aws.String("session-woken")}, nil).Once()
+
+		hasSession := func(sessionId string) func() bool {
+			return func() bool {
+				contents, _ := os.ReadFile(sessions)
+				return strings.Contains(string(contents), sessionId)
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			// Terminating the session is not seen by the process of a session dropped while asleep, so it stays open
+			// until stopped.
+			assert.Eventually(t, hasSession("session-asleep"), 10*time.Second, 10*time.Millisecond)
+			f.wakes <- time.Hour
+			assert.Eventually(t, hasSession("session-woken"), 10*time.Second, 10*time.Millisecond)
+			cancel()
+		}()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.Equal(t, "session-asleep", <-f.terminated)
+	})
+
+	t.Run("should retry a session while the network is unreachable", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(nil, &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ENETUNREACH)}).Once()
+		expectSession(f, "db.example.com", "5432", "5432")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			cancel()
+			return nil
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+	})
+
+	t.Run("should stop on network errors that waiting would not fix", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 5432})
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(nil, &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("sendto", syscall.EPERM)}).Once()
+
+		err := f.cmd.Run(context.Background())
+		require.ErrorContains(t, err, "forward db.example.com failed: failed to start session")
+		require.ErrorIs(t, err, syscall.EPERM)
+	})
+
+	t.Run("should forward Redis by default", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemotePort: 6379, LocalPort: 16379})
+		f.configStore.EXPECT().GetConfigValue(mock.Anything, "redis_host").Return("cache.example.com", nil).Once()
+		expectSession(f, "cache.example.com", "6379", "16379")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			cancel()
+			return nil
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+	})
+
+	t.Run("should use the forwards file of the environment", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{RemotePort: 6379, LocalPort: 6379})
+		require.NoError(t, os.MkdirAll(internal.GetEnvironmentDir(internal.DefaultEnvironment), 0755))
+		require.NoError(t, os.WriteFile(internal.GetForwardsPath(internal.DefaultEnvironment), []byte(`
+forwards:
+  - name: opensearch
+    remote_host: search.example.com
+    remote_port: 443
+    local_port: 9200
+`), 0644))
+		expectSession(f, "search.example.com", "443", "9200")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			cancel()
+			return nil
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+	})
+
+	t.Run("should map hostnames in the hosts file while connected", func(t *testing.T) {
+		hostsFile := filepath.Join(t.TempDir(), "hosts")
+		original := "127.0.0.1\tlocalhost\n# BEGIN funcie default\n127.0.0.1\tstale.example.com\n# END funcie default\n"
+		require.NoError(t, os.WriteFile(hostsFile, []byte(original), 0644))
+
+		f := newFixture(t, &funcli.ConnectConfig{
+			ConfigFile: writeForwards(t, `
+forwards:
+  - name: rds
+    remote_host: db.example.com
+    remote_port: 5432
+    local_port: 15432
+  - name: server-bastion
+    remote_host: 10.0.0.1
+`),
+			Hosts:     true,
+			HostsFile: hostsFile,
+		})
+		expectSession(f, "db.example.com", "5432", "5432")
+		expectSession(f, "10.0.0.1", "8082", "8082")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var opened sync.WaitGroup
+		opened.Add(2)
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, _ interface{}) error {
+			opened.Done()
+			<-ctx.Done()
+			return ctx.Err()
+		}).Times(2)
+		var connected []byte
+		go func() {
+			opened.Wait()
+			connected, _ = os.ReadFile(hostsFile)
+			cancel()
+		}()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.Equal(t, "127.0.0.1\tlocalhost\n# BEGIN funcie default\n127.0.0.1\tdb.example.com\n# END funcie default\n", string(connected))
+
+		contents, err := os.ReadFile(hostsFile)
+		require.NoError(t, err)
+		require.Equal(t, "127.0.0.1\tlocalhost\n", string(contents))
+	})
+
+	t.Run("should restore the hosts file after a session process exits", func(t *testing.T) {
+		sessions := filepath.Join(t.TempDir(), "sessions")
+		t.Setenv(tunnelSessionsEnv, sessions)
+		hostsFile := filepath.Join(t.TempDir(), "hosts")
+		require.NoError(t, os.WriteFile(hostsFile, []byte("127.0.0.1\tlocalhost\n"), 0644))
+
+		f := newFixture(t, &funcli.ConnectConfig{
+			RemoteHost: "db.example.com",
+			RemotePort: 5432,
+			LocalPort:  15432,
+			Hosts:      true,
+			HostsFile:  hostsFile,
+		})
+		f.cmd = funcli.NewConnectCommand(f.cliConfig, f.configStore, f.ssmClient, newTunnelSessionTunneller(f.cliConfig), f.connectivity)
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(&ssm.StartSessionOutput{SessionId: aws.String("session-closed")}, nil).Once()
+		f.ssmClient.EXPECT().StartSession(mock.Anything, mock.Anything).
+			Return(&ssm.StartSessionOutput{SessionId: aws.String("session-open")}, nil).Once()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			assert.Eventually(t, func() bool {
+				contents, _ := os.ReadFile(sessions)
+				return strings.Contains(string(contents), "session-open")
+			}, 10*time.Second, 10*time.Millisecond)
+			cancel()
+		}()
+
+		require.NoError(t, f.cmd.Run(ctx))
+
+		contents, err := os.ReadFile(hostsFile)
+		require.NoError(t, err)
+		require.Equal(t, "127.0.0.1\tlocalhost\n", string(contents))
+	})
+
+	t.Run("should remove hostnames left by a previous connect that did not stop cleanly", func(t *testing.T) {
+		hostsFile := filepath.Join(t.TempDir(), "hosts")
+		stale := "127.0.0.1\tlocalhost\n# BEGIN funcie default\n127.0.0.1\tdb.example.com\n# END funcie default\n" +
+			"# BEGIN funcie staging\n127.0.0.1\tstaging.example.com\n# END funcie staging\n"
+		require.NoError(t, os.WriteFile(hostsFile, []byte(stale), 0644))
+
+		f := newFixture(t, &funcli.ConnectConfig{RemoteHost: "db.example.com", RemotePort: 5432, LocalPort: 15432, HostsFile: hostsFile})
+		expectSession(f, "db.example.com", "5432", "15432")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var connected []byte
+		f.tunneller.EXPECT().OpenTunnel(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, interface{}) error {
+			connected, _ = os.ReadFile(hostsFile)
+			cancel()
+			return nil
+		}).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.Equal(t, "127.0.0.1\tlocalhost\n# BEGIN funcie staging\n127.0.0.1\tstaging.example.com\n# END funcie staging\n", string(connected))
+	})
+
+	t.Run("should reject hostnames that would share a port", func(t *testing.T) {
+		f := newFixture(t, &funcli.ConnectConfig{
+			ConfigFile: writeForwards(t, `
+forwards:
+  - name: primary
+    remote_host: primary.example.com
+    remote_port: 5432
+    local_port: 5432
+  - name: replica
+    remote_host: replica.example.com
+    remote_port: 5432
+    local_port: 5433
+`),
+			Hosts:     true,
+			HostsFile: filepath.Join(t.TempDir(), "hosts"),
+		})
+
+		require.ErrorContains(t, f.cmd.Run(context.Background()), "both use local port 5432")
+	})
+
+	t.Run("should reject invalid forwards", func(t *testing.T) {
+		for contents, expected := range map[string]string{
+			"forwards: []": "no forwards",
+			"forwards:\n  - name: rds\n    local_port: 5432":                                                            "needs a remote_host",
+			"forwards:\n  - name: redis\n  - name: redis":                                                               "more than once",
+			"forwards:\n  - name: redis\n  - name: other\n    remote_host: a\n    remote_port: 1\n    local_port: 6379": "both use local port 6379",
+			"forwards:\n  - name: redis\n    port: 1":                                                                   "field port not found",
+		} {
+			f := newFixture(t, &funcli.ConnectConfig{ConfigFile: writeForwards(t, contents)})
+			require.ErrorContains(t, f.cmd.Run(context.Background()), expected)
+		}
+	})
+}
+
+// tunnelSessionsEnv is the environment variable naming the file that TestTunnelSessionProcess records its sessions in.
+const tunnelSessionsEnv = "FUNCIE_TEST_TUNNEL_SESSIONS"
+
+// newTunnelSessionTunneller returns a tunneller that runs TestTunnelSessionProcess as the process of each session.
+func newTunnelSessionTunneller(cliConfig *funcli.CliConfig) funcli.Tunneller {
+	return funcli.NewSsmTunnellerWithCommand(cliConfig, os.Args[0], "-test.run=^TestTunnelSessionProcess$")
+}
+
+// TestTunnelSessionProcess stands in for the process of a tunnel session when run by newTunnelSessionTunneller.
+// It records the session, then exits as the session manager plugin does once a session named session-closed is
+// closed, or otherwise stays open until stopped.
+func TestTunnelSessionProcess(t *testing.T) {
+	path := os.Getenv(tunnelSessionsEnv)
+	if path == "" {
+		t.Skip("only runs as the process of a tunnel session")
+	}
+
+	var session struct {
+		SessionId string `json:"sessionId"`
+	}
+	require.NoError(t, json.NewDecoder(os.Stdin).Decode(&session))
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(session.SessionId + "\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	if session.SessionId == "session-closed" {
+		os.Exit(0)
+	}
+	time.Sleep(time.Hour)
+}
+
+// apiError is an AWS API error with the given code.
+type apiError struct {
+	code string
+}
+
+func (e *apiError) Error() string {
+	return e.code
+}
+
+func (e *apiError) ErrorCode() string {
+	return e.code
+}
+
+// readStatus reads the status that funcie connect serves on the Unix socket at the given path.
+func readStatus(socketPath string) (funcli.ConnectStatus, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://funcie-connect/status")
+	if err != nil {
+		return funcli.ConnectStatus{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var status funcli.ConnectStatus
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	return status, err
+}
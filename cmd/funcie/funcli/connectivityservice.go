@@ -4,23 +4,42 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"syscall"
 	"time"
 )
 
 // ConnectivityService provides utilities for testing / awaiting internet connectivity.
 type ConnectivityService interface {
 	// WaitForConnectivity waits for the given endpoint to be reachable, or the context to be done.
+	// Errors expected while offline are retried with exponential backoff; others are returned.
 	WaitForConnectivity(ctx context.Context, endpoint string) error
+	// Wakes returns a channel receiving roughly how long the machine was asleep each time that it wakes from sleep,
+	// until the context is done.
+	Wakes(ctx context.Context) <-chan time.Duration
 }
 
 type HttpConnectivityServiceOptions struct {
+	// RetryInterval is how long to wait before the first retry of an unreachable endpoint.
 	RetryInterval time.Duration
+	// MaxRetryInterval is the longest to wait between retries, as the interval doubles after each.
+	MaxRetryInterval time.Duration
+	// RequestTimeout is how long to wait for the endpoint to respond to a single request.
+	RequestTimeout time.Duration
+	// WakeCheckInterval is how often to check whether the machine woke from sleep.
+	WakeCheckInterval time.Duration
+	// WakeThreshold is how much later than expected a check needs to run to be treated as waking from sleep.
+	WakeThreshold time.Duration
+	// Clock returns the current time.
+	Clock func() time.Time
 }
 
 type httpConnectivityService struct {
-	opts HttpConnectivityServiceOptions
+	opts   HttpConnectivityServiceOptions
+	client *http.Client
 }
 
 type HttpConnectivityServiceOptionSetter func(*HttpConnectivityServiceOptions)
@@ -32,10 +51,45 @@ func WithRetryInterval(interval time.Duration) HttpConnectivityServiceOptionSett
 	}
 }
 
+// WithMaxRetryInterval sets the longest interval between retries for the HttpConnectivityService.
+func WithMaxRetryInterval(interval time.Duration) HttpConnectivityServiceOptionSetter {
+	return func(opts *HttpConnectivityServiceOptions) {
+		opts.MaxRetryInterval = interval
+	}
+}
+
+// WithRequestTimeout sets the timeout of each request made by the HttpConnectivityService.
+func WithRequestTimeout(timeout time.Duration) HttpConnectivityServiceOptionSetter {
+	return func(opts *HttpConnectivityServiceOptions) {
+		opts.RequestTimeout = timeout
+	}
+}
+
+// WithWakeDetection sets how often the HttpConnectivityService checks for waking from sleep, and how late a check
+// needs to be to count as waking.
+func WithWakeDetection(interval time.Duration, threshold time.Duration) HttpConnectivityServiceOptionSetter {
+	return func(opts *HttpConnectivityServiceOptions) {
+		opts.WakeCheckInterval = interval
+		opts.WakeThreshold = threshold
+	}
+}
+
+// WithClock sets the source of the current time for the HttpConnectivityService.
+func WithClock(clock func() time.Time) HttpConnectivityServiceOptionSetter {
+	return func(opts *HttpConnectivityServiceOptions) {
+		opts.Clock = clock
+	}
+}
+
 // NewHttpConnectivityService creates a new HttpConnectivityService with optional settings.
 func NewHttpConnectivityService(opts ...HttpConnectivityServiceOptionSetter) ConnectivityService {
 	config := &HttpConnectivityServiceOptions{
-		RetryInterval: 1 * time.Second,
+		RetryInterval:     1 * time.Second,
+		MaxRetryInterval:  30 * time.Second,
+		RequestTimeout:    10 * time.Second,
+		WakeCheckInterval: 5 * time.Second,
+		WakeThreshold:     10 * time.Second,
+		Clock:             time.Now,
 	}
 
 	for _, setter := range opts {
@@ -43,41 +97,125 @@ func NewHttpConnectivityService(opts ...HttpConnectivityServiceOptionSetter) Con
 	}
 
 	return &httpConnectivityService{
-		opts: *config,
+		opts:   *config,
+		client: &http.Client{Timeout: config.RequestTimeout},
 	}
 }
 
 func (s *httpConnectivityService) WaitForConnectivity(ctx context.Context, endpoint string) error {
 	hadOutage := false
+	retryInterval := s.opts.RetryInterval
 	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodOptions, endpoint, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Close = true
+
+		resp, err := s.client.Do(req)
+		if err == nil {
+			if hadOutage {
+				log.Println("Internet connectivity restored")
+			}
+			_ = resp.Body.Close()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !isTransientNetworkError(err) {
+			return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+		}
+
+		if !hadOutage {
+			hadOutage = true
+			log.Printf("Internet connectivity outage detected (%v), waiting for it to be restored...\n", err)
+		}
+
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		default:
-			req, err := http.NewRequestWithContext(ctx, http.MethodOptions, endpoint, nil)
-			if err != nil {
-				return fmt.Errorf("failed to create request: %w", err)
-			}
+		case <-time.After(retryInterval):
+		}
+		retryInterval = min(retryInterval*2, s.opts.MaxRetryInterval)
+	}
+}
+
+func (s *httpConnectivityService) Wakes(ctx context.Context) <-chan time.Duration {
+	wakes := make(chan time.Duration)
 
-			req.Close = true
+	go func() {
+		defer close(wakes)
 
-			resp, err := http.DefaultClient.Do(req)
-			if err == nil {
-				if hadOutage {
-					log.Println("Internet connectivity restored")
-				}
-				_ = resp.Body.Close()
-				return nil
+		ticker := time.NewTicker(s.opts.WakeCheckInterval)
+		defer ticker.Stop()
+
+		last := s.opts.Clock()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
 			}
-			if errors.Is(err, http.ErrServerClosed) || errors.Is(err, http.ErrHandlerTimeout) {
-				if !hadOutage {
-					hadOutage = true
-					log.Println("Internet connectivity outage detected, waiting for it to be restored...")
-				}
-				time.Sleep(s.opts.RetryInterval)
+
+			now := s.opts.Clock()
+			slept := sleptBetween(last, now, s.opts.WakeCheckInterval)
+			last = now
+			if slept < s.opts.WakeThreshold {
 				continue
 			}
-			return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case wakes <- slept:
+			}
 		}
+	}()
+
+	return wakes
+}
+
+// sleptBetween returns how much longer than the expected interval passed between two checks.
+// The monotonic clock stops while asleep on some platforms and keeps running on others, whereas the wall clock always
+// includes the time spent asleep, so the longer of the two is used.
+func sleptBetween(last time.Time, now time.Time, interval time.Duration) time.Duration {
+	elapsed := max(now.Sub(last), now.Round(0).Sub(last.Round(0)))
+	return max(elapsed-interval, 0)
+}
+
+// isTransientNetworkError returns whether the error is one expected while offline, such as after waking from sleep
+// or while changing networks, rather than one that retrying would not fix.
+func isTransientNetworkError(err error) bool {
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, http.ErrHandlerTimeout) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
 	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	// Other socket operations failing, or connections failing for other reasons such as being denied, are not fixed
+	// by waiting.
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return (opErr.Op == "dial" || opErr.Op == "read") && (opErr.Timeout() || isOfflineErrno(opErr.Err))
+	}
+
+	// Includes TLS handshake timeouts and requests exceeding the timeout of the client.
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return isOfflineErrno(err)
+}
+
+// isOfflineErrno returns whether the error is from a connection being refused, reset, or unable to reach its host.
+func isOfflineErrno(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETDOWN)
 }
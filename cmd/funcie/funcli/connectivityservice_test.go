@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"github.com/Kapps/funcie/cmd/funcie/funcli"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -76,3 +78,77 @@ func TestWaitForConnectivity_DelayedServerStart(t *testing.T) {
 	err := service.WaitForConnectivity(ctx, url)
 	require.NoError(t, err)
 }
+
+func TestWaitForConnectivity_DroppedConnections(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	service := funcli.NewHttpConnectivityService(funcli.WithRetryInterval(10 * time.Millisecond))
+
+	// Drops the first few connections without a response, as happens while the network is coming back.
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 3 {
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	err := service.WaitForConnectivity(ctx, server.URL)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, requests.Load())
+}
+
+func TestWaitForConnectivity_DnsFailure(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	service := funcli.NewHttpConnectivityService(funcli.WithRetryInterval(10 * time.Millisecond))
+
+	err := service.WaitForConnectivity(ctx, "https://funcie.invalid")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWaitForConnectivity_PermanentError(t *testing.T) {
+	service := funcli.NewHttpConnectivityService(funcli.WithRetryInterval(10 * time.Millisecond))
+
+	err := service.WaitForConnectivity(context.Background(), "ftp://funcie.invalid")
+	require.ErrorContains(t, err, "unsupported protocol scheme")
+}
+
+func TestWakes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Each check runs a millisecond after the last, except for one that runs after an hour asleep.
+	var lock sync.Mutex
+	now := time.Now().Round(0)
+	checks := 0
+	clock := func() time.Time {
+		lock.Lock()
+		defer lock.Unlock()
+
+		checks++
+		if checks == 3 {
+			now = now.Add(time.Hour)
+		}
+		now = now.Add(time.Millisecond)
+		return now
+	}
+
+	service := funcli.NewHttpConnectivityService(
+		funcli.WithWakeDetection(time.Millisecond, time.Minute),
+		funcli.WithClock(clock),
+	)
+
+	select {
+	case slept := <-service.Wakes(ctx):
+		require.Equal(t, time.Hour, slept)
+	case <-ctx.Done():
+		require.Fail(t, "did not detect waking from sleep")
+	}
+}
@@ -0,0 +1,188 @@
+package funcli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// connectStatusPath is the path that funcie connect serves its status on, over its status socket.
+const connectStatusPath = "/status"
+
+// ForwardStatus is the state of a forward opened by funcie connect.
+type ForwardStatus struct {
+	// Name is the name of the forward.
+	Name string `json:"name"`
+	// LocalPort is the local port that the forward listens on.
+	LocalPort int `json:"localPort"`
+	// RemoteHost is the host that the forward connects to.
+	RemoteHost string `json:"remoteHost"`
+	// RemotePort is the port that the forward connects to.
+	RemotePort int `json:"remotePort"`
+	// SessionId is the ID of the SSM session of the forward, if one is open.
+	SessionId string `json:"sessionId,omitempty"`
+	// Restarts is the number of times that the forward has been restarted.
+	Restarts int `json:"restarts"`
+	// LastError is the reason that the forward last stopped, if it stopped with an error.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// ConnectStatus is the state of a running funcie connect, as reported on its status socket.
+type ConnectStatus struct {
+	// Pid is the process ID of funcie connect.
+	Pid int `json:"pid"`
+	// Forwards are the states of each of the forwards.
+	Forwards []ForwardStatus `json:"forwards"`
+}
+
+// connectStatusTracker records the state of the forwards of funcie connect as their sessions start and stop.
+type connectStatusTracker struct {
+	lock   sync.Mutex
+	status ConnectStatus
+	// stops are the functions that stop the tunnels of the forwards with a session open, by name.
+	stops map[string]func()
+}
+
+func newConnectStatusTracker(forwards []Forward) *connectStatusTracker {
+	status := ConnectStatus{
+		Pid:      os.Getpid(),
+		Forwards: make([]ForwardStatus, 0, len(forwards)),
+	}
+	for _, forward := range forwards {
+		status.Forwards = append(status.Forwards, ForwardStatus{
+			Name:       forward.Name,
+			LocalPort:  forward.LocalPort,
+			RemoteHost: forward.RemoteHost,
+			RemotePort: forward.RemotePort,
+		})
+	}
+
+	return &connectStatusTracker{status: status, stops: make(map[string]func())}
+}
+
+// Snapshot returns a copy of the current state.
+func (t *connectStatusTracker) Snapshot() ConnectStatus {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	res := t.status
+	res.Forwards = append([]ForwardStatus(nil), t.status.Forwards...)
+	return res
+}
+
+// SessionStarted records that the forward opened a session with the given ID, whose tunnel is stopped by stop.
+func (t *connectStatusTracker) SessionStarted(name string, sessionId string, stop func()) {
+	t.update(name, func(forward *ForwardStatus) {
+		forward.SessionId = sessionId
+		t.stops[name] = stop
+	})
+}
+
+// StopTunnel stops the tunnel of the forward if it has a session open, so that the forward is restarted.
+func (t *connectStatusTracker) StopTunnel(name string) {
+	t.lock.Lock()
+	stop := t.stops[name]
+	t.lock.Unlock()
+
+	if stop != nil {
+		stop()
+	}
+}
+
+// SessionStopped records that the session of the forward stopped, and that the forward is being restarted.
+func (t *connectStatusTracker) SessionStopped(name string, err error) {
+	t.update(name, func(forward *ForwardStatus) {
+		forward.SessionId = ""
+		delete(t.stops, name)
+		forward.Restarts++
+		forward.LastError = ""
+		if err != nil {
+			forward.LastError = err.Error()
+		}
+	})
+}
+
+func (t *connectStatusTracker) update(name string, fn func(forward *ForwardStatus)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for i := range t.status.Forwards {
+		if t.status.Forwards[i].Name == name {
+			fn(&t.status.Forwards[i])
+		}
+	}
+}
+
+// serveConnectStatus serves the state of the tracker on a Unix socket at the given path, replacing any left by a
+// previous funcie connect. Returns a function that stops serving and removes the socket.
+func serveConnectStatus(socketPath string, tracker *connectStatusTracker) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create status socket directory: %w", err)
+	}
+	if err := os.Remove(socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale status socket %v: %w", socketPath, err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on status socket %v: %w", socketPath, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(connectStatusPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(tracker.Snapshot())
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: time.Second}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	return func() {
+		_ = server.Close()
+		_ = os.Remove(socketPath)
+	}, nil
+}
+
+// readConnectStatus reads the state of the funcie connect serving its status on the Unix socket at the given path.
+func readConnectStatus(ctx context.Context, socketPath string) (ConnectStatus, error) {
+	client := &http.Client{
+		Timeout: time.Second,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://funcie-connect"+connectStatusPath, nil)
+	if err != nil {
+		return ConnectStatus{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ConnectStatus{}, fmt.Errorf("failed to read status from %v: %w", socketPath, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return ConnectStatus{}, fmt.Errorf("failed to read status from %v: unexpected status %v", socketPath, resp.Status)
+	}
+
+	var status ConnectStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return ConnectStatus{}, fmt.Errorf("failed to parse status from %v: %w", socketPath, err)
+	}
+
+	return status, nil
+}
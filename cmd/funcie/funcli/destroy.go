@@ -6,6 +6,7 @@ import (
 	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
 	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
 	"os"
+	"strings"
 )
 
 type DestroyConfig struct {
@@ -14,33 +15,62 @@ type DestroyConfig struct {
 type DestroyCommand struct {
 	cliConfig       *CliConfig
 	terraformClient tools.TerraformClient
+	containers      BastionContainerManager
 }
 
 func NewDestroyCommand(
 	cliConfig *CliConfig,
 	terraformClient tools.TerraformClient,
+	containers BastionContainerManager,
 ) *DestroyCommand {
 	return &DestroyCommand{
 		cliConfig:       cliConfig,
 		terraformClient: terraformClient,
+		containers:      containers,
 	}
 }
 
 func (c *DestroyCommand) Run(ctx context.Context) error {
-	tfDir := internal.GetTerraformDir()
-	varsFile := internal.GetTerraformVarsPath()
+	env := c.cliConfig.Environment
+	tfDir := internal.GetTerraformDir(env)
+	varsFile := internal.GetTerraformVarsPath(env)
 
 	_, err := os.Stat(varsFile)
 	if os.IsNotExist(err) {
-		return fmt.Errorf("could not find an instance of funcie to destroy")
+		return fmt.Errorf("could not find an instance of funcie to destroy in the %v environment", env)
 	}
 
 	if err := c.terraformClient.Destroy(tfDir, varsFile); err != nil {
 		return fmt.Errorf("failed to destroy funcie instance: %w", err)
 	}
 
-	fmt.Println("The funcie infrastructure has been destroyed.")
-	fmt.Println("Destroying the docker container is not yet implemented. Please run `docker ps` and `docker rm` to remove the container.")
+	// The vars mark the environment as deployed, so remove them once there is nothing left to destroy.
+	if err := os.Remove(varsFile); err != nil {
+		return fmt.Errorf("failed to remove terraform vars: %w", err)
+	}
+	if err := os.Remove(internal.GetDeployedVersionPath(env)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove deployed version: %w", err)
+	}
+
+	fmt.Printf("The funcie infrastructure for the %v environment has been destroyed.\n", env)
+
+	// The client bastion container is shared by every environment, so only remove it along with the last one.
+	remaining, err := deployedEnvironments()
+	if err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		fmt.Printf("The client bastion container was kept, as other environments still use it (%v).\n", strings.Join(remaining, ", "))
+		return nil
+	}
+
+	removed, err := c.containers.Remove()
+	if err != nil {
+		return fmt.Errorf("failed to remove client bastion container: %w", err)
+	}
+	if removed {
+		fmt.Println("The client bastion container has been removed.")
+	}
 
 	return nil
 }
@@ -0,0 +1,64 @@
+package funcli_test
+
+import (
+	"context"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/mocks"
+	toolMocks "github.com/Kapps/funcie/cmd/funcie/funcli/tools/mocks"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestDestroyCommand(t *testing.T) {
+	ctx := context.Background()
+
+	deploy := func(t *testing.T, env string) {
+		require.NoError(t, os.MkdirAll(internal.GetEnvironmentDir(env), 0755))
+		require.NoError(t, os.WriteFile(internal.GetTerraformVarsPath(env), []byte("region = \"us-east-1\"\n"), 0644))
+	}
+
+	newCommand := func(t *testing.T, env string) (*funcli.DestroyCommand, *toolMocks.TerraformClient, *mocks.BastionContainerManager) {
+		cliConfig := funcli.NewCliConfig("1.2.3")
+		cliConfig.Environment = env
+		cliConfig.DestroyConfig = &funcli.DestroyConfig{}
+
+		terraform := toolMocks.NewTerraformClient(t)
+		containers := mocks.NewBastionContainerManager(t)
+		return funcli.NewDestroyCommand(cliConfig, terraform, containers), terraform, containers
+	}
+
+	t.Run("should remove the client bastion container with the last environment", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		deploy(t, "staging")
+
+		cmd, terraform, containers := newCommand(t, "staging")
+		terraform.EXPECT().Destroy(internal.GetTerraformDir("staging"), internal.GetTerraformVarsPath("staging")).Return(nil).Once()
+		containers.EXPECT().Remove().Return(true, nil).Once()
+
+		require.NoError(t, cmd.Run(ctx))
+		require.NoFileExists(t, internal.GetTerraformVarsPath("staging"))
+	})
+
+	t.Run("should keep the client bastion container while other environments are deployed", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		deploy(t, internal.DefaultEnvironment)
+		deploy(t, "staging")
+
+		cmd, terraform, _ := newCommand(t, "staging")
+		terraform.EXPECT().Destroy(internal.GetTerraformDir("staging"), internal.GetTerraformVarsPath("staging")).Return(nil).Once()
+
+		require.NoError(t, cmd.Run(ctx))
+		require.NoFileExists(t, internal.GetTerraformVarsPath("staging"))
+		require.FileExists(t, internal.GetTerraformVarsPath(internal.DefaultEnvironment))
+	})
+
+	t.Run("should fail when the environment is not deployed", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+
+		cmd, _, _ := newCommand(t, "staging")
+
+		require.ErrorContains(t, cmd.Run(ctx), "could not find an instance of funcie to destroy in the staging environment")
+	})
+}
@@ -0,0 +1,167 @@
+package funcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	clientbastion "github.com/Kapps/funcie/cmd/client-bastion/bastion"
+	serverbastion "github.com/Kapps/funcie/cmd/server-bastion/bastion"
+	"github.com/Kapps/funcie/pkg/funcie/runtimeapi"
+	"github.com/Kapps/funcie/pkg/funcie/transports"
+	"github.com/Kapps/funcie/pkg/funcie/transports/memory"
+	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
+	"github.com/Kapps/funcie/pkg/receiver"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type DevConfig struct {
+	ServerBastionAddress string        `arg:"--server-bastion-address" help:"The address that the server bastion listens on for requests from Lambdas." default:"127.0.0.1:8082"`
+	ClientBastionAddress string        `arg:"--client-bastion-address" help:"The address that the client bastion listens on for local applications." default:"127.0.0.1:24193"`
+	RuntimeApiAddress    string        `arg:"--runtime-api-address" help:"The address of the emulated Lambda Runtime API and Invoke API." default:"127.0.0.1:9001"`
+	FunctionName         string        `arg:"--function-name" help:"The name of the emulated Lambda function." default:"function"`
+	Timeout              time.Duration `arg:"--timeout" help:"The timeout of the emulated Lambda function." default:"5m"`
+}
+
+// DevCommand runs the whole funcie pipeline locally, with no AWS account or Redis required.
+// The server and client bastions communicate through an in-memory transport, while an emulated Lambda Runtime API
+// runs the deployed binary as if it were in a Lambda.
+type DevCommand struct {
+	cliConfig *CliConfig
+	output    io.Writer
+}
+
+// NewDevCommand creates a new DevCommand that writes to stdout.
+func NewDevCommand(cliConfig *CliConfig) *DevCommand {
+	return NewDevCommandWithOutput(cliConfig, os.Stdout)
+}
+
+// NewDevCommandWithOutput creates a new DevCommand that writes to the given output.
+func NewDevCommandWithOutput(cliConfig *CliConfig, output io.Writer) *DevCommand {
+	return &DevCommand{
+		cliConfig: cliConfig,
+		output:    output,
+	}
+}
+
+func (c *DevCommand) Run(ctx context.Context) error {
+	conf := c.cliConfig.DevConfig
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	bus := memory.NewBus()
+	consumer := memory.NewConsumer(bus, utils.NewClientHandlerRouter())
+	if err := consumer.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect consumer: %w", err)
+	}
+
+	fallbackPolicies := receiver.NewMemoryFallbackPolicyStore()
+	shadowReporter, err := clientbastion.NewShadowReporter(clientbastion.NewConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create shadow reporter: %w", err)
+	}
+	defer func() { _ = shadowReporter.Close() }()
+
+	serverHandler := serverbastion.NewRequestHandler(memory.NewPublisher(bus), fallbackPolicies)
+	serverHost := transports.NewHost(conf.ServerBastionAddress, transports.NewMessageProcessor(serverHandler))
+
+	clientConfig := clientbastion.NewConfig()
+	clientConfig.RuntimeApiPollTimeout = 5 * time.Second
+	runtimeEmulator := clientbastion.NewRuntimeEmulator(clientConfig, clientbastion.NewHTTPApplicationClient(&http.Client{}))
+	hostTranslator := clientbastion.NewPassthroughHostTranslator()
+	clientHandler := clientbastion.NewHandler(
+		receiver.NewMemoryApplicationRegistry(),
+		runtimeEmulator,
+		consumer,
+		hostTranslator,
+		fallbackPolicies,
+		shadowReporter,
+	)
+	clientProcessor := transports.NewMessageProcessor(clientHandler)
+	clientHost := transports.NewHost(
+		conf.ClientBastionAddress,
+		clientProcessor,
+		transports.WithHandler("/shadow", clientbastion.NewShadowTailHandler(shadowReporter)),
+		transports.WithHandler(clientbastion.RuntimeApiPath, runtimeEmulator.Handler(clientProcessor, conf.FunctionName)),
+		transports.WithHandler(clientbastion.HostTranslationPath, clientbastion.NewHostTranslationHandler(hostTranslator)),
+	)
+
+	runtime := runtimeapi.NewRuntime(
+		slog.Default(),
+		runtimeapi.WithFunctionName(conf.FunctionName),
+		runtimeapi.WithTimeout(conf.Timeout),
+	)
+	mux := http.NewServeMux()
+	mux.Handle("/2018-06-01/", runtime)
+	mux.Handle(runtimeapi.InvokePathPattern, runtimeapi.NewInvokeHandler(runtime))
+	runtimeServer := &http.Server{
+		Addr:    conf.RuntimeApiAddress,
+		Handler: mux,
+	}
+
+	errs := make(chan error, 4)
+	go func() {
+		errs <- ignoreClosed(serverHost.Listen(ctx), "server bastion")
+	}()
+	go func() {
+		errs <- ignoreClosed(clientHost.Listen(ctx), "client bastion")
+	}()
+	go func() {
+		errs <- ignoreClosed(runtimeServer.ListenAndServe(), "runtime API")
+	}()
+	go func() {
+		if err := consumer.Consume(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			errs <- fmt.Errorf("consumer stopped: %w", err)
+		}
+	}()
+
+	c.printInstructions()
+
+	select {
+	case <-ctx.Done():
+		err = nil
+	case err = <-errs:
+	}
+
+	closeCtx := context.Background()
+	_ = serverHost.Close(closeCtx)
+	_ = clientHost.Close(closeCtx)
+	_ = runtimeServer.Close()
+
+	return err
+}
+
+func (c *DevCommand) printInstructions() {
+	conf := c.cliConfig.DevConfig
+
+	_, _ = fmt.Fprintf(c.output, "funcie is running locally:\n")
+	_, _ = fmt.Fprintf(c.output, "  server bastion: http://%v\n", conf.ServerBastionAddress)
+	_, _ = fmt.Fprintf(c.output, "  client bastion: http://%v\n", conf.ClientBastionAddress)
+	_, _ = fmt.Fprintf(c.output, "  runtime API:    http://%v\n\n", conf.RuntimeApiAddress)
+	_, _ = fmt.Fprintf(c.output, "Run your deployed Lambda binary with:\n")
+	_, _ = fmt.Fprintf(c.output, "  AWS_LAMBDA_RUNTIME_API=%v AWS_LAMBDA_FUNCTION_NAME=%v FUNCIE_SERVER_BASTION_ENDPOINT=http://%v/dispatch\n\n",
+		conf.RuntimeApiAddress, conf.FunctionName, conf.ServerBastionAddress)
+	_, _ = fmt.Fprintf(c.output, "Run your local code with:\n")
+	_, _ = fmt.Fprintf(c.output, "  FUNCIE_CLIENT_BASTION_ENDPOINT=http://%v FUNCIE_SERVER_BASTION_ENDPOINT=http://%v/dispatch\n\n",
+		conf.ClientBastionAddress, conf.ServerBastionAddress)
+	_, _ = fmt.Fprintf(c.output, "Or, for a runtime without the funcie library, run your local code with:\n")
+	_, _ = fmt.Fprintf(c.output, "  AWS_LAMBDA_RUNTIME_API=%v AWS_LAMBDA_FUNCTION_NAME=%v\n\n",
+		conf.ClientBastionAddress, conf.FunctionName)
+	_, _ = fmt.Fprintf(c.output, "Then invoke the function with:\n")
+	_, _ = fmt.Fprintf(c.output, "  curl -d '{}' http://%v%v%v/invocations\n",
+		conf.RuntimeApiAddress, runtimeapi.InvokePathPattern, conf.FunctionName)
+}
+
+// ignoreClosed returns nil if the error is from a server being closed, or otherwise wraps it with the server's name.
+func ignoreClosed(err error, name string) error {
+	if err == nil || errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return fmt.Errorf("%v stopped: %w", name, err)
+}
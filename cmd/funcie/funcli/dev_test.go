@@ -0,0 +1,134 @@
+package funcli_test
+
+import (
+	"context"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/Kapps/funcie/pkg/funcie/runtimeapi"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDevCommand(t *testing.T) {
+	// freeAddress returns a local address that nothing is listening on.
+	freeAddress := func(t *testing.T) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		address := listener.Addr().String()
+		require.NoError(t, listener.Close())
+		return address
+	}
+
+	newConfig := func(t *testing.T) *funcli.CliConfig {
+		cliConfig := funcli.NewCliConfig("test")
+		cliConfig.DevConfig = &funcli.DevConfig{
+			ServerBastionAddress: freeAddress(t),
+			ClientBastionAddress: freeAddress(t),
+			RuntimeApiAddress:    freeAddress(t),
+			FunctionName:         "app",
+			Timeout:              time.Minute,
+		}
+		return cliConfig
+	}
+
+	waitForHealthy := func(t *testing.T, url string) {
+		require.Eventually(t, func() bool {
+			resp, err := http.Get(url)
+			if err != nil {
+				return false
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode == http.StatusOK
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	t.Run("should run the bastions and runtime API until cancelled", func(t *testing.T) {
+		cliConfig := newConfig(t)
+		conf := cliConfig.DevConfig
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		output := &lockedBuffer{}
+		errs := make(chan error, 1)
+		go func() {
+			errs <- funcli.NewDevCommandWithOutput(cliConfig, output).Run(ctx)
+		}()
+
+		waitForHealthy(t, "http://"+conf.ServerBastionAddress+"/health")
+		waitForHealthy(t, "http://"+conf.ClientBastionAddress+"/health")
+		require.Contains(t, output.String(), "AWS_LAMBDA_RUNTIME_API="+conf.RuntimeApiAddress+" AWS_LAMBDA_FUNCTION_NAME=app")
+		require.Contains(t, output.String(), "AWS_LAMBDA_RUNTIME_API="+conf.ClientBastionAddress+" AWS_LAMBDA_FUNCTION_NAME=app\n")
+
+		// Stands in for the deployed binary, echoing the payload of the next invocation.
+		runtimeUrl := "http://" + conf.RuntimeApiAddress + "/2018-06-01/runtime/invocation/"
+		go func() {
+			resp, err := http.Get(runtimeUrl + "next")
+			if err != nil {
+				return
+			}
+			payload, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			requestId := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+			resp, err = http.Post(runtimeUrl+requestId+"/response", "application/json", strings.NewReader(string(payload)))
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		resp, err := http.Post("http://"+conf.RuntimeApiAddress+runtimeapi.InvokePathPattern+"app/invocations",
+			"application/json", strings.NewReader(`{"name":"funcie"}`))
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, `{"name":"funcie"}`, string(body))
+
+		cancel()
+		select {
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "funcie dev did not stop after being cancelled")
+		}
+
+		_, err = http.Get("http://" + conf.ServerBastionAddress + "/health")
+		require.Error(t, err)
+	})
+
+	t.Run("should fail when an address is in use", func(t *testing.T) {
+		cliConfig := newConfig(t)
+
+		listener, err := net.Listen("tcp", cliConfig.DevConfig.RuntimeApiAddress)
+		require.NoError(t, err)
+		defer func() { _ = listener.Close() }()
+
+		err = funcli.NewDevCommandWithOutput(cliConfig, io.Discard).Run(context.Background())
+		require.ErrorContains(t, err, "runtime API stopped")
+	})
+}
+
+// lockedBuffer is a buffer that may be written to while being read from another goroutine.
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,77 @@
+package funcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	aws2 "github.com/Kapps/funcie/cmd/funcie/funcli/aws"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// connectStopTimeout is how long to wait for funcie connect to exit after being asked to stop.
+const connectStopTimeout = 10 * time.Second
+
+type DisconnectConfig struct{}
+
+// DisconnectCommand stops the funcie connect running for the environment and terminates its SSM sessions.
+type DisconnectCommand struct {
+	cliConfig *CliConfig
+	ssmClient aws2.SsmClient
+	output    io.Writer
+}
+
+func NewDisconnectCommand(cliConfig *CliConfig, ssmClient aws2.SsmClient) *DisconnectCommand {
+	return &DisconnectCommand{
+		cliConfig: cliConfig,
+		ssmClient: ssmClient,
+		output:    os.Stdout,
+	}
+}
+
+func (c *DisconnectCommand) Run(ctx context.Context) error {
+	env := c.cliConfig.Environment
+	pidPath := internal.GetConnectPidPath(env)
+
+	pid, running := readRunningPid(pidPath)
+	if !running {
+		_ = os.Remove(pidPath)
+		return fmt.Errorf("funcie connect is not running for the %v environment", env)
+	}
+
+	// Read the sessions before stopping, as the status socket goes away with the process.
+	status, err := readConnectStatus(ctx, internal.GetConnectSocketPath(env))
+	if err != nil {
+		log.Printf("Failed to read the sessions of funcie connect, so they will be left to time out: %v\n", err)
+	}
+
+	if err := stopProcess(pid, connectStopTimeout); err != nil {
+		return fmt.Errorf("failed to stop funcie connect: %w", err)
+	}
+	_ = os.Remove(pidPath)
+
+	var errs []error
+	terminated := 0
+	for _, forward := range status.Forwards {
+		if forward.SessionId == "" {
+			continue
+		}
+
+		_, err := c.ssmClient.TerminateSession(ctx, &ssm.TerminateSessionInput{
+			SessionId: aws.String(forward.SessionId),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to terminate session %v of forward %v: %w", forward.SessionId, forward.Name, err))
+			continue
+		}
+		terminated++
+	}
+
+	_, _ = fmt.Fprintf(c.output, "Disconnected from the %v environment, stopping pid %v and terminating %v sessions.\n", env, pid, terminated)
+	return errors.Join(errs...)
+}
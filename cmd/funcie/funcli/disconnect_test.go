@@ -0,0 +1,95 @@
+package funcli_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	awsMocks "github.com/Kapps/funcie/cmd/funcie/funcli/aws/mocks"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+)
+
+func TestDisconnectCommand(t *testing.T) {
+	ctx := context.Background()
+
+	newCommand := func(t *testing.T) (*funcli.DisconnectCommand, *awsMocks.SsmClient) {
+		t.Setenv("HOME", t.TempDir())
+		require.NoError(t, os.MkdirAll(internal.GetEnvironmentDir(internal.DefaultEnvironment), 0755))
+
+		cliConfig := funcli.NewCliConfig("1.2.3")
+		cliConfig.Environment = internal.DefaultEnvironment
+		cliConfig.DisconnectConfig = &funcli.DisconnectConfig{}
+
+		ssmClient := awsMocks.NewSsmClient(t)
+		return funcli.NewDisconnectCommand(cliConfig, ssmClient), ssmClient
+	}
+
+	t.Run("should fail when not connected", func(t *testing.T) {
+		cmd, _ := newCommand(t)
+		pidPath := internal.GetConnectPidPath(internal.DefaultEnvironment)
+		require.NoError(t, os.WriteFile(pidPath, []byte("not-a-pid\n"), 0644))
+
+		require.ErrorContains(t, cmd.Run(ctx), "not running for the default environment")
+		require.NoFileExists(t, pidPath)
+	})
+
+	t.Run("should stop the process and terminate its sessions", func(t *testing.T) {
+		cmd, ssmClient := newCommand(t)
+
+		// Stands in for funcie connect running in the background.
+		proc := exec.Command("sleep", "60")
+		require.NoError(t, proc.Start())
+		exited := make(chan struct{})
+		go func() {
+			_ = proc.Wait()
+			close(exited)
+		}()
+		pidPath := internal.GetConnectPidPath(internal.DefaultEnvironment)
+		require.NoError(t, os.WriteFile(pidPath, []byte(strconv.Itoa(proc.Process.Pid)), 0644))
+
+		serveStatus(t, internal.GetConnectSocketPath(internal.DefaultEnvironment), funcli.ConnectStatus{
+			Pid: proc.Process.Pid,
+			Forwards: []funcli.ForwardStatus{
+				{Name: "redis", SessionId: "session-1"},
+				{Name: "rds", Restarts: 1, LastError: "connection lost"},
+				{Name: "opensearch", SessionId: "session-2"},
+			},
+		})
+
+		ssmClient.EXPECT().TerminateSession(mock.Anything, &ssm.TerminateSessionInput{SessionId: aws.String("session-1")}).
+			Return(&ssm.TerminateSessionOutput{}, nil).Once()
+		ssmClient.EXPECT().TerminateSession(mock.Anything, &ssm.TerminateSessionInput{SessionId: aws.String("session-2")}).
+			Return(&ssm.TerminateSessionOutput{}, nil).Once()
+
+		require.NoError(t, cmd.Run(ctx))
+
+		<-exited
+		require.NoFileExists(t, pidPath)
+	})
+}
+
+// serveStatus serves the status on a Unix socket at the given path until the test ends.
+func serveStatus(t *testing.T, socketPath string, status funcli.ConnectStatus) {
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(status)
+	})}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		_ = server.Close()
+		_ = os.Remove(socketPath)
+	})
+}
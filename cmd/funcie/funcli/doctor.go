@@ -0,0 +1,386 @@
+package funcli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	clientbastion "github.com/Kapps/funcie/cmd/client-bastion/bastion"
+	funcAws "github.com/Kapps/funcie/cmd/funcie/funcli/aws"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	"github.com/fatih/color"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// minTerraformVersion is the oldest version of Terraform that the funcie module and plan output are known to work with.
+const minTerraformVersion = "1.0.0"
+
+// doctorTimeout is how long to wait for each network check.
+const doctorTimeout = 5 * time.Second
+
+// requiredActions are the IAM actions that the CLI and the funcie Terraform module need.
+var requiredActions = []string{
+	"ec2:DescribeVpcs",
+	"ec2:DescribeSubnets",
+	"ec2:DescribeRouteTables",
+	"ec2:RunInstances",
+	"ec2:CreateSecurityGroup",
+	"elasticache:DescribeCacheClusters",
+	"elasticache:CreateCacheCluster",
+	"iam:CreateRole",
+	"iam:PassRole",
+	"ssm:GetParameter",
+	"ssm:PutParameter",
+	"ssm:StartSession",
+	"ssm:TerminateSession",
+}
+
+// deployedParameters are the SSM parameters, under /funcie/<env>/, that a deployment of funcie creates.
+var deployedParameters = []string{"bastion_host", "bastion_instance_id", "redis_host"}
+
+type DoctorConfig struct {
+	RedisPort      int    `arg:"--redis-port" help:"The local port that funcie connect forwards Redis to." default:"6379"`
+	BastionAddress string `arg:"--bastion-address" help:"The address of the client bastion." default:"127.0.0.1:24193"`
+}
+
+// DoctorStatus is the outcome of a diagnostic check.
+type DoctorStatus string
+
+const (
+	// DoctorPass indicates that the check found no problems.
+	DoctorPass DoctorStatus = "pass"
+	// DoctorWarn indicates a problem that only affects some uses of funcie, or that could not be checked.
+	DoctorWarn DoctorStatus = "warn"
+	// DoctorFail indicates a problem that prevents funcie from working.
+	DoctorFail DoctorStatus = "fail"
+)
+
+// DoctorResult is the result of a single diagnostic check.
+type DoctorResult struct {
+	// Name is the name of what was checked.
+	Name string
+	// Status is the outcome of the check.
+	Status DoctorStatus
+	// Message describes what was found.
+	Message string
+	// Fix suggests how to resolve the problem, if the check did not pass.
+	Fix string
+}
+
+// DoctorCommand checks the prerequisites and configuration that funcie relies on, suggesting fixes for any problems.
+type DoctorCommand struct {
+	cliConfig   *CliConfig
+	runner      tools.ProcessRunner
+	configStore ConfigStore
+	permissions funcAws.PermissionChecker
+	httpClient  *http.Client
+	output      io.Writer
+}
+
+func NewDoctorCommand(
+	cliConfig *CliConfig,
+	runner tools.ProcessRunner,
+	configStore ConfigStore,
+	permissions funcAws.PermissionChecker,
+) *DoctorCommand {
+	return &DoctorCommand{
+		cliConfig:   cliConfig,
+		runner:      runner,
+		configStore: configStore,
+		permissions: permissions,
+		httpClient:  &http.Client{Timeout: doctorTimeout},
+		output:      os.Stdout,
+	}
+}
+
+func (c *DoctorCommand) Run(ctx context.Context) error {
+	results := c.Check(ctx)
+
+	failed := 0
+	for _, result := range results {
+		status := color.GreenString("pass")
+		switch result.Status {
+		case DoctorWarn:
+			status = color.YellowString("warn")
+		case DoctorFail:
+			status = color.RedString("fail")
+			failed++
+		}
+
+		_, _ = fmt.Fprintf(c.output, "[%v] %v: %v\n", status, result.Name, result.Message)
+		if result.Status != DoctorPass && result.Fix != "" {
+			_, _ = fmt.Fprintf(c.output, "       %v\n", result.Fix)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v checks failed", failed, len(results))
+	}
+
+	return nil
+}
+
+// Check runs each diagnostic check in turn, returning their results.
+func (c *DoctorCommand) Check(ctx context.Context) []DoctorResult {
+	results := []DoctorResult{
+		c.checkTerraform(),
+		c.checkGit(),
+		c.checkDocker(),
+		c.checkModuleRepo(),
+	}
+
+	credentials, identity := c.checkCredentials(ctx)
+	results = append(results, credentials)
+	if credentials.Status == DoctorPass {
+		results = append(results, c.checkPermissions(ctx, identity), c.checkParameters(ctx))
+	} else {
+		skipped := "Skipped, as the AWS credentials could not be checked."
+		results = append(results,
+			DoctorResult{Name: "IAM permissions", Status: DoctorWarn, Message: skipped},
+			DoctorResult{Name: "SSM parameters", Status: DoctorWarn, Message: skipped},
+		)
+	}
+
+	results = append(results, c.checkRedisPort())
+
+	bastion := c.checkBastionHealth(ctx)
+	results = append(results, bastion)
+	if bastion.Status == DoctorPass {
+		results = append(results, c.checkHostTranslation(ctx))
+	} else {
+		results = append(results, DoctorResult{
+			Name:    "Host translation",
+			Status:  DoctorWarn,
+			Message: "Skipped, as the client bastion is not reachable.",
+		})
+	}
+
+	return results
+}
+
+func (c *DoctorCommand) checkTerraform() DoctorResult {
+	result := DoctorResult{Name: "Terraform"}
+
+	out, err := c.runner.RunWithOptions("terraform", tools.RunnerOpts{Args: []string{"version", "-json"}, Quiet: true})
+	if err != nil {
+		return failed(result, fmt.Sprintf("Terraform is not available: %v", err),
+			"Install Terraform from https://developer.hashicorp.com/terraform/install.")
+	}
+
+	var version struct {
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal([]byte(out), &version); err != nil {
+		return warned(result, fmt.Sprintf("Could not determine the Terraform version: %v", err), "")
+	}
+
+	cmp, err := internal.CompareVersions(version.TerraformVersion, minTerraformVersion)
+	if err != nil {
+		return warned(result, fmt.Sprintf("Could not determine the Terraform version: %v", err), "")
+	}
+	if cmp < 0 {
+		return warned(result, fmt.Sprintf("Terraform v%v is older than v%v.", version.TerraformVersion, minTerraformVersion),
+			fmt.Sprintf("Upgrade Terraform to v%v or later.", minTerraformVersion))
+	}
+
+	return passed(result, fmt.Sprintf("Terraform v%v.", version.TerraformVersion))
+}
+
+func (c *DoctorCommand) checkGit() DoctorResult {
+	result := DoctorResult{Name: "Git"}
+
+	out, err := c.runner.RunWithOptions("git", tools.RunnerOpts{Args: []string{"--version"}, Quiet: true})
+	if err != nil {
+		return failed(result, fmt.Sprintf("Git is not available: %v", err), "Install Git from https://git-scm.com/downloads.")
+	}
+
+	return passed(result, fmt.Sprintf("%v.", strings.TrimSpace(out)))
+}
+
+func (c *DoctorCommand) checkDocker() DoctorResult {
+	result := DoctorResult{Name: "Docker"}
+
+	out, err := c.runner.RunWithOptions("docker", tools.RunnerOpts{
+		Args:  []string{"version", "--format", "{{.Server.Version}}"},
+		Quiet: true,
+	})
+	if err != nil {
+		// Docker is only needed to run the client bastion in a container.
+		return warned(result, fmt.Sprintf("Docker is not available or not running: %v", err),
+			"Install and start Docker, or run the client bastion natively with funcie bastion --detach.")
+	}
+
+	return passed(result, fmt.Sprintf("Docker v%v.", strings.TrimSpace(out)))
+}
+
+func (c *DoctorCommand) checkModuleRepo() DoctorResult {
+	result := DoctorResult{Name: "Module repository"}
+	tag := "v" + strings.TrimSpace(c.cliConfig.versionString)
+
+	out, err := c.runner.RunWithOptions("git", tools.RunnerOpts{
+		Args: []string{"ls-remote", "--tags", tfModuleRepo, "refs/tags/" + tag},
+		// Fail rather than prompt for a passphrase or to trust the host.
+		Env:   map[string]string{"GIT_SSH_COMMAND": "ssh -o BatchMode=yes -o ConnectTimeout=10"},
+		Quiet: true,
+	})
+	if err != nil {
+		return failed(result, fmt.Sprintf("Could not access %v over SSH: %v", tfModuleRepo, err),
+			"Add an SSH key to your GitHub account (https://github.com/settings/keys) and load it with ssh-add.")
+	}
+	if strings.TrimSpace(out) == "" {
+		return failed(result, fmt.Sprintf("The %v tag was not found in %v.", tag, tfModuleRepo),
+			"Install a released version of the CLI.")
+	}
+
+	return passed(result, fmt.Sprintf("Found %v in %v.", tag, tfModuleRepo))
+}
+
+// checkCredentials checks the AWS region and credentials, returning the ARN of the identity they belong to.
+func (c *DoctorCommand) checkCredentials(ctx context.Context) (DoctorResult, string) {
+	result := DoctorResult{Name: "AWS credentials"}
+
+	if c.cliConfig.Region == "" {
+		return failed(result, "No AWS region is configured.",
+			"Set AWS_REGION, configure a default region with aws configure, or pass --region."), ""
+	}
+
+	identity, err := c.permissions.CallerIdentity(ctx)
+	if err != nil {
+		return failed(result, fmt.Sprintf("The AWS credentials are not valid: %v", err),
+			"Configure credentials with aws configure or aws sso login, or select a profile with AWS_PROFILE."), ""
+	}
+
+	return passed(result, fmt.Sprintf("Authenticated as %v in %v.", identity, c.cliConfig.Region)), identity
+}
+
+func (c *DoctorCommand) checkPermissions(ctx context.Context, identity string) DoctorResult {
+	result := DoctorResult{Name: "IAM permissions"}
+
+	denied, err := c.permissions.DeniedActions(ctx, identity, requiredActions)
+	if err != nil {
+		return warned(result, fmt.Sprintf("Could not simulate the policies of %v: %v", identity, err),
+			"Grant iam:SimulatePrincipalPolicy to check permissions, or check them manually.")
+	}
+	if len(denied) > 0 {
+		return failed(result, fmt.Sprintf("Not allowed: %v.", strings.Join(denied, ", ")),
+			fmt.Sprintf("Grant these actions to %v.", identity))
+	}
+
+	return passed(result, fmt.Sprintf("All %v required actions are allowed.", len(requiredActions)))
+}
+
+func (c *DoctorCommand) checkParameters(ctx context.Context) DoctorResult {
+	env := c.cliConfig.Environment
+	result := DoctorResult{Name: "SSM parameters"}
+
+	var missing []string
+	for _, name := range deployedParameters {
+		if _, err := c.configStore.GetConfigValue(ctx, name); err != nil {
+			missing = append(missing, fmt.Sprintf("/funcie/%v/%v", env, name))
+		}
+	}
+
+	switch {
+	case len(missing) == 0:
+		return passed(result, fmt.Sprintf("Found the parameters under /funcie/%v/.", env))
+	case len(missing) == len(deployedParameters) && !environmentDeployed(env):
+		return warned(result, fmt.Sprintf("The %v environment is not deployed.", env), "Run funcie init to deploy it.")
+	default:
+		return failed(result, fmt.Sprintf("Missing %v.", strings.Join(missing, ", ")),
+			"Run funcie upgrade to redeploy the environment, or funcie init if it was never deployed in this region.")
+	}
+}
+
+func (c *DoctorCommand) checkRedisPort() DoctorResult {
+	result := DoctorResult{Name: "Local Redis port"}
+	address := fmt.Sprintf("127.0.0.1:%v", c.cliConfig.DoctorConfig.RedisPort)
+
+	conn, err := net.DialTimeout("tcp", address, doctorTimeout)
+	if err != nil {
+		return warned(result, fmt.Sprintf("Nothing is listening on %v.", address),
+			"Run funcie connect to forward Redis to this port.")
+	}
+	_ = conn.Close()
+
+	return passed(result, fmt.Sprintf("Listening on %v.", address))
+}
+
+func (c *DoctorCommand) checkBastionHealth(ctx context.Context) DoctorResult {
+	result := DoctorResult{Name: "Client bastion"}
+	healthUrl := fmt.Sprintf("http://%v/health", c.cliConfig.DoctorConfig.BastionAddress)
+
+	resp, err := c.get(ctx, healthUrl)
+	if err != nil {
+		return warned(result, fmt.Sprintf("The client bastion is not reachable: %v", err),
+			"Start it with funcie bastion --detach, or in Docker with funcie bastion upgrade.")
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return failed(result, fmt.Sprintf("The client bastion is unhealthy, responding with %v.", resp.Status),
+			"Check its logs with funcie bastion logs, or docker logs "+BastionContainerName+".")
+	}
+
+	return passed(result, fmt.Sprintf("Healthy at %v.", c.cliConfig.DoctorConfig.BastionAddress))
+}
+
+func (c *DoctorCommand) checkHostTranslation(ctx context.Context) DoctorResult {
+	result := DoctorResult{Name: "Host translation"}
+	translationUrl := fmt.Sprintf("http://%v%v", c.cliConfig.DoctorConfig.BastionAddress, clientbastion.HostTranslationPath)
+
+	resp, err := c.get(ctx, translationUrl)
+	if err != nil {
+		return warned(result, fmt.Sprintf("Could not get the host translation: %v", err), "")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return warned(result, "The client bastion does not report its host translation.",
+			"Run funcie bastion upgrade to update it.")
+	}
+
+	var status clientbastion.HostTranslationStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return warned(result, fmt.Sprintf("Could not read the host translation: %v", err), "")
+	}
+	if status.Error != "" {
+		return failed(result, fmt.Sprintf("The %v strategy could not translate localhost: %v", status.Strategy, status.Error),
+			"Set FUNCIE_HOST_OVERRIDE on the client bastion to an address of this machine that it can reach.")
+	}
+
+	return passed(result, fmt.Sprintf("Using the %v strategy; localhost is reached at %v.", status.Strategy, status.Localhost))
+}
+
+func (c *DoctorCommand) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func passed(result DoctorResult, message string) DoctorResult {
+	result.Status = DoctorPass
+	result.Message = message
+	return result
+}
+
+func warned(result DoctorResult, message string, fix string) DoctorResult {
+	result.Status = DoctorWarn
+	result.Message = message
+	result.Fix = fix
+	return result
+}
+
+func failed(result DoctorResult, message string, fix string) DoctorResult {
+	result.Status = DoctorFail
+	result.Message = message
+	result.Fix = fix
+	return result
+}
@@ -0,0 +1,213 @@
+package funcli_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	clientbastion "github.com/Kapps/funcie/cmd/client-bastion/bastion"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	awsMocks "github.com/Kapps/funcie/cmd/funcie/funcli/aws/mocks"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/mocks"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeRunner returns canned outputs for commands, keyed by the command and its arguments.
+type fakeRunner map[string]string
+
+func (r fakeRunner) Run(cmd string, args ...string) (string, error) {
+	return r.RunWithOptions(cmd, tools.RunnerOpts{Args: args})
+}
+
+func (r fakeRunner) RunWithOptions(cmd string, options tools.RunnerOpts) (string, error) {
+	command := strings.Join(append([]string{cmd}, options.Args...), " ")
+	out, ok := r[command]
+	if !ok {
+		return "", fmt.Errorf("failed to run command %v: not found", cmd)
+	}
+	return out, nil
+}
+
+func TestDoctorCommand(t *testing.T) {
+	ctx := context.Background()
+
+	const moduleRepoCommand = "git ls-remote --tags git@github.com:Kapps/terraform-aws-funcie.git refs/tags/v1.2.3"
+
+	healthyRunner := func() fakeRunner {
+		return fakeRunner{
+			"terraform version -json":                     `{"terraform_version":"1.7.5"}`,
+			"git --version":                               "git version 2.43.0\n",
+			"docker version --format {{.Server.Version}}": "26.1.0\n",
+			moduleRepoCommand:                             "abc123\trefs/tags/v1.2.3\n",
+		}
+	}
+
+	newBastion := func(t *testing.T, status *clientbastion.HostTranslationStatus) string {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {})
+		if status != nil {
+			mux.HandleFunc(clientbastion.HostTranslationPath, func(w http.ResponseWriter, _ *http.Request) {
+				_ = json.NewEncoder(w).Encode(status)
+			})
+		}
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+		return strings.TrimPrefix(server.URL, "http://")
+	}
+
+	newRedis := func(t *testing.T) int {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = listener.Close() })
+		return listener.Addr().(*net.TCPAddr).Port
+	}
+
+	unusedPort := func(t *testing.T) int {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		port := listener.Addr().(*net.TCPAddr).Port
+		require.NoError(t, listener.Close())
+		return port
+	}
+
+	type fixture struct {
+		configStore *mocks.ConfigStore
+		permissions *awsMocks.PermissionChecker
+		cmd         *funcli.DoctorCommand
+	}
+
+	newFixture := func(t *testing.T, runner fakeRunner, conf *funcli.DoctorConfig) *fixture {
+		t.Setenv("HOME", t.TempDir())
+
+		cliConfig := funcli.NewCliConfig("1.2.3\n")
+		cliConfig.Region = "us-east-1"
+		cliConfig.Environment = "default"
+		cliConfig.DoctorConfig = conf
+
+		f := &fixture{
+			configStore: mocks.NewConfigStore(t),
+			permissions: awsMocks.NewPermissionChecker(t),
+		}
+		f.cmd = funcli.NewDoctorCommand(cliConfig, runner, f.configStore, f.permissions)
+		return f
+	}
+
+	statuses := func(results []funcli.DoctorResult) map[string]funcli.DoctorStatus {
+		res := make(map[string]funcli.DoctorStatus)
+		for _, result := range results {
+			res[result.Name] = result.Status
+		}
+		return res
+	}
+
+	find := func(t *testing.T, results []funcli.DoctorResult, name string) funcli.DoctorResult {
+		for _, result := range results {
+			if result.Name == name {
+				return result
+			}
+		}
+		require.Failf(t, "result not found", "no result for %v", name)
+		return funcli.DoctorResult{}
+	}
+
+	t.Run("should pass when everything is set up", func(t *testing.T) {
+		f := newFixture(t, healthyRunner(), &funcli.DoctorConfig{
+			RedisPort:      newRedis(t),
+			BastionAddress: newBastion(t, &clientbastion.HostTranslationStatus{Strategy: "known-hosts", Localhost: "host.docker.internal"}),
+		})
+		f.permissions.EXPECT().CallerIdentity(ctx).Return("arn:aws:iam::123456789012:user/dev", nil).Once()
+		f.permissions.EXPECT().DeniedActions(ctx, "arn:aws:iam::123456789012:user/dev", mock.Anything).Return(nil, nil).Once()
+		f.configStore.EXPECT().GetConfigValue(ctx, mock.Anything).Return("value", nil).Times(3)
+
+		results := f.cmd.Check(ctx)
+		for _, result := range results {
+			require.Equal(t, funcli.DoctorPass, result.Status, "%v: %v", result.Name, result.Message)
+		}
+		require.Contains(t, find(t, results, "Host translation").Message, "host.docker.internal")
+	})
+
+	t.Run("should report problems with fixes", func(t *testing.T) {
+		runner := healthyRunner()
+		delete(runner, "terraform version -json")
+		delete(runner, "docker version --format {{.Server.Version}}")
+		runner[moduleRepoCommand] = ""
+
+		f := newFixture(t, runner, &funcli.DoctorConfig{
+			RedisPort:      unusedPort(t),
+			BastionAddress: newBastion(t, &clientbastion.HostTranslationStatus{Strategy: "override", Error: "boom"}),
+		})
+		f.permissions.EXPECT().CallerIdentity(ctx).Return("arn:aws:iam::123456789012:user/dev", nil).Once()
+		f.permissions.EXPECT().DeniedActions(ctx, mock.Anything, mock.Anything).Return([]string{"ssm:StartSession"}, nil).Once()
+		f.configStore.EXPECT().GetConfigValue(ctx, "bastion_host").Return("10.0.0.1", nil).Once()
+		f.configStore.EXPECT().GetConfigValue(ctx, mock.Anything).Return("", errors.New("not found")).Times(2)
+
+		results := f.cmd.Check(ctx)
+		require.Equal(t, map[string]funcli.DoctorStatus{
+			"Terraform":         funcli.DoctorFail,
+			"Git":               funcli.DoctorPass,
+			"Docker":            funcli.DoctorWarn,
+			"Module repository": funcli.DoctorFail,
+			"AWS credentials":   funcli.DoctorPass,
+			"IAM permissions":   funcli.DoctorFail,
+			"SSM parameters":    funcli.DoctorFail,
+			"Local Redis port":  funcli.DoctorWarn,
+			"Client bastion":    funcli.DoctorPass,
+			"Host translation":  funcli.DoctorFail,
+		}, statuses(results))
+
+		for _, result := range results {
+			if result.Status == funcli.DoctorFail {
+				require.NotEmpty(t, result.Fix, result.Name)
+			}
+		}
+		require.Contains(t, find(t, results, "IAM permissions").Message, "ssm:StartSession")
+		require.Contains(t, find(t, results, "SSM parameters").Message, "/funcie/default/bastion_instance_id")
+	})
+
+	t.Run("should skip checks that depend on failed ones", func(t *testing.T) {
+		f := newFixture(t, healthyRunner(), &funcli.DoctorConfig{
+			RedisPort:      unusedPort(t),
+			BastionAddress: fmt.Sprintf("127.0.0.1:%v", unusedPort(t)),
+		})
+		f.permissions.EXPECT().CallerIdentity(ctx).Return("", errors.New("expired")).Once()
+
+		results := f.cmd.Check(ctx)
+		require.Equal(t, funcli.DoctorFail, find(t, results, "AWS credentials").Status)
+		require.Equal(t, funcli.DoctorWarn, find(t, results, "IAM permissions").Status)
+		require.Equal(t, funcli.DoctorWarn, find(t, results, "SSM parameters").Status)
+		require.Equal(t, funcli.DoctorWarn, find(t, results, "Client bastion").Status)
+		require.Equal(t, funcli.DoctorWarn, find(t, results, "Host translation").Status)
+	})
+
+	t.Run("should fail if any check fails", func(t *testing.T) {
+		f := newFixture(t, healthyRunner(), &funcli.DoctorConfig{
+			RedisPort:      newRedis(t),
+			BastionAddress: newBastion(t, nil),
+		})
+		f.permissions.EXPECT().CallerIdentity(ctx).Return("", errors.New("expired")).Once()
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "1 of 10 checks failed")
+	})
+
+	t.Run("should warn if the environment is not deployed", func(t *testing.T) {
+		f := newFixture(t, healthyRunner(), &funcli.DoctorConfig{
+			RedisPort:      newRedis(t),
+			BastionAddress: newBastion(t, nil),
+		})
+		f.permissions.EXPECT().CallerIdentity(ctx).Return("arn:aws:iam::123456789012:user/dev", nil).Once()
+		f.permissions.EXPECT().DeniedActions(ctx, mock.Anything, mock.Anything).Return(nil, nil).Once()
+		f.configStore.EXPECT().GetConfigValue(ctx, mock.Anything).Return("", errors.New("not found")).Times(3)
+
+		results := f.cmd.Check(ctx)
+		require.Equal(t, funcli.DoctorWarn, find(t, results, "SSM parameters").Status)
+		require.Contains(t, find(t, results, "SSM parameters").Fix, "funcie init")
+		require.Equal(t, funcli.DoctorWarn, find(t, results, "Host translation").Status)
+	})
+}
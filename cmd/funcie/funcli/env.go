@@ -0,0 +1,256 @@
+package funcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// environmentNamePattern restricts environment names to those that are safe to use in paths and SSM parameter names.
+var environmentNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+type EnvConfig struct {
+	List   *EnvListConfig   `arg:"subcommand:list" help:"List the funcie environments, marking the selected one."`
+	Use    *EnvUseConfig    `arg:"subcommand:use" help:"Select the funcie environment used by other commands."`
+	Show   *EnvShowConfig   `arg:"subcommand:show" help:"Show the state of a funcie environment."`
+	Remove *EnvRemoveConfig `arg:"subcommand:remove" help:"Remove the local state of a funcie environment."`
+}
+
+type EnvListConfig struct{}
+
+type EnvUseConfig struct {
+	Name string `arg:"positional,required" help:"The name of the environment to select."`
+}
+
+type EnvShowConfig struct {
+	Name string `arg:"positional" help:"The name of the environment to show; defaults to the selected environment."`
+}
+
+type EnvRemoveConfig struct {
+	Name  string `arg:"positional,required" help:"The name of the environment to remove."`
+	Force bool   `arg:"--force" help:"Remove the environment even if it may still be deployed."`
+}
+
+// EnvCommand manages the named funcie environments, each of which has its own deployment and local state.
+type EnvCommand struct {
+	cliConfig *CliConfig
+	output    io.Writer
+}
+
+// NewEnvCommand creates a new EnvCommand that writes to stdout.
+func NewEnvCommand(cliConfig *CliConfig) *EnvCommand {
+	return NewEnvCommandWithOutput(cliConfig, os.Stdout)
+}
+
+// NewEnvCommandWithOutput creates a new EnvCommand that writes to the given output.
+func NewEnvCommandWithOutput(cliConfig *CliConfig, output io.Writer) *EnvCommand {
+	return &EnvCommand{
+		cliConfig: cliConfig,
+		output:    output,
+	}
+}
+
+func (c *EnvCommand) Run(_ context.Context) error {
+	conf := c.cliConfig.EnvConfig
+
+	switch {
+	case conf.Use != nil:
+		return c.use(conf.Use.Name)
+	case conf.Show != nil:
+		name := conf.Show.Name
+		if name == "" {
+			name = c.cliConfig.Environment
+		}
+		return c.show(name)
+	case conf.Remove != nil:
+		return c.remove(conf.Remove.Name, conf.Remove.Force)
+	default:
+		return c.list()
+	}
+}
+
+func (c *EnvCommand) list() error {
+	envs, err := ListEnvironments()
+	if err != nil {
+		return err
+	}
+
+	current := CurrentEnvironment()
+	if !slices.Contains(envs, current) {
+		envs = append(envs, current)
+		sort.Strings(envs)
+	}
+
+	for _, env := range envs {
+		marker := " "
+		if env == current {
+			marker = "*"
+		}
+
+		status := "not deployed"
+		if environmentDeployed(env) {
+			status = "deployed"
+		}
+
+		_, _ = fmt.Fprintf(c.output, "%v %v (%v)\n", marker, env, status)
+	}
+
+	return nil
+}
+
+func (c *EnvCommand) use(name string) error {
+	if err := SetCurrentEnvironment(name); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(c.output, "Now using the %v environment.\n", name)
+	if !environmentDeployed(name) {
+		_, _ = fmt.Fprintln(c.output, "It has not been deployed yet; run funcie init to deploy it.")
+	}
+
+	return nil
+}
+
+func (c *EnvCommand) show(name string) error {
+	if err := ValidateEnvironmentName(name); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(c.output, "Environment: %v\n", name)
+	_, _ = fmt.Fprintf(c.output, "Selected:    %v\n", name == CurrentEnvironment())
+	_, _ = fmt.Fprintf(c.output, "State:       %v\n", internal.GetEnvironmentDir(name))
+	_, _ = fmt.Fprintf(c.output, "SSM prefix:  /funcie/%v/\n", name)
+
+	vars, err := os.ReadFile(internal.GetTerraformVarsPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		_, _ = fmt.Fprintln(c.output, "Deployed:    false")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read terraform vars: %w", err)
+	}
+
+	_, _ = fmt.Fprintln(c.output, "Deployed:    true")
+	_, _ = fmt.Fprintln(c.output, "Parameters:")
+	for _, line := range strings.Split(strings.TrimSpace(string(vars)), "\n") {
+		_, _ = fmt.Fprintf(c.output, "  %v\n", line)
+	}
+
+	return nil
+}
+
+func (c *EnvCommand) remove(name string, force bool) error {
+	if err := ValidateEnvironmentName(name); err != nil {
+		return err
+	}
+	if name == internal.DefaultEnvironment {
+		return fmt.Errorf("the %v environment cannot be removed", internal.DefaultEnvironment)
+	}
+	if environmentDeployed(name) && !force {
+		return fmt.Errorf("the %v environment may still be deployed; run funcie --env %v destroy first, or pass --force", name, name)
+	}
+
+	if err := os.RemoveAll(internal.GetEnvironmentDir(name)); err != nil {
+		return fmt.Errorf("failed to remove environment %v: %w", name, err)
+	}
+
+	if CurrentEnvironment() == name {
+		if err := SetCurrentEnvironment(internal.DefaultEnvironment); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(c.output, "Removed the %v environment; now using the %v environment.\n", name, internal.DefaultEnvironment)
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(c.output, "Removed the %v environment.\n", name)
+	return nil
+}
+
+// ValidateEnvironmentName returns an error if the name cannot be used for a funcie environment.
+func ValidateEnvironmentName(name string) error {
+	if !environmentNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment name %q; use letters, numbers, dashes, and underscores", name)
+	}
+	return nil
+}
+
+// CurrentEnvironment returns the funcie environment selected with funcie env use, or the default environment.
+func CurrentEnvironment() string {
+	contents, err := os.ReadFile(internal.GetCurrentEnvironmentPath())
+	if err != nil {
+		return internal.DefaultEnvironment
+	}
+
+	name := strings.TrimSpace(string(contents))
+	if ValidateEnvironmentName(name) != nil {
+		return internal.DefaultEnvironment
+	}
+
+	return name
+}
+
+// SetCurrentEnvironment selects the funcie environment used when none is given with --env.
+func SetCurrentEnvironment(name string) error {
+	if err := ValidateEnvironmentName(name); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(internal.GetFuncieBaseDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create funcie directory: %w", err)
+	}
+	if err := os.WriteFile(internal.GetCurrentEnvironmentPath(), []byte(name+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to select environment %v: %w", name, err)
+	}
+
+	return nil
+}
+
+// ListEnvironments returns the names of the funcie environments with local state, in order.
+func ListEnvironments() ([]string, error) {
+	var envs []string
+	if environmentDeployed(internal.DefaultEnvironment) {
+		envs = append(envs, internal.DefaultEnvironment)
+	}
+
+	entries, err := os.ReadDir(internal.GetEnvironmentsDir())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to list environments: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && ValidateEnvironmentName(entry.Name()) == nil {
+			envs = append(envs, entry.Name())
+		}
+	}
+
+	sort.Strings(envs)
+	return envs, nil
+}
+
+// deployedEnvironments returns the names of the funcie environments that are deployed, in order.
+func deployedEnvironments() ([]string, error) {
+	envs, err := ListEnvironments()
+	if err != nil {
+		return nil, err
+	}
+
+	var deployed []string
+	for _, env := range envs {
+		if environmentDeployed(env) {
+			deployed = append(deployed, env)
+		}
+	}
+	return deployed, nil
+}
+
+// environmentDeployed returns whether funcie init has deployed the environment without it being destroyed since.
+func environmentDeployed(name string) bool {
+	_, err := os.Stat(internal.GetTerraformVarsPath(name))
+	return err == nil
+}
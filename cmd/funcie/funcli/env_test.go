@@ -0,0 +1,111 @@
+package funcli_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvCommand(t *testing.T) {
+	ctx := context.Background()
+
+	runEnv := func(t *testing.T, conf *funcli.EnvConfig) (string, error) {
+		cliConfig := funcli.NewCliConfig("test")
+		cliConfig.EnvConfig = conf
+		require.NoError(t, cliConfig.ResolveEnvironment())
+
+		var output bytes.Buffer
+		cmd := funcli.NewEnvCommandWithOutput(cliConfig, &output)
+
+		err := cmd.Run(ctx)
+		return output.String(), err
+	}
+
+	deploy := func(t *testing.T, env string) {
+		require.NoError(t, os.MkdirAll(internal.GetEnvironmentDir(env), 0755))
+		require.NoError(t, os.WriteFile(internal.GetTerraformVarsPath(env), []byte("region = \"us-east-1\"\n"), 0644))
+	}
+
+	t.Run("should default to the default environment", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+
+		require.Equal(t, internal.DefaultEnvironment, funcli.CurrentEnvironment())
+
+		output, err := runEnv(t, &funcli.EnvConfig{})
+		require.NoError(t, err)
+		require.Equal(t, "* default (not deployed)\n", output)
+	})
+
+	t.Run("should keep the default environment at the legacy location", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+
+		require.Equal(t, internal.GetFuncieBaseDir(), internal.GetEnvironmentDir(internal.DefaultEnvironment))
+		require.Equal(t, filepath.Join(internal.GetEnvironmentsDir(), "staging"), internal.GetEnvironmentDir("staging"))
+	})
+
+	t.Run("should select and list environments", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		deploy(t, internal.DefaultEnvironment)
+		deploy(t, "staging")
+
+		output, err := runEnv(t, &funcli.EnvConfig{Use: &funcli.EnvUseConfig{Name: "dev"}})
+		require.NoError(t, err)
+		require.Contains(t, output, "Now using the dev environment.")
+		require.Contains(t, output, "not been deployed")
+		require.Equal(t, "dev", funcli.CurrentEnvironment())
+
+		output, err = runEnv(t, &funcli.EnvConfig{List: &funcli.EnvListConfig{}})
+		require.NoError(t, err)
+		require.Equal(t, "  default (deployed)\n* dev (not deployed)\n  staging (deployed)\n", output)
+	})
+
+	t.Run("should reject invalid environment names", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+
+		_, err := runEnv(t, &funcli.EnvConfig{Use: &funcli.EnvUseConfig{Name: "../prod"}})
+		require.ErrorContains(t, err, "invalid environment name")
+		require.Equal(t, internal.DefaultEnvironment, funcli.CurrentEnvironment())
+	})
+
+	t.Run("should show the selected environment", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		deploy(t, "staging")
+		require.NoError(t, funcli.SetCurrentEnvironment("staging"))
+
+		output, err := runEnv(t, &funcli.EnvConfig{Show: &funcli.EnvShowConfig{}})
+		require.NoError(t, err)
+		require.Contains(t, output, "Environment: staging\n")
+		require.Contains(t, output, "Selected:    true\n")
+		require.Contains(t, output, "SSM prefix:  /funcie/staging/\n")
+		require.Contains(t, output, "Deployed:    true\n")
+		require.Contains(t, output, `  region = "us-east-1"`)
+	})
+
+	t.Run("should only remove a deployed environment when forced", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		deploy(t, "staging")
+		require.NoError(t, funcli.SetCurrentEnvironment("staging"))
+
+		_, err := runEnv(t, &funcli.EnvConfig{Remove: &funcli.EnvRemoveConfig{Name: "staging"}})
+		require.ErrorContains(t, err, "may still be deployed")
+		require.DirExists(t, internal.GetEnvironmentDir("staging"))
+
+		output, err := runEnv(t, &funcli.EnvConfig{Remove: &funcli.EnvRemoveConfig{Name: "staging", Force: true}})
+		require.NoError(t, err)
+		require.Contains(t, output, "now using the default environment")
+		require.NoDirExists(t, internal.GetEnvironmentDir("staging"))
+		require.Equal(t, internal.DefaultEnvironment, funcli.CurrentEnvironment())
+	})
+
+	t.Run("should not remove the default environment", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+
+		_, err := runEnv(t, &funcli.EnvConfig{Remove: &funcli.EnvRemoveConfig{Name: internal.DefaultEnvironment, Force: true}})
+		require.ErrorContains(t, err, "cannot be removed")
+	})
+}
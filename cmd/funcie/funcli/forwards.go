@@ -0,0 +1,144 @@
+package funcli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strings"
+)
+
+// Forward is a remote host and port that funcie connect forwards a local port to, through the server bastion.
+type Forward struct {
+	// Name identifies the forward in output. The names of the known forwards fill in any settings not given.
+	Name string `yaml:"name"`
+	// RemoteHost is the host to forward to, if not read from RemoteHostParameter.
+	RemoteHost string `yaml:"remote_host"`
+	// RemoteHostParameter is the name of the SSM parameter under /funcie/<env>/ holding the host to forward to.
+	RemoteHostParameter string `yaml:"remote_host_parameter"`
+	// RemotePort is the port to forward to.
+	RemotePort int `yaml:"remote_port"`
+	// LocalPort is the local port to forward from.
+	LocalPort int `yaml:"local_port"`
+}
+
+// ForwardsFile is the YAML file listing the forwards for funcie connect.
+type ForwardsFile struct {
+	Forwards []Forward `yaml:"forwards"`
+}
+
+// knownForwards are the forwards to parts of a funcie deployment, which need only be named in a forwards file.
+var knownForwards = map[string]Forward{
+	"redis": {
+		Name:                "redis",
+		RemoteHostParameter: "redis_host",
+		RemotePort:          6379,
+		LocalPort:           6379,
+	},
+	"server-bastion": {
+		Name:                "server-bastion",
+		RemoteHostParameter: "bastion_host",
+		RemotePort:          8082,
+		LocalPort:           8082,
+	},
+}
+
+// loadForwardsFile reads the forwards from the given YAML file, filling in the settings of known forwards and
+// checking that each forward has a remote host and ports, and that no two forwards share a name or local port.
+func loadForwardsFile(path string) ([]Forward, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read forwards %v: %w", path, err)
+	}
+
+	var file ForwardsFile
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse forwards %v: %w", path, err)
+	}
+
+	if len(file.Forwards) == 0 {
+		return nil, fmt.Errorf("no forwards are listed in %v", path)
+	}
+
+	names := make(map[string]bool)
+	localPorts := make(map[int]string)
+	forwards := make([]Forward, 0, len(file.Forwards))
+	for _, forward := range file.Forwards {
+		forward = withKnownForward(forward)
+
+		switch {
+		case forward.Name == "":
+			return nil, fmt.Errorf("a forward in %v has no name", path)
+		case names[forward.Name]:
+			return nil, fmt.Errorf("forward %v is listed more than once in %v", forward.Name, path)
+		case forward.RemoteHost == "" && forward.RemoteHostParameter == "":
+			return nil, fmt.Errorf("forward %v needs a remote_host or remote_host_parameter", forward.Name)
+		case forward.RemotePort <= 0:
+			return nil, fmt.Errorf("forward %v needs a remote_port", forward.Name)
+		case forward.LocalPort <= 0:
+			return nil, fmt.Errorf("forward %v needs a local_port", forward.Name)
+		}
+
+		if other, ok := localPorts[forward.LocalPort]; ok {
+			return nil, fmt.Errorf("forwards %v and %v both use local port %v", other, forward.Name, forward.LocalPort)
+		}
+
+		names[forward.Name] = true
+		localPorts[forward.LocalPort] = forward.Name
+		forwards = append(forwards, forward)
+	}
+
+	return forwards, nil
+}
+
+// withKnownForward fills in the settings not given for a known forward.
+func withKnownForward(forward Forward) Forward {
+	known, ok := knownForwards[forward.Name]
+	if !ok {
+		return forward
+	}
+
+	if forward.RemoteHost == "" && forward.RemoteHostParameter == "" {
+		forward.RemoteHostParameter = known.RemoteHostParameter
+	}
+	if forward.RemotePort == 0 {
+		forward.RemotePort = known.RemotePort
+	}
+	if forward.LocalPort == 0 {
+		forward.LocalPort = known.LocalPort
+	}
+
+	return forward
+}
+
+// resolveForward returns the forward with its remote host read from its SSM parameter, if it has one.
+func resolveForward(ctx context.Context, configStore ConfigStore, forward Forward) (Forward, error) {
+	if forward.RemoteHost != "" {
+		return forward, nil
+	}
+
+	host, err := configStore.GetConfigValue(ctx, forward.RemoteHostParameter)
+	if err != nil {
+		return Forward{}, fmt.Errorf("failed to get remote host of forward %v: %w", forward.Name, err)
+	}
+
+	// Parameters such as redis_host may include the port of the endpoint.
+	forward.RemoteHost = strings.Split(host, ":")[0]
+	return forward, nil
+}
+
+// defaultForwardsPath returns the forwards file that funcie connect uses, if it exists, when no other is given.
+func defaultForwardsPath(env string) string {
+	path := internal.GetForwardsPath(env)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+
+	return path
+}
@@ -0,0 +1,116 @@
+package funcli
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// hostsLoopbackAddress is the address that the hostnames of forwards are mapped to, which the tunnels listen on.
+const hostsLoopbackAddress = "127.0.0.1"
+
+// defaultHostsFilePath returns the path of the hosts file of the operating system.
+func defaultHostsFilePath() string {
+	if runtime.GOOS == "windows" {
+		return `C:\Windows\System32\drivers\etc\hosts`
+	}
+	return "/etc/hosts"
+}
+
+// hostsBlockMarkers returns the comments that the entries managed for the funcie environment are placed between.
+func hostsBlockMarkers(env string) (string, string) {
+	return fmt.Sprintf("# BEGIN funcie %v", env), fmt.Sprintf("# END funcie %v", env)
+}
+
+// updateHostsFile maps each of the hostnames to the loopback address in the hosts file at the given path, replacing
+// any entries previously managed for the funcie environment. Removes the managed entries if there are no hostnames.
+func updateHostsFile(path string, env string, hostnames []string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read hosts file %v: %w", path, err)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read hosts file %v: %w", path, err)
+	}
+
+	begin, end := hostsBlockMarkers(env)
+	lines := removeHostsBlock(strings.Split(strings.TrimRight(string(contents), "\n"), "\n"), begin, end)
+	if len(hostnames) > 0 {
+		lines = append(lines, begin)
+		for _, hostname := range hostnames {
+			lines = append(lines, fmt.Sprintf("%v\t%v", hostsLoopbackAddress, hostname))
+		}
+		lines = append(lines, end)
+	}
+
+	// Write in place rather than replacing the file, as the hosts file may be a mount, such as in a container.
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write hosts file %v (funcie connect --hosts needs to run as an administrator, such as with sudo -E): %w", path, err)
+	}
+
+	return nil
+}
+
+// hasHostsBlock returns whether the hosts file at the given path contains entries managed for the funcie environment.
+func hasHostsBlock(path string, env string) bool {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	begin, _ := hostsBlockMarkers(env)
+	return slices.ContainsFunc(strings.Split(string(contents), "\n"), func(line string) bool {
+		return strings.TrimSpace(line) == begin
+	})
+}
+
+// removeHostsBlock returns the lines without those between, and including, the given markers.
+func removeHostsBlock(lines []string, begin string, end string) []string {
+	res := make([]string, 0, len(lines))
+	inBlock := false
+	for _, line := range lines {
+		switch {
+		case strings.TrimSpace(line) == begin:
+			inBlock = true
+		case strings.TrimSpace(line) == end:
+			inBlock = false
+		case !inBlock:
+			res = append(res, line)
+		}
+	}
+
+	return res
+}
+
+// mapForwardHosts makes each forward to a hostname listen on its remote port, so that the hostname can be mapped
+// to the loopback address and reached with the same host and port as from within the VPC.
+// Returns the forwards along with the hostnames to map; forwards to IP addresses are left as they are.
+func mapForwardHosts(forwards []Forward) ([]Forward, []string, error) {
+	var hostnames []string
+	localPorts := make(map[int]string)
+	mapped := make([]Forward, 0, len(forwards))
+
+	for _, forward := range forwards {
+		if net.ParseIP(forward.RemoteHost) == nil {
+			forward.LocalPort = forward.RemotePort
+			if !slices.ContainsFunc(hostnames, func(h string) bool { return strings.EqualFold(h, forward.RemoteHost) }) {
+				hostnames = append(hostnames, forward.RemoteHost)
+			}
+		}
+
+		if other, ok := localPorts[forward.LocalPort]; ok {
+			return nil, nil, fmt.Errorf("forwards %v and %v both use local port %v once their hostnames are mapped; "+
+				"connect to them separately without --hosts", other, forward.Name, forward.LocalPort)
+		}
+
+		localPorts[forward.LocalPort] = forward.Name
+		mapped = append(mapped, forward)
+	}
+
+	return mapped, hostnames, nil
+}
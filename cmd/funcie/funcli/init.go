@@ -1,21 +1,46 @@
 package funcli
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Kapps/funcie/cmd/funcie/funcli/aws"
 	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
 	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
 	"github.com/charmbracelet/huh"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log"
 	"os"
-	"runtime"
+	"slices"
 	"strings"
 )
 
 const tfModuleRepo = "git@github.com:Kapps/terraform-aws-funcie.git"
-const dockerClientImage = "public.ecr.aws/w1h1o7p8/funcie-client-bastion"
+
+// initCreateResource is the value of the VPC or Redis cluster options that has funcie provision a new one.
+const initCreateResource = "new"
 
 type InitConfig struct {
+	ConfigFile        string   `arg:"--config,-c" help:"A YAML file providing any of the options below; flags take precedence over the file."`
+	Vpc               string   `arg:"--vpc" help:"The ID of the VPC to deploy to, or 'new' to have funcie provision / reuse a VPC."`
+	PrivateSubnets    []string `arg:"--private-subnets" help:"The IDs of the private subnets to use for resources such as the ElastiCache instance."`
+	PublicSubnets     []string `arg:"--public-subnets" help:"The IDs of the public subnets to use for resources such as the bastion host."`
+	RedisCluster      string   `arg:"--redis-cluster" help:"The name of the ElastiCache cluster to use, or 'new' to have funcie provision / reuse a cluster."`
+	AutoLaunchBastion *bool    `arg:"--auto-launch-bastion" help:"Run the client bastion in Docker, restarting it automatically."`
+	Yes               bool     `arg:"--yes,-y" help:"Do not prompt; use the defaults for any choices not given, and deploy without confirmation."`
+	Plan              bool     `arg:"--plan" help:"Show the Terraform plan and an estimated monthly cost before deploying, then apply exactly that plan."`
+}
+
+// InitFile is the YAML file accepted by funcie init --config, with the same choices as the flags.
+type InitFile struct {
+	Vpc               string   `yaml:"vpc"`
+	PrivateSubnets    []string `yaml:"private_subnets"`
+	PublicSubnets     []string `yaml:"public_subnets"`
+	RedisCluster      string   `yaml:"redis_cluster"`
+	AutoLaunchBastion *bool    `yaml:"auto_launch_bastion"`
+	Yes               bool     `yaml:"yes"`
 }
 
 type InitCommand struct {
@@ -23,7 +48,7 @@ type InitCommand struct {
 	resourceList    aws.ResourceLister
 	gitClient       tools.GitClient
 	terraformClient tools.TerraformClient
-	dockerClient    tools.DockerClient
+	containers      BastionContainerManager
 }
 
 type TerraformVars struct {
@@ -32,6 +57,7 @@ type TerraformVars struct {
 	PublicSubnets  []string `yaml:"public_subnet_ids"`
 	RedisHost      string   `yaml:"redis_host"`
 	Region         string   `yaml:"region"`
+	Environment    string   `yaml:"environment"`
 }
 
 func NewInitCommand(
@@ -39,32 +65,39 @@ func NewInitCommand(
 	resourceList aws.ResourceLister,
 	gitClient tools.GitClient,
 	terraformClient tools.TerraformClient,
-	dockerClient tools.DockerClient,
+	containers BastionContainerManager,
 ) *InitCommand {
 	return &InitCommand{
 		cliConfig:       cliConfig,
 		resourceList:    resourceList,
 		gitClient:       gitClient,
 		terraformClient: terraformClient,
-		dockerClient:    dockerClient,
+		containers:      containers,
 	}
 }
 
 func (c *InitCommand) Run(ctx context.Context) error {
-	vpc, err := c.promptVpc(ctx)
+	conf := c.cliConfig.InitConfig
+	if err := loadInitFile(conf); err != nil {
+		return err
+	}
+
+	vpc, err := c.promptVpc(ctx, conf)
 	if err != nil {
 		return fmt.Errorf("failed to prompt for VPC: %w", err)
 	}
 
 	var privSubnets, pubSubnets []aws.Subnet
 	if vpc.Id != "" {
-		privSubnets, pubSubnets, err = c.promptSubnet(ctx, vpc.Id)
+		privSubnets, pubSubnets, err = c.promptSubnet(ctx, conf, vpc.Id)
 		if err != nil {
 			return fmt.Errorf("failed to prompt for subnet: %w", err)
 		}
+	} else if conf.PrivateSubnets != nil || conf.PublicSubnets != nil {
+		return fmt.Errorf("--private-subnets and --public-subnets can only be used with an existing VPC given by --vpc, not a funcie-managed one")
 	}
 
-	elastiCache, err := c.promptElasticache(ctx)
+	elastiCache, err := c.promptElasticache(ctx, conf)
 	if err != nil {
 		return fmt.Errorf("failed to prompt for ElastiCache cluster: %w", err)
 	}
@@ -74,6 +107,7 @@ func (c *InitCommand) Run(ctx context.Context) error {
 		PrivateSubnets: make([]string, 0, len(privSubnets)),
 		PublicSubnets:  make([]string, 0, len(pubSubnets)),
 		Region:         c.cliConfig.Region,
+		Environment:    c.cliConfig.Environment,
 	}
 
 	if elastiCache != nil {
@@ -89,23 +123,77 @@ func (c *InitCommand) Run(ctx context.Context) error {
 		vars.PublicSubnets = append(vars.PublicSubnets, subnet.Id)
 	}
 
-	if err := c.runTerraform(vars); err != nil {
+	if err := c.runTerraform(conf, vars); err != nil {
 		return fmt.Errorf("failed to write terraform vars: %w", err)
 	}
 
-	if err := c.promptDockerRun(ctx); err != nil {
+	if err := c.promptDockerRun(ctx, conf); err != nil {
 		return fmt.Errorf("failed to configure docker container: %w", err)
 	}
 
 	return nil
 }
 
-func (c *InitCommand) promptVpc(ctx context.Context) (aws.Vpc, error) {
+// loadInitFile fills in any options not given as flags from the YAML file in conf.ConfigFile, if there is one.
+func loadInitFile(conf *InitConfig) error {
+	if conf.ConfigFile == "" {
+		return nil
+	}
+
+	contents, err := os.ReadFile(conf.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read init config %v: %w", conf.ConfigFile, err)
+	}
+
+	var file InitFile
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse init config %v: %w", conf.ConfigFile, err)
+	}
+
+	if conf.Vpc == "" {
+		conf.Vpc = file.Vpc
+	}
+	if conf.PrivateSubnets == nil {
+		conf.PrivateSubnets = file.PrivateSubnets
+	}
+	if conf.PublicSubnets == nil {
+		conf.PublicSubnets = file.PublicSubnets
+	}
+	if conf.RedisCluster == "" {
+		conf.RedisCluster = file.RedisCluster
+	}
+	if conf.AutoLaunchBastion == nil {
+		conf.AutoLaunchBastion = file.AutoLaunchBastion
+	}
+	conf.Yes = conf.Yes || file.Yes
+
+	return nil
+}
+
+func (c *InitCommand) promptVpc(ctx context.Context, conf *InitConfig) (aws.Vpc, error) {
 	vpcs, err := c.resourceList.ListVpcs(ctx)
 	if err != nil {
 		return aws.Vpc{}, fmt.Errorf("failed to list vpcs: %w", err)
 	}
 
+	switch conf.Vpc {
+	case initCreateResource:
+		return aws.Vpc{}, nil
+	case "":
+		if conf.Yes {
+			return aws.Vpc{}, nil
+		}
+	default:
+		for _, vpc := range vpcs {
+			if vpc.Id == conf.Vpc {
+				return vpc, nil
+			}
+		}
+		return aws.Vpc{}, fmt.Errorf("VPC %v was not found in region %v", conf.Vpc, c.cliConfig.Region)
+	}
+
 	var selected aws.Vpc
 	err = huh.NewSelect[aws.Vpc]().
 		Title("Which VPC would you like to use?").
@@ -123,7 +211,21 @@ func (c *InitCommand) promptVpc(ctx context.Context) (aws.Vpc, error) {
 	return selected, nil
 }
 
-func (c *InitCommand) promptSubnet(ctx context.Context, vpcId string) ([]aws.Subnet, []aws.Subnet, error) {
+func (c *InitCommand) promptSubnet(ctx context.Context, conf *InitConfig, vpcId string) ([]aws.Subnet, []aws.Subnet, error) {
+	if conf.Yes {
+		// Guessing which subnets of an existing VPC to deploy to could expose resources, so they must be given.
+		var missing []string
+		if conf.PrivateSubnets == nil {
+			missing = append(missing, "--private-subnets")
+		}
+		if conf.PublicSubnets == nil {
+			missing = append(missing, "--public-subnets")
+		}
+		if len(missing) > 0 {
+			return nil, nil, fmt.Errorf("%v must be given to use the existing VPC %v without prompting", strings.Join(missing, " and "), vpcId)
+		}
+	}
+
 	subnets, err := c.resourceList.ListSubnets(ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list subnets: %w", err)
@@ -146,35 +248,81 @@ func (c *InitCommand) promptSubnet(ctx context.Context, vpcId string) ([]aws.Sub
 		}
 	}
 
-	err = huh.NewMultiSelect[aws.Subnet]().
-		Title("Which private subnets would you like to use?").
-		Options(huh.NewOptions[aws.Subnet](filtered...)...).
-		Description("This will be used for resources such as the Elasticache instance.").
-		Value(&privSubnets).
-		Run()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to select private subnets: %w", err)
+	if conf.PrivateSubnets != nil {
+		privSubnets, err = findSubnets(filtered, conf.PrivateSubnets, vpcId)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if !conf.Yes {
+		err = huh.NewMultiSelect[aws.Subnet]().
+			Title("Which private subnets would you like to use?").
+			Options(huh.NewOptions[aws.Subnet](filtered...)...).
+			Description("This will be used for resources such as the Elasticache instance.").
+			Value(&privSubnets).
+			Run()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to select private subnets: %w", err)
+		}
 	}
 
-	err = huh.NewMultiSelect[aws.Subnet]().
-		Title("Which public subnets would you like to use?").
-		Options(huh.NewOptions[aws.Subnet](filtered...)...).
-		Description("This will be used for resources such as the bastion host.").
-		Value(&pubSubnets).
-		Run()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to select public subnets: %w", err)
+	if conf.PublicSubnets != nil {
+		pubSubnets, err = findSubnets(filtered, conf.PublicSubnets, vpcId)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if !conf.Yes {
+		err = huh.NewMultiSelect[aws.Subnet]().
+			Title("Which public subnets would you like to use?").
+			Options(huh.NewOptions[aws.Subnet](filtered...)...).
+			Description("This will be used for resources such as the bastion host.").
+			Value(&pubSubnets).
+			Run()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to select public subnets: %w", err)
+		}
 	}
 
 	return privSubnets, pubSubnets, nil
 }
 
-func (c *InitCommand) promptElasticache(ctx context.Context) (*aws.ElastiCacheCluster, error) {
+// findSubnets returns the subnets with the given IDs, which must all be in the given VPC.
+func findSubnets(subnets []aws.Subnet, ids []string, vpcId string) ([]aws.Subnet, error) {
+	found := make([]aws.Subnet, 0, len(ids))
+	for _, id := range ids {
+		idx := slices.IndexFunc(subnets, func(subnet aws.Subnet) bool {
+			return subnet.Id == id
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("subnet %v was not found in VPC %v", id, vpcId)
+		}
+		found = append(found, subnets[idx])
+	}
+
+	return found, nil
+}
+
+func (c *InitCommand) promptElasticache(ctx context.Context, conf *InitConfig) (*aws.ElastiCacheCluster, error) {
 	clusters, err := c.resourceList.ListElastiCacheClusters(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list ElastiCache clusters: %w", err)
 	}
 
+	switch conf.RedisCluster {
+	case initCreateResource:
+		return nil, nil
+	case "":
+		if conf.Yes {
+			return nil, nil
+		}
+	default:
+		for _, cluster := range clusters {
+			if cluster.Name == conf.RedisCluster {
+				return &cluster, nil
+			}
+		}
+		return nil, fmt.Errorf("ElastiCache cluster %v was not found in region %v", conf.RedisCluster, c.cliConfig.Region)
+	}
+
 	var selected aws.ElastiCacheCluster
 	err = huh.NewSelect[aws.ElastiCacheCluster]().
 		Title("Which ElastiCache cluster would you like to use?").
@@ -196,61 +344,73 @@ func (c *InitCommand) promptElasticache(ctx context.Context) (*aws.ElastiCacheCl
 	return &selected, nil
 }
 
-func (c *InitCommand) promptDockerRun(_ context.Context) error {
-	var confirmed bool
-	err := huh.NewConfirm().
-		Title("Would you like to configure the client bastion to auto-launch?").
-		Affirmative("Yes, auto-launch the client bastion").
-		Value(&confirmed).
-		Run()
+func (c *InitCommand) promptDockerRun(_ context.Context, conf *InitConfig) error {
+	existing, err := c.containers.Find()
 	if err != nil {
-		return fmt.Errorf("failed to confirm docker run: %w", err)
+		return fmt.Errorf("failed to find existing client bastion container: %w", err)
 	}
-
-	if !confirmed {
+	if existing != nil {
+		fmt.Printf("A client bastion container (%v) already exists with image %v and is %v.\n", existing.Name, existing.Image, existing.State)
+		if existing.Image != c.containers.Image() {
+			fmt.Printf("Run funcie bastion upgrade to switch it to %v.\n", c.containers.Image())
+		}
 		return nil
 	}
 
-	// TODO: Determine appropriate redis host value for Linux/Windows.
-	redisHost := "localhost"
-	if runtime.GOOS == "darwin" {
-		redisHost = "host.docker.internal"
+	var confirmed bool
+	if conf.AutoLaunchBastion != nil {
+		confirmed = *conf.AutoLaunchBastion
+	} else if !conf.Yes {
+		err = huh.NewConfirm().
+			Title("Would you like to configure the client bastion to auto-launch?").
+			Affirmative("Yes, auto-launch the client bastion").
+			Value(&confirmed).
+			Run()
+		if err != nil {
+			return fmt.Errorf("failed to confirm docker run: %w", err)
+		}
 	}
 
-	bastionImageUrl := strings.TrimSpace(fmt.Sprintf("%v:v%v", dockerClientImage, c.cliConfig.versionString))
-	err = c.dockerClient.RunContainer(bastionImageUrl, tools.DockerRunOptions{
-		Env: map[string]string{
-			"FUNCIE_REDIS_ADDRESS":  fmt.Sprintf("%v:%v", redisHost, "6379"),
-			"FUNCIE_LISTEN_ADDRESS": "0.0.0.0:24193",
-		},
-		ExposedPorts:  []int{24193},
-		RestartPolicy: "unless-stopped",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to run client bastion container: %w", err)
+	if !confirmed {
+		return nil
 	}
 
-	return nil
+	return c.containers.Start()
 }
 
-func (c *InitCommand) runTerraform(vars TerraformVars) error {
+func (c *InitCommand) runTerraform(conf *InitConfig, vars TerraformVars) error {
 	varFileContents := marshalVariables(vars)
 
-	baseDir := internal.GetFuncieBaseDir()
-	varFile := internal.GetTerraformVarsPath()
+	env := c.cliConfig.Environment
+	envDir := internal.GetEnvironmentDir(env)
+	varFile := internal.GetTerraformVarsPath(env)
 
-	if err := os.Mkdir(baseDir, 0755); err != nil && !os.IsExist(err) {
+	if err := os.MkdirAll(envDir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory for terraform vars: %w", err)
 	}
 
+	// The vars mark the environment as deployed, so put back whatever was there if init stops before applying.
+	previousVars, err := os.ReadFile(varFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read terraform vars from %s: %w", varFile, err)
+	}
+	applying := false
+	defer func() {
+		if !applying {
+			restoreVars(varFile, previousVars)
+		}
+	}()
+
 	if err := os.WriteFile(varFile, []byte(varFileContents), 0644); err != nil {
 		return fmt.Errorf("failed to write terraform vars to %s: %w", varFile, err)
 	}
 
+	fmt.Printf("Deploying the %v funcie environment.\n", env)
+
 	branch := "v" + strings.TrimSpace(c.cliConfig.versionString)
 	fmt.Printf("Cloning funcie terraform module with tag %v...\n", branch)
 
-	tfModuleDir := internal.GetTerraformDir()
+	tfModuleDir := internal.GetTerraformDir(env)
 	if err := c.gitClient.Checkout(tfModuleRepo, tfModuleDir, branch); err != nil {
 		return fmt.Errorf("failed to clone terraform module: %w", err)
 	}
@@ -264,14 +424,29 @@ func (c *InitCommand) runTerraform(vars TerraformVars) error {
 	fmt.Println("Terraform module initialized; will deploy module with the following parameters:")
 	fmt.Println(varFileContents)
 
-	confirmed := false
-	err := huh.NewConfirm().
-		Title("Would you like to proceed with the deployment? Remember, some resources are not part of the AWS free tier and therefore charges will apply.").
-		Affirmative("I understand, deploy module").
-		Value(&confirmed).
-		Run()
-	if err != nil {
-		return fmt.Errorf("failed to confirm deployment: %w", err)
+	var plan *tools.TerraformPlan
+	if conf.Plan {
+		plan, err = c.terraformClient.Plan(tfModuleDir, varFile, internal.GetTerraformPlanPath(env))
+		if err != nil {
+			return fmt.Errorf("failed to plan terraform configuration: %w", err)
+		}
+		defer func() { _ = os.Remove(plan.Path) }()
+
+		fmt.Println()
+		SummarizePlan(plan).Print(os.Stdout)
+		fmt.Println()
+	}
+
+	confirmed := conf.Yes
+	if !confirmed {
+		err := huh.NewConfirm().
+			Title("Would you like to proceed with the deployment? Remember, some resources are not part of the AWS free tier and therefore charges will apply.").
+			Affirmative("I understand, deploy module").
+			Value(&confirmed).
+			Run()
+		if err != nil {
+			return fmt.Errorf("failed to confirm deployment: %w", err)
+		}
 	}
 
 	if !confirmed {
@@ -279,14 +454,32 @@ func (c *InitCommand) runTerraform(vars TerraformVars) error {
 	}
 
 	fmt.Println("Applying terraform configuration...")
+	applying = true
 
-	if err := c.terraformClient.Apply(tfModuleDir, varFile); err != nil {
+	if plan != nil {
+		if err := c.terraformClient.ApplyPlan(tfModuleDir, plan.Path); err != nil {
+			return fmt.Errorf("failed to apply terraform plan: %w", err)
+		}
+	} else if err := c.terraformClient.Apply(tfModuleDir, varFile); err != nil {
 		return fmt.Errorf("failed to apply terraform configuration: %w", err)
 	}
 
 	fmt.Println("Terraform configuration applied successfully.")
 
-	return nil
+	return writeDeployedVersion(env, c.cliConfig.versionString)
+}
+
+// restoreVars puts back the terraform vars that were in place before init, removing them if there were none.
+func restoreVars(varFile string, previousVars []byte) {
+	var err error
+	if previousVars == nil {
+		err = os.Remove(varFile)
+	} else {
+		err = os.WriteFile(varFile, previousVars, 0644)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to restore the terraform vars in %v: %v\n", varFile, err)
+	}
 }
 
 func marshalVariables(vars TerraformVars) string {
@@ -296,5 +489,6 @@ private_subnet_ids = %s
 public_subnet_ids  = %s
 redis_host         = "%s"
 region             = "%s"
-`, vars.VpcId, internal.MarshalArray(vars.PrivateSubnets), internal.MarshalArray(vars.PublicSubnets), vars.RedisHost, vars.Region)
+environment        = "%s"
+`, vars.VpcId, internal.MarshalArray(vars.PrivateSubnets), internal.MarshalArray(vars.PublicSubnets), vars.RedisHost, vars.Region, vars.Environment)
 }
@@ -0,0 +1,233 @@
+package funcli_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/aws"
+	awsMocks "github.com/Kapps/funcie/cmd/funcie/funcli/aws/mocks"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/mocks"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	toolMocks "github.com/Kapps/funcie/cmd/funcie/funcli/tools/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitCommand_NonInteractive(t *testing.T) {
+	ctx := context.Background()
+
+	type fixture struct {
+		lister     *awsMocks.ResourceLister
+		git        *toolMocks.GitClient
+		terraform  *toolMocks.TerraformClient
+		containers *mocks.BastionContainerManager
+		cmd        *funcli.InitCommand
+	}
+
+	newFixture := func(t *testing.T, conf *funcli.InitConfig) *fixture {
+		t.Setenv("HOME", t.TempDir())
+
+		cliConfig := funcli.NewCliConfig("1.2.3")
+		cliConfig.Region = "us-east-1"
+		cliConfig.Environment = internal.DefaultEnvironment
+		cliConfig.InitConfig = conf
+
+		f := &fixture{
+			lister:     awsMocks.NewResourceLister(t),
+			git:        toolMocks.NewGitClient(t),
+			terraform:  toolMocks.NewTerraformClient(t),
+			containers: mocks.NewBastionContainerManager(t),
+		}
+		f.cmd = funcli.NewInitCommand(cliConfig, f.lister, f.git, f.terraform, f.containers)
+
+		f.lister.EXPECT().ListVpcs(mock.Anything).Return([]aws.Vpc{
+			{Id: "vpc-1", Name: "main"},
+			{Id: "vpc-2", Name: "other"},
+		}, nil).Maybe()
+		f.lister.EXPECT().ListSubnets(mock.Anything).Return([]aws.Subnet{
+			{Id: "subnet-private", Name: "private", VpcId: "vpc-1"},
+			{Id: "subnet-public", Name: "public", VpcId: "vpc-1", Public: true},
+			{Id: "subnet-elsewhere", Name: "elsewhere", VpcId: "vpc-2"},
+		}, nil).Maybe()
+		f.lister.EXPECT().ListElastiCacheClusters(mock.Anything).Return([]aws.ElastiCacheCluster{
+			{Name: "cache", PrimaryEndpoint: "cache.example.com"},
+		}, nil).Maybe()
+
+		return f
+	}
+
+	expectDeploy := func(f *fixture) {
+		tfDir := internal.GetTerraformDir(internal.DefaultEnvironment)
+		f.git.EXPECT().Checkout(mock.Anything, tfDir, "v1.2.3").Return(nil).Once()
+		f.terraform.EXPECT().Init(tfDir).Return(nil).Once()
+		f.terraform.EXPECT().Apply(tfDir, internal.GetTerraformVarsPath(internal.DefaultEnvironment)).Return(nil).Once()
+		f.containers.EXPECT().Find().Return(nil, nil).Once()
+	}
+
+	readVars := func(t *testing.T) string {
+		contents, err := os.ReadFile(internal.GetTerraformVarsPath(internal.DefaultEnvironment))
+		require.NoError(t, err)
+		return string(contents)
+	}
+
+	t.Run("should deploy the choices given as flags", func(t *testing.T) {
+		autoLaunch := true
+		f := newFixture(t, &funcli.InitConfig{
+			Vpc:               "vpc-1",
+			PrivateSubnets:    []string{"subnet-private"},
+			PublicSubnets:     []string{"subnet-public"},
+			RedisCluster:      "cache",
+			AutoLaunchBastion: &autoLaunch,
+			Yes:               true,
+		})
+		expectDeploy(f)
+		f.containers.EXPECT().Start().Return(nil).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+
+		vars := readVars(t)
+		require.Contains(t, vars, `vpc_id             = "vpc-1"`)
+		require.Contains(t, vars, `private_subnet_ids = ["subnet-private"]`)
+		require.Contains(t, vars, `public_subnet_ids  = ["subnet-public"]`)
+		require.Contains(t, vars, `redis_host         = "cache.example.com"`)
+		require.Contains(t, vars, `region             = "us-east-1"`)
+		require.Contains(t, vars, `environment        = "default"`)
+	})
+
+	t.Run("should use the defaults for choices not given", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Yes: true})
+		expectDeploy(f)
+
+		require.NoError(t, f.cmd.Run(ctx))
+
+		vars := readVars(t)
+		require.Contains(t, vars, `vpc_id             = ""`)
+		require.Contains(t, vars, `redis_host         = ""`)
+	})
+
+	t.Run("should apply exactly the plan that was shown", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Yes: true, Plan: true})
+		tfDir := internal.GetTerraformDir(internal.DefaultEnvironment)
+		planPath := internal.GetTerraformPlanPath(internal.DefaultEnvironment)
+		f.git.EXPECT().Checkout(mock.Anything, tfDir, "v1.2.3").Return(nil).Once()
+		f.terraform.EXPECT().Init(tfDir).Return(nil).Once()
+		f.terraform.EXPECT().Plan(tfDir, internal.GetTerraformVarsPath(internal.DefaultEnvironment), planPath).Return(&tools.TerraformPlan{Path: planPath}, nil).Once()
+		f.terraform.EXPECT().ApplyPlan(tfDir, planPath).Return(nil).Once()
+		f.containers.EXPECT().Find().Return(nil, nil).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+	})
+
+	t.Run("should not leave the environment marked as deployed when init fails before applying", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Yes: true})
+		tfDir := internal.GetTerraformDir(internal.DefaultEnvironment)
+		f.git.EXPECT().Checkout(mock.Anything, tfDir, "v1.2.3").Return(nil).Once()
+		f.terraform.EXPECT().Init(tfDir).Return(errors.New("init failed")).Once()
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "init failed")
+		require.NoFileExists(t, internal.GetTerraformVarsPath(internal.DefaultEnvironment))
+	})
+
+	t.Run("should keep the vars of a previous deployment when init fails before applying", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Yes: true})
+		varsPath := internal.GetTerraformVarsPath(internal.DefaultEnvironment)
+		require.NoError(t, os.MkdirAll(internal.GetEnvironmentDir(internal.DefaultEnvironment), 0755))
+		require.NoError(t, os.WriteFile(varsPath, []byte("region = \"us-east-1\"\n"), 0644))
+
+		tfDir := internal.GetTerraformDir(internal.DefaultEnvironment)
+		f.git.EXPECT().Checkout(mock.Anything, tfDir, "v1.2.3").Return(errors.New("clone failed")).Once()
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "clone failed")
+		require.Equal(t, "region = \"us-east-1\"\n", readVars(t))
+	})
+
+	t.Run("should keep the vars when applying fails, as resources may have been created", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Yes: true})
+		tfDir := internal.GetTerraformDir(internal.DefaultEnvironment)
+		f.git.EXPECT().Checkout(mock.Anything, tfDir, "v1.2.3").Return(nil).Once()
+		f.terraform.EXPECT().Init(tfDir).Return(nil).Once()
+		f.terraform.EXPECT().Apply(tfDir, internal.GetTerraformVarsPath(internal.DefaultEnvironment)).Return(errors.New("apply failed")).Once()
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "apply failed")
+		require.FileExists(t, internal.GetTerraformVarsPath(internal.DefaultEnvironment))
+	})
+
+	t.Run("should read choices from a config file, preferring flags", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "funcie.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte(`
+vpc: vpc-1
+private_subnets: [subnet-private]
+public_subnets: [subnet-public]
+redis_cluster: cache
+auto_launch_bastion: false
+yes: true
+`), 0644))
+
+		f := newFixture(t, &funcli.InitConfig{ConfigFile: configPath, RedisCluster: "new"})
+		expectDeploy(f)
+
+		require.NoError(t, f.cmd.Run(ctx))
+
+		vars := readVars(t)
+		require.Contains(t, vars, `vpc_id             = "vpc-1"`)
+		require.Contains(t, vars, `private_subnet_ids = ["subnet-private"]`)
+		require.Contains(t, vars, `public_subnet_ids  = ["subnet-public"]`)
+		require.Contains(t, vars, `redis_host         = ""`)
+	})
+
+	t.Run("should reject unknown options in a config file", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "funcie.yaml")
+		require.NoError(t, os.WriteFile(configPath, []byte("vcp: vpc-1\n"), 0644))
+
+		f := newFixture(t, &funcli.InitConfig{ConfigFile: configPath, Yes: true})
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "failed to parse init config")
+	})
+
+	t.Run("should reject a VPC that does not exist", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Vpc: "vpc-missing", Yes: true})
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "VPC vpc-missing was not found in region us-east-1")
+	})
+
+	t.Run("should reject subnets from another VPC", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{
+			Vpc:            "vpc-1",
+			PrivateSubnets: []string{"subnet-elsewhere"},
+			PublicSubnets:  []string{"subnet-public"},
+			Yes:            true,
+		})
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "subnet subnet-elsewhere was not found in VPC vpc-1")
+	})
+
+	t.Run("should require the subnets of an existing VPC when not prompting", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Vpc: "vpc-1", Yes: true})
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "--private-subnets and --public-subnets must be given to use the existing VPC vpc-1 without prompting")
+	})
+
+	t.Run("should name only the missing subnet flag", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Vpc: "vpc-1", PrivateSubnets: []string{"subnet-private"}, Yes: true})
+
+		err := f.cmd.Run(ctx)
+		require.ErrorContains(t, err, "--public-subnets must be given")
+		require.NotContains(t, err.Error(), "--private-subnets")
+	})
+
+	t.Run("should reject subnets for a funcie-managed VPC", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{Vpc: "new", PublicSubnets: []string{"subnet-public"}, Yes: true})
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "can only be used with an existing VPC")
+	})
+
+	t.Run("should reject an ElastiCache cluster that does not exist", func(t *testing.T) {
+		f := newFixture(t, &funcli.InitConfig{RedisCluster: "missing", Yes: true})
+
+		require.ErrorContains(t, f.cmd.Run(ctx), "ElastiCache cluster missing was not found")
+	})
+}
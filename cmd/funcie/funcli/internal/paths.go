@@ -10,12 +10,75 @@ func GetFuncieBaseDir() string {
 	return path.Join(os.Getenv("HOME"), ".funcie/")
 }
 
-// GetTerraformDir returns the directory where the Terraform repository for funcie is stored.
-func GetTerraformDir() string {
-	return path.Join(GetFuncieBaseDir(), "terraform-aws-funcie/")
+// DefaultEnvironment is the name of the funcie environment used when no other is selected.
+const DefaultEnvironment = "default"
+
+// GetEnvironmentsDir returns the directory containing the state of each named funcie environment.
+func GetEnvironmentsDir() string {
+	return path.Join(GetFuncieBaseDir(), "environments/")
+}
+
+// GetEnvironmentDir returns the directory where the state of the given funcie environment is stored.
+// The default environment uses the base directory, where the state of every deployment was stored before
+// environments were introduced.
+func GetEnvironmentDir(env string) string {
+	if env == DefaultEnvironment {
+		return GetFuncieBaseDir()
+	}
+	return path.Join(GetEnvironmentsDir(), env)
+}
+
+// GetCurrentEnvironmentPath returns the path to the file holding the name of the selected funcie environment.
+func GetCurrentEnvironmentPath() string {
+	return path.Join(GetFuncieBaseDir(), "environment")
+}
+
+// GetTerraformDir returns the directory where the Terraform repository for the funcie environment is stored.
+func GetTerraformDir(env string) string {
+	return path.Join(GetEnvironmentDir(env), "terraform-aws-funcie/")
+}
+
+// GetTerraformVarsPath returns the path to the Terraform variables file used for init/destroy of the funcie environment.
+func GetTerraformVarsPath(env string) string {
+	return path.Join(GetEnvironmentDir(env), "funcli.tfvars")
+}
+
+// GetTerraformPlanPath returns the path to the Terraform plan saved by init --plan for the funcie environment.
+func GetTerraformPlanPath(env string) string {
+	return path.Join(GetEnvironmentDir(env), "funcli.tfplan")
+}
+
+// GetDeployedVersionPath returns the path to the file holding the funcie version deployed to the funcie environment.
+func GetDeployedVersionPath(env string) string {
+	return path.Join(GetEnvironmentDir(env), "funcli.version")
+}
+
+// GetForwardsPath returns the path to the file listing the forwards that funcie connect opens for the funcie environment.
+func GetForwardsPath(env string) string {
+	return path.Join(GetEnvironmentDir(env), "forwards.yaml")
+}
+
+// GetConnectPidPath returns the path to the pidfile of funcie connect for the funcie environment.
+func GetConnectPidPath(env string) string {
+	return path.Join(GetEnvironmentDir(env), "connect.pid")
+}
+
+// GetConnectLogPath returns the path to the log file of funcie connect running in the background for the funcie environment.
+func GetConnectLogPath(env string) string {
+	return path.Join(GetEnvironmentDir(env), "connect.log")
+}
+
+// GetConnectSocketPath returns the path to the Unix socket that funcie connect reports the status of its forwards on.
+func GetConnectSocketPath(env string) string {
+	return path.Join(GetEnvironmentDir(env), "connect.sock")
+}
+
+// GetBastionPidPath returns the path to the pidfile of a client bastion running in the background.
+func GetBastionPidPath() string {
+	return path.Join(GetFuncieBaseDir(), "bastion.pid")
 }
 
-// GetTerraformVarsPath returns the path to the Terraform variables file used for init/destroy.
-func GetTerraformVarsPath() string {
-	return path.Join(GetFuncieBaseDir(), "funcli.tfvars")
+// GetBastionLogPath returns the path to the log file of a client bastion running in the background.
+func GetBastionLogPath() string {
+	return path.Join(GetFuncieBaseDir(), "bastion.log")
 }
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two versions in the form major.minor.patch, with an optional leading v.
+// Returns a negative number if a is older than b, a positive number if a is newer than b, or 0 if they are the same.
+func CompareVersions(a string, b string) (int, error) {
+	aParts, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bParts, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range aParts {
+		if aParts[i] != bParts[i] {
+			return aParts[i] - bParts[i], nil
+		}
+	}
+
+	return 0, nil
+}
+
+func parseVersion(version string) ([3]int, error) {
+	var parts [3]int
+
+	fields := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	if len(fields) != len(parts) {
+		return parts, fmt.Errorf("invalid version %q; expected major.minor.patch", version)
+	}
+
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("invalid version %q; expected major.minor.patch", version)
+		}
+		parts[i] = n
+	}
+
+	return parts, nil
+}
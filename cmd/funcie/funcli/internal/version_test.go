@@ -0,0 +1,66 @@
+package internal
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "equal",
+			a:    "0.6.9",
+			b:    "v0.6.9",
+			want: 0,
+		},
+		{
+			name: "older patch",
+			a:    "0.6.9",
+			b:    "0.6.10",
+			want: -1,
+		},
+		{
+			name: "newer minor",
+			a:    "v0.7.0",
+			b:    "v0.6.9",
+			want: 1,
+		},
+		{
+			name: "newer major",
+			a:    "1.0.0",
+			b:    "0.9.9",
+			want: 1,
+		},
+		{
+			name:    "invalid",
+			a:       "dev",
+			b:       "0.6.9",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CompareVersions(tt.a, tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompareVersions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sign(got) != tt.want {
+				t.Errorf("CompareVersions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,153 @@
+package funcli
+
+import (
+	"fmt"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// hoursPerMonth is the average number of hours in a month, as used by AWS pricing.
+const hoursPerMonth = 730
+
+// instanceHourlyPrices are approximate on-demand prices in USD per hour for the instance and node types that funcie
+// deployments use, based on us-east-1. Prices in other regions are usually similar, but may be somewhat higher.
+var instanceHourlyPrices = map[string]float64{
+	"t2.nano":          0.0058,
+	"t2.micro":         0.0116,
+	"t2.small":         0.023,
+	"t3.nano":          0.0052,
+	"t3.micro":         0.0104,
+	"t3.small":         0.0208,
+	"t3a.nano":         0.0047,
+	"t3a.micro":        0.0094,
+	"t3a.small":        0.0188,
+	"t4g.nano":         0.0042,
+	"t4g.micro":        0.0084,
+	"t4g.small":        0.0168,
+	"cache.t2.micro":   0.017,
+	"cache.t3.micro":   0.017,
+	"cache.t3.small":   0.034,
+	"cache.t4g.micro":  0.016,
+	"cache.t4g.small":  0.032,
+	"cache.t4g.medium": 0.065,
+}
+
+// resourceHourlyPrices are approximate prices in USD per hour for resource types with a fixed price, based on us-east-1.
+var resourceHourlyPrices = map[string]float64{
+	"aws_nat_gateway": 0.045,
+}
+
+// PlanSummary summarizes the changes in a Terraform plan, and roughly estimates what the deployment will cost.
+type PlanSummary struct {
+	// Create are the addresses of the resources that will be created.
+	Create []string
+	// Update are the addresses of the resources that will be updated in place.
+	Update []string
+	// Replace are the addresses of the resources that will be destroyed and created again.
+	Replace []string
+	// Destroy are the addresses of the resources that will be destroyed.
+	Destroy []string
+	// MonthlyCost is the estimated monthly cost in USD of the priced resources once the plan is applied.
+	MonthlyCost float64
+	// Unpriced are the instance or node types found in the plan that have no known price.
+	Unpriced []string
+}
+
+// SummarizePlan summarizes the changes in the given plan and estimates the monthly cost of the resulting deployment.
+// Only instances, cache nodes, and NAT gateways are priced; other resources are usually free or negligible.
+func SummarizePlan(plan *tools.TerraformPlan) PlanSummary {
+	var summary PlanSummary
+
+	for _, change := range plan.ResourceChanges {
+		switch {
+		case slices.Contains(change.Actions, "create") && slices.Contains(change.Actions, "delete"):
+			summary.Replace = append(summary.Replace, change.Address)
+		case slices.Contains(change.Actions, "create"):
+			summary.Create = append(summary.Create, change.Address)
+		case slices.Contains(change.Actions, "update"):
+			summary.Update = append(summary.Update, change.Address)
+		case slices.Contains(change.Actions, "delete"):
+			summary.Destroy = append(summary.Destroy, change.Address)
+			continue
+		}
+
+		if change.After == nil {
+			continue
+		}
+
+		hourly, unpriced := estimateHourlyPrice(change)
+		summary.MonthlyCost += hourly * hoursPerMonth
+		if unpriced != "" && !slices.Contains(summary.Unpriced, unpriced) {
+			summary.Unpriced = append(summary.Unpriced, unpriced)
+		}
+	}
+
+	sort.Strings(summary.Unpriced)
+	return summary
+}
+
+// estimateHourlyPrice returns the hourly price of the resource once changed,
+// or the instance type of the resource if it has no known price.
+func estimateHourlyPrice(change tools.TerraformResourceChange) (float64, string) {
+	if price, ok := resourceHourlyPrices[change.Type]; ok {
+		return price, ""
+	}
+
+	var instanceType string
+	count := 1.0
+	switch change.Type {
+	case "aws_instance":
+		instanceType, _ = change.After["instance_type"].(string)
+	case "aws_elasticache_cluster":
+		instanceType, _ = change.After["node_type"].(string)
+		if nodes, ok := change.After["num_cache_nodes"].(float64); ok {
+			count = nodes
+		}
+	case "aws_elasticache_replication_group":
+		instanceType, _ = change.After["node_type"].(string)
+		if nodes, ok := change.After["num_cache_clusters"].(float64); ok {
+			count = nodes
+		}
+	default:
+		return 0, ""
+	}
+
+	if instanceType == "" {
+		return 0, ""
+	}
+
+	price, ok := instanceHourlyPrices[instanceType]
+	if !ok {
+		return 0, instanceType
+	}
+
+	return price * count, ""
+}
+
+// Print writes the summary in a form similar to the Terraform plan output.
+func (s PlanSummary) Print(output io.Writer) {
+	_, _ = fmt.Fprintf(output, "Plan: %v to add, %v to change, %v to replace, %v to destroy.\n",
+		len(s.Create), len(s.Update), len(s.Replace), len(s.Destroy))
+
+	for _, group := range []struct {
+		symbol    string
+		addresses []string
+	}{
+		{"+", s.Create},
+		{"~", s.Update},
+		{"-/+", s.Replace},
+		{"-", s.Destroy},
+	} {
+		for _, address := range group.addresses {
+			_, _ = fmt.Fprintf(output, "  %3v %v\n", group.symbol, address)
+		}
+	}
+
+	_, _ = fmt.Fprintf(output, "Estimated cost once applied: ~$%.2f USD/month (approximate on-demand us-east-1 prices).\n", s.MonthlyCost)
+	if len(s.Unpriced) > 0 {
+		_, _ = fmt.Fprintf(output, "Not included in the estimate, as no price is known: %v.\n", strings.Join(s.Unpriced, ", "))
+	}
+}
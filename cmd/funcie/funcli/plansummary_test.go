@@ -0,0 +1,67 @@
+package funcli_test
+
+import (
+	"bytes"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSummarizePlan(t *testing.T) {
+	plan := &tools.TerraformPlan{
+		ResourceChanges: []tools.TerraformResourceChange{
+			{
+				Address: "module.funcie.aws_instance.bastion",
+				Type:    "aws_instance",
+				Actions: []string{"create"},
+				After:   map[string]interface{}{"instance_type": "t4g.nano"},
+			},
+			{
+				Address: "module.funcie.aws_elasticache_cluster.redis",
+				Type:    "aws_elasticache_cluster",
+				Actions: []string{"delete", "create"},
+				After:   map[string]interface{}{"node_type": "cache.t4g.micro", "num_cache_nodes": float64(2)},
+			},
+			{
+				Address: "module.funcie.aws_nat_gateway.nat",
+				Type:    "aws_nat_gateway",
+				Actions: []string{"no-op"},
+				After:   map[string]interface{}{},
+			},
+			{
+				Address: "module.funcie.aws_instance.nat",
+				Type:    "aws_instance",
+				Actions: []string{"update"},
+				After:   map[string]interface{}{"instance_type": "x9.huge"},
+			},
+			{
+				Address: "module.funcie.aws_instance.old",
+				Type:    "aws_instance",
+				Actions: []string{"delete"},
+			},
+			{
+				Address: "module.funcie.aws_ssm_parameter.endpoint",
+				Type:    "aws_ssm_parameter",
+				Actions: []string{"create"},
+				After:   map[string]interface{}{"name": "/funcie/default/endpoint"},
+			},
+		},
+	}
+
+	summary := funcli.SummarizePlan(plan)
+
+	require.Equal(t, []string{"module.funcie.aws_instance.bastion", "module.funcie.aws_ssm_parameter.endpoint"}, summary.Create)
+	require.Equal(t, []string{"module.funcie.aws_instance.nat"}, summary.Update)
+	require.Equal(t, []string{"module.funcie.aws_elasticache_cluster.redis"}, summary.Replace)
+	require.Equal(t, []string{"module.funcie.aws_instance.old"}, summary.Destroy)
+	require.Equal(t, []string{"x9.huge"}, summary.Unpriced)
+	require.InDelta(t, (0.0042+2*0.016+0.045)*730, summary.MonthlyCost, 0.001)
+
+	var output bytes.Buffer
+	summary.Print(&output)
+	require.Contains(t, output.String(), "Plan: 2 to add, 1 to change, 1 to replace, 1 to destroy.")
+	require.Contains(t, output.String(), "-/+ module.funcie.aws_elasticache_cluster.redis")
+	require.Contains(t, output.String(), "~$59.28 USD/month")
+	require.Contains(t, output.String(), "no price is known: x9.huge.")
+}
@@ -0,0 +1,102 @@
+package funcli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// startDetachedProcess runs the funcie executable with the given arguments in a new background process, writing its
+// output to the log file. Returns the process along with a channel receiving the result of waiting for it to exit.
+func startDetachedProcess(args []string, logPath string) (*exec.Cmd, <-chan error, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find the funcie executable: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open log file %v: %w", logPath, err)
+	}
+	defer func() { _ = logFile.Close() }()
+
+	proc := exec.Command(executable, args...)
+	proc.Stdout = logFile
+	proc.Stderr = logFile
+	// Start a new session so that the process outlives the terminal that started it.
+	proc.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := proc.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- proc.Wait()
+	}()
+
+	return proc, exited, nil
+}
+
+// stopProcess asks the process with the given pid to exit, and waits up to the timeout for it to do so.
+func stopProcess(pid int, timeout time.Duration) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("failed to find process %v: %w", pid, err)
+	}
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to signal process %v: %w", pid, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for processExists(pid) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("process %v did not exit within %v", pid, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return nil
+}
+
+// readRunningPid returns the pid in the given pidfile, and whether that process is still running.
+func readRunningPid(pidPath string) (int, bool) {
+	contents, err := os.ReadFile(pidPath)
+	if err != nil {
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+
+	return pid, processExists(pid)
+}
+
+func writePidFile(pidPath string, pid int) error {
+	if err := os.MkdirAll(filepath.Dir(pidPath), 0755); err != nil {
+		return fmt.Errorf("failed to create pidfile directory: %w", err)
+	}
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write pidfile %v: %w", pidPath, err)
+	}
+	return nil
+}
+
+// processExists returns whether a process with the given pid is running, by sending it the null signal.
+func processExists(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,142 @@
+package funcli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// tailBodyLimit is the number of bytes of each response body shown for a mismatch.
+const tailBodyLimit = 512
+
+type TailConfig struct {
+	Application     string `arg:"--app,-a" help:"Only show shadow mode comparisons for the given application."`
+	MismatchesOnly  bool   `arg:"--mismatches-only,-m" help:"Only show comparisons where the local and deployed results differed."`
+	JSON            bool   `arg:"--json" help:"Print each comparison as a line of JSON, such as to keep a diff log."`
+	BastionEndpoint string `arg:"--bastion-endpoint" help:"The endpoint of the local client bastion." default:"http://127.0.0.1:24193"`
+}
+
+// TailCommand follows the shadow mode comparisons reported to the client bastion.
+type TailCommand struct {
+	cliConfig *CliConfig
+	client    *http.Client
+	output    io.Writer
+}
+
+// NewTailCommand creates a new TailCommand that writes to stdout.
+func NewTailCommand(cliConfig *CliConfig) *TailCommand {
+	return NewTailCommandWithOutput(cliConfig, os.Stdout)
+}
+
+// NewTailCommandWithOutput creates a new TailCommand that writes to the given output.
+func NewTailCommandWithOutput(cliConfig *CliConfig, output io.Writer) *TailCommand {
+	return &TailCommand{
+		cliConfig: cliConfig,
+		client:    &http.Client{},
+		output:    output,
+	}
+}
+
+func (c *TailCommand) Run(ctx context.Context) error {
+	conf := c.cliConfig.TailConfig
+
+	endpoint, err := url.Parse(conf.BastionEndpoint)
+	if err != nil {
+		return fmt.Errorf("invalid bastion endpoint %v: %w", conf.BastionEndpoint, err)
+	}
+	endpoint = endpoint.JoinPath("shadow")
+	if conf.Application != "" {
+		endpoint.RawQuery = url.Values{"application": {conf.Application}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the client bastion; is it running? %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("client bastion returned status %v; it may need to be upgraded to support shadow mode", resp.Status)
+	}
+
+	_, _ = fmt.Fprintln(c.output, "Waiting for shadow mode comparisons...")
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var diff messages.ShadowDiff
+		if err := json.Unmarshal(scanner.Bytes(), &diff); err != nil {
+			return fmt.Errorf("failed to read comparison: %w", err)
+		}
+
+		if conf.MismatchesOnly && diff.Matched() {
+			continue
+		}
+
+		if conf.JSON {
+			_, _ = fmt.Fprintln(c.output, scanner.Text())
+			continue
+		}
+
+		c.printDiff(&diff)
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("lost connection to the client bastion: %w", err)
+	}
+
+	return nil
+}
+
+func (c *TailCommand) printDiff(diff *messages.ShadowDiff) {
+	status := "MATCH"
+	if !diff.Matched() {
+		status = fmt.Sprintf("MISMATCH %v", diff.Mismatches)
+	}
+
+	latency := diff.LatencyDelta.Round(time.Millisecond).String()
+	if diff.LatencyDelta >= 0 {
+		latency = "+" + latency
+	}
+
+	_, _ = fmt.Fprintf(c.output, "%v  %v  %v  %v  latency %v (deployed %v, local %v)\n",
+		diff.Time.Local().Format(time.TimeOnly),
+		diff.Application,
+		diff.RequestId,
+		status,
+		latency,
+		diff.Deployed.Duration.Round(time.Millisecond),
+		diff.Local.Duration.Round(time.Millisecond),
+	)
+
+	if diff.Matched() {
+		return
+	}
+
+	_, _ = fmt.Fprintf(c.output, "    deployed: %v\n", formatShadowResult(diff.Deployed))
+	_, _ = fmt.Fprintf(c.output, "    local:    %v\n", formatShadowResult(diff.Local))
+}
+
+func formatShadowResult(result messages.ShadowResult) string {
+	if result.Error != nil {
+		return fmt.Sprintf("error: %v", result.Error.Message)
+	}
+
+	body := result.Body
+	if len(body) > tailBodyLimit {
+		return fmt.Sprintf("%s... (%v bytes)", body[:tailBodyLimit], len(body))
+	}
+	return string(body)
+}
@@ -0,0 +1,116 @@
+package funcli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailCommand(t *testing.T) {
+	ctx := context.Background()
+
+	matched := &messages.ShadowDiff{
+		Application:  "app",
+		RequestId:    "req-1",
+		Deployed:     messages.ShadowResult{Body: []byte(`{"a":1}`), Duration: 100 * time.Millisecond},
+		Local:        messages.ShadowResult{Body: []byte(`{"a":1}`), Duration: 150 * time.Millisecond},
+		LatencyDelta: 50 * time.Millisecond,
+	}
+	mismatched := &messages.ShadowDiff{
+		Application:  "app",
+		RequestId:    "req-2",
+		Deployed:     messages.ShadowResult{Body: []byte(`{"a":1}`)},
+		Local:        messages.ShadowResult{Error: funcie.NewProxyError("boom")},
+		LatencyDelta: 20 * time.Millisecond,
+		Mismatches:   []messages.ShadowMismatch{messages.ShadowMismatchError},
+	}
+
+	// serveDiffs serves the diffs as the client bastion would, then ends the stream.
+	serveDiffs := func(t *testing.T, diffs ...*messages.ShadowDiff) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", funcie.StreamContentType)
+			for _, diff := range diffs {
+				_ = json.NewEncoder(w).Encode(diff)
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	runTail := func(t *testing.T, conf *funcli.TailConfig) (string, error) {
+		cliConfig := funcli.NewCliConfig("test")
+		cliConfig.TailConfig = conf
+
+		var output bytes.Buffer
+		err := funcli.NewTailCommandWithOutput(cliConfig, &output).Run(ctx)
+		return output.String(), err
+	}
+
+	t.Run("should print each comparison", func(t *testing.T) {
+		server := serveDiffs(t, matched, mismatched)
+
+		output, err := runTail(t, &funcli.TailConfig{BastionEndpoint: server.URL})
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 5)
+		require.Equal(t, "Waiting for shadow mode comparisons...", lines[0])
+		require.Contains(t, lines[1], "app  req-1  MATCH  latency +50ms (deployed 100ms, local 150ms)")
+		require.Contains(t, lines[2], "app  req-2  MISMATCH [error]  latency +20ms")
+		require.Equal(t, `    deployed: {"a":1}`, lines[3])
+		require.Equal(t, "    local:    error: boom", lines[4])
+	})
+
+	t.Run("should only print mismatches", func(t *testing.T) {
+		server := serveDiffs(t, matched, mismatched)
+
+		output, err := runTail(t, &funcli.TailConfig{BastionEndpoint: server.URL, MismatchesOnly: true})
+		require.NoError(t, err)
+
+		require.NotContains(t, output, "req-1")
+		require.Contains(t, output, "req-2")
+	})
+
+	t.Run("should print comparisons as JSON", func(t *testing.T) {
+		server := serveDiffs(t, matched)
+
+		output, err := runTail(t, &funcli.TailConfig{BastionEndpoint: server.URL, JSON: true})
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 2)
+
+		var diff messages.ShadowDiff
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &diff))
+		require.Equal(t, "req-1", diff.RequestId)
+	})
+
+	t.Run("should filter to the application", func(t *testing.T) {
+		var query string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Path + "?" + r.URL.RawQuery
+		}))
+		defer server.Close()
+
+		_, err := runTail(t, &funcli.TailConfig{BastionEndpoint: server.URL, Application: "my app"})
+		require.NoError(t, err)
+		require.Equal(t, "/shadow?application=my+app", query)
+	})
+
+	t.Run("should fail when the bastion does not support shadow mode", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		_, err := runTail(t, &funcli.TailConfig{BastionEndpoint: server.URL})
+		require.ErrorContains(t, err, "404 Not Found")
+	})
+}
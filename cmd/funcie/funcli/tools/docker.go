@@ -3,10 +3,16 @@ package tools
 import (
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
 )
 
 // DockerRunOptions provides options for how to run a container.
 type DockerRunOptions struct {
+	// Name is the name to give the container, or an empty string to let Docker generate one.
+	Name string
+	// Labels is a map of labels to set on the container.
+	Labels map[string]string
 	// Env is a map of environment variables to set in the container.
 	Env map[string]string
 	// ExposedPorts is a map of ports to expose on the container.
@@ -15,10 +21,34 @@ type DockerRunOptions struct {
 	RestartPolicy string
 }
 
+// DockerContainer describes an existing Docker container.
+type DockerContainer struct {
+	// Id is the ID of the container.
+	Id string
+	// Name is the name of the container.
+	Name string
+	// Image is the image that the container was created from.
+	Image string
+	// State is the state of the container, such as running or exited.
+	State string
+}
+
+// Running returns whether the container is currently running.
+func (c DockerContainer) Running() bool {
+	return c.State == "running"
+}
+
 // DockerClient provides an interface for interacting with Docker containers.
 type DockerClient interface {
 	// RunContainer runs a container with the specified image and options.
 	RunContainer(image string, opts DockerRunOptions) error
+	// PullImage pulls the specified image, which should include a tag to pin the version.
+	PullImage(image string) error
+	// ListContainers returns all containers, running or not, that match the given filter, such as label=key=value.
+	// An empty filter returns every container.
+	ListContainers(filter string) ([]DockerContainer, error)
+	// RemoveContainer stops and removes the specified container.
+	RemoveContainer(id string) error
 }
 
 type dockerCliClient struct {
@@ -35,8 +65,16 @@ func NewDockerCliClient(runner ProcessRunner) DockerClient {
 func (d *dockerCliClient) RunContainer(image string, opts DockerRunOptions) error {
 	args := []string{"run", "-d"}
 
-	for k, v := range opts.Env {
-		args = append(args, "-e", k+"="+v)
+	if opts.Name != "" {
+		args = append(args, "--name", opts.Name)
+	}
+
+	for _, k := range sortedKeys(opts.Labels) {
+		args = append(args, "--label", k+"="+opts.Labels[k])
+	}
+
+	for _, k := range sortedKeys(opts.Env) {
+		args = append(args, "-e", k+"="+opts.Env[k])
 	}
 
 	for _, port := range opts.ExposedPorts {
@@ -58,3 +96,61 @@ func (d *dockerCliClient) RunContainer(image string, opts DockerRunOptions) erro
 
 	return nil
 }
+
+func (d *dockerCliClient) PullImage(image string) error {
+	if _, err := d.runner.Run("docker", "pull", image); err != nil {
+		return fmt.Errorf("failed to pull image %v: %w", image, err)
+	}
+
+	return nil
+}
+
+func (d *dockerCliClient) ListContainers(filter string) ([]DockerContainer, error) {
+	args := []string{"ps", "-a", "--no-trunc", "--format", "{{.ID}}\t{{.Names}}\t{{.Image}}\t{{.State}}"}
+	if filter != "" {
+		args = append(args, "--filter", filter)
+	}
+
+	out, err := d.runner.RunWithOptions("docker", RunnerOpts{
+		Args:  args,
+		Quiet: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers matching %v: %w", filter, err)
+	}
+
+	var containers []DockerContainer
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "\t")
+		if len(fields) != 4 {
+			continue
+		}
+
+		containers = append(containers, DockerContainer{
+			Id:    fields[0],
+			Name:  fields[1],
+			Image: fields[2],
+			State: fields[3],
+		})
+	}
+
+	return containers, nil
+}
+
+func (d *dockerCliClient) RemoveContainer(id string) error {
+	if _, err := d.runner.Run("docker", "rm", "-f", id); err != nil {
+		return fmt.Errorf("failed to remove container %v: %w", id, err)
+	}
+
+	return nil
+}
+
+// sortedKeys returns the keys of the map in order, so that commands are deterministic.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
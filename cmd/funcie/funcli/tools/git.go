@@ -3,6 +3,7 @@ package tools
 import (
 	"fmt"
 	"os"
+	"strings"
 )
 
 // GitClient provides an interface for interacting with Git repositories.
@@ -14,6 +15,9 @@ type GitClient interface {
 	// If the directory does not exist, it will be created and the repository cloned.
 	// If the directory exists, it will be updated with the latest changes on the given branch from the repository.
 	Checkout(url string, directory string, branch string) error
+	// CurrentTag returns the tag that is checked out in the specified directory.
+	// Fails if the checked out commit is not tagged.
+	CurrentTag(directory string) (string, error)
 }
 
 type gitCliClient struct {
@@ -65,3 +69,15 @@ func (g *gitCliClient) Checkout(url string, directory string, branch string) err
 
 	return nil
 }
+
+func (g *gitCliClient) CurrentTag(directory string) (string, error) {
+	out, err := g.runner.RunWithOptions("git", RunnerOpts{
+		Args:  []string{"-C", directory, "describe", "--tags", "--exact-match"},
+		Quiet: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the tag checked out in %v: %w", directory, err)
+	}
+
+	return strings.TrimSpace(out), nil
+}
@@ -19,6 +19,8 @@ type RunnerOpts struct {
 	Env map[string]string
 	// WorkingDir is the working directory to run the command in, or an empty string to use the current working directory.
 	WorkingDir string
+	// Quiet hides the command and its output from the user; the output is still returned.
+	Quiet bool
 }
 
 // ProcessRunner provides a helper interface for running shell commands.
@@ -48,6 +50,10 @@ func (p *processRunner) RunWithOptions(cmd string, options RunnerOpts) (string,
 	var out bytes.Buffer
 	proc.Stdout = io.MultiWriter(os.Stdout, &out)
 	proc.Stderr = os.Stderr
+	if options.Quiet {
+		proc.Stdout = &out
+		proc.Stderr = &out
+	}
 
 	if options.Env != nil {
 		for k, v := range options.Env {
@@ -65,9 +71,14 @@ func (p *processRunner) RunWithOptions(cmd string, options RunnerOpts) (string,
 	// TODO: Validate this and if other signals are needed
 	proc.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
-	fmt.Println(color.HiBlackString(fmt.Sprintf("%v %v", cmd, strings.Join(options.Args, " "))))
+	if !options.Quiet {
+		fmt.Println(color.HiBlackString(fmt.Sprintf("%v %v", cmd, strings.Join(options.Args, " "))))
+	}
 
 	if err := proc.Run(); err != nil {
+		if options.Quiet && out.Len() > 0 {
+			return "", fmt.Errorf("failed to run command %v: %w: %v", cmd, err, strings.TrimSpace(out.String()))
+		}
 		return "", fmt.Errorf("failed to run command %v: %w", cmd, err)
 	}
 
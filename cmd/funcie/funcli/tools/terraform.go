@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
@@ -10,10 +11,48 @@ type TerraformClient interface {
 	Init(directory string) error
 	// Apply applies a Terraform configuration with variables from the specified file.
 	Apply(directory string, varFilePath string) error
+	// Plan creates an execution plan with variables from the specified file, saving it to planPath.
+	Plan(directory string, varFilePath string, planPath string) (*TerraformPlan, error)
+	// ApplyPlan applies a plan saved by Plan, making exactly the changes that it showed.
+	ApplyPlan(directory string, planPath string) error
 	// Destroy destroys a Terraform project.
 	Destroy(directory string, varFilePath string) error
 }
 
+// TerraformPlan describes the changes in a saved Terraform plan.
+type TerraformPlan struct {
+	// Path is the path of the saved plan.
+	Path string
+	// ResourceChanges are the changes to each resource in the plan, including resources that are unchanged.
+	ResourceChanges []TerraformResourceChange
+}
+
+// TerraformResourceChange describes the change to a single resource in a Terraform plan.
+type TerraformResourceChange struct {
+	// Address is the full address of the resource, such as module.funcie.aws_instance.bastion.
+	Address string
+	// Type is the type of the resource, such as aws_instance.
+	Type string
+	// Actions are the actions taken on the resource; one of no-op, create, read, update, or delete,
+	// or both delete and create when the resource is replaced.
+	Actions []string
+	// After are the attributes of the resource once the change is applied, or nil if it is deleted.
+	// Attributes that are not known until apply are omitted.
+	After map[string]interface{}
+}
+
+// terraformShowOutput is the subset of the terraform show -json output for a plan that is used.
+type terraformShowOutput struct {
+	ResourceChanges []struct {
+		Address string `json:"address"`
+		Type    string `json:"type"`
+		Change  struct {
+			Actions []string               `json:"actions"`
+			After   map[string]interface{} `json:"after"`
+		} `json:"change"`
+	} `json:"resource_changes"`
+}
+
 type terraformCliClient struct {
 	runner ProcessRunner
 }
@@ -50,6 +89,51 @@ func (t *terraformCliClient) Apply(directory string, varFilePath string) error {
 	return nil
 }
 
+func (t *terraformCliClient) Plan(directory string, varFilePath string, planPath string) (*TerraformPlan, error) {
+	_, err := t.run("terraform", "-chdir="+directory, "plan", "-input=false", "-out="+planPath, "-var-file="+varFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan Terraform configuration in directory %v: %w", directory, err)
+	}
+
+	out, err := t.runner.RunWithOptions("terraform", RunnerOpts{
+		Args:  []string{"-chdir=" + directory, "show", "-json", planPath},
+		Env:   map[string]string{"TF_IN_AUTOMATION": "true"},
+		Quiet: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to show Terraform plan %v: %w", planPath, err)
+	}
+
+	var show terraformShowOutput
+	if err := json.Unmarshal([]byte(out), &show); err != nil {
+		return nil, fmt.Errorf("failed to parse Terraform plan %v: %w", planPath, err)
+	}
+
+	plan := &TerraformPlan{
+		Path:            planPath,
+		ResourceChanges: make([]TerraformResourceChange, 0, len(show.ResourceChanges)),
+	}
+	for _, change := range show.ResourceChanges {
+		plan.ResourceChanges = append(plan.ResourceChanges, TerraformResourceChange{
+			Address: change.Address,
+			Type:    change.Type,
+			Actions: change.Change.Actions,
+			After:   change.Change.After,
+		})
+	}
+
+	return plan, nil
+}
+
+func (t *terraformCliClient) ApplyPlan(directory string, planPath string) error {
+	_, err := t.run("terraform", "-chdir="+directory, "apply", "-input=false", planPath)
+	if err != nil {
+		return fmt.Errorf("failed to apply Terraform plan %v in directory %v: %w", planPath, directory, err)
+	}
+
+	return nil
+}
+
 func (t *terraformCliClient) Destroy(directory string, varFilePath string) error {
 	_, err := t.run("terraform", "-chdir="+directory, "destroy", "-input=false", "-auto-approve", "-var-file="+varFilePath)
 	if err != nil {
@@ -1,8 +1,12 @@
 package funcli
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/session-manager-plugin/src/datachannel"
 	"github.com/aws/session-manager-plugin/src/log"
@@ -10,8 +14,23 @@ import (
 	_ "github.com/aws/session-manager-plugin/src/sessionmanagerplugin/session/portsession"
 	_ "github.com/aws/session-manager-plugin/src/sessionmanagerplugin/session/shellsession"
 	"github.com/google/uuid"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
 )
 
+// TunnelSessionArg is the argument that runs the funcie executable as the process of a single tunnel session, with
+// the session read from stdin, rather than as the CLI.
+const TunnelSessionArg = "__tunnel-session"
+
+// tunnelSessionStopTimeout is how long a tunnel session process has to exit after being asked to stop before it is killed.
+const tunnelSessionStopTimeout = 5 * time.Second
+
+// tunnelSessionParentCheckInterval is how often a tunnel session process checks whether funcie connect is still running.
+const tunnelSessionParentCheckInterval = time.Second
+
 // SsmTunnellerOptions contains the options for OpenTunnel using SSM.
 type SsmTunnellerOptions struct {
 	// Output is the output of the StartSession API call.
@@ -23,17 +42,41 @@ type SsmTunnellerOptions struct {
 // Tunneller is an interface for creating a tunnel to a remote host.
 type Tunneller interface {
 	// OpenTunnel starts a tunnel to a remote host using the provider-specific options.
+	// Blocks until the tunnel closes, returning nil if it closed normally, or until the context is done.
 	OpenTunnel(ctx context.Context, opts interface{}) error
 }
 
+// tunnelSession is the session that a tunnel session process opens, which it reads from stdin.
+type tunnelSession struct {
+	SessionId  string `json:"sessionId"`
+	StreamUrl  string `json:"streamUrl"`
+	TokenValue string `json:"tokenValue"`
+	Endpoint   string `json:"endpoint"`
+	InstanceId string `json:"instanceId"`
+}
+
 type ssmTunnel struct {
-	region string
+	region  string
+	command []string
+}
+
+// NewSsmTunneller creates a new Tunneller that opens each SSM session in a new process of the funcie executable.
+// The session manager plugin exits the process when a session ends, so this keeps funcie connect running.
+func NewSsmTunneller(conf *CliConfig) (Tunneller, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the funcie executable: %w", err)
+	}
+
+	return NewSsmTunnellerWithCommand(conf, executable, TunnelSessionArg), nil
 }
 
-// NewSsmTunneller creates a new WebhookTunnel.
-func NewSsmTunneller(conf *CliConfig) Tunneller {
+// NewSsmTunnellerWithCommand creates a new Tunneller that opens each SSM session by running the given command,
+// which must run RunTunnelSession.
+func NewSsmTunnellerWithCommand(conf *CliConfig, command ...string) Tunneller {
 	return &ssmTunnel{
-		region: conf.Region,
+		region:  conf.Region,
+		command: command,
 	}
 }
 
@@ -45,15 +88,73 @@ func (t *ssmTunnel) OpenTunnel(ctx context.Context, opts interface{}) error {
 		return fmt.Errorf("failed to resolve endpoint: %w", err)
 	}
 
+	input, err := json.Marshal(&tunnelSession{
+		SessionId:  aws.ToString(ssmOpts.Output.SessionId),
+		StreamUrl:  aws.ToString(ssmOpts.Output.StreamUrl),
+		TokenValue: aws.ToString(ssmOpts.Output.TokenValue),
+		Endpoint:   ep.URL,
+		InstanceId: ssmOpts.InstanceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, t.command[0], t.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Use a separate process group so that an interrupt only reaches funcie connect, which then stops the session.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = tunnelSessionStopTimeout
+
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("session process failed: %w", err)
+	}
+
+	return nil
+}
+
+// RunTunnelSession opens the SSM session read from the input as JSON, forwarding its port until the session ends.
+// The session manager plugin exits the process once the session ends, so this should run in its own process.
+// The process also exits once its parent does, so that an orphaned session does not keep the port.
+func RunTunnelSession(input io.Reader) error {
+	var sess tunnelSession
+	if err := json.NewDecoder(input).Decode(&sess); err != nil {
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+
+	go exitWithParent(os.Getppid())
+
 	ssmSession := &session.Session{
 		DataChannel: &datachannel.DataChannel{},
-		SessionId:   *ssmOpts.Output.SessionId,
-		StreamUrl:   *ssmOpts.Output.StreamUrl,
-		TokenValue:  *ssmOpts.Output.TokenValue,
-		Endpoint:    ep.URL,
+		SessionId:   sess.SessionId,
+		StreamUrl:   sess.StreamUrl,
+		TokenValue:  sess.TokenValue,
+		Endpoint:    sess.Endpoint,
 		ClientId:    fmt.Sprintf("funcie-%v", uuid.NewString()),
-		TargetId:    ssmOpts.InstanceID,
+		TargetId:    sess.InstanceId,
 	}
 
-	return ssmSession.Execute(log.Logger(false, ssmSession.ClientId))
+	if err := ssmSession.Execute(log.Logger(false, ssmSession.ClientId)); err != nil {
+		return fmt.Errorf("session %v failed: %w", sess.SessionId, err)
+	}
+	return nil
+}
+
+// exitWithParent exits the process once the parent with the given pid is no longer its parent.
+func exitWithParent(ppid int) {
+	for {
+		time.Sleep(tunnelSessionParentCheckInterval)
+		if os.Getppid() != ppid {
+			_, _ = fmt.Fprintln(os.Stderr, errors.New("funcie connect stopped; closing the session"))
+			os.Exit(1)
+		}
+	}
 }
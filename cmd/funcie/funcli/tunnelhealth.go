@@ -0,0 +1,56 @@
+package funcli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// tunnelProbeTimeout is how long a health probe waits for a reply through a tunnel.
+const tunnelProbeTimeout = 5 * time.Second
+
+// tunnelProbeFailureThreshold is how many health probes in a row need to fail for a session to be restarted.
+const tunnelProbeFailureThreshold = 3
+
+// tunnelProbe checks that a tunnel listening on the given local address still reaches its remote host.
+type tunnelProbe func(ctx context.Context, address string) error
+
+// pingRedis is a tunnelProbe that sends a Redis PING through the tunnel, succeeding on any reply.
+// An error reply, such as one asking for authentication, still shows that Redis was reached.
+func pingRedis(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, tunnelProbeTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %v: %w", address, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("*1\r\n$4\r\nPING\r\n")); err != nil {
+		return fmt.Errorf("failed to send PING to %v: %w", address, err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("no reply to PING from %v: %w", address, err)
+	}
+	if !strings.HasPrefix(reply, "+") && !strings.HasPrefix(reply, "-") {
+		return fmt.Errorf("unexpected reply to PING from %v: %q", address, strings.TrimSpace(reply))
+	}
+
+	return nil
+}
+
+// isRedisForward returns whether the forward is to the Redis of the funcie deployment, which can be probed with PING.
+func isRedisForward(forward Forward) bool {
+	return forward.RemoteHostParameter == knownForwards["redis"].RemoteHostParameter
+}
@@ -0,0 +1,211 @@
+package funcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	"github.com/charmbracelet/huh"
+	"io"
+	"os"
+	"strings"
+)
+
+type UpgradeConfig struct {
+	Yes bool `arg:"--yes,-y" help:"Apply the upgrade without asking for confirmation."`
+}
+
+// UpgradeCommand moves an existing deployment to the version of the Terraform module and client bastion matching the CLI.
+type UpgradeCommand struct {
+	cliConfig       *CliConfig
+	configStore     ConfigStore
+	gitClient       tools.GitClient
+	terraformClient tools.TerraformClient
+	containers      BastionContainerManager
+	output          io.Writer
+}
+
+func NewUpgradeCommand(
+	cliConfig *CliConfig,
+	configStore ConfigStore,
+	gitClient tools.GitClient,
+	terraformClient tools.TerraformClient,
+	containers BastionContainerManager,
+) *UpgradeCommand {
+	return &UpgradeCommand{
+		cliConfig:       cliConfig,
+		configStore:     configStore,
+		gitClient:       gitClient,
+		terraformClient: terraformClient,
+		containers:      containers,
+		output:          os.Stdout,
+	}
+}
+
+func (c *UpgradeCommand) Run(ctx context.Context) error {
+	env := c.cliConfig.Environment
+	varFile := internal.GetTerraformVarsPath(env)
+	if _, err := os.Stat(varFile); os.IsNotExist(err) {
+		return fmt.Errorf("could not find an instance of funcie to upgrade in the %v environment; run funcie init instead", env)
+	}
+
+	target := strings.TrimSpace(c.cliConfig.versionString)
+	deployed, err := c.deployedVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmp, err := internal.CompareVersions(target, deployed)
+	if err != nil {
+		return fmt.Errorf("failed to compare versions: %w", err)
+	}
+	if cmp < 0 {
+		return fmt.Errorf("the %v environment is deployed with funcie v%v, which is newer than this CLI (v%v); "+
+			"downgrades are not supported, so upgrade the CLI instead", env, deployed, target)
+	}
+
+	if cmp == 0 {
+		_, _ = fmt.Fprintf(c.output, "The funcie infrastructure for the %v environment is already at v%v.\n", env, target)
+	} else if err := c.upgradeInfrastructure(env, deployed, target); err != nil {
+		return err
+	}
+
+	upgraded, err := c.containers.Upgrade()
+	if err != nil {
+		return fmt.Errorf("failed to upgrade client bastion container: %w", err)
+	}
+	if upgraded {
+		_, _ = fmt.Fprintf(c.output, "The client bastion container now runs %v.\n", c.containers.Image())
+	} else {
+		_, _ = fmt.Fprintln(c.output, "The client bastion container is already up to date.")
+	}
+
+	return nil
+}
+
+// deployedVersion returns the funcie version deployed to the environment, from local state if present, then from SSM,
+// and finally from the tag of the Terraform module checkout for deployments made before the version was recorded.
+func (c *UpgradeCommand) deployedVersion(ctx context.Context) (string, error) {
+	env := c.cliConfig.Environment
+
+	version, err := readDeployedVersion(env)
+	if err != nil {
+		return "", err
+	}
+	if version != "" {
+		return version, nil
+	}
+
+	if version, err := c.configStore.GetConfigValue(ctx, "version"); err == nil {
+		return strings.TrimPrefix(strings.TrimSpace(version), "v"), nil
+	}
+
+	tag, err := c.gitClient.CurrentTag(internal.GetTerraformDir(env))
+	if err != nil {
+		return "", fmt.Errorf("could not determine the funcie version deployed to the %v environment: %w", env, err)
+	}
+
+	return strings.TrimPrefix(tag, "v"), nil
+}
+
+// upgradeInfrastructure applies the Terraform module for the target version with the existing vars,
+// checking out the deployed version again if the upgrade fails.
+func (c *UpgradeCommand) upgradeInfrastructure(env string, deployed string, target string) error {
+	tfModuleDir := internal.GetTerraformDir(env)
+	varFile := internal.GetTerraformVarsPath(env)
+
+	_, _ = fmt.Fprintf(c.output, "Upgrading the %v funcie environment from v%v to v%v.\n", env, deployed, target)
+
+	if err := c.gitClient.Checkout(tfModuleRepo, tfModuleDir, "v"+target); err != nil {
+		return fmt.Errorf("failed to check out terraform module: %w", err)
+	}
+
+	if err := c.applyModule(tfModuleDir, varFile, internal.GetTerraformPlanPath(env)); err != nil {
+		if !errors.Is(err, errUpgradeCancelled) {
+			_, _ = fmt.Fprintf(c.output, "The upgrade failed; checking out v%v of the terraform module again.\n", deployed)
+			err = fmt.Errorf("failed to upgrade funcie infrastructure: %w", err)
+		}
+		return errors.Join(err, c.rollback(tfModuleDir, deployed))
+	}
+
+	_, _ = fmt.Fprintln(c.output, "Terraform configuration applied successfully.")
+
+	return writeDeployedVersion(env, target)
+}
+
+var errUpgradeCancelled = errors.New("upgrade cancelled")
+
+// applyModule initializes the checked out Terraform module, then plans and applies it once confirmed.
+func (c *UpgradeCommand) applyModule(tfModuleDir string, varFile string, planPath string) error {
+	if err := c.terraformClient.Init(tfModuleDir); err != nil {
+		return fmt.Errorf("failed to initialize terraform module: %w", err)
+	}
+
+	plan, err := c.terraformClient.Plan(tfModuleDir, varFile, planPath)
+	if err != nil {
+		return fmt.Errorf("failed to plan terraform configuration: %w", err)
+	}
+	defer func() { _ = os.Remove(plan.Path) }()
+
+	_, _ = fmt.Fprintln(c.output)
+	SummarizePlan(plan).Print(c.output)
+	_, _ = fmt.Fprintln(c.output)
+
+	confirmed := c.cliConfig.UpgradeConfig.Yes
+	if !confirmed {
+		err := huh.NewConfirm().
+			Title("Would you like to apply the upgrade?").
+			Affirmative("Upgrade").
+			Value(&confirmed).
+			Run()
+		if err != nil {
+			return fmt.Errorf("failed to confirm upgrade: %w", err)
+		}
+	}
+
+	if !confirmed {
+		return errUpgradeCancelled
+	}
+
+	if err := c.terraformClient.ApplyPlan(tfModuleDir, plan.Path); err != nil {
+		return fmt.Errorf("failed to apply terraform plan: %w", err)
+	}
+
+	return nil
+}
+
+// rollback checks out the given version of the Terraform module again, so that it matches the deployed infrastructure.
+func (c *UpgradeCommand) rollback(tfModuleDir string, version string) error {
+	if err := c.gitClient.Checkout(tfModuleRepo, tfModuleDir, "v"+version); err != nil {
+		return fmt.Errorf("failed to roll back terraform module to v%v: %w", version, err)
+	}
+	if err := c.terraformClient.Init(tfModuleDir); err != nil {
+		return fmt.Errorf("failed to initialize terraform module after rolling back to v%v: %w", version, err)
+	}
+
+	return nil
+}
+
+// readDeployedVersion returns the funcie version recorded as deployed to the environment, or an empty string if none is.
+func readDeployedVersion(env string) (string, error) {
+	contents, err := os.ReadFile(internal.GetDeployedVersionPath(env))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read deployed version: %w", err)
+	}
+
+	return strings.TrimSpace(string(contents)), nil
+}
+
+// writeDeployedVersion records the funcie version deployed to the environment.
+func writeDeployedVersion(env string, version string) error {
+	version = strings.TrimSpace(version) + "\n"
+	if err := os.WriteFile(internal.GetDeployedVersionPath(env), []byte(version), 0644); err != nil {
+		return fmt.Errorf("failed to write deployed version: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package funcli_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Kapps/funcie/cmd/funcie/funcli"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/internal"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/mocks"
+	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
+	toolMocks "github.com/Kapps/funcie/cmd/funcie/funcli/tools/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestUpgradeCommand(t *testing.T) {
+	ctx := context.Background()
+	env := internal.DefaultEnvironment
+
+	type fixture struct {
+		configStore *mocks.ConfigStore
+		git         *toolMocks.GitClient
+		terraform   *toolMocks.TerraformClient
+		containers  *mocks.BastionContainerManager
+		cmd         *funcli.UpgradeCommand
+	}
+
+	newFixture := func(t *testing.T, deployedVersion string) *fixture {
+		t.Setenv("HOME", t.TempDir())
+
+		require.NoError(t, os.MkdirAll(internal.GetEnvironmentDir(env), 0755))
+		require.NoError(t, os.WriteFile(internal.GetTerraformVarsPath(env), []byte("region = \"us-east-1\"\n"), 0644))
+		if deployedVersion != "" {
+			require.NoError(t, os.WriteFile(internal.GetDeployedVersionPath(env), []byte(deployedVersion+"\n"), 0644))
+		}
+
+		cliConfig := funcli.NewCliConfig("1.2.3\n")
+		cliConfig.Environment = env
+		cliConfig.UpgradeConfig = &funcli.UpgradeConfig{Yes: true}
+
+		f := &fixture{
+			configStore: mocks.NewConfigStore(t),
+			git:         toolMocks.NewGitClient(t),
+			terraform:   toolMocks.NewTerraformClient(t),
+			containers:  mocks.NewBastionContainerManager(t),
+		}
+		f.cmd = funcli.NewUpgradeCommand(cliConfig, f.configStore, f.git, f.terraform, f.containers)
+
+		return f
+	}
+
+	readVersion := func(t *testing.T) string {
+		contents, err := os.ReadFile(internal.GetDeployedVersionPath(env))
+		require.NoError(t, err)
+		return string(contents)
+	}
+
+	expectPlan := func(f *fixture) {
+		tfDir := internal.GetTerraformDir(env)
+		planPath := internal.GetTerraformPlanPath(env)
+		f.terraform.EXPECT().Init(tfDir).Return(nil).Once()
+		f.terraform.EXPECT().Plan(tfDir, internal.GetTerraformVarsPath(env), planPath).
+			Return(&tools.TerraformPlan{Path: planPath}, nil).Once()
+	}
+
+	t.Run("should upgrade the infrastructure and client bastion", func(t *testing.T) {
+		f := newFixture(t, "1.1.0")
+		tfDir := internal.GetTerraformDir(env)
+
+		f.git.EXPECT().Checkout(mock.Anything, tfDir, "v1.2.3").Return(nil).Once()
+		expectPlan(f)
+		f.terraform.EXPECT().ApplyPlan(tfDir, internal.GetTerraformPlanPath(env)).Return(nil).Once()
+		f.containers.EXPECT().Upgrade().Return(true, nil).Once()
+		f.containers.EXPECT().Image().Return("image:v1.2.3").Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+		require.Equal(t, "1.2.3\n", readVersion(t))
+	})
+
+	t.Run("should roll back the checkout if apply fails", func(t *testing.T) {
+		f := newFixture(t, "1.1.0")
+		tfDir := internal.GetTerraformDir(env)
+
+		checkout := f.git.EXPECT().Checkout(mock.Anything, tfDir, "v1.2.3").Return(nil).Once()
+		expectPlan(f)
+		apply := f.terraform.EXPECT().ApplyPlan(tfDir, internal.GetTerraformPlanPath(env)).Return(errors.New("boom")).Once()
+		f.git.EXPECT().Checkout(mock.Anything, tfDir, "v1.1.0").Return(nil).Once().NotBefore(checkout, apply)
+		f.terraform.EXPECT().Init(tfDir).Return(nil).Once()
+
+		err := f.cmd.Run(ctx)
+		require.ErrorContains(t, err, "boom")
+		require.Equal(t, "1.1.0\n", readVersion(t))
+	})
+
+	t.Run("should refuse to downgrade", func(t *testing.T) {
+		f := newFixture(t, "1.3.0")
+
+		err := f.cmd.Run(ctx)
+		require.ErrorContains(t, err, "downgrades are not supported")
+	})
+
+	t.Run("should only upgrade the client bastion if the infrastructure is up to date", func(t *testing.T) {
+		f := newFixture(t, "1.2.3")
+		f.containers.EXPECT().Upgrade().Return(false, nil).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+	})
+
+	t.Run("should detect the deployed version from SSM", func(t *testing.T) {
+		f := newFixture(t, "")
+		f.configStore.EXPECT().GetConfigValue(ctx, "version").Return("v1.2.3", nil).Once()
+		f.containers.EXPECT().Upgrade().Return(false, nil).Once()
+
+		require.NoError(t, f.cmd.Run(ctx))
+	})
+
+	t.Run("should detect the deployed version from the module checkout", func(t *testing.T) {
+		f := newFixture(t, "")
+		f.configStore.EXPECT().GetConfigValue(ctx, "version").Return("", errors.New("not found")).Once()
+		f.git.EXPECT().CurrentTag(internal.GetTerraformDir(env)).Return("v1.3.0", nil).Once()
+
+		err := f.cmd.Run(ctx)
+		require.ErrorContains(t, err, "deployed with funcie v1.3.0")
+	})
+
+	t.Run("should fail without a deployment", func(t *testing.T) {
+		f := newFixture(t, "")
+		require.NoError(t, os.Remove(internal.GetTerraformVarsPath(env)))
+
+		err := f.cmd.Run(ctx)
+		require.ErrorContains(t, err, "run funcie init instead")
+	})
+}
@@ -2,8 +2,8 @@ package main
 
 import (
 	"context"
-	_ "embed"
 	"fmt"
+	"github.com/Kapps/funcie"
 	"github.com/Kapps/funcie/cmd/funcie/funcli"
 	funcliAws "github.com/Kapps/funcie/cmd/funcie/funcli/aws"
 	"github.com/Kapps/funcie/cmd/funcie/funcli/tools"
@@ -12,21 +12,29 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/elasticache"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"go.uber.org/fx"
 	"os"
 )
 
-//go:embed version.txt
-var version string
-
 // Runnable is an interface for commands that can be run.
 type Runnable interface {
 	Run(ctx context.Context) error
 }
 
 func main() {
-	cliConfig := funcli.NewCliConfig(version)
+	// funcie connect runs each tunnel session in its own process of this executable.
+	if len(os.Args) > 1 && os.Args[1] == funcli.TunnelSessionArg {
+		if err := funcli.RunTunnelSession(os.Stdin); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cliConfig := funcli.NewCliConfig(funcie.Version())
 
 	argConfig := arg.Config{
 		Program: "funcie",
@@ -40,6 +48,10 @@ func main() {
 
 	parser.MustParse(os.Args[1:])
 
+	if err := cliConfig.ResolveEnvironment(); err != nil {
+		parser.Fail(err.Error())
+	}
+
 	cli, err := makeCli(cliConfig)
 	if err != nil {
 		fmt.Println("Failed to initialize CLI:", err)
@@ -74,19 +86,30 @@ func makeCli(cliConfig *funcli.CliConfig) (*cli, error) {
 			fx.Annotate(ssm.NewFromConfig, fx.As(new(funcliAws.SsmClient))),
 			fx.Annotate(ec2.NewFromConfig, fx.As(new(funcliAws.EC2Client))),
 			fx.Annotate(elasticache.NewFromConfig, fx.As(new(funcliAws.ElastiCacheClient))),
+			fx.Annotate(sts.NewFromConfig, fx.As(new(funcliAws.StsClient))),
+			fx.Annotate(iam.NewFromConfig, fx.As(new(funcliAws.IamClient))),
 			loadAwsConfig,
 			funcli.NewConfigStore,
 			funcli.NewConnectCommand,
+			funcli.NewDisconnectCommand,
 			funcli.NewInitCommand,
 			newCli,
 			funcli.NewSsmTunneller,
 			funcli.NewHttpConnectivityService,
 			funcliAws.NewAwsResourceLister,
+			funcliAws.NewAwsPermissionChecker,
 			tools.NewProcessRunner,
 			tools.NewGitCliClient,
 			tools.NewTerraformCliClient,
 			tools.NewDockerCliClient,
 			funcli.NewDestroyCommand,
+			funcli.NewUpgradeCommand,
+			funcli.NewTailCommand,
+			funcli.NewDevCommand,
+			funcli.NewBastionCommand,
+			funcli.NewBastionContainerManager,
+			funcli.NewEnvCommand,
+			funcli.NewDoctorCommand,
 		),
 		fx.NopLogger,
 		fx.Populate(&res),
@@ -120,15 +143,42 @@ func loadAwsConfig(cliConfig *funcli.CliConfig) (aws.Config, error) {
 func newCli(
 	conf *funcli.CliConfig,
 	connectCmd *funcli.ConnectCommand,
+	disconnectCmd *funcli.DisconnectCommand,
 	initCmd *funcli.InitCommand,
 	destroyCmd *funcli.DestroyCommand,
+	upgradeCmd *funcli.UpgradeCommand,
+	tailCmd *funcli.TailCommand,
+	devCmd *funcli.DevCommand,
+	bastionCmd *funcli.BastionCommand,
+	envCmd *funcli.EnvCommand,
+	doctorCmd *funcli.DoctorCommand,
 ) *cli {
 	inst := &cli{
 		commands: make(map[interface{}]Runnable),
 	}
 	inst.RegisterCommand(conf.ConnectConfig, connectCmd)
+	inst.RegisterCommand(conf.DisconnectConfig, disconnectCmd)
 	inst.RegisterCommand(conf.InitConfig, initCmd)
 	inst.RegisterCommand(conf.DestroyConfig, destroyCmd)
+	inst.RegisterCommand(conf.UpgradeConfig, upgradeCmd)
+	inst.RegisterCommand(conf.TailConfig, tailCmd)
+	inst.RegisterCommand(conf.DevConfig, devCmd)
+	inst.RegisterCommand(conf.BastionConfig, bastionCmd)
+	if conf.BastionConfig != nil {
+		// The bastion command handles its own subcommands, which the parser reports instead of the bastion config.
+		inst.RegisterCommand(conf.BastionConfig.Stop, bastionCmd)
+		inst.RegisterCommand(conf.BastionConfig.Logs, bastionCmd)
+		inst.RegisterCommand(conf.BastionConfig.Restart, bastionCmd)
+		inst.RegisterCommand(conf.BastionConfig.Upgrade, bastionCmd)
+	}
+	inst.RegisterCommand(conf.EnvConfig, envCmd)
+	if conf.EnvConfig != nil {
+		inst.RegisterCommand(conf.EnvConfig.List, envCmd)
+		inst.RegisterCommand(conf.EnvConfig.Use, envCmd)
+		inst.RegisterCommand(conf.EnvConfig.Show, envCmd)
+		inst.RegisterCommand(conf.EnvConfig.Remove, envCmd)
+	}
+	inst.RegisterCommand(conf.DoctorConfig, doctorCmd)
 
 	return inst
 }
@@ -0,0 +1,18 @@
+#!/bin/sh
+# Builds the funcie Lambda layer, which contains the extension and the wrapper script that points runtimes at it.
+# Usage: build-layer.sh [arch] [output], where arch is amd64 or arm64 (defaults to amd64).
+set -e
+
+ARCH="${1:-amd64}"
+OUTPUT="$(realpath "${2:-funcie-layer-$ARCH.zip}")"
+ROOT="$(cd "$(dirname "$0")/../.." && pwd)"
+BUILD_DIR="$(mktemp -d)"
+trap 'rm -rf "$BUILD_DIR"' EXIT
+
+mkdir -p "$BUILD_DIR/extensions"
+(cd "$ROOT" && CGO_ENABLED=0 GOOS=linux GOARCH="$ARCH" go build -o "$BUILD_DIR/extensions/funcie" ./cmd/lambda-extension)
+cp "$ROOT/cmd/lambda-extension/funcie-wrapper" "$BUILD_DIR/funcie-wrapper"
+
+rm -f "$OUTPUT"
+(cd "$BUILD_DIR" && zip -qr "$OUTPUT" extensions funcie-wrapper)
+echo "Built $OUTPUT"
@@ -0,0 +1,52 @@
+package extension
+
+import (
+	"fmt"
+	"os"
+)
+
+// Config is the configuration of the funcie Lambda extension.
+type Config struct {
+	// RuntimeApiAddress is the address of the Lambda Runtime and Extensions APIs, as host:port.
+	RuntimeApiAddress string `json:"runtimeApiAddress"`
+	// ListenAddress is the address that the proxied Runtime API is served on for the runtime of the function.
+	// The funcie wrapper script points the runtime at this address.
+	ListenAddress string `json:"listenAddress"`
+	// ApplicationId is the ID of the application that invocations are forwarded for.
+	ApplicationId string `json:"applicationId"`
+}
+
+// NewConfigFromEnvironment creates a new Config from environment variables.
+// The following environment variables are used:
+//
+//	AWS_LAMBDA_RUNTIME_API (required; set by Lambda)
+//	FUNCIE_EXTENSION_LISTEN_ADDRESS (optional; defaults to 127.0.0.1:9140)
+//	FUNCIE_APPLICATION_ID (optional; defaults to AWS_LAMBDA_FUNCTION_NAME)
+func NewConfigFromEnvironment() *Config {
+	applicationId := os.Getenv("FUNCIE_APPLICATION_ID")
+	if applicationId == "" {
+		applicationId = requiredEnv("AWS_LAMBDA_FUNCTION_NAME")
+	}
+
+	return &Config{
+		RuntimeApiAddress: requiredEnv("AWS_LAMBDA_RUNTIME_API"),
+		ListenAddress:     optionalEnv("FUNCIE_EXTENSION_LISTEN_ADDRESS", "127.0.0.1:9140"),
+		ApplicationId:     applicationId,
+	}
+}
+
+func requiredEnv(name string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		panic(fmt.Sprintf("required environment variable %s not set", name))
+	}
+	return value
+}
+
+func optionalEnv(name string, defaultValue string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,43 @@
+package extension_test
+
+import (
+	"github.com/Kapps/funcie/cmd/lambda-extension/extension"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewConfigFromEnvironment(t *testing.T) {
+	t.Run("should default the application ID to the function name", func(t *testing.T) {
+		t.Setenv("AWS_LAMBDA_RUNTIME_API", "127.0.0.1:9001")
+		t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "function")
+		t.Setenv("FUNCIE_APPLICATION_ID", "")
+
+		config := extension.NewConfigFromEnvironment()
+
+		require.Equal(t, "127.0.0.1:9001", config.RuntimeApiAddress)
+		require.Equal(t, "127.0.0.1:9140", config.ListenAddress)
+		require.Equal(t, "function", config.ApplicationId)
+	})
+
+	t.Run("should not require the function name when the application ID is set", func(t *testing.T) {
+		t.Setenv("AWS_LAMBDA_RUNTIME_API", "127.0.0.1:9001")
+		t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "")
+		t.Setenv("FUNCIE_APPLICATION_ID", "app")
+		t.Setenv("FUNCIE_EXTENSION_LISTEN_ADDRESS", "127.0.0.1:9200")
+
+		config := extension.NewConfigFromEnvironment()
+
+		require.Equal(t, "127.0.0.1:9200", config.ListenAddress)
+		require.Equal(t, "app", config.ApplicationId)
+	})
+
+	t.Run("should panic when neither the application ID nor function name are set", func(t *testing.T) {
+		t.Setenv("AWS_LAMBDA_RUNTIME_API", "127.0.0.1:9001")
+		t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "")
+		t.Setenv("FUNCIE_APPLICATION_ID", "")
+
+		require.PanicsWithValue(t, "required environment variable AWS_LAMBDA_FUNCTION_NAME not set", func() {
+			extension.NewConfigFromEnvironment()
+		})
+	})
+}
@@ -0,0 +1,112 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const extensionApiPathPrefix = "/2020-01-01/extension/"
+
+// EventTypeShutdown is the type of the event sent to extensions when the execution environment is shutting down.
+const EventTypeShutdown = "SHUTDOWN"
+
+// Event is a lifecycle event sent to an extension by the Lambda Extensions API.
+type Event struct {
+	// EventType is the type of the event, such as EventTypeShutdown.
+	EventType string `json:"eventType"`
+	// DeadlineMs is the time, in milliseconds since the epoch, that the extension must finish handling the event by.
+	DeadlineMs int64 `json:"deadlineMs"`
+	// ShutdownReason is the reason for a shutdown event, such as "spindown" or "timeout".
+	ShutdownReason string `json:"shutdownReason,omitempty"`
+}
+
+// ExtensionClient communicates with the Lambda Extensions API, which manages the lifecycle of an external extension.
+type ExtensionClient interface {
+	// Register registers the extension with the given name, which must match the file name of the extension.
+	// The extension is only registered for shutdown events, as invocations are intercepted through the Runtime API instead.
+	Register(ctx context.Context, name string) error
+	// NextEvent signals that the extension is ready, and waits for the next lifecycle event.
+	NextEvent(ctx context.Context) (*Event, error)
+}
+
+type httpExtensionClient struct {
+	address     string
+	client      *http.Client
+	extensionId string
+}
+
+// NewExtensionClient creates a new ExtensionClient for the Extensions API at the given address, as host:port.
+func NewExtensionClient(address string, client *http.Client) ExtensionClient {
+	return &httpExtensionClient{
+		address: address,
+		client:  client,
+	}
+}
+
+func (c *httpExtensionClient) Register(ctx context.Context, name string) error {
+	body, err := json.Marshal(map[string][]string{"events": {EventTypeShutdown}})
+	if err != nil {
+		return fmt.Errorf("marshal registration: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url("register"), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create registration request: %w", err)
+	}
+	req.Header.Set("Lambda-Extension-Name", name)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("register extension %v: %w", name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("register extension %v: unexpected status code %d: %s", name, resp.StatusCode, string(responseBody))
+	}
+
+	c.extensionId = resp.Header.Get("Lambda-Extension-Identifier")
+	if c.extensionId == "" {
+		return fmt.Errorf("register extension %v: no extension identifier in response", name)
+	}
+
+	return nil
+}
+
+func (c *httpExtensionClient) NextEvent(ctx context.Context) (*Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("event/next"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create next event request: %w", err)
+	}
+	req.Header.Set("Lambda-Extension-Identifier", c.extensionId)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get next event: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read next event: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get next event: unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("unmarshal next event: %w", err)
+	}
+
+	return &event, nil
+}
+
+func (c *httpExtensionClient) url(path string) string {
+	return fmt.Sprintf("http://%v%v%v", c.address, extensionApiPathPrefix, path)
+}
@@ -0,0 +1,160 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/clients/go/funcietunnel"
+	"github.com/Kapps/funcie/pkg/funcie/runtimeapi"
+	"github.com/aws/aws-lambda-go/lambda"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxFunctionTimeout is the longest timeout that a Lambda function can have.
+// Invocations use the deadline from the Runtime API, so this only bounds how long the runtime has to poll for the first one.
+const maxFunctionTimeout = 15 * time.Minute
+
+// ForwardingErrorType is the error type reported to Lambda for invocations that failed within funcie,
+// such as when the local handler returned an error or the fallback policy did not allow the deployed handler to run.
+const ForwardingErrorType = "Funcie.ForwardingError"
+
+// RuntimeProxy sits between the Lambda Runtime API and the runtime of the function, so that invocations can be
+// forwarded to the server bastion without any changes to the function's code.
+// Invocations that no local consumer handles are passed on to the runtime, according to the fallback policy.
+type RuntimeProxy interface {
+	// Handler returns the http.Handler serving the Runtime API to the runtime of the function.
+	Handler() http.Handler
+	// Run receives invocations from the Lambda Runtime API and posts their results, until the context is cancelled.
+	// Invocations are only received once the runtime of the function has finished initializing and polls for one.
+	Run(ctx context.Context) error
+}
+
+type runtimeProxy struct {
+	upstream     RuntimeClient
+	runtime      runtimeapi.Runtime
+	handler      lambda.Handler
+	initError    http.Handler
+	runtimeReady chan struct{}
+	readyOnce    sync.Once
+	logger       *slog.Logger
+}
+
+// NewRuntimeProxy creates a new RuntimeProxy that receives invocations from upstream and forwards them with the client.
+// The options control how invocations are routed, in the same way as for funcietunnel.NewLambdaFunctionProxy.
+func NewRuntimeProxy(
+	config *Config,
+	upstream RuntimeClient,
+	client funcietunnel.BastionClient,
+	logger *slog.Logger,
+	opts ...funcietunnel.ProxyOptionSetter,
+) RuntimeProxy {
+	runtime := runtimeapi.NewRuntime(
+		logger,
+		runtimeapi.WithFunctionName(config.ApplicationId),
+		runtimeapi.WithTimeout(maxFunctionTimeout),
+	)
+
+	// The deployed handler is the runtime of the function, which receives the invocation through the proxied Runtime API.
+	deployed := func(ctx context.Context, payload json.RawMessage) (io.Reader, error) {
+		response, err := runtime.Invoke(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(response), nil
+	}
+
+	return &runtimeProxy{
+		upstream:     upstream,
+		runtime:      runtime,
+		handler:      funcietunnel.NewLambdaHandler(config.ApplicationId, client, deployed, logger, opts...),
+		initError:    httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: config.RuntimeApiAddress}),
+		runtimeReady: make(chan struct{}),
+		logger:       logger,
+	}
+}
+
+func (p *runtimeProxy) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case runtimeApiPathPrefix + "init/error":
+			// Lambda has to know that the runtime failed to initialize, so that the sandbox is recreated.
+			p.initError.ServeHTTP(w, req)
+			return
+		case runtimeApiPathPrefix + "invocation/next":
+			p.readyOnce.Do(func() {
+				close(p.runtimeReady)
+			})
+		}
+
+		p.runtime.ServeHTTP(w, req)
+	})
+}
+
+func (p *runtimeProxy) Run(ctx context.Context) error {
+	// Polling for an invocation tells Lambda that initialization is complete, so wait until the runtime has initialized.
+	select {
+	case <-p.runtimeReady:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.logger.InfoContext(ctx, "runtime initialized; waiting for invocations")
+
+	for {
+		invocation, err := p.upstream.Next(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("get next invocation: %w", err)
+		}
+
+		p.invoke(ctx, invocation)
+	}
+}
+
+// invoke handles a single invocation, posting its result to the Runtime API.
+func (p *runtimeProxy) invoke(ctx context.Context, invocation *Invocation) {
+	p.logger.DebugContext(ctx, "received invocation", "requestId", invocation.RequestId)
+
+	invocationCtx := runtimeapi.ContextWithInvocationHeader(ctx, invocation.Header)
+	if !invocation.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		invocationCtx, cancel = context.WithDeadline(invocationCtx, invocation.Deadline)
+		defer cancel()
+	}
+
+	response, err := p.handler.Invoke(invocationCtx, invocation.Payload)
+	if err != nil {
+		p.logger.DebugContext(ctx, "invocation failed", "requestId", invocation.RequestId, "error", err)
+		if err := p.upstream.Fail(ctx, invocation.RequestId, toFunctionError(err)); err != nil {
+			p.logger.ErrorContext(ctx, "failed to post invocation error", "requestId", invocation.RequestId, "error", err)
+		}
+		return
+	}
+
+	if err := p.upstream.Respond(ctx, invocation.RequestId, response); err != nil {
+		p.logger.ErrorContext(ctx, "failed to post invocation response", "requestId", invocation.RequestId, "error", err)
+	}
+}
+
+// toFunctionError returns the error reported by the runtime of the function, or otherwise wraps an error from funcie.
+func toFunctionError(err error) *runtimeapi.FunctionError {
+	var functionErr *runtimeapi.FunctionError
+	if errors.As(err, &functionErr) {
+		return functionErr
+	}
+
+	return &runtimeapi.FunctionError{
+		Message: err.Error(),
+		Type:    ForwardingErrorType,
+	}
+}
@@ -0,0 +1,178 @@
+package extension_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/Kapps/funcie/clients/go/funcietunnel/mocks"
+	"github.com/Kapps/funcie/cmd/lambda-extension/extension"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRuntimeProxy(t *testing.T) {
+	// startProxy starts a proxy between the fake Lambda and a fake runtime, returning the URL of the proxied Runtime API.
+	startProxy := func(t *testing.T, fake *fakeLambda, client *mocks.BastionClient) string {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		config := &extension.Config{RuntimeApiAddress: fake.address(), ApplicationId: "app"}
+		proxy := extension.NewRuntimeProxy(config, extension.NewRuntimeClient(fake.address(), &http.Client{}), client, slog.Default())
+		server := httptest.NewServer(proxy.Handler())
+		t.Cleanup(server.Close)
+		t.Cleanup(cancel)
+
+		go func() {
+			_ = proxy.Run(ctx)
+		}()
+
+		return server.URL
+	}
+
+	// waitForRuntime polls the proxy as a runtime that never handles an invocation would, so that the proxy starts.
+	waitForRuntime := func(t *testing.T, runtimeUrl string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, runtimeUrl+"/2018-06-01/runtime/invocation/next", nil)
+		require.NoError(t, err)
+		go func() {
+			resp, err := http.DefaultClient.Do(req)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+	}
+
+	noConsumer := func(_ context.Context, message *funcie.Message) (*funcie.Response, error) {
+		return funcie.NewResponse(message.ID, nil, funcie.ErrNoActiveConsumer), nil
+	}
+
+	t.Run("should pass invocations to the runtime when no consumer is active", func(t *testing.T) {
+		fake := newFakeLambda(t)
+		client := mocks.NewBastionClient(t)
+		client.EXPECT().SendRequest(mock.Anything, mock.Anything).RunAndReturn(noConsumer).Once()
+		runtimeUrl := startProxy(t, fake, client)
+
+		fake.invocations <- fakeInvocation{requestId: "request-1", payload: `{"name":"funcie"}`}
+
+		resp, err := http.Get(runtimeUrl + "/2018-06-01/runtime/invocation/next")
+		require.NoError(t, err)
+		payload, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		require.Equal(t, `{"name":"funcie"}`, string(payload))
+		require.Equal(t, "request-1", resp.Header.Get("Lambda-Runtime-Aws-Request-Id"))
+		require.Equal(t, "trace-request-1", resp.Header.Get("Lambda-Runtime-Trace-Id"))
+
+		postResult(t, runtimeUrl, "request-1", "response", `{"greeting":"hello"}`)
+
+		result := fake.nextResult(t)
+		require.Equal(t, fakeResult{requestId: "request-1", action: "response", body: `{"greeting":"hello"}`}, result)
+	})
+
+	t.Run("should respond with the result of the local handler", func(t *testing.T) {
+		fake := newFakeLambda(t)
+		client := mocks.NewBastionClient(t)
+		client.EXPECT().SendRequest(mock.Anything, mock.Anything).RunAndReturn(
+			func(_ context.Context, message *funcie.Message) (*funcie.Response, error) {
+				request, err := funcie.UnmarshalMessagePayload[messages.ForwardRequestMessage](message)
+				require.NoError(t, err)
+				require.Equal(t, "app", request.Application)
+				require.JSONEq(t, `{"name":"funcie"}`, string(request.Payload.Body))
+
+				payload := messages.NewForwardRequestResponsePayload(json.RawMessage(`{"greeting":"local"}`))
+				return funcie.MarshalResponsePayload(funcie.NewResponseWithPayload(message.ID, payload, nil))
+			}).Once()
+		runtimeUrl := startProxy(t, fake, client)
+		waitForRuntime(t, runtimeUrl)
+
+		fake.invocations <- fakeInvocation{requestId: "request-1", payload: `{"name":"funcie"}`}
+
+		result := fake.nextResult(t)
+		require.Equal(t, fakeResult{requestId: "request-1", action: "response", body: `{"greeting":"local"}`}, result)
+	})
+
+	t.Run("should report errors from the runtime", func(t *testing.T) {
+		fake := newFakeLambda(t)
+		client := mocks.NewBastionClient(t)
+		client.EXPECT().SendRequest(mock.Anything, mock.Anything).RunAndReturn(noConsumer).Once()
+		runtimeUrl := startProxy(t, fake, client)
+
+		fake.invocations <- fakeInvocation{requestId: "request-1", payload: `{}`}
+
+		resp, err := http.Get(runtimeUrl + "/2018-06-01/runtime/invocation/next")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		postResult(t, runtimeUrl, "request-1", "error", `{"errorMessage":"boom","errorType":"Failure"}`)
+
+		result := fake.nextResult(t)
+		require.Equal(t, "error", result.action)
+		require.Equal(t, "Failure", result.errorType)
+		require.JSONEq(t, `{"errorMessage":"boom","errorType":"Failure"}`, result.body)
+	})
+
+	t.Run("should report errors from the local handler", func(t *testing.T) {
+		fake := newFakeLambda(t)
+		client := mocks.NewBastionClient(t)
+		client.EXPECT().SendRequest(mock.Anything, mock.Anything).RunAndReturn(
+			func(_ context.Context, message *funcie.Message) (*funcie.Response, error) {
+				return funcie.NewResponse(message.ID, nil, funcie.NewProxyError("local failure")), nil
+			}).Once()
+		runtimeUrl := startProxy(t, fake, client)
+		waitForRuntime(t, runtimeUrl)
+
+		fake.invocations <- fakeInvocation{requestId: "request-1", payload: `{}`}
+
+		result := fake.nextResult(t)
+		require.Equal(t, "error", result.action)
+		require.Equal(t, extension.ForwardingErrorType, result.errorType)
+		require.Contains(t, result.body, "local failure")
+	})
+
+	t.Run("should pass initialization errors to Lambda", func(t *testing.T) {
+		fake := newFakeLambda(t)
+		runtimeUrl := startProxy(t, fake, mocks.NewBastionClient(t))
+
+		resp, err := http.Post(runtimeUrl+"/2018-06-01/runtime/init/error", "application/json",
+			bytes.NewReader([]byte(`{"errorMessage":"bad config","errorType":"Init"}`)))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		result := fake.nextResult(t)
+		require.Equal(t, "init/error", result.action)
+		require.JSONEq(t, `{"errorMessage":"bad config","errorType":"Init"}`, result.body)
+	})
+}
+
+func TestExtensionClient(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeLambda(t)
+	client := extension.NewExtensionClient(fake.address(), &http.Client{})
+
+	require.NoError(t, client.Register(ctx, "funcie"))
+	require.Equal(t, "funcie", <-fake.registered)
+
+	fake.events <- extension.Event{EventType: extension.EventTypeShutdown, ShutdownReason: "spindown"}
+	event, err := client.NextEvent(ctx)
+	require.NoError(t, err)
+	require.Equal(t, extension.EventTypeShutdown, event.EventType)
+	require.Equal(t, "spindown", event.ShutdownReason)
+}
+
+// postResult posts the result of an invocation to the proxied Runtime API, as a runtime would.
+func postResult(t *testing.T, runtimeUrl string, requestId string, action string, body string) {
+	url := runtimeUrl + "/2018-06-01/runtime/invocation/" + requestId + "/" + action
+	resp, err := http.Post(url, "application/json", bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
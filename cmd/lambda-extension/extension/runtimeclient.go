@@ -0,0 +1,130 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie/runtimeapi"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const runtimeApiPathPrefix = "/2018-06-01/runtime/"
+
+// Invocation is an invocation received from the Lambda Runtime API.
+type Invocation struct {
+	// RequestId is the ID of the invocation, which its result must be posted for.
+	RequestId string
+	// Deadline is when the invocation times out.
+	Deadline time.Time
+	// Header contains the Lambda-Runtime-* headers of the invocation, such as its trace ID and client context.
+	Header http.Header
+	// Payload is the event that the function was invoked with.
+	Payload []byte
+}
+
+// RuntimeClient communicates with the Lambda Runtime API on behalf of the runtime of the function.
+type RuntimeClient interface {
+	// Next waits for the next invocation of the function.
+	Next(ctx context.Context) (*Invocation, error)
+	// Respond posts the response of the invocation with the given request ID.
+	Respond(ctx context.Context, requestId string, body []byte) error
+	// Fail posts the error of the invocation with the given request ID.
+	Fail(ctx context.Context, requestId string, functionErr *runtimeapi.FunctionError) error
+}
+
+type httpRuntimeClient struct {
+	address string
+	client  *http.Client
+}
+
+// NewRuntimeClient creates a new RuntimeClient for the Runtime API at the given address, as host:port.
+func NewRuntimeClient(address string, client *http.Client) RuntimeClient {
+	return &httpRuntimeClient{
+		address: address,
+		client:  client,
+	}
+}
+
+func (c *httpRuntimeClient) Next(ctx context.Context) (*Invocation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url("invocation/next"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create next invocation request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get next invocation: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read next invocation: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get next invocation: unexpected status code %d: %s", resp.StatusCode, string(payload))
+	}
+
+	invocation := &Invocation{
+		RequestId: resp.Header.Get("Lambda-Runtime-Aws-Request-Id"),
+		Header:    http.Header{},
+		Payload:   payload,
+	}
+	for name, values := range resp.Header {
+		if strings.HasPrefix(name, "Lambda-Runtime-") {
+			invocation.Header[name] = values
+		}
+	}
+	if deadlineMs, err := strconv.ParseInt(resp.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64); err == nil {
+		invocation.Deadline = time.UnixMilli(deadlineMs)
+	}
+
+	return invocation, nil
+}
+
+func (c *httpRuntimeClient) Respond(ctx context.Context, requestId string, body []byte) error {
+	return c.post(ctx, fmt.Sprintf("invocation/%v/response", requestId), body, nil)
+}
+
+func (c *httpRuntimeClient) Fail(ctx context.Context, requestId string, functionErr *runtimeapi.FunctionError) error {
+	body, err := json.Marshal(functionErr)
+	if err != nil {
+		return fmt.Errorf("marshal error: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("Lambda-Runtime-Function-Error-Type", functionErr.Type)
+	return c.post(ctx, fmt.Sprintf("invocation/%v/error", requestId), body, header)
+}
+
+func (c *httpRuntimeClient) post(ctx context.Context, path string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url(path), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request to %v: %w", path, err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to %v: %w", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusAccepted {
+		responseBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("post to %v: unexpected status code %d: %s", path, resp.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
+
+func (c *httpRuntimeClient) url(path string) string {
+	return fmt.Sprintf("http://%v%v%v", c.address, runtimeApiPathPrefix, path)
+}
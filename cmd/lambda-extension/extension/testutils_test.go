@@ -0,0 +1,104 @@
+package extension_test
+
+import (
+	"encoding/json"
+	"github.com/Kapps/funcie/cmd/lambda-extension/extension"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeLambda is a fake of the Lambda Runtime and Extensions APIs, which queues invocations and records their results.
+type fakeLambda struct {
+	server      *httptest.Server
+	invocations chan fakeInvocation
+	results     chan fakeResult
+	events      chan extension.Event
+	registered  chan string
+}
+
+type fakeInvocation struct {
+	requestId string
+	payload   string
+}
+
+type fakeResult struct {
+	requestId string
+	action    string
+	errorType string
+	body      string
+}
+
+func newFakeLambda(t *testing.T) *fakeLambda {
+	fake := &fakeLambda{
+		invocations: make(chan fakeInvocation, 10),
+		results:     make(chan fakeResult, 10),
+		events:      make(chan extension.Event, 10),
+		registered:  make(chan string, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/2020-01-01/extension/register", func(w http.ResponseWriter, req *http.Request) {
+		fake.registered <- req.Header.Get("Lambda-Extension-Name")
+		w.Header().Set("Lambda-Extension-Identifier", "extension-id")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/2020-01-01/extension/event/next", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Lambda-Extension-Identifier") != "extension-id" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		select {
+		case event := <-fake.events:
+			_ = json.NewEncoder(w).Encode(event)
+		case <-req.Context().Done():
+		}
+	})
+	mux.HandleFunc("/2018-06-01/runtime/invocation/next", func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case invocation := <-fake.invocations:
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", invocation.requestId)
+			w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10))
+			w.Header().Set("Lambda-Runtime-Trace-Id", "trace-"+invocation.requestId)
+			_, _ = w.Write([]byte(invocation.payload))
+		case <-req.Context().Done():
+		}
+	})
+	mux.HandleFunc("/2018-06-01/runtime/", func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		path := strings.TrimPrefix(req.URL.Path, "/2018-06-01/runtime/")
+		result := fakeResult{action: path, errorType: req.Header.Get("Lambda-Runtime-Function-Error-Type"), body: string(body)}
+		if requestPath, ok := strings.CutPrefix(path, "invocation/"); ok {
+			result.requestId, result.action, _ = strings.Cut(requestPath, "/")
+		}
+		fake.results <- result
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	fake.server = httptest.NewServer(mux)
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+// address returns the address of the fake APIs, in the host:port format of AWS_LAMBDA_RUNTIME_API.
+func (f *fakeLambda) address() string {
+	return strings.TrimPrefix(f.server.URL, "http://")
+}
+
+// nextResult waits for the next result posted to the fake Runtime API.
+func (f *fakeLambda) nextResult(t *testing.T) fakeResult {
+	select {
+	case result := <-f.results:
+		return result
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for a result")
+		return fakeResult{}
+	}
+}
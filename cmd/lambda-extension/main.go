@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/clients/go/funcietunnel"
+	"github.com/Kapps/funcie/cmd/lambda-extension/extension"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+func main() {
+	funcie.ConfigureLogging()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx); err != nil {
+		slog.ErrorContext(ctx, "funcie extension failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context) error {
+	conf := extension.NewConfigFromEnvironment()
+	funcieConfig, err := loadFuncieConfig(ctx, conf.ApplicationId)
+	if err != nil {
+		return err
+	}
+
+	logger := slog.Default()
+	proxy := extension.NewRuntimeProxy(
+		conf,
+		extension.NewRuntimeClient(conf.RuntimeApiAddress, &http.Client{}),
+		funcietunnel.NewHTTPBastionClient(funcieConfig.ServerBastionEndpoint, logger),
+		logger,
+		funcietunnel.WithFallbackPolicy(funcieConfig.FallbackPolicy),
+		funcietunnel.WithShadowMode(funcieConfig.ShadowMode, funcieConfig.ShadowTimeout),
+		funcietunnel.WithCircuitBreaker(funcieConfig.CircuitBreaker),
+	)
+
+	// Listen before registering, as the runtime starts once every extension has registered.
+	listener, err := net.Listen("tcp", conf.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("listen on %v: %w", conf.ListenAddress, err)
+	}
+	server := &http.Server{Handler: proxy.Handler()}
+	defer func() { _ = server.Close() }()
+
+	errs := make(chan error, 3)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("serve runtime API: %w", err)
+		}
+	}()
+
+	extensionClient := extension.NewExtensionClient(conf.RuntimeApiAddress, &http.Client{})
+	if err := extensionClient.Register(ctx, filepath.Base(os.Args[0])); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := proxy.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			errs <- err
+		}
+	}()
+
+	go func() {
+		for {
+			event, err := extensionClient.NextEvent(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if event.EventType == extension.EventTypeShutdown {
+				slog.InfoContext(ctx, "shutting down", "reason", event.ShutdownReason)
+				errs <- nil
+				return
+			}
+		}
+	}()
+
+	select {
+	case err = <-errs:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// loadFuncieConfig loads the funcie configuration in the same way as funcietunnel.Start,
+// falling back to SSM when the server bastion endpoint is not set.
+func loadFuncieConfig(ctx context.Context, applicationId string) (*funcietunnel.FuncieConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	awsConfig, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+
+	return funcietunnel.NewConfig(ctx, applicationId, ssm.NewFromConfig(awsConfig)), nil
+}
@@ -11,13 +11,16 @@ import (
 )
 
 type requestHandler struct {
-	publisher funcie.Publisher
+	publisher        funcie.Publisher
+	fallbackPolicies funcie.FallbackPolicyStore
 }
 
 // NewRequestHandler creates a new RequestHandler.
-func NewRequestHandler(publisher funcie.Publisher) transports.MessageHandler {
+// Fallback policy overrides from the store are included in every forwarded response, so the Lambda can apply them.
+func NewRequestHandler(publisher funcie.Publisher, fallbackPolicies funcie.FallbackPolicyStore) transports.MessageHandler {
 	return &requestHandler{
-		publisher: publisher,
+		publisher:        publisher,
+		fallbackPolicies: fallbackPolicies,
 	}
 }
 
@@ -29,6 +32,10 @@ func (r *requestHandler) Deregister(_ context.Context, _ messages.Deregistration
 	return nil, fmt.Errorf("deregister unsupported")
 }
 
+func (r *requestHandler) SetFallbackPolicy(_ context.Context, _ messages.SetFallbackPolicyMessage) (*messages.SetFallbackPolicyResponse, error) {
+	return nil, fmt.Errorf("set fallback policy unsupported")
+}
+
 func (r *requestHandler) ForwardRequest(ctx context.Context, message messages.ForwardRequestMessage) (*messages.ForwardRequestResponse, error) {
 	slog.DebugContext(ctx, "forwarding request", "message", &message)
 
@@ -41,9 +48,13 @@ func (r *requestHandler) ForwardRequest(ctx context.Context, message messages.Fo
 	if err != nil {
 		if errors.Is(err, funcie.ErrNoActiveConsumer) || errors.Is(err, funcie.ErrApplicationNotFound) {
 			// If the application is not found, return a successful response with the not found error.
-			return funcie.NewResponseWithPayload[messages.ForwardRequestResponsePayload](
+			response := funcie.NewResponseWithPayload[messages.ForwardRequestResponsePayload](
 				message.ID, nil, funcie.ErrNoActiveConsumer,
-			), nil
+			)
+			if policy := r.getFallbackPolicy(ctx, message.Application); policy != "" {
+				response.Data = &messages.ForwardRequestResponsePayload{FallbackPolicy: policy}
+			}
+			return response, nil
 		}
 		return nil, fmt.Errorf("publish request: %w", err)
 	}
@@ -53,5 +64,21 @@ func (r *requestHandler) ForwardRequest(ctx context.Context, message messages.Fo
 		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
+	if policy := r.getFallbackPolicy(ctx, message.Application); policy != "" {
+		unmarshaledResp.Data.FallbackPolicy = policy
+	}
+
 	return unmarshaledResp, nil
 }
+
+// getFallbackPolicy returns the fallback policy override for the application, or an empty policy if there is none.
+// Failing to load the override is not fatal, as the Lambda falls back to its configured policy.
+func (r *requestHandler) getFallbackPolicy(ctx context.Context, applicationName string) funcie.FallbackPolicy {
+	policy, err := r.fallbackPolicies.GetFallbackPolicy(ctx, applicationName)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to get fallback policy override", "application", applicationName, "error", err)
+		return ""
+	}
+
+	return policy
+}
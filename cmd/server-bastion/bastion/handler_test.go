@@ -14,7 +14,7 @@ func TestNewRequestHandler(t *testing.T) {
 	t.Parallel()
 
 	publisher := mocks.NewPublisher(t)
-	handler := NewRequestHandler(publisher)
+	handler := NewRequestHandler(publisher, mocks.NewFallbackPolicyStore(t))
 	require.NotNil(t, handler)
 }
 
@@ -22,7 +22,7 @@ func TestRegister(t *testing.T) {
 	t.Parallel()
 
 	publisher := mocks.NewPublisher(t)
-	handler := NewRequestHandler(publisher)
+	handler := NewRequestHandler(publisher, mocks.NewFallbackPolicyStore(t))
 
 	resp, err := handler.Register(context.TODO(), messages.RegistrationMessage{})
 	require.Nil(t, resp)
@@ -34,7 +34,7 @@ func TestDeregister(t *testing.T) {
 	t.Parallel()
 
 	publisher := mocks.NewPublisher(t)
-	handler := NewRequestHandler(publisher)
+	handler := NewRequestHandler(publisher, mocks.NewFallbackPolicyStore(t))
 
 	resp, err := handler.Deregister(context.TODO(), messages.DeregistrationMessage{})
 	require.Nil(t, resp)
@@ -46,9 +46,10 @@ func TestForwardRequest(t *testing.T) {
 	t.Parallel()
 
 	publisher := mocks.NewPublisher(t)
+	fallbackPolicies := mocks.NewFallbackPolicyStore(t)
 	ctx := context.Background()
 
-	handler := NewRequestHandler(publisher)
+	handler := NewRequestHandler(publisher, fallbackPolicies)
 
 	forwardPayload := messages.NewForwardRequestPayload([]byte("{}"))
 	forwardMessage := funcie.NewMessageWithPayload("app", messages.MessageKindForwardRequest, *forwardPayload)
@@ -64,6 +65,7 @@ func TestForwardRequest(t *testing.T) {
 		require.NoError(t, err)
 
 		publisher.EXPECT().Publish(ctx, marshaledForwardMessage).Return(marshaledResponse, nil).Once()
+		fallbackPolicies.EXPECT().GetFallbackPolicy(ctx, "app").Return("", nil).Once()
 
 		resp, err := handler.ForwardRequest(ctx, *forwardMessage)
 		require.NotNil(t, resp)
@@ -77,6 +79,7 @@ func TestForwardRequest(t *testing.T) {
 		)
 
 		publisher.EXPECT().Publish(ctx, marshaledForwardMessage).Return(nil, funcie.ErrNoActiveConsumer).Once()
+		fallbackPolicies.EXPECT().GetFallbackPolicy(ctx, "app").Return("", nil).Once()
 
 		resp, err := handler.ForwardRequest(ctx, *forwardMessage)
 		require.NoError(t, err)
@@ -92,6 +95,7 @@ func TestForwardRequest(t *testing.T) {
 		)
 
 		publisher.EXPECT().Publish(ctx, marshaledForwardMessage).Return(nil, funcie.ErrApplicationNotFound).Once()
+		fallbackPolicies.EXPECT().GetFallbackPolicy(ctx, "app").Return("", nil).Once()
 
 		resp, err := handler.ForwardRequest(ctx, *forwardMessage)
 		require.NoError(t, err)
@@ -99,4 +103,16 @@ func TestForwardRequest(t *testing.T) {
 
 		RequireEqualResponse(t, response, resp)
 	})
+
+	t.Run("fallback policy override", func(t *testing.T) {
+		publisher.EXPECT().Publish(ctx, marshaledForwardMessage).Return(nil, funcie.ErrNoActiveConsumer).Once()
+		fallbackPolicies.EXPECT().GetFallbackPolicy(ctx, "app").Return(funcie.FallbackPolicyNever, nil).Once()
+
+		resp, err := handler.ForwardRequest(ctx, *forwardMessage)
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+
+		require.Equal(t, funcie.ErrNoActiveConsumer.Error(), resp.Error.Message)
+		require.Equal(t, funcie.FallbackPolicyNever, resp.Data.FallbackPolicy)
+	})
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/Kapps/funcie/pkg/funcie/transports"
 	r "github.com/Kapps/funcie/pkg/funcie/transports/redis"
 	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
+	"github.com/Kapps/funcie/pkg/receiver"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/fx"
 	"log/slog"
@@ -23,6 +24,10 @@ func newRedisClient(config *bastion.Config) *redis.Client {
 	})
 }
 
+func newFallbackPolicyStore(redisClient *redis.Client) funcie.FallbackPolicyStore {
+	return receiver.NewRedisFallbackPolicyStore(redisClient)
+}
+
 func newPublisher(redisClient *redis.Client, config *bastion.Config) funcie.Publisher {
 	return r.NewPublisher(redisClient, config.RequestChannel)
 }
@@ -54,6 +59,7 @@ func main() {
 			bastion.NewConfigFromEnvironment,
 			newRedisClient,
 			newPublisher,
+			newFallbackPolicyStore,
 			bastion.NewRequestHandler,
 			newHost,
 			newMessageProcessor,
@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 // Endpoint represents a target destination for funcie requests or bastions.
@@ -17,6 +18,9 @@ type Endpoint struct {
 	Host string `json:"host"`
 	// Port is the port number of the endpoint.
 	Port int `json:"port"`
+	// Path is the optional path prefix of the endpoint, such as "/apps/my-app".
+	// This is used when multiple applications are served from the same host and port.
+	Path string `json:"path,omitempty"`
 }
 
 // NewEndpoint creates a new Endpoint with the given scheme, host, and port.
@@ -28,13 +32,14 @@ func NewEndpoint(scheme string, host string, port int) Endpoint {
 	}
 }
 
-// String returns a URL representation of the endpoint.
+// String returns a URL representation of the endpoint, without a trailing slash.
 func (e Endpoint) String() string {
-	return fmt.Sprintf("%v://%v:%v", e.Scheme, e.Host, e.Port)
+	return fmt.Sprintf("%v://%v:%v%v", e.Scheme, e.Host, e.Port, e.Path)
 }
 
 // NewEndpointFromAddress creates a new funcie Endpoint from parsing the given address.
-// Example: https://127.0.0.1:8080
+// Any path in the address is kept as the path prefix of the endpoint.
+// Example: https://127.0.0.1:8080/apps/my-app
 func NewEndpointFromAddress(address string) (Endpoint, error) {
 	parsed, err := url.Parse(address)
 	if err != nil {
@@ -51,7 +56,9 @@ func NewEndpointFromAddress(address string) (Endpoint, error) {
 		return Endpoint{}, fmt.Errorf("converting port %v to int: %w", port, err)
 	}
 
-	return NewEndpoint(parsed.Scheme, host, parsedPort), nil
+	endpoint := NewEndpoint(parsed.Scheme, host, parsedPort)
+	endpoint.Path = strings.TrimSuffix(parsed.Path, "/")
+	return endpoint, nil
 }
 
 // MustNewEndpointFromAddress creates a new funcie Endpoint from parsing the given address.
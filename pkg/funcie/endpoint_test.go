@@ -29,6 +29,14 @@ func TestNewEndpointFromAddress(t *testing.T) {
 		require.Equal(t, 8080, endpoint.Port)
 	})
 
+	t.Run("should keep the path of the address", func(t *testing.T) {
+		endpoint, err := NewEndpointFromAddress("http://127.0.0.1:8080/apps/my-app/")
+		require.NoError(t, err)
+
+		require.Equal(t, "/apps/my-app", endpoint.Path)
+		require.Equal(t, "http://127.0.0.1:8080/apps/my-app", endpoint.String())
+	})
+
 	t.Run("should return an error if the address is invalid", func(t *testing.T) {
 		_, err := NewEndpointFromAddress("invalid")
 		require.Error(t, err)
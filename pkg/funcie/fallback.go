@@ -0,0 +1,75 @@
+package funcie
+
+import (
+	"context"
+	"fmt"
+)
+
+// FallbackPolicy controls when a Lambda proxy handles a request using the deployed code rather than the local code.
+type FallbackPolicy string
+
+const (
+	// FallbackPolicyNever never runs the deployed code, instead returning an error if the request can not be proxied.
+	// This can be relaxed at runtime by a fallback policy override.
+	FallbackPolicyNever FallbackPolicy = "never"
+	// FallbackPolicyOnUnavailable runs the deployed code when no local consumer is available to handle the request.
+	FallbackPolicyOnUnavailable FallbackPolicy = "on-unavailable"
+	// FallbackPolicyOnError runs the deployed code when no local consumer is available, or when the local code returns an error.
+	FallbackPolicyOnError FallbackPolicy = "on-error"
+	// FallbackPolicyLocalOnly never runs the deployed code, and can not be overridden at runtime.
+	// This is intended for environments that must never run the deployed code.
+	FallbackPolicyLocalOnly FallbackPolicy = "local-only"
+)
+
+// DefaultFallbackPolicy is the fallback policy used when none is configured.
+const DefaultFallbackPolicy = FallbackPolicyOnUnavailable
+
+// FallbackPolicies returns all valid fallback policies.
+func FallbackPolicies() []FallbackPolicy {
+	return []FallbackPolicy{
+		FallbackPolicyNever,
+		FallbackPolicyOnUnavailable,
+		FallbackPolicyOnError,
+		FallbackPolicyLocalOnly,
+	}
+}
+
+// ParseFallbackPolicy parses the given string into a FallbackPolicy, returning an error if it is not a valid policy.
+func ParseFallbackPolicy(value string) (FallbackPolicy, error) {
+	for _, policy := range FallbackPolicies() {
+		if string(policy) == value {
+			return policy, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid fallback policy %q; expected one of %v", value, FallbackPolicies())
+}
+
+// FallbackWhenUnavailable returns whether the deployed code should run when no local consumer handles the request.
+func (p FallbackPolicy) FallbackWhenUnavailable() bool {
+	return p == FallbackPolicyOnUnavailable || p == FallbackPolicyOnError
+}
+
+// FallbackOnError returns whether the deployed code should run when the local code returns an error.
+func (p FallbackPolicy) FallbackOnError() bool {
+	return p == FallbackPolicyOnError
+}
+
+// WithOverride returns the policy that is in effect when the given runtime override is applied to this policy.
+// An empty override leaves the policy unchanged, and FallbackPolicyLocalOnly can not be overridden.
+func (p FallbackPolicy) WithOverride(override FallbackPolicy) FallbackPolicy {
+	if override == "" || p == FallbackPolicyLocalOnly {
+		return p
+	}
+
+	return override
+}
+
+// FallbackPolicyStore stores the runtime fallback policy overrides of applications.
+type FallbackPolicyStore interface {
+	// SetFallbackPolicy overrides the fallback policy of the given application.
+	// An empty policy removes the override.
+	SetFallbackPolicy(ctx context.Context, applicationName string, policy FallbackPolicy) error
+	// GetFallbackPolicy gets the fallback policy override of the given application, or an empty policy if there is none.
+	GetFallbackPolicy(ctx context.Context, applicationName string) (FallbackPolicy, error)
+}
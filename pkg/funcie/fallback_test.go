@@ -0,0 +1,26 @@
+package funcie_test
+
+import (
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseFallbackPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, err := funcie.ParseFallbackPolicy("on-error")
+	require.NoError(t, err)
+	require.Equal(t, funcie.FallbackPolicyOnError, policy)
+
+	_, err = funcie.ParseFallbackPolicy("sometimes")
+	require.ErrorContains(t, err, "invalid fallback policy")
+}
+
+func TestFallbackPolicy_WithOverride(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, funcie.FallbackPolicyNever, funcie.FallbackPolicyNever.WithOverride(""))
+	require.Equal(t, funcie.FallbackPolicyOnError, funcie.FallbackPolicyNever.WithOverride(funcie.FallbackPolicyOnError))
+	require.Equal(t, funcie.FallbackPolicyLocalOnly, funcie.FallbackPolicyLocalOnly.WithOverride(funcie.FallbackPolicyOnError))
+}
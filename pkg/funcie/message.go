@@ -25,6 +25,9 @@ type MessageBase[T any] struct {
 	Payload T `json:"payload"`
 	// Created is the time the message was created.
 	Created time.Time `json:"created"`
+	// Protocol describes the protocol version and capabilities of the sender.
+	// This is nil for messages sent by versions of funcie that predate protocol negotiation.
+	Protocol *ProtocolInfo `json:"protocol,omitempty"`
 }
 
 // NewMessage creates a new message with the given payload.
@@ -36,6 +39,7 @@ func NewMessage(application string, kind MessageKind, payload []byte) *Message {
 		Kind:        kind,
 		Payload:     serialized,
 		Created:     time.Now().UTC().Truncate(time.Millisecond),
+		Protocol:    NewProtocolInfo(),
 	}
 }
 
@@ -47,6 +51,7 @@ func NewMessageWithPayload[T any](application string, kind MessageKind, payload
 		Kind:        kind,
 		Payload:     payload,
 		Created:     time.Now().UTC().Truncate(time.Millisecond),
+		Protocol:    NewProtocolInfo(),
 	}
 }
 
@@ -60,6 +65,7 @@ func UnmarshalMessagePayload[MessageType MessageBase[T], T any](message *Message
 
 	return &MessageType{
 		ID: message.ID, Kind: message.Kind, Application: message.Application, Payload: payload, Created: message.Created,
+		Protocol: message.Protocol,
 	}, nil
 }
 
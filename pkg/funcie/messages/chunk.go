@@ -0,0 +1,47 @@
+package messages
+
+import (
+	"github.com/Kapps/funcie/pkg/funcie"
+)
+
+// MessageKindResponseChunk is the kind of a message containing a piece of a streamed response.
+// Chunks are relayed in order through the ResponseStream of the response they belong to.
+const MessageKindResponseChunk funcie.MessageKind = "RESPONSE_CHUNK"
+
+// ResponseChunkMessage is a message containing a piece of a streamed response.
+type ResponseChunkMessage = funcie.MessageBase[ResponseChunkPayload]
+
+// ResponseChunkPayload is the payload for a single chunk of a streamed response.
+type ResponseChunkPayload struct {
+	// RequestId is the ID of the message that this chunk is a response to.
+	RequestId string `json:"requestId"`
+	// Sequence is the zero-based position of this chunk within the stream.
+	Sequence int `json:"sequence"`
+	// Data is the raw bytes of this chunk of the response body.
+	Data []byte `json:"data,omitempty"`
+	// Final indicates that this is the last chunk of the stream.
+	Final bool `json:"final,omitempty"`
+	// Error is set if the stream was terminated early due to an error.
+	// An error is only ever set on the final chunk.
+	Error *funcie.ProxyError `json:"error,omitempty"`
+}
+
+// NewResponseChunkPayload creates a new ResponseChunkPayload for the given request with the given data.
+func NewResponseChunkPayload(requestId string, sequence int, data []byte) *ResponseChunkPayload {
+	return &ResponseChunkPayload{
+		RequestId: requestId,
+		Sequence:  sequence,
+		Data:      data,
+	}
+}
+
+// NewFinalResponseChunkPayload creates a new ResponseChunkPayload that ends the stream for the given request.
+// If err is not nil, the stream is terminated with the given error.
+func NewFinalResponseChunkPayload(requestId string, sequence int, err error) *ResponseChunkPayload {
+	return &ResponseChunkPayload{
+		RequestId: requestId,
+		Sequence:  sequence,
+		Final:     true,
+		Error:     funcie.NewProxyErrorFromError(err),
+	}
+}
@@ -0,0 +1,36 @@
+package messages
+
+import (
+	"github.com/Kapps/funcie/pkg/funcie"
+)
+
+// MessageKindSetFallbackPolicy is a request to a client bastion to override the fallback policy of an application.
+const MessageKindSetFallbackPolicy funcie.MessageKind = "SET_FALLBACK_POLICY"
+
+// SetFallbackPolicyMessage is a message containing a request to override a fallback policy.
+type SetFallbackPolicyMessage = funcie.MessageBase[SetFallbackPolicyPayload]
+
+// SetFallbackPolicyResponse is a message containing the response to a fallback policy override.
+type SetFallbackPolicyResponse = funcie.ResponseBase[SetFallbackPolicyResponsePayload]
+
+// SetFallbackPolicyPayload is a request to override the fallback policy of the application the message is for.
+type SetFallbackPolicyPayload struct {
+	// Policy is the policy to use, or empty to remove the override and use the policy configured in the Lambda.
+	Policy funcie.FallbackPolicy `json:"policy"`
+}
+
+// NewSetFallbackPolicyPayload creates a new SetFallbackPolicyPayload with the given policy.
+func NewSetFallbackPolicyPayload(policy funcie.FallbackPolicy) *SetFallbackPolicyPayload {
+	return &SetFallbackPolicyPayload{
+		Policy: policy,
+	}
+}
+
+// SetFallbackPolicyResponsePayload is a response to a fallback policy override.
+type SetFallbackPolicyResponsePayload struct {
+}
+
+// NewSetFallbackPolicyResponsePayload creates a new SetFallbackPolicyResponsePayload.
+func NewSetFallbackPolicyResponsePayload() *SetFallbackPolicyResponsePayload {
+	return &SetFallbackPolicyResponsePayload{}
+}
@@ -17,11 +17,23 @@ type ForwardRequestResponse = funcie.ResponseBase[ForwardRequestResponsePayload]
 // ForwardRequestPayload is the payload for an invocation message.
 type ForwardRequestPayload struct {
 	Body json.RawMessage `json:"body"`
+	// Shadow is the result of the deployed handler when the request is forwarded in shadow mode.
+	// The local result is then only compared against it, and is not used to respond to the Lambda invocation.
+	Shadow *ShadowResult `json:"shadow,omitempty"`
 }
 
 // ForwardRequestResponsePayload is the payload for an invocation response.
 type ForwardRequestResponsePayload struct {
 	Body json.RawMessage `json:"body"`
+	// Streaming indicates that the body is not included in this payload, and is instead delivered as a
+	// sequence of RESPONSE_CHUNK messages in the stream that follows the response.
+	Streaming bool `json:"streaming,omitempty"`
+	// ContentType is the content type reported by a streaming handler, such as the Lambda HTTP integration response type.
+	ContentType string `json:"contentType,omitempty"`
+	// FallbackPolicy is the runtime override of the fallback policy for the application, if any.
+	// Unlike the rest of the payload, this is set by the server bastion even if the response has an error,
+	// so that the Lambda is able to apply the override when no consumer handled the request.
+	FallbackPolicy funcie.FallbackPolicy `json:"fallbackPolicy,omitempty"`
 }
 
 // NewForwardRequestPayload creates a new ForwardRequestPayload with the given body.
@@ -37,3 +49,12 @@ func NewForwardRequestResponsePayload(body json.RawMessage) *ForwardRequestRespo
 		Body: body,
 	}
 }
+
+// NewStreamingForwardRequestResponsePayload creates a new ForwardRequestResponsePayload for a response
+// whose body is streamed as RESPONSE_CHUNK messages with the given content type.
+func NewStreamingForwardRequestResponsePayload(contentType string) *ForwardRequestResponsePayload {
+	return &ForwardRequestResponsePayload{
+		Streaming:   true,
+		ContentType: contentType,
+	}
+}
@@ -14,6 +14,8 @@ type RegistrationMessage = funcie.MessageBase[RegistrationRequestPayload]
 // RegistrationResponse is a message containing a registration response.
 type RegistrationResponse = funcie.ResponseBase[RegistrationResponsePayload]
 
+// RegistrationRequestPayload is a request to register an application.
+// The protocol version and capabilities of the application are negotiated through the Protocol of the message.
 type RegistrationRequestPayload struct {
 	// Name is the name of the application.
 	Name string `json:"name"`
@@ -34,11 +36,16 @@ type RegistrationResponsePayload struct {
 	// RegistrationId is a unique ID that can be used to deregister the application.
 	// For now, this is unused.
 	RegistrationId uuid.UUID
+	// Protocol describes the protocol version, release, and capabilities of the bastion that handled the registration.
+	// This is nil if the bastion predates protocol negotiation.
+	Protocol *funcie.ProtocolInfo `json:"protocol,omitempty"`
 }
 
 // NewRegistrationResponsePayload creates a new RegistrationResponsePayload with the given registration ID.
+// The payload describes the protocol supported by this version of funcie.
 func NewRegistrationResponsePayload(registrationId uuid.UUID) *RegistrationResponsePayload {
 	return &RegistrationResponsePayload{
 		RegistrationId: registrationId,
+		Protocol:       funcie.NewProtocolInfo(),
 	}
 }
@@ -0,0 +1,90 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"reflect"
+	"time"
+)
+
+// ShadowResult is the result of either the deployed or local handler for a request that is forwarded in shadow mode.
+type ShadowResult struct {
+	// Body is the response of the handler, if it succeeded.
+	// This is not necessarily JSON, as streamed responses may contain arbitrary data.
+	Body []byte `json:"body,omitempty"`
+	// Error is the error returned by the handler, if any.
+	Error *funcie.ProxyError `json:"error,omitempty"`
+	// Duration is how long the handler took to handle the request.
+	Duration time.Duration `json:"duration"`
+}
+
+// ShadowMismatch is a way in which the local result of a shadowed request differed from the deployed result.
+type ShadowMismatch string
+
+const (
+	// ShadowMismatchBody indicates that both handlers succeeded, but returned different responses.
+	ShadowMismatchBody ShadowMismatch = "body"
+	// ShadowMismatchError indicates that only one of the handlers returned an error, or that they returned different errors.
+	ShadowMismatchError ShadowMismatch = "error"
+	// ShadowMismatchLatency indicates that the duration of the handlers differed by more than the latency threshold.
+	ShadowMismatchLatency ShadowMismatch = "latency"
+)
+
+// ShadowDiff is the comparison of the deployed and local results of a request forwarded in shadow mode.
+type ShadowDiff struct {
+	// Application is the application that handled the request.
+	Application string `json:"application"`
+	// RequestId is the ID of the forwarded request.
+	RequestId string `json:"requestId"`
+	// Time is when the comparison was made.
+	Time time.Time `json:"time"`
+	// Deployed is the result of the deployed handler.
+	Deployed ShadowResult `json:"deployed"`
+	// Local is the result of the local handler.
+	Local ShadowResult `json:"local"`
+	// LatencyDelta is how much longer the local handler took than the deployed handler; negative if it was faster.
+	LatencyDelta time.Duration `json:"latencyDelta"`
+	// Mismatches contains each way in which the results differed, or is empty if they matched.
+	Mismatches []ShadowMismatch `json:"mismatches,omitempty"`
+}
+
+// NewShadowDiff compares the deployed and local results of the given shadowed request.
+// A latency delta is only considered a mismatch if it exceeds the given threshold.
+// Bodies are compared semantically if both are JSON, so that formatting and key order do not cause mismatches.
+func NewShadowDiff(application string, requestId string, deployed ShadowResult, local ShadowResult, latencyThreshold time.Duration) *ShadowDiff {
+	diff := &ShadowDiff{
+		Application:  application,
+		RequestId:    requestId,
+		Time:         time.Now().UTC().Truncate(time.Millisecond),
+		Deployed:     deployed,
+		Local:        local,
+		LatencyDelta: local.Duration - deployed.Duration,
+	}
+
+	switch {
+	case !reflect.DeepEqual(deployed.Error, local.Error):
+		diff.Mismatches = append(diff.Mismatches, ShadowMismatchError)
+	case deployed.Error == nil && !equalBodies(deployed.Body, local.Body):
+		diff.Mismatches = append(diff.Mismatches, ShadowMismatchBody)
+	}
+
+	if diff.LatencyDelta > latencyThreshold || -diff.LatencyDelta > latencyThreshold {
+		diff.Mismatches = append(diff.Mismatches, ShadowMismatchLatency)
+	}
+
+	return diff
+}
+
+// Matched returns whether the local result matched the deployed result.
+func (d *ShadowDiff) Matched() bool {
+	return len(d.Mismatches) == 0
+}
+
+func equalBodies(a []byte, b []byte) bool {
+	var aValue, bValue interface{}
+	if json.Unmarshal(a, &aValue) != nil || json.Unmarshal(b, &bValue) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
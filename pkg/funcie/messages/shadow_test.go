@@ -0,0 +1,97 @@
+package messages_test
+
+import (
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNewShadowDiff(t *testing.T) {
+	const threshold = 100 * time.Millisecond
+
+	cases := []struct {
+		name       string
+		deployed   messages.ShadowResult
+		local      messages.ShadowResult
+		mismatches []messages.ShadowMismatch
+		delta      time.Duration
+	}{
+		{
+			name:     "should match identical results",
+			deployed: messages.ShadowResult{Body: []byte(`{"a":1}`), Duration: 50 * time.Millisecond},
+			local:    messages.ShadowResult{Body: []byte(`{"a":1}`), Duration: 60 * time.Millisecond},
+			delta:    10 * time.Millisecond,
+		},
+		{
+			name:     "should match JSON bodies with different formatting and key order",
+			deployed: messages.ShadowResult{Body: []byte(`{"a":1,"b":[1,2]}`)},
+			local:    messages.ShadowResult{Body: []byte("{ \"b\": [1, 2],\n \"a\": 1 }")},
+		},
+		{
+			name:       "should compare non-JSON bodies byte for byte",
+			deployed:   messages.ShadowResult{Body: []byte("hello")},
+			local:      messages.ShadowResult{Body: []byte("hello ")},
+			mismatches: []messages.ShadowMismatch{messages.ShadowMismatchBody},
+		},
+		{
+			name:       "should report different bodies",
+			deployed:   messages.ShadowResult{Body: []byte(`{"a":1}`)},
+			local:      messages.ShadowResult{Body: []byte(`{"a":2}`)},
+			mismatches: []messages.ShadowMismatch{messages.ShadowMismatchBody},
+		},
+		{
+			name:       "should report an error from only one handler",
+			deployed:   messages.ShadowResult{Body: []byte(`{"a":1}`)},
+			local:      messages.ShadowResult{Error: funcie.NewProxyError("boom")},
+			mismatches: []messages.ShadowMismatch{messages.ShadowMismatchError},
+		},
+		{
+			name:       "should report different errors",
+			deployed:   messages.ShadowResult{Error: funcie.NewProxyError("boom")},
+			local:      messages.ShadowResult{Error: funcie.NewProxyError("bang")},
+			mismatches: []messages.ShadowMismatch{messages.ShadowMismatchError},
+		},
+		{
+			name:     "should match the same errors regardless of body",
+			deployed: messages.ShadowResult{Error: funcie.NewProxyError("boom")},
+			local:    messages.ShadowResult{Error: funcie.NewProxyError("boom"), Body: []byte("partial")},
+		},
+		{
+			name:       "should report a local handler slower than the threshold",
+			deployed:   messages.ShadowResult{Body: []byte(`1`), Duration: 50 * time.Millisecond},
+			local:      messages.ShadowResult{Body: []byte(`1`), Duration: 200 * time.Millisecond},
+			mismatches: []messages.ShadowMismatch{messages.ShadowMismatchLatency},
+			delta:      150 * time.Millisecond,
+		},
+		{
+			name:       "should report a local handler faster than the threshold",
+			deployed:   messages.ShadowResult{Body: []byte(`1`), Duration: 300 * time.Millisecond},
+			local:      messages.ShadowResult{Body: []byte(`1`), Duration: 50 * time.Millisecond},
+			mismatches: []messages.ShadowMismatch{messages.ShadowMismatchLatency},
+			delta:      -250 * time.Millisecond,
+		},
+		{
+			name:       "should report both a body and latency mismatch",
+			deployed:   messages.ShadowResult{Body: []byte(`1`)},
+			local:      messages.ShadowResult{Body: []byte(`2`), Duration: time.Second},
+			mismatches: []messages.ShadowMismatch{messages.ShadowMismatchBody, messages.ShadowMismatchLatency},
+			delta:      time.Second,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := messages.NewShadowDiff("app", "req", tc.deployed, tc.local, threshold)
+
+			require.Equal(t, "app", diff.Application)
+			require.Equal(t, "req", diff.RequestId)
+			require.Equal(t, tc.deployed, diff.Deployed)
+			require.Equal(t, tc.local, diff.Local)
+			require.Equal(t, tc.delta, diff.LatencyDelta)
+			require.Equal(t, tc.mismatches, diff.Mismatches)
+			require.Equal(t, len(tc.mismatches) == 0, diff.Matched())
+		})
+	}
+}
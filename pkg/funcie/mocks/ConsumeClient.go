@@ -24,6 +24,62 @@ func (_m *ConsumeClient) EXPECT() *ConsumeClient_Expecter {
 	return &ConsumeClient_Expecter{mock: &_m.Mock}
 }
 
+// LPush provides a mock function with given fields: ctx, key, values
+func (_m *ConsumeClient) LPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd {
+	var _ca []interface{}
+	_ca = append(_ca, ctx, key)
+	_ca = append(_ca, values...)
+	ret := _m.Called(_ca...)
+
+	var r0 *redis.IntCmd
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...interface{}) *redis.IntCmd); ok {
+		r0 = rf(ctx, key, values...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*redis.IntCmd)
+		}
+	}
+
+	return r0
+}
+
+// ConsumeClient_LPush_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LPush'
+type ConsumeClient_LPush_Call struct {
+	*mock.Call
+}
+
+// LPush is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - values ...interface{}
+func (_e *ConsumeClient_Expecter) LPush(ctx interface{}, key interface{}, values ...interface{}) *ConsumeClient_LPush_Call {
+	return &ConsumeClient_LPush_Call{Call: _e.mock.On("LPush",
+		append([]interface{}{ctx, key}, values...)...)}
+}
+
+func (_c *ConsumeClient_LPush_Call) Run(run func(ctx context.Context, key string, values ...interface{})) *ConsumeClient_LPush_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]interface{}, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(interface{})
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *ConsumeClient_LPush_Call) Return(_a0 *redis.IntCmd) *ConsumeClient_LPush_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *ConsumeClient_LPush_Call) RunAndReturn(run func(context.Context, string, ...interface{}) *redis.IntCmd) *ConsumeClient_LPush_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Publish provides a mock function with given fields: ctx, channel, message
 func (_m *ConsumeClient) Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd {
 	ret := _m.Called(ctx, channel, message)
@@ -0,0 +1,100 @@
+package funcie
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ProtocolVersion is the version of the funcie wire protocol implemented by this package.
+// This must be incremented whenever a change is made that older clients or bastions can not understand.
+const ProtocolVersion = 1
+
+// MinProtocolVersion is the oldest protocol version that this package is still able to communicate with.
+const MinProtocolVersion = 1
+
+// legacyProtocolVersion is the protocol version assumed for peers that predate protocol negotiation.
+const legacyProtocolVersion = 1
+
+// ErrIncompatibleProtocol is returned when a peer speaks a protocol version that can not be understood.
+var ErrIncompatibleProtocol = errors.New("incompatible funcie protocol version")
+
+// Capability is an optional feature of the funcie protocol that a peer supports.
+type Capability string
+
+// CapabilityResponseStreaming indicates that the peer is able to send or relay streamed responses,
+// delivered as a sequence of RESPONSE_CHUNK messages following the response.
+const CapabilityResponseStreaming Capability = "response-streaming"
+
+// SupportedCapabilities returns the capabilities that are supported by this version of funcie.
+func SupportedCapabilities() []Capability {
+	return []Capability{
+		CapabilityResponseStreaming,
+	}
+}
+
+// ProtocolInfo describes the protocol version, release, and capabilities of one side of a funcie connection.
+type ProtocolInfo struct {
+	// Version is the protocol version that the peer speaks.
+	Version int `json:"version"`
+	// Release is the funcie release version of the peer, such as "0.6.10".
+	Release string `json:"release"`
+	// Capabilities are the optional protocol features that the peer supports.
+	Capabilities []Capability `json:"capabilities,omitempty"`
+}
+
+// NewProtocolInfo creates a ProtocolInfo describing this version of funcie.
+func NewProtocolInfo() *ProtocolInfo {
+	return &ProtocolInfo{
+		Version:      ProtocolVersion,
+		Release:      Version(),
+		Capabilities: SupportedCapabilities(),
+	}
+}
+
+// HasCapability returns true if the peer described by this ProtocolInfo supports the given capability.
+// A nil ProtocolInfo represents a peer that predates negotiation, which supports no optional features.
+func (p *ProtocolInfo) HasCapability(capability Capability) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(p.Capabilities, capability)
+}
+
+// String returns a human-readable description of the peer's version.
+func (p *ProtocolInfo) String() string {
+	if p == nil {
+		return fmt.Sprintf("an unknown version (protocol %d)", legacyProtocolVersion)
+	}
+	release := p.Release
+	if release == "" {
+		release = "unknown"
+	}
+	return fmt.Sprintf("v%s (protocol %d)", release, p.Version)
+}
+
+// CheckProtocolCompatibility returns ErrIncompatibleProtocol if the remote peer can not be communicated with.
+// The peer name is a human-readable description of the remote side, such as "client bastion", used to make
+// the returned error actionable. A nil remote is treated as a peer that predates protocol negotiation.
+func CheckProtocolCompatibility(remote *ProtocolInfo, peer string) error {
+	remoteVersion := legacyProtocolVersion
+	if remote != nil {
+		remoteVersion = remote.Version
+	}
+
+	local := NewProtocolInfo()
+	switch {
+	case remoteVersion < MinProtocolVersion:
+		return fmt.Errorf(
+			"%w: %v is %v, but this side is %v and requires at least protocol %d; upgrade the %v to v%v",
+			ErrIncompatibleProtocol, peer, remote, local, MinProtocolVersion, peer, local.Release,
+		)
+	case remoteVersion > ProtocolVersion:
+		return fmt.Errorf(
+			"%w: %v is %v, but this side is %v; upgrade this side to at least v%v or downgrade the %v to v%v",
+			ErrIncompatibleProtocol, peer, remote, local, remote.Release, peer, local.Release,
+		)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package funcie_test
+
+import (
+	. "github.com/Kapps/funcie/pkg/funcie"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewProtocolInfo(t *testing.T) {
+	t.Parallel()
+
+	info := NewProtocolInfo()
+	require.Equal(t, ProtocolVersion, info.Version)
+	require.Equal(t, Version(), info.Release)
+	require.NotEmpty(t, info.Release)
+	require.True(t, info.HasCapability(CapabilityResponseStreaming))
+}
+
+func TestProtocolInfo_HasCapability(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should treat legacy peers as supporting no capabilities", func(t *testing.T) {
+		var info *ProtocolInfo
+		require.False(t, info.HasCapability(CapabilityResponseStreaming))
+		require.False(t, info.HasCapability("unknown"))
+	})
+
+	t.Run("should only return capabilities that are present", func(t *testing.T) {
+		info := &ProtocolInfo{Version: ProtocolVersion, Capabilities: []Capability{"foo"}}
+		require.True(t, info.HasCapability("foo"))
+		require.False(t, info.HasCapability(CapabilityResponseStreaming))
+	})
+}
+
+func TestCheckProtocolCompatibility(t *testing.T) {
+	t.Parallel()
+
+	t.Run("should accept the current protocol", func(t *testing.T) {
+		require.NoError(t, CheckProtocolCompatibility(NewProtocolInfo(), "client bastion"))
+	})
+
+	t.Run("should accept legacy peers", func(t *testing.T) {
+		require.NoError(t, CheckProtocolCompatibility(nil, "client bastion"))
+	})
+
+	t.Run("should reject peers that are too old", func(t *testing.T) {
+		remote := &ProtocolInfo{Version: MinProtocolVersion - 1, Release: "0.0.1"}
+		err := CheckProtocolCompatibility(remote, "client bastion")
+		require.ErrorIs(t, err, ErrIncompatibleProtocol)
+		require.Contains(t, err.Error(), "client bastion is v0.0.1")
+		require.Contains(t, err.Error(), "upgrade the client bastion to v"+Version())
+	})
+
+	t.Run("should reject peers that are too new", func(t *testing.T) {
+		remote := &ProtocolInfo{Version: ProtocolVersion + 1, Release: "99.0.0"}
+		err := CheckProtocolCompatibility(remote, "client bastion")
+		require.ErrorIs(t, err, ErrIncompatibleProtocol)
+		require.Contains(t, err.Error(), "client bastion is v99.0.0")
+		require.Contains(t, err.Error(), "upgrade this side to at least v99.0.0")
+	})
+}
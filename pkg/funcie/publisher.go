@@ -8,6 +8,10 @@ import (
 // ErrNoActiveConsumer is returned when a consumer is not active on a tunnel.
 var ErrNoActiveConsumer = errors.New("no consumer is active on this tunnel")
 
+// ErrThrottled is returned when a consumer is active, but has reached its concurrency limit and rejected the request.
+// Like ErrNoActiveConsumer, the request should be handled by the original implementation instead.
+var ErrThrottled = errors.New("the consumer is throttled by its concurrency limit")
+
 // Publisher represents the publishing a synchronous tunnel that can be used to send messages to a consumer and wait for a response.
 type Publisher interface {
 	// Publish publishes a message to the tunnel, synchronously waiting for a response from the other side.
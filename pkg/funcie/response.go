@@ -21,6 +21,13 @@ type ResponseBase[T any] struct {
 	Error *ProxyError `json:"error,omitempty"`
 	// Received is the time the response was received.
 	Received time.Time `json:"received"`
+	// Streaming indicates that this response is followed by a stream of messages, such as the chunks of a
+	// streamed response body. Transports use this to know that they must relay the stream after the response.
+	Streaming bool `json:"streaming,omitempty"`
+	// Stream contains the messages that follow this response when Streaming is set.
+	// This is never serialized directly; transports are responsible for relaying the messages of the stream
+	// after the response itself.
+	Stream ResponseStream `json:"-"`
 }
 
 // NewResponse creates a new response with the given data and the current time as the received time.
@@ -57,10 +64,12 @@ func UnmarshalResponsePayload[ResponseType ResponseBase[T], T any](response *Res
 		}
 	}
 	return &ResponseType{
-		ID:       response.ID,
-		Data:     &data,
-		Received: response.Received,
-		Error:    response.Error,
+		ID:        response.ID,
+		Data:      &data,
+		Received:  response.Received,
+		Error:     response.Error,
+		Streaming: response.Streaming,
+		Stream:    response.Stream,
 	}, nil
 }
 
@@ -77,9 +86,17 @@ func MarshalResponsePayload[T any](response *ResponseBase[T]) (*Response, error)
 	}
 
 	return &Response{
-		ID:       response.ID,
-		Data:     raw,
-		Error:    response.Error,
-		Received: response.Received,
+		ID:        response.ID,
+		Data:      raw,
+		Error:     response.Error,
+		Received:  response.Received,
+		Streaming: response.Streaming,
+		Stream:    response.Stream,
 	}, nil
 }
+
+// AttachStream marks the response as streaming, with the remaining messages being read from the given stream.
+func (r *ResponseBase[T]) AttachStream(stream ResponseStream) {
+	r.Streaming = true
+	r.Stream = stream
+}
@@ -0,0 +1,51 @@
+package runtimeapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// InvokePathPattern is the ServeMux pattern of the Lambda Invoke API, which the invoke handler should be served at
+// so that tools such as `aws lambda invoke --endpoint-url` can invoke the emulated function.
+const InvokePathPattern = "/2015-03-31/functions/"
+
+// NewInvokeHandler creates an http.Handler that invokes the function of the given runtime in the same way as the
+// Lambda Invoke API. As with Lambda, errors from the function are returned with a 200 status code and the
+// X-Amz-Function-Error header set, while failures to invoke the function use an error status code.
+func NewInvokeHandler(runtime Runtime) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "InvalidRequest", "invocations must use POST")
+			return
+		}
+
+		payload, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequestContentException", fmt.Sprintf("failed to read payload: %v", err))
+			return
+		}
+		if len(payload) == 0 {
+			payload = []byte("{}")
+		}
+
+		response, err := runtime.Invoke(req.Context(), payload)
+
+		var functionErr *FunctionError
+		if errors.As(err, &functionErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Amz-Function-Error", "Unhandled")
+			_ = json.NewEncoder(w).Encode(functionErr)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadGateway, "ServiceException", err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(response)
+	})
+}
@@ -0,0 +1,354 @@
+// Package runtimeapi emulates the Lambda Runtime API, so that a Lambda runtime can run outside of AWS.
+// A runtime pointed at the emulator with AWS_LAMBDA_RUNTIME_API polls it for invocations and posts back the results,
+// exactly as it would within a Lambda.
+package runtimeapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	runtimePathPrefix    = "/2018-06-01/runtime/"
+	invocationPathPrefix = runtimePathPrefix + "invocation/"
+	initErrorPath        = runtimePathPrefix + "init/error"
+)
+
+// ErrNoRuntime is returned when an invocation could not be started because no runtime asked for it in time.
+var ErrNoRuntime = errors.New("no runtime is polling for invocations")
+
+// FunctionError is an error reported by the runtime for an invocation, in the format used by the Lambda Runtime API.
+type FunctionError struct {
+	// Message is the error message.
+	Message string `json:"errorMessage"`
+	// Type is the type of the error, such as the name of an exception.
+	Type string `json:"errorType"`
+	// StackTrace is the stack trace reported by the runtime, if any.
+	StackTrace []string `json:"stackTrace,omitempty"`
+}
+
+func (e *FunctionError) Error() string {
+	if e.Type == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%v: %v", e.Type, e.Message)
+}
+
+// Options are the options for a Runtime.
+type Options struct {
+	// FunctionName is the name of the emulated function, used for its ARN.
+	FunctionName string
+	// Timeout is the timeout of the emulated function, which sets the deadline given to the runtime.
+	Timeout time.Duration
+	// PollTimeout is how long an invocation waits for a runtime to poll for it before failing with ErrNoRuntime.
+	// If not set, invocations wait for up to the timeout of the function.
+	PollTimeout time.Duration
+}
+
+// OptionSetter sets an option for a Runtime.
+type OptionSetter func(*Options)
+
+// WithFunctionName sets the name of the emulated function.
+func WithFunctionName(name string) OptionSetter {
+	return func(opts *Options) {
+		opts.FunctionName = name
+	}
+}
+
+// WithTimeout sets the timeout of the emulated function.
+func WithTimeout(timeout time.Duration) OptionSetter {
+	return func(opts *Options) {
+		opts.Timeout = timeout
+	}
+}
+
+// WithPollTimeout sets how long an invocation waits for a runtime to poll for it before failing with ErrNoRuntime.
+func WithPollTimeout(timeout time.Duration) OptionSetter {
+	return func(opts *Options) {
+		opts.PollTimeout = timeout
+	}
+}
+
+// Runtime is an emulated Lambda Runtime API for a single function.
+// Invocations are queued until a runtime polls for the next invocation, then wait for the runtime to post the result.
+type Runtime interface {
+	http.Handler
+	// Invoke invokes the function with the given payload, waiting for the runtime to respond.
+	// If the runtime reports an error, it is returned as a *FunctionError.
+	Invoke(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+type invocationHeaderKey struct{}
+
+// ContextWithInvocationHeader returns a context that makes Invoke send the given headers to the runtime with the invocation,
+// such as those of an invocation received from a real Lambda Runtime API. The request ID and deadline are taken from the
+// Lambda-Runtime-Aws-Request-Id and Lambda-Runtime-Deadline-Ms headers when present, rather than being generated.
+func ContextWithInvocationHeader(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, invocationHeaderKey{}, header)
+}
+
+type invocation struct {
+	requestId string
+	payload   []byte
+	deadline  time.Time
+	header    http.Header
+	results   chan invocationResult
+	// done is closed once the invoker stops waiting for the result.
+	done <-chan struct{}
+}
+
+type invocationResult struct {
+	body []byte
+	err  error
+}
+
+type runtime struct {
+	opts        Options
+	invocations chan *invocation
+	pending     map[string]*invocation
+	lock        sync.Mutex
+	logger      *slog.Logger
+}
+
+// NewRuntime creates a new emulated Lambda Runtime API.
+func NewRuntime(logger *slog.Logger, opts ...OptionSetter) Runtime {
+	options := Options{
+		FunctionName: "function",
+		Timeout:      5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.PollTimeout == 0 || options.PollTimeout > options.Timeout {
+		options.PollTimeout = options.Timeout
+	}
+
+	return &runtime{
+		opts:        options,
+		invocations: make(chan *invocation),
+		pending:     make(map[string]*invocation),
+		logger:      logger,
+	}
+}
+
+func (r *runtime) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	header, _ := ctx.Value(invocationHeaderKey{}).(http.Header)
+	inv := &invocation{
+		requestId: uuid.NewString(),
+		payload:   payload,
+		deadline:  time.Now().Add(r.opts.Timeout),
+		header:    header,
+		results:   make(chan invocationResult, 1),
+	}
+	if requestId := header.Get("Lambda-Runtime-Aws-Request-Id"); requestId != "" {
+		inv.requestId = requestId
+	}
+	if deadlineMs, err := strconv.ParseInt(header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64); err == nil {
+		inv.deadline = time.UnixMilli(deadlineMs)
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, inv.deadline)
+	defer cancel()
+	inv.done = ctx.Done()
+
+	pollCtx, cancelPoll := context.WithTimeout(ctx, r.opts.PollTimeout)
+	defer cancelPoll()
+
+	select {
+	case r.invocations <- inv:
+	case <-pollCtx.Done():
+		return nil, fmt.Errorf("%w: %w", ErrNoRuntime, pollCtx.Err())
+	}
+
+	defer r.removePending(inv.requestId)
+
+	select {
+	case result := <-inv.results:
+		return result.body, result.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for runtime to respond to invocation %v: %w", inv.requestId, ctx.Err())
+	}
+}
+
+func (r *runtime) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	switch {
+	case path == invocationPathPrefix+"next" && req.Method == http.MethodGet:
+		r.next(w, req)
+	case path == initErrorPath && req.Method == http.MethodPost:
+		r.initError(w, req)
+	case strings.HasPrefix(path, invocationPathPrefix) && req.Method == http.MethodPost:
+		requestId, action, _ := strings.Cut(strings.TrimPrefix(path, invocationPathPrefix), "/")
+		switch action {
+		case "response":
+			r.respond(w, req, requestId)
+		case "error":
+			r.fail(w, req, requestId)
+		default:
+			writeError(w, http.StatusNotFound, "InvalidRequest", fmt.Sprintf("unknown path %v", path))
+		}
+	default:
+		writeError(w, http.StatusNotFound, "InvalidRequest", fmt.Sprintf("unknown path %v", path))
+	}
+}
+
+// next hands the next invocation to the runtime, waiting until there is one.
+// If the runtime disconnects before receiving the invocation, it is queued again for the next runtime that polls.
+func (r *runtime) next(w http.ResponseWriter, req *http.Request) {
+	var inv *invocation
+	select {
+	case inv = <-r.invocations:
+	case <-req.Context().Done():
+		return
+	}
+
+	if err := req.Context().Err(); err != nil {
+		r.requeue(req.Context(), inv, err)
+		return
+	}
+
+	r.lock.Lock()
+	r.pending[inv.requestId] = inv
+	r.lock.Unlock()
+
+	r.logger.DebugContext(req.Context(), "sending invocation to runtime", "requestId", inv.requestId)
+
+	header := w.Header()
+	header.Set("Lambda-Runtime-Aws-Request-Id", inv.requestId)
+	header.Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(inv.deadline.UnixMilli(), 10))
+	header.Set("Lambda-Runtime-Invoked-Function-Arn",
+		fmt.Sprintf("arn:aws:lambda:us-east-1:000000000000:function:%v", r.opts.FunctionName))
+	header.Set("Lambda-Runtime-Trace-Id", fmt.Sprintf("Root=1-%08x-%024x", time.Now().Unix(), 0))
+	header.Set("Content-Type", "application/json")
+	for name, values := range inv.header {
+		header[name] = values
+	}
+
+	if _, err := w.Write(inv.payload); err != nil {
+		r.requeue(req.Context(), inv, err)
+		return
+	}
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		r.requeue(req.Context(), inv, err)
+	}
+}
+
+// requeue queues an invocation that the runtime failed to receive, so that the next runtime to poll receives it instead.
+func (r *runtime) requeue(ctx context.Context, inv *invocation, err error) {
+	r.logger.WarnContext(ctx, "runtime disconnected before receiving invocation, queueing it again",
+		"requestId", inv.requestId, "error", err)
+	r.removePending(inv.requestId)
+
+	go func() {
+		select {
+		case r.invocations <- inv:
+		case <-inv.done:
+		}
+	}()
+}
+
+// respond completes an invocation with the response posted by the runtime.
+// Streamed responses are read in full, with errors that occur mid-stream being reported in the trailers.
+func (r *runtime) respond(w http.ResponseWriter, req *http.Request, requestId string) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("failed to read response: %v", err))
+		return
+	}
+
+	result := invocationResult{body: body}
+	if errorType := req.Trailer.Get("Lambda-Runtime-Function-Error-Type"); errorType != "" {
+		result = invocationResult{err: readTrailerError(errorType, req.Trailer.Get("Lambda-Runtime-Function-Error-Body"))}
+	}
+
+	r.complete(w, requestId, result)
+}
+
+// fail completes an invocation with the error posted by the runtime.
+func (r *runtime) fail(w http.ResponseWriter, req *http.Request, requestId string) {
+	functionErr, err := readFunctionError(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("failed to read error: %v", err))
+		return
+	}
+
+	r.complete(w, requestId, invocationResult{err: functionErr})
+}
+
+func (r *runtime) initError(w http.ResponseWriter, req *http.Request) {
+	functionErr, err := readFunctionError(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("failed to read error: %v", err))
+		return
+	}
+
+	r.logger.ErrorContext(req.Context(), "runtime failed to initialize", "error", functionErr)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (r *runtime) complete(w http.ResponseWriter, requestId string, result invocationResult) {
+	r.lock.Lock()
+	inv, ok := r.pending[requestId]
+	delete(r.pending, requestId)
+	r.lock.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "InvalidRequestID", fmt.Sprintf("unknown request ID %v", requestId))
+		return
+	}
+
+	r.logger.Debug("received invocation result from runtime", "requestId", requestId, "error", result.err)
+
+	inv.results <- result
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (r *runtime) removePending(requestId string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.pending, requestId)
+}
+
+func readFunctionError(req *http.Request) (*FunctionError, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	functionErr := &FunctionError{Type: req.Header.Get("Lambda-Runtime-Function-Error-Type")}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, functionErr); err != nil {
+			// Runtimes are not required to send JSON, so keep the body as the message.
+			functionErr.Message = string(body)
+		}
+	}
+
+	return functionErr, nil
+}
+
+// readTrailerError reads an error reported mid-stream, which runtimes send as base64 encoded JSON in the trailers.
+func readTrailerError(errorType string, encodedBody string) *FunctionError {
+	functionErr := &FunctionError{Type: errorType}
+	body, err := base64.StdEncoding.DecodeString(encodedBody)
+	if err != nil || json.Unmarshal(body, functionErr) != nil {
+		functionErr.Message = encodedBody
+	}
+	return functionErr
+}
+
+func writeError(w http.ResponseWriter, status int, errorType string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(&FunctionError{Type: errorType, Message: message})
+}
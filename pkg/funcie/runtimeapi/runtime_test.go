@@ -0,0 +1,256 @@
+package runtimeapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"github.com/Kapps/funcie/pkg/funcie/runtimeapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuntime_Invoke(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should return the response posted by the runtime", func(t *testing.T) {
+		runtime := runtimeapi.NewRuntime(slog.Default(), runtimeapi.WithFunctionName("app"))
+		server := httptest.NewServer(runtime)
+		defer server.Close()
+
+		go func() {
+			requestId, payload := nextInvocation(t, server.URL)
+			assert.Equal(t, `{"name":"funcie"}`, string(payload))
+			postResult(t, server.URL, requestId, "response", []byte(`{"greeting":"hello"}`), nil)
+		}()
+
+		resp, err := runtime.Invoke(ctx, []byte(`{"name":"funcie"}`))
+		require.NoError(t, err)
+		require.Equal(t, `{"greeting":"hello"}`, string(resp))
+	})
+
+	t.Run("should return errors posted by the runtime", func(t *testing.T) {
+		runtime := runtimeapi.NewRuntime(slog.Default())
+		server := httptest.NewServer(runtime)
+		defer server.Close()
+
+		go func() {
+			requestId, _ := nextInvocation(t, server.URL)
+			postResult(t, server.URL, requestId, "error", []byte(`{"errorMessage":"boom","errorType":"Failure"}`), nil)
+		}()
+
+		_, err := runtime.Invoke(ctx, []byte(`{}`))
+		var functionErr *runtimeapi.FunctionError
+		require.ErrorAs(t, err, &functionErr)
+		require.Equal(t, "boom", functionErr.Message)
+		require.Equal(t, "Failure", functionErr.Type)
+	})
+
+	t.Run("should return errors from the trailers of streamed responses", func(t *testing.T) {
+		runtime := runtimeapi.NewRuntime(slog.Default())
+		server := httptest.NewServer(runtime)
+		defer server.Close()
+
+		go func() {
+			requestId, _ := nextInvocation(t, server.URL)
+			trailer := http.Header{}
+			trailer.Set("Lambda-Runtime-Function-Error-Type", "Failure")
+			trailer.Set("Lambda-Runtime-Function-Error-Body",
+				base64.StdEncoding.EncodeToString([]byte(`{"errorMessage":"mid-stream","errorType":"Failure"}`)))
+			postResult(t, server.URL, requestId, "response", []byte("partial"), trailer)
+		}()
+
+		_, err := runtime.Invoke(ctx, []byte(`{}`))
+		var functionErr *runtimeapi.FunctionError
+		require.ErrorAs(t, err, &functionErr)
+		require.Equal(t, "mid-stream", functionErr.Message)
+	})
+
+	t.Run("should time out if no runtime polls for the invocation", func(t *testing.T) {
+		runtime := runtimeapi.NewRuntime(slog.Default(), runtimeapi.WithTimeout(50*time.Millisecond))
+
+		_, err := runtime.Invoke(ctx, []byte(`{}`))
+		require.ErrorIs(t, err, runtimeapi.ErrNoRuntime)
+	})
+
+	t.Run("should stop waiting for a runtime after the poll timeout", func(t *testing.T) {
+		runtime := runtimeapi.NewRuntime(slog.Default(), runtimeapi.WithPollTimeout(50*time.Millisecond))
+
+		start := time.Now()
+		_, err := runtime.Invoke(ctx, []byte(`{}`))
+		require.ErrorIs(t, err, runtimeapi.ErrNoRuntime)
+		require.Less(t, time.Since(start), time.Minute)
+	})
+
+	t.Run("should send the invocation headers from the context", func(t *testing.T) {
+		runtime := runtimeapi.NewRuntime(slog.Default())
+		server := httptest.NewServer(runtime)
+		defer server.Close()
+
+		deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+		header := http.Header{}
+		header.Set("Lambda-Runtime-Aws-Request-Id", "upstream-id")
+		header.Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(deadline.UnixMilli(), 10))
+		header.Set("Lambda-Runtime-Client-Context", "client-context")
+
+		go func() {
+			resp, err := http.Get(server.URL + "/2018-06-01/runtime/invocation/next")
+			if !assert.NoError(t, err) {
+				return
+			}
+			_ = resp.Body.Close()
+
+			assert.Equal(t, "upstream-id", resp.Header.Get("Lambda-Runtime-Aws-Request-Id"))
+			assert.Equal(t, strconv.FormatInt(deadline.UnixMilli(), 10), resp.Header.Get("Lambda-Runtime-Deadline-Ms"))
+			assert.Equal(t, "client-context", resp.Header.Get("Lambda-Runtime-Client-Context"))
+			postResult(t, server.URL, "upstream-id", "response", []byte(`{}`), nil)
+		}()
+
+		_, err := runtime.Invoke(runtimeapi.ContextWithInvocationHeader(ctx, header), []byte(`{}`))
+		require.NoError(t, err)
+	})
+
+	t.Run("should requeue invocations that the runtime disconnected before receiving", func(t *testing.T) {
+		runtime := runtimeapi.NewRuntime(slog.Default())
+		server := httptest.NewServer(runtime)
+		defer server.Close()
+
+		results := make(chan []byte, 1)
+		go func() {
+			resp, err := runtime.Invoke(ctx, []byte(`"retry me"`))
+			if err == nil {
+				results <- resp
+			}
+			close(results)
+		}()
+
+		// A poller that disconnects after the invocation was dequeued, before the payload could be written.
+		req := httptest.NewRequest(http.MethodGet, "/2018-06-01/runtime/invocation/next", nil)
+		runtime.ServeHTTP(&disconnectedResponseWriter{header: http.Header{}}, req)
+
+		requestId, payload := nextInvocation(t, server.URL)
+		require.Equal(t, `"retry me"`, string(payload))
+		postResult(t, server.URL, requestId, "response", payload, nil)
+
+		require.Equal(t, `"retry me"`, string(<-results))
+	})
+
+	t.Run("should reject results for unknown requests", func(t *testing.T) {
+		runtime := runtimeapi.NewRuntime(slog.Default())
+		server := httptest.NewServer(runtime)
+		defer server.Close()
+
+		resp, err := http.Post(server.URL+"/2018-06-01/runtime/invocation/unknown/response", "application/json", strings.NewReader("{}"))
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestInvokeHandler(t *testing.T) {
+	runtime := runtimeapi.NewRuntime(slog.Default())
+	runtimeServer := httptest.NewServer(runtime)
+	defer runtimeServer.Close()
+	invokeServer := httptest.NewServer(runtimeapi.NewInvokeHandler(runtime))
+	defer invokeServer.Close()
+
+	invokeUrl := invokeServer.URL + runtimeapi.InvokePathPattern + "app/invocations"
+
+	t.Run("should return the response of the function", func(t *testing.T) {
+		go func() {
+			requestId, payload := nextInvocation(t, runtimeServer.URL)
+			postResult(t, runtimeServer.URL, requestId, "response", payload, nil)
+		}()
+
+		resp, err := http.Post(invokeUrl, "application/json", strings.NewReader(`"echo"`))
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Empty(t, resp.Header.Get("X-Amz-Function-Error"))
+		require.Equal(t, `"echo"`, string(body))
+	})
+
+	t.Run("should flag errors from the function", func(t *testing.T) {
+		go func() {
+			requestId, _ := nextInvocation(t, runtimeServer.URL)
+			postResult(t, runtimeServer.URL, requestId, "error", []byte(`{"errorMessage":"boom","errorType":"Failure"}`), nil)
+		}()
+
+		resp, err := http.Post(invokeUrl, "application/json", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "Unhandled", resp.Header.Get("X-Amz-Function-Error"))
+		require.JSONEq(t, `{"errorMessage":"boom","errorType":"Failure"}`, string(body))
+	})
+}
+
+// nextInvocation polls the runtime for the next invocation, as a Lambda runtime would.
+// As this usually runs alongside the test rather than in it, failures are reported with assert and an empty request ID.
+func nextInvocation(t *testing.T, runtimeUrl string) (string, []byte) {
+	resp, err := http.Get(runtimeUrl + "/2018-06-01/runtime/invocation/next")
+	if !assert.NoError(t, err) {
+		return "", nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if !assert.Equal(t, http.StatusOK, resp.StatusCode) {
+		return "", nil
+	}
+	assert.NotEmpty(t, resp.Header.Get("Lambda-Runtime-Deadline-Ms"))
+	assert.NotEmpty(t, resp.Header.Get("Lambda-Runtime-Invoked-Function-Arn"))
+
+	payload, err := io.ReadAll(resp.Body)
+	if !assert.NoError(t, err) {
+		return "", nil
+	}
+
+	return resp.Header.Get("Lambda-Runtime-Aws-Request-Id"), payload
+}
+
+// postResult posts the result of an invocation to the runtime, with the given trailers if any.
+// As this usually runs alongside the test rather than in it, failures are reported with assert.
+func postResult(t *testing.T, runtimeUrl string, requestId string, action string, body []byte, trailer http.Header) {
+	url := runtimeUrl + "/2018-06-01/runtime/invocation/" + requestId + "/" + action
+	req, err := http.NewRequest(http.MethodPost, url, io.MultiReader(bytes.NewReader(body)))
+	if !assert.NoError(t, err) {
+		return
+	}
+	req.Trailer = trailer
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+// disconnectedResponseWriter is an http.ResponseWriter for a client that has disconnected, failing every write.
+type disconnectedResponseWriter struct {
+	header http.Header
+}
+
+func (w *disconnectedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *disconnectedResponseWriter) Write([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func (w *disconnectedResponseWriter) WriteHeader(int) {}
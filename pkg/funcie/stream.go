@@ -0,0 +1,102 @@
+package funcie
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StreamContentType is the content type used when a response is followed by a stream of messages over HTTP.
+// The body is newline-delimited JSON, with the Response on the first line and each streamed Message on the following lines.
+const StreamContentType = "application/x-ndjson"
+
+// ResponseStream is a sequence of messages that are delivered incrementally after a Response, such as the
+// chunks of a streamed Lambda response.
+type ResponseStream interface {
+	// Next returns the next message in the stream, blocking until it is available.
+	// Once the stream is complete, io.EOF is returned.
+	Next(ctx context.Context) (*Message, error)
+	// Close releases any resources held by the stream.
+	// Close should be called even if the stream was read until io.EOF.
+	Close() error
+}
+
+// WriteStreamedResponse writes the given response to the HTTP response writer, followed by every message in its stream.
+// Each message is flushed as soon as it is available so that it is relayed to the other side incrementally.
+// The stream is closed once it has been fully written or an error occurs.
+func WriteStreamedResponse(ctx context.Context, w http.ResponseWriter, response *Response) error {
+	defer CloseOrLog("response stream", response.Stream)
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", StreamContentType)
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(response); err != nil {
+		return fmt.Errorf("writing response: %w", err)
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		message, err := response.Stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading stream for response %v: %w", response.ID, err)
+		}
+
+		if err := encoder.Encode(message); err != nil {
+			return fmt.Errorf("writing message %v: %w", message.ID, err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// ReadStreamedResponse reads a response written by WriteStreamedResponse from the given body.
+// The returned response has a Stream that reads the remaining messages from the body, and closes the body when closed.
+func ReadStreamedResponse(body io.ReadCloser) (*Response, error) {
+	reader := bufio.NewReader(body)
+	decoder := json.NewDecoder(reader)
+
+	var response Response
+	if err := decoder.Decode(&response); err != nil {
+		_ = body.Close()
+		return nil, fmt.Errorf("decoding streamed response: %w", err)
+	}
+
+	response.AttachStream(&decoderStream{
+		decoder: decoder,
+		body:    body,
+	})
+
+	return &response, nil
+}
+
+type decoderStream struct {
+	decoder *json.Decoder
+	body    io.Closer
+}
+
+func (s *decoderStream) Next(_ context.Context) (*Message, error) {
+	var message Message
+	if err := s.decoder.Decode(&message); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("decoding streamed message: %w", err)
+	}
+	return &message, nil
+}
+
+func (s *decoderStream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,64 @@
+package funcie_test
+
+import (
+	"context"
+	. "github.com/Kapps/funcie/pkg/funcie"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type sliceStream struct {
+	messages []*Message
+	closed   bool
+}
+
+func (s *sliceStream) Next(_ context.Context) (*Message, error) {
+	if len(s.messages) == 0 {
+		return nil, io.EOF
+	}
+	message := s.messages[0]
+	s.messages = s.messages[1:]
+	return message, nil
+}
+
+func (s *sliceStream) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestStreamedResponse_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	first := NewMessage("app", "CHUNK", []byte(`{"sequence":0}`))
+	second := NewMessage("app", "CHUNK", []byte(`{"sequence":1}`))
+	stream := &sliceStream{messages: []*Message{first, second}}
+
+	response := NewResponse("id", []byte(`{"streaming":true}`), nil)
+	response.AttachStream(stream)
+
+	recorder := httptest.NewRecorder()
+	require.NoError(t, WriteStreamedResponse(ctx, recorder, response))
+	require.True(t, stream.closed)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, StreamContentType, recorder.Header().Get("Content-Type"))
+
+	read, err := ReadStreamedResponse(io.NopCloser(recorder.Body))
+	require.NoError(t, err)
+	require.Equal(t, response.ID, read.ID)
+	require.Equal(t, *response.Data, *read.Data)
+	require.True(t, read.Streaming)
+
+	for _, expected := range []*Message{first, second} {
+		message, err := read.Stream.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, expected, message)
+	}
+
+	_, err = read.Stream.Next(ctx)
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, read.Stream.Close())
+}
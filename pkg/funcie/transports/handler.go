@@ -13,6 +13,8 @@ type MessageHandler interface {
 	Deregister(ctx context.Context, message messages.DeregistrationMessage) (*messages.DeregistrationResponse, error)
 	// ForwardRequest forwards the given request to the application specified in the request.
 	ForwardRequest(ctx context.Context, message messages.ForwardRequestMessage) (*messages.ForwardRequestResponse, error)
+	// SetFallbackPolicy overrides the fallback policy of the application specified in the request.
+	SetFallbackPolicy(ctx context.Context, message messages.SetFallbackPolicyMessage) (*messages.SetFallbackPolicyResponse, error)
 }
 
 // Implementation done on both sides of the bastion.
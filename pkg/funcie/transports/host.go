@@ -7,6 +7,7 @@ import (
 	"github.com/Kapps/funcie/pkg/funcie"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 )
 
@@ -19,36 +20,79 @@ type Host interface {
 	Close(ctx context.Context) error
 }
 
+// HostOptions are the options for a Host.
+type HostOptions struct {
+	// Handlers are additional HTTP handlers served by the host, keyed by their ServeMux pattern.
+	Handlers map[string]http.Handler
+	// Listener is the listener to serve on instead of listening on the address of the host, such as one on a random port.
+	Listener net.Listener
+}
+
+// HostOptionSetter sets an option for a Host.
+type HostOptionSetter func(*HostOptions)
+
+// WithHandler serves the given handler for the given ServeMux pattern, alongside the dispatch and health endpoints.
+func WithHandler(pattern string, handler http.Handler) HostOptionSetter {
+	return func(opts *HostOptions) {
+		opts.Handlers[pattern] = handler
+	}
+}
+
 type bastionHost struct {
 	httpServer       *http.Server
+	listener         net.Listener
 	messageProcessor MessageProcessor
 }
 
+// WithListener serves on the given listener instead of listening on the address of the host.
+func WithListener(listener net.Listener) HostOptionSetter {
+	return func(opts *HostOptions) {
+		opts.Listener = listener
+	}
+}
+
 // NewHost creates a new Host listening on the given address.
-func NewHost(address string, messageProcessor MessageProcessor) Host {
+func NewHost(address string, messageProcessor MessageProcessor, opts ...HostOptionSetter) Host {
+	options := HostOptions{
+		Handlers: make(map[string]http.Handler),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	httpServer := &http.Server{
 		Addr: address,
 	}
 	host := &bastionHost{
 		httpServer:       httpServer,
+		listener:         options.Listener,
 		messageProcessor: messageProcessor,
 	}
-	host.setHandlers()
+	host.setHandlers(options.Handlers)
 
 	return host
 }
 
-func (h *bastionHost) setHandlers() {
+func (h *bastionHost) setHandlers(handlers map[string]http.Handler) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/dispatch", h.processMessage)
 	mux.HandleFunc("/health", h.processHealthCheck)
+	for pattern, handler := range handlers {
+		mux.Handle(pattern, handler)
+	}
 
 	h.httpServer.Handler = mux
 }
 
 func (h *bastionHost) Listen(ctx context.Context) error {
 	slog.InfoContext(ctx, "listening for incoming requests", "address", h.httpServer.Addr)
-	if err := h.httpServer.ListenAndServe(); err != nil {
+	var err error
+	if h.listener != nil {
+		err = h.httpServer.Serve(h.listener)
+	} else {
+		err = h.httpServer.ListenAndServe()
+	}
+	if err != nil {
 		return fmt.Errorf("listen and serve: %w", err)
 	}
 
@@ -80,7 +124,7 @@ func (h *bastionHost) processMessage(w http.ResponseWriter, r *http.Request) {
 	var message funcie.Message
 	err = json.Unmarshal(payloadBytes, &message)
 	if err != nil {
-		slog.ErrorContext(r.Context(), "error unmarshalling message", err, "payload", string(payloadBytes))
+		slog.ErrorContext(r.Context(), "error unmarshalling message", "error", err, "payload", string(payloadBytes))
 		w.WriteHeader(http.StatusBadRequest)
 		_, _ = w.Write([]byte(fmt.Sprintf("invalid request: %v", err)))
 		return
@@ -88,17 +132,35 @@ func (h *bastionHost) processMessage(w http.ResponseWriter, r *http.Request) {
 
 	slog.DebugContext(r.Context(), "received message", "message", &message)
 
+	if err := funcie.CheckProtocolCompatibility(message.Protocol, "funcie client"); err != nil {
+		slog.WarnContext(r.Context(), "rejecting message from incompatible client", "error", err, "message", &message)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(fmt.Sprintf("incompatible request: %v", err)))
+		return
+	}
+
 	response, err := h.messageProcessor.ProcessMessage(r.Context(), &message)
 	if err != nil {
-		slog.ErrorContext(r.Context(), "error processing message", err, "message", &message)
+		slog.ErrorContext(r.Context(), "error processing message", "error", err, "message", &message)
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(fmt.Sprintf("internal server error: %v", err)))
 		return
 	}
 
+	if response.Streaming && response.Stream != nil {
+		// Once the stream has started, the status code has already been sent, so we can only log failures.
+		if err := funcie.WriteStreamedResponse(r.Context(), w, response); err != nil {
+			slog.ErrorContext(r.Context(), "error writing streamed response", "error", err, "response", response.ID)
+			return
+		}
+
+		slog.DebugContext(r.Context(), "sent streamed response", "response", response.ID)
+		return
+	}
+
 	responseBytes, err := json.Marshal(response)
 	if err != nil {
-		slog.ErrorContext(r.Context(), "error formatting response", err, "response", response)
+		slog.ErrorContext(r.Context(), "error formatting response", "error", err, "response", response)
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(fmt.Sprintf("internal server error formatting response: %v", err)))
 		return
@@ -106,7 +168,7 @@ func (h *bastionHost) processMessage(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(responseBytes)
 	if err != nil {
-		slog.ErrorContext(r.Context(), "error writing response", err, "response", response)
+		slog.ErrorContext(r.Context(), "error writing response", "error", err, "response", response)
 		w.WriteHeader(http.StatusInternalServerError)
 		_, _ = w.Write([]byte(fmt.Sprintf("internal server error writing response: %v", err)))
 		return
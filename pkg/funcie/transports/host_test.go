@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"io"
+	"net"
 	"net/http"
 	"testing"
 	"time"
@@ -85,6 +86,45 @@ func TestBastionHost_Listen(t *testing.T) {
 		)
 	})
 
+	t.Run("incompatible protocol", func(t *testing.T) {
+		message := funcie.NewMessage("app", messages.MessageKindRegister, []byte("{}"))
+		message.Protocol = &funcie.ProtocolInfo{Version: funcie.ProtocolVersion + 1, Release: "99.0.0"}
+		serialized := funcie.MustSerialize(message)
+
+		resp, err := client.Post("http://localhost:8080/dispatch", "application/json", bytes.NewReader(serialized))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		responseBytes, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		require.Contains(t, string(responseBytes), funcie.ErrIncompatibleProtocol.Error())
+		require.Contains(t, string(responseBytes), "v99.0.0")
+		require.Contains(t, string(responseBytes), "v"+funcie.Version())
+	})
+
 	err := host.Close(ctx)
 	require.NoError(t, err)
 }
+
+func TestBastionHost_WithListener(t *testing.T) {
+	ctx := context.Background()
+	processor := mocks.NewMessageProcessor(t)
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	host := transports.NewHost(listener.Addr().String(), processor, transports.WithListener(listener))
+	go func() {
+		err := host.Listen(ctx)
+		require.ErrorIs(t, err, http.ErrServerClosed)
+	}()
+	t.Cleanup(func() { _ = host.Close(ctx) })
+
+	// The listener is already accepting connections, so there is no need to wait for the host to start.
+	resp, err := http.Get("http://" + listener.Addr().String() + "/health")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
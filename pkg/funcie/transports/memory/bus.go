@@ -0,0 +1,71 @@
+// Package memory provides a transport that delivers messages between a publisher and consumers in the same process.
+// It is the reference implementation of funcie.Publisher and funcie.Consumer, and allows the server and client
+// bastions to run together without Redis.
+package memory
+
+import (
+	"github.com/Kapps/funcie/pkg/funcie"
+	"sync"
+)
+
+// delivery is a serialized message sent to a consumer, along with where to send the response.
+type delivery struct {
+	payload []byte
+	replies chan<- reply
+}
+
+// reply is the serialized response of a consumer to a delivery, or the error that prevented a response.
+type reply struct {
+	payload []byte
+	stream  funcie.ResponseStream
+	err     error
+}
+
+// Bus connects publishers to the consumers subscribed to each application.
+// A publisher and its consumers must share the same Bus.
+type Bus struct {
+	subscribers map[string]map[*consumer]struct{}
+	lock        sync.RWMutex
+}
+
+// NewBus creates a new Bus with no subscribers.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[string]map[*consumer]struct{}),
+	}
+}
+
+func (b *Bus) subscribe(applicationId string, c *consumer) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	consumers, ok := b.subscribers[applicationId]
+	if !ok {
+		consumers = make(map[*consumer]struct{})
+		b.subscribers[applicationId] = consumers
+	}
+	consumers[c] = struct{}{}
+}
+
+func (b *Bus) unsubscribe(applicationId string, c *consumer) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	consumers := b.subscribers[applicationId]
+	delete(consumers, c)
+	if len(consumers) == 0 {
+		delete(b.subscribers, applicationId)
+	}
+}
+
+// subscribersOf returns the consumers currently subscribed to the given application.
+func (b *Bus) subscribersOf(applicationId string) []*consumer {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	consumers := make([]*consumer, 0, len(b.subscribers[applicationId]))
+	for c := range b.subscribers[applicationId] {
+		consumers = append(consumers, c)
+	}
+	return consumers
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
+	"log/slog"
+)
+
+// ErrNotConnected is returned when a consumer is used before Connect is called.
+var ErrNotConnected = errors.New("consumer is not connected")
+
+// inboxSize is the number of messages that may wait for a consumer that is not currently consuming.
+const inboxSize = 64
+
+type consumer struct {
+	bus    *Bus
+	router utils.ClientHandlerRouter
+	inbox  chan delivery
+}
+
+// NewConsumer creates a new Consumer that receives the messages published on the given bus.
+func NewConsumer(bus *Bus, router utils.ClientHandlerRouter) funcie.Consumer {
+	return &consumer{
+		bus:    bus,
+		router: router,
+	}
+}
+
+func (c *consumer) Connect(_ context.Context) error {
+	if c.inbox == nil {
+		c.inbox = make(chan delivery, inboxSize)
+	}
+	return nil
+}
+
+func (c *consumer) Consume(ctx context.Context) error {
+	if c.inbox == nil {
+		return ErrNotConnected
+	}
+
+	slog.InfoContext(ctx, "starting to consume messages from memory bus")
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Warn("context cancelled", "err", ctx.Err())
+			return ctx.Err()
+		case d := <-c.inbox:
+			go func(d delivery) {
+				// Every delivery gets exactly one reply, so the publisher never waits for a response that will not arrive.
+				d.replies <- c.processMessage(ctx, d.payload)
+			}(d)
+		}
+	}
+}
+
+func (c *consumer) processMessage(ctx context.Context, payload []byte) reply {
+	var message funcie.Message
+	if err := json.Unmarshal(payload, &message); err != nil {
+		return reply{err: fmt.Errorf("unmarshalling message: %w", err)}
+	}
+
+	slog.DebugContext(ctx, "received message", "message", message.ID, "application", message.Application)
+
+	response, err := c.router.Handle(ctx, &message)
+	if isNoHandlerFound(err, response) {
+		slog.InfoContext(ctx, "unsubscribing due to no handler found", "app", message.Application)
+		// Unsubscribe so that the publisher knows right away that nothing is listening next time.
+		if unsubErr := c.Unsubscribe(ctx, message.Application); unsubErr != nil {
+			slog.ErrorContext(ctx, "error unsubscribing from application", "error", unsubErr, "app", message.Application)
+		}
+		return reply{err: funcie.ErrNoActiveConsumer}
+	}
+	if err != nil {
+		return reply{err: fmt.Errorf("error handling message: %w", err)}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return reply{err: fmt.Errorf("marshalling response: %w", err)}
+	}
+
+	return reply{payload: data, stream: response.Stream}
+}
+
+func (c *consumer) Subscribe(_ context.Context, applicationId string, handler funcie.Handler) error {
+	if c.inbox == nil {
+		return ErrNotConnected
+	}
+
+	slog.Info("subscribing to application", "application", applicationId)
+
+	if err := c.router.AddClientHandler(applicationId, handler); err != nil {
+		return fmt.Errorf("adding client handler: %w", err)
+	}
+
+	c.bus.subscribe(applicationId, c)
+	return nil
+}
+
+func (c *consumer) Unsubscribe(_ context.Context, applicationId string) error {
+	slog.Info("unsubscribing from application", "application", applicationId)
+
+	c.bus.unsubscribe(applicationId, c)
+
+	if err := c.router.RemoveClientHandler(applicationId); err != nil {
+		return fmt.Errorf("removing client handler: %w", err)
+	}
+
+	return nil
+}
+
+// isNoHandlerFound returns true if the consumer had nothing to handle the message with, either because no handler is
+// registered for the application, or because the handler no longer knows about the application.
+func isNoHandlerFound(err error, response *funcie.Response) bool {
+	if errors.Is(err, utils.ErrNoHandlerFound) {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	return response == nil || (response.Error != nil && response.Error.Message == funcie.ErrNoActiveConsumer.Error())
+}
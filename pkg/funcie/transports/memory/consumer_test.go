@@ -0,0 +1,61 @@
+package memory_test
+
+import (
+	"context"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"github.com/Kapps/funcie/pkg/funcie/transports/memory"
+	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMemoryConsumer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should require connecting first", func(t *testing.T) {
+		consumer := memory.NewConsumer(memory.NewBus(), utils.NewClientHandlerRouter())
+
+		require.ErrorIs(t, consumer.Consume(ctx), memory.ErrNotConnected)
+		require.ErrorIs(t, consumer.Subscribe(ctx, "app", echoHandler), memory.ErrNotConnected)
+	})
+
+	t.Run("should fail to unsubscribe from an application that is not subscribed", func(t *testing.T) {
+		consumer := startConsumer(t, memory.NewBus())
+
+		require.Error(t, consumer.Unsubscribe(ctx, "app"))
+	})
+
+	t.Run("should only route to the handler of the message's application", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+		consumer := startConsumer(t, bus)
+
+		require.NoError(t, consumer.Subscribe(ctx, "first", func(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+			return funcie.NewResponse(message.ID, []byte("\"first\""), nil), nil
+		}))
+		require.NoError(t, consumer.Subscribe(ctx, "second", func(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+			return funcie.NewResponse(message.ID, []byte("\"second\""), nil), nil
+		}))
+
+		resp, err := publisher.Publish(ctx, funcie.NewMessage("second", messages.MessageKindForwardRequest, []byte("{}")))
+		require.NoError(t, err)
+		require.Equal(t, "\"second\"", string(*resp.Data))
+	})
+
+	t.Run("should use another consumer when one no longer has a handler", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+		stale := startConsumer(t, bus)
+		active := startConsumer(t, bus)
+
+		require.NoError(t, stale.Subscribe(ctx, "app", func(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+			return funcie.NewResponse(message.ID, nil, funcie.ErrNoActiveConsumer), nil
+		}))
+		require.NoError(t, active.Subscribe(ctx, "app", echoHandler))
+
+		resp, err := publisher.Publish(ctx, funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\"")))
+		require.NoError(t, err)
+		require.Equal(t, "\"msg\"", string(*resp.Data))
+	})
+}
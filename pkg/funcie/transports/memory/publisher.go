@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"log/slog"
+	"time"
+)
+
+// defaultTimeout is how long to wait for a response when no timeout is set, matching the Redis transport.
+const defaultTimeout = 5 * time.Minute
+
+// PublisherOptions are the options for a memory Publisher.
+type PublisherOptions struct {
+	// Timeout is how long to wait for a consumer to respond to a message.
+	Timeout time.Duration
+}
+
+// PublisherOptionSetter sets an option for a memory Publisher.
+type PublisherOptionSetter func(*PublisherOptions)
+
+// WithTimeout sets how long to wait for a consumer to respond to a message.
+func WithTimeout(timeout time.Duration) PublisherOptionSetter {
+	return func(opts *PublisherOptions) {
+		opts.Timeout = timeout
+	}
+}
+
+type publisher struct {
+	bus  *Bus
+	opts PublisherOptions
+}
+
+// NewPublisher creates a new Publisher that publishes messages to the consumers subscribed on the given bus.
+func NewPublisher(bus *Bus, opts ...PublisherOptionSetter) funcie.Publisher {
+	options := PublisherOptions{
+		Timeout: defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &publisher{
+		bus:  bus,
+		opts: options,
+	}
+}
+
+// Publish sends the message to every consumer subscribed to its application, returning the first response.
+// If no consumer is subscribed, or every consumer no longer has a handler for the application, funcie.ErrNoActiveConsumer
+// is returned.
+func (p *publisher) Publish(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+	consumers := p.bus.subscribersOf(message.Application)
+	if len(consumers) == 0 {
+		return nil, funcie.ErrNoActiveConsumer
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	slog.InfoContext(ctx, "publishing message to memory bus",
+		"application", message.Application, "message", message.ID, "consumers", len(consumers))
+
+	// Buffered so that consumers never block on replies that are no longer being waited for.
+	replies := make(chan reply, len(consumers))
+	delivered := 0
+	for _, c := range consumers {
+		select {
+		case c.inbox <- delivery{payload: payload, replies: replies}:
+			delivered++
+		case <-ctx.Done():
+			// The consumer is connected but not consuming, and its inbox is full.
+			go discardReplies(replies, delivered)
+			return nil, fmt.Errorf("failed to deliver message to consumer: %w", ctx.Err())
+		}
+	}
+
+	var lastErr error = funcie.ErrNoActiveConsumer
+	for received := 0; received < delivered; received++ {
+		select {
+		case r := <-replies:
+			if errors.Is(r.err, funcie.ErrNoActiveConsumer) {
+				continue
+			}
+			if r.err != nil {
+				lastErr = r.err
+				continue
+			}
+
+			go discardReplies(replies, delivered-received-1)
+			return parseResponse(r)
+		case <-ctx.Done():
+			go discardReplies(replies, delivered-received)
+			return nil, fmt.Errorf("failed to get response from consumer: %w", ctx.Err())
+		}
+	}
+
+	return nil, lastErr
+}
+
+func parseResponse(r reply) (*funcie.Response, error) {
+	var response funcie.Response
+	if err := json.Unmarshal(r.payload, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response from consumer: %w", err)
+	}
+
+	if response.Streaming {
+		response.AttachStream(r.stream)
+	}
+
+	return &response, nil
+}
+
+// discardReplies waits for the given number of replies that will not be used, closing any streams they contain.
+func discardReplies(replies <-chan reply, remaining int) {
+	for i := 0; i < remaining; i++ {
+		r := <-replies
+		if r.stream != nil {
+			funcie.CloseOrLog("discarded response stream", r.stream)
+		}
+	}
+}
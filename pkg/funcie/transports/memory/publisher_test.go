@@ -0,0 +1,169 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/messages"
+	"github.com/Kapps/funcie/pkg/funcie/transports/memory"
+	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryPublisher_Publish(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should return no active consumer if nothing is subscribed", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+
+		msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\""))
+		resp, err := publisher.Publish(ctx, msg)
+		require.ErrorIs(t, err, funcie.ErrNoActiveConsumer)
+		require.Nil(t, resp)
+	})
+
+	t.Run("should return the response from the consumer", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+		consumer := startConsumer(t, bus)
+
+		require.NoError(t, consumer.Subscribe(ctx, "app", func(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+			return funcie.NewResponse(message.ID, []byte("\"resp\""), nil), nil
+		}))
+
+		msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\""))
+		resp, err := publisher.Publish(ctx, msg)
+		require.NoError(t, err)
+		require.Equal(t, msg.ID, resp.ID)
+		require.Equal(t, "\"resp\"", string(*resp.Data))
+	})
+
+	t.Run("should stop publishing once unsubscribed", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+		consumer := startConsumer(t, bus)
+
+		require.NoError(t, consumer.Subscribe(ctx, "app", echoHandler))
+		require.NoError(t, consumer.Unsubscribe(ctx, "app"))
+
+		msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\""))
+		_, err := publisher.Publish(ctx, msg)
+		require.ErrorIs(t, err, funcie.ErrNoActiveConsumer)
+	})
+
+	t.Run("should unsubscribe when the handler no longer knows the application", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+		consumer := startConsumer(t, bus)
+
+		calls := 0
+		require.NoError(t, consumer.Subscribe(ctx, "app", func(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+			calls++
+			return nil, nil
+		}))
+
+		for i := 0; i < 2; i++ {
+			msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\""))
+			_, err := publisher.Publish(ctx, msg)
+			require.ErrorIs(t, err, funcie.ErrNoActiveConsumer)
+		}
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("should return errors from the handler", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+		consumer := startConsumer(t, bus)
+
+		require.NoError(t, consumer.Subscribe(ctx, "app", func(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+			return nil, errors.New("handler failed")
+		}))
+
+		msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\""))
+		_, err := publisher.Publish(ctx, msg)
+		require.ErrorContains(t, err, "handler failed")
+	})
+
+	t.Run("should time out if the consumer does not respond", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus, memory.WithTimeout(50*time.Millisecond))
+		consumer := startConsumer(t, bus)
+
+		release := make(chan struct{})
+		defer close(release)
+		require.NoError(t, consumer.Subscribe(ctx, "app", func(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+			<-release
+			return funcie.NewResponse(message.ID, []byte("\"late\""), nil), nil
+		}))
+
+		msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\""))
+		_, err := publisher.Publish(ctx, msg)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("should time out if the consumer is not consuming", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus, memory.WithTimeout(50*time.Millisecond))
+		consumer := memory.NewConsumer(bus, utils.NewClientHandlerRouter())
+		require.NoError(t, consumer.Connect(ctx))
+		require.NoError(t, consumer.Subscribe(ctx, "app", echoHandler))
+
+		msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\""))
+		_, err := publisher.Publish(ctx, msg)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("should match concurrent responses to their requests", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+		consumer := startConsumer(t, bus)
+		require.NoError(t, consumer.Subscribe(ctx, "app", echoHandler))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				payload := []byte(fmt.Sprintf("\"msg%v\"", i))
+				msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, payload)
+
+				resp, err := publisher.Publish(ctx, msg)
+				require.NoError(t, err)
+				require.Equal(t, msg.ID, resp.ID)
+				require.Equal(t, string(payload), string(*resp.Data))
+			}(i)
+		}
+		wg.Wait()
+	})
+
+	t.Run("should relay streamed responses", func(t *testing.T) {
+		bus := memory.NewBus()
+		publisher := memory.NewPublisher(bus)
+		consumer := startConsumer(t, bus)
+
+		chunk := funcie.NewMessage("app", messages.MessageKindResponseChunk, []byte("\"chunk\""))
+		require.NoError(t, consumer.Subscribe(ctx, "app", func(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+			resp := funcie.NewResponse(message.ID, []byte("{}"), nil)
+			resp.AttachStream(&sliceStream{messages: []*funcie.Message{chunk}})
+			return resp, nil
+		}))
+
+		msg := funcie.NewMessage("app", messages.MessageKindForwardRequest, []byte("\"msg\""))
+		resp, err := publisher.Publish(ctx, msg)
+		require.NoError(t, err)
+		require.True(t, resp.Streaming)
+
+		streamed, err := resp.Stream.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, chunk.ID, streamed.ID)
+
+		_, err = resp.Stream.Next(ctx)
+		require.ErrorIs(t, err, io.EOF)
+	})
+}
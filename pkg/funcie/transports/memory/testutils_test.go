@@ -0,0 +1,54 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/funcie/transports/memory"
+	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+// startConsumer creates a connected consumer on the bus that consumes until the test completes.
+func startConsumer(t *testing.T, bus *memory.Bus) funcie.Consumer {
+	consumer := memory.NewConsumer(bus, utils.NewClientHandlerRouter())
+	require.NoError(t, consumer.Connect(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- consumer.Consume(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		require.True(t, errors.Is(<-done, context.Canceled))
+	})
+
+	return consumer
+}
+
+// echoHandler responds with the payload of the message.
+func echoHandler(_ context.Context, message *funcie.Message) (*funcie.Response, error) {
+	return funcie.NewResponse(message.ID, message.Payload, nil), nil
+}
+
+// sliceStream is a ResponseStream that returns the given messages in order.
+type sliceStream struct {
+	messages []*funcie.Message
+}
+
+func (s *sliceStream) Next(_ context.Context) (*funcie.Message, error) {
+	if len(s.messages) == 0 {
+		return nil, io.EOF
+	}
+	next := s.messages[0]
+	s.messages = s.messages[1:]
+	return next, nil
+}
+
+func (s *sliceStream) Close() error {
+	return nil
+}
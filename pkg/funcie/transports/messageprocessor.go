@@ -39,6 +39,9 @@ func (p *messageProcessor) ProcessMessage(ctx context.Context, message *funcie.M
 	case messages.MessageKindDeregister:
 		// Usually comes from host
 		return p.deregister(ctx, message)
+	case messages.MessageKindSetFallbackPolicy:
+		// Usually comes from host
+		return p.setFallbackPolicy(ctx, message)
 	default:
 		return nil, ErrUnknownMessageKind
 	}
@@ -92,3 +95,19 @@ func (p *messageProcessor) deregister(ctx context.Context, message *funcie.Messa
 	}
 	return serializedResponse, nil
 }
+
+func (p *messageProcessor) setFallbackPolicy(ctx context.Context, message *funcie.Message) (*funcie.Response, error) {
+	policyMessage, err := funcie.UnmarshalMessagePayload[messages.SetFallbackPolicyMessage](message)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal payload %v: %w", message.Payload, err)
+	}
+	resp, err := p.handler.SetFallbackPolicy(ctx, *policyMessage)
+	if err != nil {
+		return nil, fmt.Errorf("set fallback policy for application %v: %w", message.Application, err)
+	}
+	serializedResponse, err := funcie.MarshalResponsePayload(resp)
+	if err != nil {
+		return nil, fmt.Errorf("marshal response %v: %w", resp, err)
+	}
+	return serializedResponse, nil
+}
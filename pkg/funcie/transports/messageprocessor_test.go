@@ -75,4 +75,23 @@ func TestMessageProcessor_ProcessMessage(t *testing.T) {
 
 		RequireEqualResponse(t, resp, marshaledResponse)
 	})
+
+	t.Run("set fallback policy message", func(t *testing.T) {
+		t.Parallel()
+
+		payload := messages.NewSetFallbackPolicyPayload(funcie.FallbackPolicyNever)
+		message := funcie.NewMessageWithPayload("app", messages.MessageKindSetFallbackPolicy, *payload)
+		response := funcie.NewResponseWithPayload(message.ID, messages.NewSetFallbackPolicyResponsePayload(), nil)
+		handler.EXPECT().SetFallbackPolicy(ctx, *message).Return(response, nil).Once()
+
+		marshaledMessage, err := funcie.MarshalMessagePayload(*message)
+		require.NoError(t, err)
+		marshaledResponse, err := funcie.MarshalResponsePayload(response)
+		require.NoError(t, err)
+
+		resp, err := processor.ProcessMessage(ctx, marshaledMessage)
+		require.NoError(t, err)
+
+		RequireEqualResponse(t, resp, marshaledResponse)
+	})
 }
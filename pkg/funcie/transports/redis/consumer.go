@@ -3,10 +3,12 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/Kapps/funcie/pkg/funcie"
 	"github.com/Kapps/funcie/pkg/funcie/transports/utils"
 	"github.com/redis/go-redis/v9"
+	"io"
 	"log/slog"
 )
 
@@ -23,6 +25,7 @@ type PubSub interface {
 type ConsumeClient interface {
 	Subscribe(ctx context.Context, channels ...string) PubSub
 	RPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
+	LPush(ctx context.Context, key string, values ...interface{}) *redis.IntCmd
 	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
 }
 
@@ -102,7 +105,7 @@ func (c *Consumer) Consume(ctx context.Context) error {
 				if err != nil {
 					// If we get an error processing the message, we still want to continue our loop.
 					// So we just log the error and keep going.
-					slog.ErrorContext(ctx, "error processing message", err)
+					slog.ErrorContext(ctx, "error processing message", "error", err)
 				}
 			}(msg)
 		}
@@ -125,7 +128,7 @@ func (c *Consumer) processMessage(ctx context.Context, msg *redis.Message) error
 		unsubErr := c.Unsubscribe(ctx, message.Application)
 		if unsubErr != nil {
 			// An error unsubscribing isn't the end of the world. We can still continue and still want to return the original error.
-			slog.ErrorContext(ctx, "error unsubscribing from channel", err, "channel", msg.Channel)
+			slog.ErrorContext(ctx, "error unsubscribing from channel", "error", unsubErr, "channel", msg.Channel)
 		}
 	}
 	if err != nil {
@@ -143,6 +146,46 @@ func (c *Consumer) processMessage(ctx context.Context, msg *redis.Message) error
 		return fmt.Errorf("error pushing response to queue: %w", err)
 	}
 
+	if response.Stream != nil {
+		if err := c.relayStream(ctx, message.ID, response.Stream); err != nil {
+			return fmt.Errorf("error relaying stream for response %v: %w", response.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// relayStream pushes each message in the stream to the stream key for the message, followed by an end of stream marker.
+// Messages are pushed to the head of the list so that the publisher receives them in order when popping from the tail.
+func (c *Consumer) relayStream(ctx context.Context, messageId string, stream funcie.ResponseStream) error {
+	defer funcie.CloseOrLog(fmt.Sprintf("response stream for message %v", messageId), stream)
+
+	streamKey := GetStreamKeyForMessage(c.baseChannelName, messageId)
+	for {
+		streamed, err := stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// Still end the stream so the publisher does not wait for messages that will never arrive.
+			slog.ErrorContext(ctx, "error reading response stream", "error", err, "message", messageId)
+			break
+		}
+
+		data, err := json.Marshal(streamed)
+		if err != nil {
+			return fmt.Errorf("marshalling streamed message: %w", err)
+		}
+
+		if err := c.redisClient.LPush(ctx, streamKey, string(data)).Err(); err != nil {
+			return fmt.Errorf("pushing streamed message to queue: %w", err)
+		}
+	}
+
+	if err := c.redisClient.LPush(ctx, streamKey, endOfStreamMarker).Err(); err != nil {
+		return fmt.Errorf("pushing end of stream to queue: %w", err)
+	}
+
 	return nil
 }
 
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"github.com/Kapps/funcie/pkg/funcie"
 	"github.com/redis/go-redis/v9"
+	"io"
 	"log/slog"
 	"time"
 )
 
 var ttl = time.Minute * 5
 
+// endOfStreamMarker is pushed to the stream key of a message once all messages of a streamed response have been pushed.
+const endOfStreamMarker = "funcie:end-of-stream"
+
 type PublishClient interface {
 	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
 	BRPop(ctx context.Context, timeout time.Duration, keys ...string) *redis.StringSliceCmd
@@ -81,5 +85,50 @@ func (p *redisPublisher) Publish(ctx context.Context, message *funcie.Message) (
 		return nil, fmt.Errorf("failed to unmarshal response from consumer: %w", err)
 	}
 
+	if response.Streaming {
+		response.AttachStream(&redisResponseStream{
+			redisClient: p.redisClient,
+			streamKey:   GetStreamKeyForMessage(p.baseChannelName, message.ID),
+		})
+	}
+
 	return &response, nil
 }
+
+// redisResponseStream is a ResponseStream that pops the messages pushed by the consumer for a streamed response.
+type redisResponseStream struct {
+	redisClient PublishClient
+	streamKey   string
+	done        bool
+}
+
+func (s *redisResponseStream) Next(ctx context.Context) (*funcie.Message, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+
+	resp, err := s.redisClient.BRPop(ctx, ttl, s.streamKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get streamed message from consumer: %w", err)
+	}
+
+	if len(resp) != 2 {
+		panic(fmt.Sprintf("expected response to be a list of two items, got %d", len(resp)))
+	}
+
+	if resp[1] == endOfStreamMarker {
+		s.done = true
+		return nil, io.EOF
+	}
+
+	var message funcie.Message
+	if err := json.Unmarshal([]byte(resp[1]), &message); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal streamed message from consumer: %w", err)
+	}
+
+	return &message, nil
+}
+
+func (s *redisResponseStream) Close() error {
+	return nil
+}
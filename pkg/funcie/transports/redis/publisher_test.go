@@ -10,6 +10,7 @@ import (
 	"github.com/go-faker/faker/v4"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
+	"io"
 	"testing"
 	"time"
 )
@@ -49,4 +50,51 @@ func TestRedisPublisher_Publish(t *testing.T) {
 
 		require.Equal(t, response, resp)
 	})
+
+	t.Run("should attach a stream for streamed responses", func(t *testing.T) {
+		t.Parallel()
+
+		message := funcie.NewMessage(appId, messages.MessageKindForwardRequest, []byte("\"hello\""))
+		serializedMessage, err := json.Marshal(message)
+		require.NoError(t, err)
+
+		response := funcie.NewResponse(message.ID, []byte("{}"), nil)
+		response.Streaming = true
+		serializedResponse, err := json.Marshal(response)
+		require.NoError(t, err)
+
+		chunk := funcie.NewMessage(appId, messages.MessageKindResponseChunk, []byte("{}"))
+		serializedChunk, err := json.Marshal(chunk)
+		require.NoError(t, err)
+
+		publishResult := redis.NewIntCmd(ctx)
+		publishResult.SetVal(1)
+		redisClient.On("Publish", ctx, channel, serializedMessage).Return(publishResult)
+
+		responseKey := GetResponseKeyForMessage(baseChannelName, message.ID)
+		popResult := redis.NewStringSliceCmd(ctx)
+		popResult.SetVal([]string{responseKey, string(serializedResponse)})
+		redisClient.EXPECT().BRPop(ctx, time.Minute*5, responseKey).Return(popResult)
+
+		streamKey := GetStreamKeyForMessage(baseChannelName, message.ID)
+		chunkResult := redis.NewStringSliceCmd(ctx)
+		chunkResult.SetVal([]string{streamKey, string(serializedChunk)})
+		endResult := redis.NewStringSliceCmd(ctx)
+		endResult.SetVal([]string{streamKey, "funcie:end-of-stream"})
+		redisClient.EXPECT().BRPop(ctx, time.Minute*5, streamKey).Return(chunkResult).Once()
+		redisClient.EXPECT().BRPop(ctx, time.Minute*5, streamKey).Return(endResult).Once()
+
+		resp, err := publisher.Publish(ctx, message)
+		require.NoError(t, err)
+		require.True(t, resp.Streaming)
+		require.NotNil(t, resp.Stream)
+
+		streamed, err := resp.Stream.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, chunk, streamed)
+
+		_, err = resp.Stream.Next(ctx)
+		require.ErrorIs(t, err, io.EOF)
+		require.NoError(t, resp.Stream.Close())
+	})
 }
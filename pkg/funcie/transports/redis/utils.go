@@ -15,6 +15,11 @@ func GetResponseKeyForMessage(baseChannelName string, messageId string) string {
 	return fmt.Sprintf("%v:resp:%v", baseChannelName, messageId)
 }
 
+// GetStreamKeyForMessage returns the Redis key for the messages streamed after the response of a message.
+func GetStreamKeyForMessage(baseChannelName string, messageId string) string {
+	return fmt.Sprintf("%v:stream", GetResponseKeyForMessage(baseChannelName, messageId))
+}
+
 // GetChannelNameForApplication returns the Redis channel name for the given application ID.
 func GetChannelNameForApplication(baseChannelName string, applicationId string) string {
 	if applicationId == "" {
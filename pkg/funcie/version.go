@@ -0,0 +1,11 @@
+package funcie
+
+import (
+	"github.com/Kapps/funcie"
+)
+
+// Version returns the funcie release version that this package was built from, such as "0.6.10".
+// This is read from the VERSION file at the root of the repository.
+func Version() string {
+	return funcie.Version()
+}
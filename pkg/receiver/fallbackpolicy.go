@@ -0,0 +1,35 @@
+package receiver
+
+import (
+	"context"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"sync"
+)
+
+type memoryFallbackPolicyStore struct {
+	policies sync.Map
+}
+
+// NewMemoryFallbackPolicyStore creates a new in-memory store of fallback policy overrides.
+// This is only useful when the client and server bastions run in the same process.
+func NewMemoryFallbackPolicyStore() funcie.FallbackPolicyStore {
+	return &memoryFallbackPolicyStore{}
+}
+
+func (s *memoryFallbackPolicyStore) SetFallbackPolicy(_ context.Context, applicationName string, policy funcie.FallbackPolicy) error {
+	if policy == "" {
+		s.policies.Delete(applicationName)
+		return nil
+	}
+
+	s.policies.Store(applicationName, policy)
+	return nil
+}
+
+func (s *memoryFallbackPolicyStore) GetFallbackPolicy(_ context.Context, applicationName string) (funcie.FallbackPolicy, error) {
+	policy, ok := s.policies.Load(applicationName)
+	if !ok {
+		return "", nil
+	}
+	return policy.(funcie.FallbackPolicy), nil
+}
@@ -0,0 +1,38 @@
+package receiver_test
+
+import (
+	"context"
+	"github.com/Kapps/funcie/pkg/funcie"
+	. "github.com/Kapps/funcie/pkg/receiver"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMemoryFallbackPolicyStore_Integration(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryFallbackPolicyStore()
+
+	t.Run("loading with no override", func(t *testing.T) {
+		policy, err := store.GetFallbackPolicy(ctx, "test")
+		require.NoError(t, err)
+		require.Empty(t, policy)
+	})
+
+	t.Run("setting an override", func(t *testing.T) {
+		err := store.SetFallbackPolicy(ctx, "test", funcie.FallbackPolicyNever)
+		require.NoError(t, err)
+
+		policy, err := store.GetFallbackPolicy(ctx, "test")
+		require.NoError(t, err)
+		require.Equal(t, funcie.FallbackPolicyNever, policy)
+	})
+
+	t.Run("removing an override", func(t *testing.T) {
+		err := store.SetFallbackPolicy(ctx, "test", "")
+		require.NoError(t, err)
+
+		policy, err := store.GetFallbackPolicy(ctx, "test")
+		require.NoError(t, err)
+		require.Empty(t, policy)
+	})
+}
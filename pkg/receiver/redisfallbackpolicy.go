@@ -0,0 +1,61 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"github.com/Kapps/funcie/pkg/funcie"
+)
+
+type redisFallbackPolicyStore struct {
+	redisClient RedisClient
+}
+
+var fallbackPolicyKeyBase = "funcie:fallback"
+
+// NewRedisFallbackPolicyStore creates a new Redis-backed store of fallback policy overrides.
+// The store is shared between the client bastion, which sets overrides, and the server bastion, which reads them.
+func NewRedisFallbackPolicyStore(redisClient RedisClient) funcie.FallbackPolicyStore {
+	return &redisFallbackPolicyStore{redisClient: redisClient}
+}
+
+func (r *redisFallbackPolicyStore) SetFallbackPolicy(ctx context.Context, applicationName string, policy funcie.FallbackPolicy) error {
+	key := getFallbackPolicyKey(applicationName)
+
+	if policy == "" {
+		if err := r.redisClient.Del(ctx, key).Err(); err != nil {
+			return fmt.Errorf("remove fallback policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := r.redisClient.HSet(ctx, key, "policy", string(policy)).Err(); err != nil {
+		return fmt.Errorf("set fallback policy: %w", err)
+	}
+
+	return nil
+}
+
+func (r *redisFallbackPolicyStore) GetFallbackPolicy(ctx context.Context, applicationName string) (funcie.FallbackPolicy, error) {
+	key := getFallbackPolicyKey(applicationName)
+
+	res := r.redisClient.HGetAll(ctx, key)
+	if err := res.Err(); err != nil {
+		return "", fmt.Errorf("getting fallback policy with key %v: %w", key, err)
+	}
+
+	value, ok := res.Val()["policy"]
+	if !ok {
+		return "", nil
+	}
+
+	policy, err := funcie.ParseFallbackPolicy(value)
+	if err != nil {
+		return "", fmt.Errorf("parsing fallback policy with key %v: %w", key, err)
+	}
+
+	return policy, nil
+}
+
+func getFallbackPolicyKey(applicationName string) string {
+	return fmt.Sprintf("%s:%s", fallbackPolicyKeyBase, applicationName)
+}
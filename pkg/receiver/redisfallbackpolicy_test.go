@@ -0,0 +1,66 @@
+package receiver_test
+
+import (
+	"context"
+	"github.com/Kapps/funcie/pkg/funcie"
+	"github.com/Kapps/funcie/pkg/receiver"
+	"github.com/Kapps/funcie/pkg/receiver/mocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRedisFallbackPolicyStore(t *testing.T) {
+	t.Parallel()
+
+	redisClient := mocks.NewRedisClient(t)
+	store := receiver.NewRedisFallbackPolicyStore(redisClient)
+	ctx := context.Background()
+
+	t.Run("should set a fallback policy", func(t *testing.T) {
+		redisClient.EXPECT().HSet(ctx, "funcie:fallback:app1", "policy", "on-error").
+			Return(redis.NewIntCmd(ctx, 1)).Once()
+
+		err := store.SetFallbackPolicy(ctx, "app1", funcie.FallbackPolicyOnError)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("should remove the fallback policy when set to empty", func(t *testing.T) {
+		redisClient.EXPECT().Del(ctx, "funcie:fallback:app1").
+			Return(redis.NewIntCmd(ctx, 1)).Once()
+
+		err := store.SetFallbackPolicy(ctx, "app1", "")
+
+		require.NoError(t, err)
+	})
+
+	t.Run("should get a fallback policy", func(t *testing.T) {
+		redisClient.EXPECT().HGetAll(ctx, "funcie:fallback:app1").
+			Return(redis.NewMapStringStringResult(map[string]string{"policy": "never"}, nil)).Once()
+
+		policy, err := store.GetFallbackPolicy(ctx, "app1")
+
+		require.NoError(t, err)
+		require.Equal(t, funcie.FallbackPolicyNever, policy)
+	})
+
+	t.Run("should return an empty policy if there is no override", func(t *testing.T) {
+		redisClient.EXPECT().HGetAll(ctx, "funcie:fallback:app1").
+			Return(redis.NewMapStringStringResult(map[string]string{}, nil)).Once()
+
+		policy, err := store.GetFallbackPolicy(ctx, "app1")
+
+		require.NoError(t, err)
+		require.Empty(t, policy)
+	})
+
+	t.Run("should return an error for an invalid policy", func(t *testing.T) {
+		redisClient.EXPECT().HGetAll(ctx, "funcie:fallback:app1").
+			Return(redis.NewMapStringStringResult(map[string]string{"policy": "sometimes"}, nil)).Once()
+
+		_, err := store.GetFallbackPolicy(ctx, "app1")
+
+		require.ErrorContains(t, err, "invalid fallback policy")
+	})
+}
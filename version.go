@@ -0,0 +1,15 @@
+// Package funcie holds the release version of the repository, which every funcie component reports.
+package funcie
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed VERSION
+var version string
+
+// Version returns the release version in the VERSION file, such as "0.6.10".
+func Version() string {
+	return strings.TrimSpace(version)
+}